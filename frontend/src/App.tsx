@@ -4,6 +4,7 @@ import { useTranslation } from 'react-i18next'
 import { AppShell } from '@/components/layout/AppShell'
 import { useWebSocket } from '@/hooks/useWebSocket'
 import { useServerInfo } from '@/hooks/useServerInfo'
+import { useMetricsHistory } from '@/hooks/useMetricsHistory'
 import { useNcduScan } from '@/hooks/useNcduScan'
 import { useAuthSession } from '@/hooks/useAuthSession'
 import { useStore } from '@/store'
@@ -17,6 +18,7 @@ import FirewallPage from '@/pages/FirewallPage'
 import PackagesPage from '@/pages/PackagesPage'
 import LoginPage from '@/pages/LoginPage'
 import AdminPage from '@/pages/AdminPage'
+import TwoFactorSetupPage from '@/pages/TwoFactorSetupPage'
 
 function AuthenticatedRoutes() {
   const { fetchStatus } = useNcduScan()
@@ -24,6 +26,7 @@ function AuthenticatedRoutes() {
 
   useWebSocket(onNcduReady)
   useServerInfo()
+  useMetricsHistory()
 
   return (
     <AppShell>
@@ -36,6 +39,7 @@ function AuthenticatedRoutes() {
         <Route path="/packages" element={<PackagesPage />} />
         <Route path="/settings" element={<SettingsPage />} />
         <Route path="/admin" element={<AdminPage />} />
+        <Route path="/2fa/setup" element={<TwoFactorSetupPage />} />
         <Route path="/login" element={<Navigate to="/" replace />} />
         <Route path="*" element={<DashboardPage />} />
       </Routes>
@@ -49,6 +53,7 @@ function AppRoutes() {
   const fontSize = useStore((s) => s.fontSize)
   const authUser = useStore((s) => s.authUser)
   const authLoading = useStore((s) => s.authLoading)
+  const enrollmentRequired = useStore((s) => s.twoFactorEnrollmentRequired)
   const { i18n } = useTranslation()
 
   useAuthSession()
@@ -89,6 +94,17 @@ function AppRoutes() {
     )
   }
 
+  // Until an admin covered by the 2FA policy enrolls, the server rejects
+  // everything outside /api/auth/, so the enrollment screen is all there is.
+  if (enrollmentRequired) {
+    return (
+      <Routes>
+        <Route path="/2fa/setup" element={<TwoFactorSetupPage />} />
+        <Route path="*" element={<Navigate to="/2fa/setup" replace />} />
+      </Routes>
+    )
+  }
+
   return <AuthenticatedRoutes />
 }
 
@@ -0,0 +1,100 @@
+import { useCallback, useEffect, useState } from 'react'
+import { useTranslation } from 'react-i18next'
+import { Card } from '@/components/ui/Card'
+import { CardTitle } from '@/components/ui/CardTitle'
+import { Button } from '@/components/ui/Button'
+import { useToast } from '@/hooks/useToast'
+import { useStore } from '@/store'
+import { errorMessage, readAPIError } from '@/lib/httpError'
+import type { SessionInfo } from '@/types/api'
+
+// SessionsCard lists the signed-in sessions the caller may see (their own, or
+// everyone's with users:manage) and revokes them.
+export function SessionsCard() {
+  const { t } = useTranslation()
+  const authUser = useStore((s) => s.authUser)
+  const setAuthUser = useStore((s) => s.setAuthUser)
+  const { showError, showSuccess } = useToast()
+  const [sessions, setSessions] = useState<SessionInfo[]>([])
+  const [loading, setLoading] = useState(true)
+  const [revoking, setRevoking] = useState('')
+
+  const loadSessions = useCallback(async () => {
+    const res = await fetch('/api/sessions')
+    if (!res.ok) {
+      throw new Error(await readAPIError(res, t('sessions.loadError')))
+    }
+    const data = await res.json() as { sessions?: SessionInfo[] }
+    setSessions(data.sessions ?? [])
+  }, [t])
+
+  useEffect(() => {
+    loadSessions()
+      .catch((err) => showError(errorMessage(err, t('sessions.loadError'))))
+      .finally(() => setLoading(false))
+  }, [loadSessions, showError, t])
+
+  async function handleRevoke(session: SessionInfo) {
+    if (revoking) return
+    setRevoking(session.id)
+    try {
+      const res = await fetch(`/api/sessions/${encodeURIComponent(session.id)}`, { method: 'DELETE' })
+      if (!res.ok) {
+        throw new Error(await readAPIError(res, t('sessions.revokeError')))
+      }
+      if (session.current) {
+        // Revoking this browser's session is a sign-out; with no user the
+        // app routes to the login page.
+        setAuthUser(null)
+        return
+      }
+      showSuccess(t('sessions.revoked'))
+      await loadSessions()
+    } catch (err) {
+      showError(errorMessage(err, t('sessions.revokeError')))
+    } finally {
+      setRevoking('')
+    }
+  }
+
+  const showOwners = sessions.some((s) => s.user_id !== authUser?.id)
+
+  return (
+    <Card>
+      <CardTitle>{t('sessions.title')}</CardTitle>
+      <div className="space-y-2">
+        {sessions.map((session) => (
+          <div key={session.id} className="border-b border-border-base pb-2 text-xs font-mono">
+            <div className="flex items-center justify-between gap-2">
+              <div className="flex items-center gap-2 min-w-0">
+                {showOwners && <span className="text-text-primary">{session.username}</span>}
+                <span className="text-text-primary">{session.ip || '—'}</span>
+                {session.current && (
+                  <span className="px-1.5 py-0.5 rounded bg-accent-green text-bg-primary text-[10px] font-semibold">
+                    {t('sessions.current')}
+                  </span>
+                )}
+              </div>
+              <Button
+                variant="danger"
+                onClick={() => void handleRevoke(session)}
+                disabled={revoking !== ''}
+              >
+                {t('sessions.revoke')}
+              </Button>
+            </div>
+            <div className="text-text-muted mt-1 truncate" title={session.user_agent}>
+              {session.user_agent || t('sessions.unknownAgent')}
+            </div>
+            <div className="text-text-muted mt-1">
+              {t('sessions.lastSeen', { time: new Date(session.last_seen_at).toLocaleString() })}
+            </div>
+          </div>
+        ))}
+        {!loading && sessions.length === 0 && (
+          <p className="text-sm text-text-secondary">{t('sessions.empty')}</p>
+        )}
+      </div>
+    </Card>
+  )
+}
@@ -6,6 +6,7 @@ import { ProgressBar } from '@/components/ui/ProgressBar'
 import { getThresholdColor } from '@/lib/thresholdColor'
 import { formatBytes } from '@/lib/formatBytes'
 import { formatBps } from '@/lib/formatBps'
+import { formatHours } from '@/lib/formatHours'
 
 interface DiskCardProps {
   disk: DiskMetrics
@@ -16,6 +17,7 @@ function areEqual(prev: DiskCardProps, next: DiskCardProps): boolean {
   return (
     prev.disk.percent    === next.disk.percent    &&
     prev.disk.used_bytes === next.disk.used_bytes &&
+    prev.disk.forecast?.hours_to_full === next.disk.forecast?.hours_to_full &&
     prev.io?.read_bps    === next.io?.read_bps    &&
     prev.io?.write_bps   === next.io?.write_bps
   )
@@ -24,6 +26,7 @@ function areEqual(prev: DiskCardProps, next: DiskCardProps): boolean {
 export const DiskCard = memo(function DiskCard({ disk, io }: DiskCardProps) {
   const pct = Math.round(disk.percent)
   const { t } = useTranslation()
+  const hoursToFull = disk.forecast?.hours_to_full
 
   return (
     <Card>
@@ -42,6 +45,14 @@ export const DiskCard = memo(function DiskCard({ disk, io }: DiskCardProps) {
         <span>{t('disk.free')}: {formatBytes(disk.free_bytes)}</span>
         <span>{t('disk.total')}: {formatBytes(disk.total_bytes)}</span>
       </div>
+      {hoursToFull != null && (
+        <div
+          className={`text-[10px] font-mono mt-1.5 ${hoursToFull <= 24 ? 'text-accent-red' : 'text-text-secondary'}`}
+          title={disk.forecast?.full_at}
+        >
+          {t('disk.forecastFull', { time: formatHours(hoursToFull) })}
+        </div>
+      )}
       {io && (
         <div className="flex justify-between text-[10px] font-mono mt-1.5">
           <span>R: <span className="text-accent-blue">{formatBps(io.read_bps)}</span></span>
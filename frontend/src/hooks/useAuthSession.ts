@@ -5,6 +5,7 @@ import type { AuthMeResponse } from '@/types/api'
 export function useAuthSession() {
   const setAuthUser = useStore((s) => s.setAuthUser)
   const setAuthLoading = useStore((s) => s.setAuthLoading)
+  const setEnrollmentRequired = useStore((s) => s.setTwoFactorEnrollmentRequired)
 
   useEffect(() => {
     let cancelled = false
@@ -19,6 +20,7 @@ export function useAuthSession() {
       .then((data) => {
         if (cancelled) return
         if (data?.user) {
+          setEnrollmentRequired(data.two_factor_enrollment_required === true)
           setAuthUser(data.user)
         } else if (data?.auth_disabled) {
           setAuthUser({ id: 0, username: 'public', role: 'viewer' })
@@ -39,5 +41,5 @@ export function useAuthSession() {
     return () => {
       cancelled = true
     }
-  }, [setAuthLoading, setAuthUser])
+  }, [setAuthLoading, setAuthUser, setEnrollmentRequired])
 }
@@ -0,0 +1,33 @@
+import { useEffect } from 'react'
+import { HISTORY_LENGTH, useStore } from '@/store'
+import { CHART_HISTORY_SERIES, historyToCharts } from '@/lib/metricsHistory'
+import type { MetricsHistory } from '@/types/metrics'
+
+// useMetricsHistory backfills the dashboard charts from the stored metrics
+// history once on mount, so they do not start empty after a reload.
+export function useMetricsHistory() {
+  const backfillHistory = useStore((s) => s.backfillHistory)
+
+  useEffect(() => {
+    const stepSec = Math.max(1, Math.round(useStore.getState().updateIntervalMs / 1000))
+    const to = Math.floor(Date.now() / 1000)
+    const params = new URLSearchParams({
+      from: String(to - stepSec * HISTORY_LENGTH),
+      to: String(to),
+      step: String(stepSec),
+      series: CHART_HISTORY_SERIES.join(','),
+    })
+    const controller = new AbortController()
+
+    fetch(`/api/metrics/history?${params}`, { signal: controller.signal })
+      .then((r) => {
+        if (!r.ok) throw new Error(`HTTP ${r.status}`)
+        return r.json() as Promise<MetricsHistory>
+      })
+      .then((history) => backfillHistory(historyToCharts(history, HISTORY_LENGTH)))
+      .catch((err) => {
+        if (!controller.signal.aborted) console.error('Failed to load metrics history:', err)
+      })
+    return () => controller.abort()
+  }, [backfillHistory])
+}
@@ -21,6 +21,8 @@ describe('alerts helpers', () => {
 
   it('formatAlertStateLabel normalizes labels', () => {
     expect(formatAlertStateLabel('critical')).toBe('CRITICAL')
+    expect(formatAlertStateLabel('flapping')).toBe('FLAPPING')
+    expect(formatAlertStateLabel('digest')).toBe('DIGEST')
     expect(formatAlertStateLabel('none')).toBe('NORMAL')
   })
 })
@@ -29,6 +29,8 @@ export function formatAlertStateLabel(state: string): string {
   if (normalized === 'critical') return 'CRITICAL'
   if (normalized === 'warning') return 'WARNING'
   if (normalized === 'recovery') return 'RECOVERY'
+  if (normalized === 'flapping') return 'FLAPPING'
   if (normalized === 'test') return 'TEST'
+  if (normalized === 'digest') return 'DIGEST'
   return 'NORMAL'
 }
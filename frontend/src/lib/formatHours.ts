@@ -0,0 +1,6 @@
+export function formatHours(h: number | null | undefined): string {
+  if (h == null) return '—'
+  if (h < 1) return Math.max(1, Math.round(h * 60)) + 'm'
+  if (h < 48) return Math.round(h) + 'h'
+  return Math.round(h / 24) + 'd'
+}
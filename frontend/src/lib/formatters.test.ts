@@ -1,6 +1,7 @@
 import { describe, expect, it } from 'vitest'
 import { formatBytes } from './formatBytes'
 import { formatBps } from './formatBps'
+import { formatHours } from './formatHours'
 
 describe('formatBytes', () => {
   it('returns em dash for nullish values', () => {
@@ -26,3 +27,15 @@ describe('formatBps', () => {
     expect(formatBps(1024)).toBe('1.0 KB/s')
   })
 })
+
+describe('formatHours', () => {
+  it('returns em dash for nullish values', () => {
+    expect(formatHours(undefined)).toBe('—')
+  })
+
+  it('formats minutes, hours, and days', () => {
+    expect(formatHours(0.25)).toBe('15m')
+    expect(formatHours(19.6)).toBe('20h')
+    expect(formatHours(24 * 7)).toBe('7d')
+  })
+})
@@ -1,10 +1,18 @@
+import { useStore } from '@/store'
+
 interface ErrorPayload {
   error?: string
+  two_factor_enrollment_required?: boolean
 }
 
 export async function readAPIError(response: Response, fallback: string): Promise<string> {
   try {
     const payload = await response.json() as ErrorPayload
+    // The admin 2FA policy can switch on mid-session; the server then
+    // rejects everything until this user enrolls, so route them there.
+    if (payload.two_factor_enrollment_required === true) {
+      useStore.getState().setTwoFactorEnrollmentRequired(true)
+    }
     if (typeof payload.error === 'string' && payload.error.trim() !== '') {
       return payload.error
     }
@@ -0,0 +1,31 @@
+import { describe, expect, it } from 'vitest'
+import { historyToCharts } from '@/lib/metricsHistory'
+
+describe('historyToCharts', () => {
+  const point = (t: string, avg: number) => ({ t, avg, min: avg, max: avg })
+
+  it('sums labelled series per bucket and orders buckets oldest first', () => {
+    const charts = historyToCharts({
+      from: '', to: '', step_sec: 2, tier: 'raw',
+      series: [
+        { name: 'cpu.percent', points: [point('2026-03-01T00:00:02Z', 20), point('2026-03-01T00:00:00Z', 10)] },
+        { name: 'net.recv_bps:eth0', points: [point('2026-03-01T00:00:00Z', 100), point('2026-03-01T00:00:02Z', 200)] },
+        { name: 'net.recv_bps:eth1', points: [point('2026-03-01T00:00:02Z', 5)] },
+        { name: 'load.1', points: [point('2026-03-01T00:00:04Z', 1)] },
+      ],
+    }, 60)
+
+    expect(charts['cpu.percent']).toEqual([10, 20])
+    expect(charts['net.recv_bps']).toEqual([100, 205])
+    expect(charts['swap.percent']).toEqual([0, 0])
+  })
+
+  it('keeps only the newest buckets', () => {
+    const charts = historyToCharts({
+      from: '', to: '', step_sec: 2, tier: 'raw',
+      series: [{ name: 'memory.percent', points: [point('2026-03-01T00:00:00Z', 1), point('2026-03-01T00:00:02Z', 2), point('2026-03-01T00:00:04Z', 3)] }],
+    }, 2)
+
+    expect(charts['memory.percent']).toEqual([2, 3])
+  })
+})
@@ -0,0 +1,46 @@
+import type { MetricsHistory } from '@/types/metrics'
+
+// Series the dashboard charts are backfilled from. Per-interface and
+// per-device series are requested by base name and summed like the live
+// totals.
+export const CHART_HISTORY_SERIES = [
+  'cpu.percent',
+  'memory.percent',
+  'swap.percent',
+  'net.recv_bps',
+  'net.sent_bps',
+  'diskio.read_bps',
+  'diskio.write_bps',
+] as const
+
+export type ChartHistorySeries = typeof CHART_HISTORY_SERIES[number]
+
+export type ChartHistories = Record<ChartHistorySeries, number[]>
+
+// historyToCharts turns a /api/metrics/history result into one value per
+// bucket for each chart, oldest first, keeping the newest `length` buckets.
+// A bucket missing from a series counts as 0.
+export function historyToCharts(history: MetricsHistory, length: number): ChartHistories {
+  const buckets = new Set<string>()
+  const sums = new Map<ChartHistorySeries, Map<string, number>>()
+  for (const s of history.series ?? []) {
+    const base = s.name.split(':')[0] as ChartHistorySeries
+    if (!CHART_HISTORY_SERIES.includes(base)) continue
+    const byBucket = sums.get(base) ?? new Map<string, number>()
+    sums.set(base, byBucket)
+    for (const p of s.points) {
+      buckets.add(p.t)
+      byBucket.set(p.t, (byBucket.get(p.t) ?? 0) + p.avg)
+    }
+  }
+
+  const times = [...buckets]
+    .sort((a, b) => Date.parse(a) - Date.parse(b))
+    .slice(-length)
+  const out = {} as ChartHistories
+  for (const name of CHART_HISTORY_SERIES) {
+    const byBucket = sums.get(name)
+    out[name] = times.map((t) => byBucket?.get(t) ?? 0)
+  }
+  return out
+}
@@ -235,6 +235,7 @@ export default function AdminPage() {
             className="w-full bg-bg-primary border border-border-base rounded-base px-3 py-1.5 text-xs font-mono text-text-primary focus:outline-none focus:border-accent-blue"
           >
             <option value="viewer">viewer</option>
+            <option value="operator">operator</option>
             <option value="admin">admin</option>
           </select>
         </div>
@@ -260,6 +261,7 @@ export default function AdminPage() {
                   className="w-full bg-bg-primary border border-border-base rounded-base px-3 py-1.5 text-xs font-mono text-text-primary focus:outline-none focus:border-accent-blue"
                 >
                   <option value="viewer">viewer</option>
+                  <option value="operator">operator</option>
                   <option value="admin">admin</option>
                 </select>
                 <input
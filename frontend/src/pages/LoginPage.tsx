@@ -6,7 +6,7 @@ import { Card } from '@/components/ui/Card'
 import { Button } from '@/components/ui/Button'
 import { Spinner } from '@/components/ui/Spinner'
 import { useStore } from '@/store'
-import type { AuthUser } from '@/types/api'
+import type { LoginResponse } from '@/types/api'
 
 export default function LoginPage() {
   const { t } = useTranslation()
@@ -14,11 +14,14 @@ export default function LoginPage() {
   const authUser = useStore((s) => s.authUser)
   const authLoading = useStore((s) => s.authLoading)
   const setAuthUser = useStore((s) => s.setAuthUser)
+  const setEnrollmentRequired = useStore((s) => s.setTwoFactorEnrollmentRequired)
 
   const [username, setUsername] = useState('admin')
   const [password, setPassword] = useState('')
   const [submitting, setSubmitting] = useState(false)
   const [error, setError] = useState('')
+  const [challenge, setChallenge] = useState('')
+  const [code, setCode] = useState('')
 
   if (!authLoading && authUser) {
     return <Navigate to="/" replace />
@@ -32,20 +35,34 @@ export default function LoginPage() {
     setError('')
 
     try {
-      const res = await fetch('/api/auth/login', {
-        method: 'POST',
-        headers: { 'Content-Type': 'application/json' },
-        body: JSON.stringify({ username, password }),
-      })
+      // With 2FA enabled the password step returns a challenge that the code
+      // is posted against.
+      const res = challenge
+        ? await fetch('/api/auth/login/2fa', {
+          method: 'POST',
+          headers: { 'Content-Type': 'application/json' },
+          body: JSON.stringify({ challenge, code }),
+        })
+        : await fetch('/api/auth/login', {
+          method: 'POST',
+          headers: { 'Content-Type': 'application/json' },
+          body: JSON.stringify({ username, password }),
+        })
 
-      const data = await res.json().catch(() => ({})) as { user?: AuthUser; error?: string }
+      const data = await res.json().catch(() => ({})) as LoginResponse
+      if (res.ok && data.two_factor_required && data.challenge) {
+        setChallenge(data.challenge)
+        setCode('')
+        return
+      }
       if (!res.ok || !data.user) {
         setError(data.error || t('auth.invalidCredentials'))
         return
       }
 
+      setEnrollmentRequired(data.two_factor_enrollment_required === true)
       setAuthUser(data.user)
-      navigate('/', { replace: true })
+      navigate(data.two_factor_enrollment_required ? '/2fa/setup' : '/', { replace: true })
     } catch {
       setError(t('auth.loginFailed'))
     } finally {
@@ -58,34 +75,66 @@ export default function LoginPage() {
       <Card className="w-full max-w-sm space-y-4">
         <h1 className="text-lg font-semibold font-mono text-text-primary">{t('auth.signIn')}</h1>
         <form className="space-y-3" onSubmit={handleSubmit}>
-          <div>
-            <label className="block text-xs font-mono text-text-secondary mb-1">{t('auth.username')}</label>
-            <input
-              type="text"
-              autoComplete="username"
-              value={username}
-              onChange={(e) => setUsername(e.target.value)}
-              className="w-full bg-bg-primary border border-border-base rounded-base px-3 py-1.5 text-xs font-mono text-text-primary focus:outline-none focus:border-accent-blue"
-            />
-          </div>
+          {challenge ? (
+            <div>
+              <label className="block text-xs font-mono text-text-secondary mb-1">{t('auth.twoFactorCode')}</label>
+              <input
+                type="text"
+                inputMode="numeric"
+                autoComplete="one-time-code"
+                autoFocus
+                value={code}
+                onChange={(e) => setCode(e.target.value)}
+                className="w-full bg-bg-primary border border-border-base rounded-base px-3 py-1.5 text-xs font-mono text-text-primary focus:outline-none focus:border-accent-blue"
+              />
+              <p className="mt-1 text-xs text-text-secondary">{t('auth.twoFactorHint')}</p>
+            </div>
+          ) : (
+            <>
+              <div>
+                <label className="block text-xs font-mono text-text-secondary mb-1">{t('auth.username')}</label>
+                <input
+                  type="text"
+                  autoComplete="username"
+                  value={username}
+                  onChange={(e) => setUsername(e.target.value)}
+                  className="w-full bg-bg-primary border border-border-base rounded-base px-3 py-1.5 text-xs font-mono text-text-primary focus:outline-none focus:border-accent-blue"
+                />
+              </div>
 
-          <div>
-            <label className="block text-xs font-mono text-text-secondary mb-1">{t('auth.password')}</label>
-            <input
-              type="password"
-              autoComplete="current-password"
-              value={password}
-              onChange={(e) => setPassword(e.target.value)}
-              className="w-full bg-bg-primary border border-border-base rounded-base px-3 py-1.5 text-xs font-mono text-text-primary focus:outline-none focus:border-accent-blue"
-            />
-          </div>
+              <div>
+                <label className="block text-xs font-mono text-text-secondary mb-1">{t('auth.password')}</label>
+                <input
+                  type="password"
+                  autoComplete="current-password"
+                  value={password}
+                  onChange={(e) => setPassword(e.target.value)}
+                  className="w-full bg-bg-primary border border-border-base rounded-base px-3 py-1.5 text-xs font-mono text-text-primary focus:outline-none focus:border-accent-blue"
+                />
+              </div>
+            </>
+          )}
 
           {error && <p className="text-xs text-accent-red">{error}</p>}
 
           <Button type="submit" className="w-full justify-center" disabled={submitting}>
             {submitting && <Spinner />}
-            {t('auth.signIn')}
+            {challenge ? t('auth.verify') : t('auth.signIn')}
           </Button>
+          {challenge && (
+            <Button
+              type="button"
+              variant="ghost"
+              className="w-full justify-center"
+              onClick={() => {
+                setChallenge('')
+                setCode('')
+                setError('')
+              }}
+            >
+              {t('auth.backToSignIn')}
+            </Button>
+          )}
         </form>
       </Card>
     </div>
@@ -4,6 +4,7 @@ import { useStore } from '@/store'
 import { Card } from '@/components/ui/Card'
 import { CardTitle } from '@/components/ui/CardTitle'
 import { Button } from '@/components/ui/Button'
+import { SessionsCard } from '@/components/account/SessionsCard'
 import { useToast } from '@/hooks/useToast'
 import { canManageAlerts, normalizeRetryDelays, parseCommaList } from '@/lib/alerts'
 import type { Theme, Language } from '@/store'
@@ -62,6 +63,7 @@ export default function SettingsPage() {
   const [emailsInput, setEmailsInput] = useState('')
   const [chatIDsInput, setChatIDsInput] = useState('')
   const [retryDelaysInput, setRetryDelaysInput] = useState('1,5,15')
+  const [outboxMaxAgeHours, setOutboxMaxAgeHours] = useState('24')
   const [telegramTokenInput, setTelegramTokenInput] = useState('')
   const [gmailAddressInput, setGmailAddressInput] = useState('')
   const [gmailPasswordInput, setGmailPasswordInput] = useState('')
@@ -106,6 +108,7 @@ export default function SettingsPage() {
         setEmailsInput(cfg.recipient_emails.join(', '))
         setChatIDsInput(cfg.telegram_chat_ids.join(', '))
         setRetryDelaysInput(cfg.retry_delays_sec.join(','))
+        setOutboxMaxAgeHours(String(cfg.outbox_max_age_hours))
         setGmailAddressInput(cfg.gmail_address ?? '')
         setRotateTelegramSecret(false)
         setRotateGmailSecret(false)
@@ -224,6 +227,7 @@ export default function SettingsPage() {
       recipient_emails: parseCommaList(emailsInput),
       telegram_chat_ids: parseCommaList(chatIDsInput),
       retry_delays_sec: normalizeRetryDelays(retryDelaysInput),
+      outbox_max_age_hours: Number(outboxMaxAgeHours),
       telegram_bot_token: rotateTelegramSecret ? telegramTokenInput.trim() : '',
       clear_telegram_bot_token: clearTelegramSecret,
       gmail_address: gmailAddressInput.trim(),
@@ -596,6 +600,11 @@ export default function SettingsPage() {
                 <input className="mt-1 w-full bg-bg-primary border border-border-base rounded-base px-2 py-1.5 text-text-primary" value={retryDelaysInput} onChange={(e) => setRetryDelaysInput(e.target.value)} disabled={!manageAlerts} />
               </label>
 
+              <label className="text-xs font-mono text-text-secondary">
+                {t('settings.outboxMaxAgeHours')}
+                <input className="mt-1 w-full bg-bg-primary border border-border-base rounded-base px-2 py-1.5 text-text-primary" value={outboxMaxAgeHours} onChange={(e) => setOutboxMaxAgeHours(e.target.value)} disabled={!manageAlerts} />
+              </label>
+
               <label className="text-xs font-mono text-text-secondary sm:col-span-2">
                 Telegram Bot Token
                 <div className="mt-1 flex flex-wrap items-center gap-2">
@@ -706,6 +715,8 @@ export default function SettingsPage() {
           </div>
         )}
       </Card>
+
+      {serverInfo?.auth_enabled && authUser && authUser.id !== 0 && <SessionsCard />}
     </div>
   )
 }
@@ -0,0 +1,187 @@
+import { useEffect, useState } from 'react'
+import type { FormEvent } from 'react'
+import { useNavigate } from 'react-router-dom'
+import { useTranslation } from 'react-i18next'
+import { Card } from '@/components/ui/Card'
+import { Button } from '@/components/ui/Button'
+import { Spinner } from '@/components/ui/Spinner'
+import { useStore } from '@/store'
+import { errorMessage, readAPIError } from '@/lib/httpError'
+import type { TwoFactorSetup } from '@/types/api'
+
+// TwoFactorSetupPage enrolls the current user in TOTP: it shows the QR code,
+// confirms the first code and then shows the recovery codes exactly once.
+// When the admin policy requires 2FA it is the only page the app renders.
+export default function TwoFactorSetupPage() {
+  const { t } = useTranslation()
+  const navigate = useNavigate()
+  const authUser = useStore((s) => s.authUser)
+  const setAuthUser = useStore((s) => s.setAuthUser)
+  const enrollmentRequired = useStore((s) => s.twoFactorEnrollmentRequired)
+  const setEnrollmentRequired = useStore((s) => s.setTwoFactorEnrollmentRequired)
+
+  const [setup, setSetup] = useState<TwoFactorSetup | null>(null)
+  const [code, setCode] = useState('')
+  const [submitting, setSubmitting] = useState(false)
+  const [error, setError] = useState('')
+  const [recoveryCodes, setRecoveryCodes] = useState<string[]>([])
+  const [copied, setCopied] = useState(false)
+
+  useEffect(() => {
+    let cancelled = false
+
+    fetch('/api/auth/2fa/setup', { method: 'POST' })
+      .then(async (res) => {
+        if (!res.ok) {
+          throw new Error(await readAPIError(res, t('auth.twoFactorSetupStartError')))
+        }
+        return res.json() as Promise<TwoFactorSetup>
+      })
+      .then((data) => {
+        if (!cancelled) setSetup(data)
+      })
+      .catch((err) => {
+        if (!cancelled) setError(errorMessage(err, t('auth.twoFactorSetupStartError')))
+      })
+
+    return () => {
+      cancelled = true
+    }
+  }, [t])
+
+  async function handleConfirm(e: FormEvent<HTMLFormElement>) {
+    e.preventDefault()
+    if (submitting) return
+
+    setSubmitting(true)
+    setError('')
+    try {
+      const res = await fetch('/api/auth/2fa/confirm', {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ code: code.trim() }),
+      })
+      if (!res.ok) {
+        setError(await readAPIError(res, t('auth.twoFactorSetupConfirmError')))
+        return
+      }
+      const data = await res.json() as { recovery_codes: string[] }
+      setRecoveryCodes(data.recovery_codes)
+    } catch {
+      setError(t('auth.twoFactorSetupConfirmError'))
+    } finally {
+      setSubmitting(false)
+    }
+  }
+
+  async function handleCopy() {
+    try {
+      await navigator.clipboard.writeText(recoveryCodes.join('\n'))
+      setCopied(true)
+    } catch {
+      // clipboard unavailable (plain http); the codes stay on screen
+    }
+  }
+
+  function handleDone() {
+    if (authUser) {
+      setAuthUser({ ...authUser, two_factor_enabled: true })
+    }
+    setEnrollmentRequired(false)
+    navigate('/', { replace: true })
+  }
+
+  async function handleLogout() {
+    try {
+      await fetch('/api/auth/logout', { method: 'POST' })
+    } catch {
+      // no-op
+    }
+    setEnrollmentRequired(false)
+    setAuthUser(null)
+    navigate('/login', { replace: true })
+  }
+
+  const card = (
+    <Card className="w-full max-w-sm space-y-4">
+      {recoveryCodes.length > 0 ? (
+        <>
+          <h1 className="text-lg font-semibold font-mono text-text-primary">{t('auth.recoveryCodesTitle')}</h1>
+          <p className="text-xs text-text-secondary">{t('auth.recoveryCodesHint')}</p>
+          <ul className="grid grid-cols-2 gap-1.5 rounded-base border border-border-base bg-bg-primary p-3 text-xs font-mono text-text-primary">
+            {recoveryCodes.map((c) => <li key={c}>{c}</li>)}
+          </ul>
+          <div className="flex items-center gap-2">
+            <Button type="button" variant="ghost" onClick={handleCopy}>{t('auth.recoveryCodesCopy')}</Button>
+            {copied && <span className="text-xs text-accent-green">{t('auth.recoveryCodesCopied')}</span>}
+          </div>
+          <Button type="button" className="w-full justify-center" onClick={handleDone}>
+            {t('auth.recoveryCodesDone')}
+          </Button>
+        </>
+      ) : (
+        <>
+          <h1 className="text-lg font-semibold font-mono text-text-primary">{t('auth.twoFactorSetupTitle')}</h1>
+          {enrollmentRequired && (
+            <p className="text-xs text-accent-yellow">{t('auth.twoFactorSetupRequired')}</p>
+          )}
+          {!setup && !error && (
+            <div className="flex justify-center py-6"><Spinner className="w-6 h-6" /></div>
+          )}
+          {setup && (
+            <form className="space-y-3" onSubmit={handleConfirm}>
+              <p className="text-xs text-text-secondary">{t('auth.twoFactorSetupScan')}</p>
+              {setup.qr_svg && (
+                <img
+                  src={`data:image/svg+xml;charset=utf-8,${encodeURIComponent(setup.qr_svg)}`}
+                  alt={t('auth.twoFactorSetupTitle')}
+                  className="mx-auto w-48 h-48 rounded-base"
+                />
+              )}
+              <div>
+                <p className="text-xs text-text-secondary mb-1">{t('auth.twoFactorSetupManual')}</p>
+                <code className="block break-all rounded-base border border-border-base bg-bg-primary px-2 py-1.5 text-xs font-mono text-text-primary select-all">
+                  {setup.secret}
+                </code>
+              </div>
+              <div>
+                <label className="block text-xs font-mono text-text-secondary mb-1">{t('auth.twoFactorCode')}</label>
+                <input
+                  type="text"
+                  inputMode="numeric"
+                  autoComplete="one-time-code"
+                  autoFocus
+                  value={code}
+                  onChange={(e) => setCode(e.target.value)}
+                  className="w-full bg-bg-primary border border-border-base rounded-base px-3 py-1.5 text-xs font-mono text-text-primary focus:outline-none focus:border-accent-blue"
+                />
+              </div>
+              {error && <p className="text-xs text-accent-red">{error}</p>}
+              <Button type="submit" className="w-full justify-center" disabled={submitting || code.trim() === ''}>
+                {submitting && <Spinner />}
+                {t('auth.twoFactorEnable')}
+              </Button>
+            </form>
+          )}
+          {!setup && error && <p className="text-xs text-accent-red">{error}</p>}
+          {enrollmentRequired && (
+            <Button type="button" variant="ghost" className="w-full justify-center" onClick={handleLogout}>
+              {t('auth.logout')}
+            </Button>
+          )}
+        </>
+      )}
+    </Card>
+  )
+
+  // Inside the app shell the card sits in the page; on its own it is centred
+  // like the login screen.
+  if (!enrollmentRequired) {
+    return card
+  }
+  return (
+    <div className="min-h-screen flex items-center justify-center bg-bg-primary px-4">
+      {card}
+    </div>
+  )
+}
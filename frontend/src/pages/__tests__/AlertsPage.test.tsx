@@ -44,6 +44,7 @@ describe('AlertsPage (jsdom)', () => {
           recipient_emails: [],
           telegram_chat_ids: [],
           retry_delays_sec: [1, 5, 15],
+          outbox_max_age_hours: 24,
           has_telegram_token: true,
           telegram_token_mask: '****abcd',
           has_gmail_password: true,
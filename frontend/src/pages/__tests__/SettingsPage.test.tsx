@@ -42,6 +42,7 @@ describe('SettingsPage (jsdom)', () => {
           recipient_emails: ['ops@example.com'],
           telegram_chat_ids: ['-100123'],
           retry_delays_sec: [1, 5, 15],
+          outbox_max_age_hours: 24,
           has_telegram_token: true,
           telegram_token_mask: '****1234',
           has_gmail_password: true,
@@ -66,6 +67,7 @@ describe('SettingsPage (jsdom)', () => {
           recipient_emails: ['ops@example.com'],
           telegram_chat_ids: ['-100123'],
           retry_delays_sec: [1, 5, 15],
+          outbox_max_age_hours: 24,
           has_telegram_token: true,
           telegram_token_mask: '****9999',
           has_gmail_password: true,
@@ -4,6 +4,7 @@ import { immer } from 'zustand/middleware/immer'
 import type { Snapshot } from '@/types/metrics'
 import type { ScanResult } from '@/types/ncdu'
 import type { AuthUser, ServerInfo } from '@/types/api'
+import type { ChartHistories } from '@/lib/metricsHistory'
 
 export type Theme = 'dark' | 'light'
 export type Language = 'en' | 'vi'
@@ -15,6 +16,9 @@ interface MetricsState {
   cpuHistory: number[]
   memHistory: number[]
   swapHistory: number[]
+  // liveSamples counts the chart points pushed by the WebSocket, so a
+  // history backfill that arrives late keeps them.
+  liveSamples: number
 }
 
 interface NcduState {
@@ -34,6 +38,7 @@ interface ServerInfoState {
 interface AuthState {
   authUser: AuthUser | null
   authLoading: boolean
+  twoFactorEnrollmentRequired: boolean
 }
 
 export type ToastVariant = 'info' | 'success' | 'error'
@@ -62,6 +67,7 @@ interface PreferencesState {
 export interface AppState extends MetricsState, NcduState, ConnectionState, ServerInfoState, PreferencesState, ToastState, AuthState {
   // Metrics actions
   setSnapshot: (snapshot: Snapshot) => void
+  backfillHistory: (history: ChartHistories) => void
 
   // Ncdu actions
   setScanPath: (path: string) => void
@@ -77,6 +83,7 @@ export interface AppState extends MetricsState, NcduState, ConnectionState, Serv
   // Auth actions
   setAuthUser: (user: AuthUser | null) => void
   setAuthLoading: (loading: boolean) => void
+  setTwoFactorEnrollmentRequired: (required: boolean) => void
 
   // Preferences actions
   setTheme: (theme: Theme) => void
@@ -92,7 +99,7 @@ export interface AppState extends MetricsState, NcduState, ConnectionState, Serv
   removeToast: (id: string) => void
 }
 
-const HISTORY_LENGTH = 60
+export const HISTORY_LENGTH = 60
 
 function pushHistory(arr: number[], val: number): number[] {
   const next = [...arr, val]
@@ -100,6 +107,12 @@ function pushHistory(arr: number[], val: number): number[] {
   return next
 }
 
+// backfill puts stored values in front of the `live` newest points of arr.
+function backfill(arr: number[], stored: number[], live: number): number[] {
+  const next = [...stored, ...arr.slice(arr.length - live)].slice(-HISTORY_LENGTH)
+  return [...Array(HISTORY_LENGTH - next.length).fill(0), ...next]
+}
+
 export const useStore = create<AppState>()(
   subscribeWithSelector(
     immer((set) => ({
@@ -110,6 +123,7 @@ export const useStore = create<AppState>()(
       cpuHistory: Array(HISTORY_LENGTH).fill(0),
       memHistory: Array(HISTORY_LENGTH).fill(0),
       swapHistory: Array(HISTORY_LENGTH).fill(0),
+      liveSamples: 0,
       scanPath: (localStorage.getItem('defaultScanPath')) ?? '/',
       scanResult: null,
       isScanning: false,
@@ -117,6 +131,7 @@ export const useStore = create<AppState>()(
       serverInfo: null,
       authUser: null,
       authLoading: true,
+      twoFactorEnrollmentRequired: false,
       toasts: [],
       theme: (localStorage.getItem('theme') as Theme) ?? 'dark',
       language: (localStorage.getItem('language') as Language) ?? 'en',
@@ -163,6 +178,23 @@ export const useStore = create<AppState>()(
           state.cpuHistory  = pushHistory(state.cpuHistory,  snapshot.cpu?.total_percent ?? 0)
           state.memHistory  = pushHistory(state.memHistory,  snapshot.memory?.percent    ?? 0)
           state.swapHistory = pushHistory(state.swapHistory, snapshot.swap?.percent      ?? 0)
+          state.liveSamples = Math.min(state.liveSamples + 1, HISTORY_LENGTH)
+        }),
+
+      backfillHistory: (history) =>
+        set((state) => {
+          const live = state.liveSamples
+          state.netHistory = [
+            backfill(state.netHistory[0], history['net.recv_bps'], live),
+            backfill(state.netHistory[1], history['net.sent_bps'], live),
+          ]
+          state.diskIOHistory = [
+            backfill(state.diskIOHistory[0], history['diskio.read_bps'], live),
+            backfill(state.diskIOHistory[1], history['diskio.write_bps'], live),
+          ]
+          state.cpuHistory  = backfill(state.cpuHistory,  history['cpu.percent'],    live)
+          state.memHistory  = backfill(state.memHistory,  history['memory.percent'], live)
+          state.swapHistory = backfill(state.swapHistory, history['swap.percent'],   live)
         }),
 
       setScanPath:   (path)   => set((s) => { s.scanPath = path }),
@@ -172,6 +204,7 @@ export const useStore = create<AppState>()(
       setServerInfo: (info)   => set((s) => { s.serverInfo = info }),
       setAuthUser: (user)     => set((s) => { s.authUser = user }),
       setAuthLoading: (loading) => set((s) => { s.authLoading = loading }),
+      setTwoFactorEnrollmentRequired: (required) => set((s) => { s.twoFactorEnrollmentRequired = required }),
 
       setTheme: (theme) => set((s) => {
         s.theme = theme
@@ -1,10 +1,80 @@
-export type AlertLevel = 'none' | 'warning' | 'critical' | 'recovery' | 'test'
+import type { ProcessInfo } from './metrics'
+
+export type AlertLevel = 'none' | 'warning' | 'critical' | 'recovery' | 'flapping' | 'test' | 'digest'
 
 export interface ChannelResult {
-  channel: 'telegram' | 'email' | string
+  channel: 'telegram' | 'email' | 'slack' | 'discord' | 'teams' | 'ntfy' | 'gotify' | 'webhook' | string
+  target?: string
   success: boolean
   attempts: number
   error_message?: string
+  queued?: boolean
+}
+
+export type IncidentStatus = 'open' | 'acknowledged' | 'resolved'
+
+export interface EscalationStep {
+  delay_min: number
+  channels: string[]
+  recipient_emails?: string[]
+  telegram_chat_ids?: string[]
+}
+
+export interface EscalationPolicy {
+  name: string
+  level: 'warning' | 'critical'
+  steps: EscalationStep[]
+}
+
+export interface EscalationRef {
+  incident_id: number
+  policy: string
+  step: number
+}
+
+export interface AlertIncident {
+  id: number
+  rule_id?: number
+  series: string
+  level: AlertLevel
+  status: IncidentStatus
+  opened_at: string
+  acked_at?: string
+  acked_by?: string
+  ack_note?: string
+  resolved_at?: string
+  event_count: number
+  critical_at?: string
+  escalations?: Record<string, number>
+  time_to_ack_sec?: number
+  time_to_resolve_sec?: number
+}
+
+export interface AlertOutboxItem {
+  id: number
+  event_id: number
+  channel: string
+  target?: string
+  level: AlertLevel
+  attempts: number
+  next_attempt_at: string
+  last_error?: string
+  created_at: string
+}
+
+export interface WebhookHeader {
+  name: string
+  has_value: boolean
+  value_mask: string
+}
+
+/** URL, header values and signing secret are write-only; the API serves masks. */
+export interface Webhook {
+  id: string
+  url_mask: string
+  headers: WebhookHeader[]
+  has_secret: boolean
+  secret_mask: string
 }
 
 export interface AlertConfig {
@@ -20,16 +90,103 @@ export interface AlertConfig {
   email_enabled: boolean
   recipient_emails: string[]
   telegram_chat_ids: string[]
+  smtp_host: string
+  smtp_port: number
+  smtp_security: 'starttls' | 'tls' | 'none'
+  smtp_username: string
+  smtp_from: string
+  email_html: boolean
+  slack_enabled: boolean
+  discord_enabled: boolean
+  teams_enabled: boolean
+  ntfy_enabled: boolean
+  ntfy_server_url: string
+  ntfy_topic: string
+  gotify_enabled: boolean
+  gotify_server_url: string
+  webhook_enabled: boolean
+  webhooks: Webhook[]
   retry_delays_sec: number[]
+  outbox_max_age_hours: number
+  flap_window_sec: number
+  flap_threshold: number
+  dashboard_url: string
+  digest_enabled: boolean
+  digest_period: DigestPeriod
+  digest_cron: string
+  digest_timezone: string
+  digest_sections: DigestSection[]
+  routes: Partial<Record<AlertLevel, string[]>>
+  escalation_policies: EscalationPolicy[]
+  forecast_enabled: boolean
+  forecast_horizon_hours: number
   has_telegram_token: boolean
   telegram_token_mask: string
+  has_smtp_password: boolean
+  smtp_password_mask: string
+  /** @deprecated alias of has_smtp_password */
   has_gmail_password: boolean
+  /** @deprecated alias of smtp_password_mask */
   gmail_password_mask: string
+  /** @deprecated alias of smtp_from */
   gmail_address: string
+  has_slack_webhook: boolean
+  slack_webhook_mask: string
+  has_discord_webhook: boolean
+  discord_webhook_mask: string
+  has_teams_webhook: boolean
+  teams_webhook_mask: string
+  has_ntfy_token: boolean
+  ntfy_token_mask: string
+  has_gotify_token: boolean
+  gotify_token_mask: string
   secrets_writable: boolean
   read_only: boolean
 }
 
+export interface NotificationTemplate {
+  channel: string
+  level: AlertLevel | '*'
+  subject: string
+  body: string
+  updated_at: string
+}
+
+export interface AlertTemplatesResponse {
+  templates: NotificationTemplate[]
+  channels: string[]
+  levels: string[]
+}
+
+export interface TemplatePreview {
+  subject: string
+  message: string
+}
+
+export type MaintenanceKind = 'weekly' | 'cron'
+
+export interface MaintenanceWindow {
+  id: number
+  name: string
+  enabled: boolean
+  kind: MaintenanceKind
+  weekdays: number[]
+  start_time: string
+  end_time: string
+  cron: string
+  duration_min: number
+  timezone: string
+  created_at: string
+  updated_at: string
+}
+
+export interface MaintenanceOccurrence {
+  window_id: number
+  name: string
+  start: string
+  end: string
+}
+
 export interface AlertStatus {
   current_state: AlertLevel
   last_cpu_percent: number
@@ -39,7 +196,18 @@ export interface AlertStatus {
   last_warning_at?: string
   last_critical_at?: string
   last_recovery_at?: string
+  flapping_since?: string
+  rules: AlertRuleStatus[]
   read_only: boolean
+  active_maintenance?: MaintenanceOccurrence
+  next_maintenance?: MaintenanceOccurrence
+  disk_forecasts: DiskForecastStatus[]
+}
+
+export interface DiskForecastStatus {
+  mountpoint: string
+  since: string
+  hours_to_full?: number
 }
 
 export interface AlertEvent {
@@ -47,10 +215,94 @@ export interface AlertEvent {
   level: AlertLevel
   message: string
   cpu_percent: number
+  rule_id?: number
+  incident_id?: number
+  series: string
+  value: number
   channels: ChannelResult[]
+  processes?: {
+    by_cpu: ProcessInfo[]
+    by_memory: ProcessInfo[]
+  }
+  escalation?: EscalationRef
+  created_at: string
+}
+
+export interface AlertRule {
+  id: number
+  name: string
+  enabled: boolean
+  series: string
+  warning: number
+  warning_for_sec: number
+  critical: number
+  critical_for_sec: number
+  recovery: number
+  recovery_for_sec: number
+  cooldown_sec: number
   created_at: string
+  updated_at: string
+}
+
+export interface AlertRuleStatus {
+  rule_id: number
+  name: string
+  series: string
+  state: AlertLevel
+  last_value?: number
+  last_evaluated_at?: string
+  flapping_since?: string
 }
 
 export interface AlertHistoryResponse {
   events: AlertEvent[]
 }
+
+export type DigestPeriod = 'daily' | 'weekly'
+
+export type DigestSection = 'resources' | 'alerts' | 'disk_growth' | 'updates' | 'exposures'
+
+export interface DigestResource {
+  series: string
+  name: string
+  avg: number
+  peak: number
+}
+
+export interface DiskGrowth {
+  mountpoint: string
+  start_bytes: number
+  end_bytes: number
+  growth_bytes: number
+}
+
+export interface Digest {
+  host: string
+  period: DigestPeriod
+  from: string
+  to: string
+  resources?: DigestResource[]
+  alerts?: { fired: number; by_level: Partial<Record<AlertLevel, number>> }
+  disk_growth?: DiskGrowth[]
+  updates?: { manager?: string; total: number; names?: string[]; error?: string }
+  exposures?: {
+    total: number
+    items?: { port: number; protocol: string; address: string; process: string; risk: string }[]
+    error?: string
+  }
+  errors?: string[]
+  text: string
+  html: string
+}
+
+export interface AlertKeyStatus {
+  current_key_id?: string
+  previous_key_ids: string[]
+  secrets_by_key: Record<string, number>
+  pending_rotation: number
+}
+
+export interface AlertKeyRotation {
+  rotated: number
+  keys: AlertKeyStatus
+}
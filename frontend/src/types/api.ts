@@ -1,11 +1,27 @@
 import type { Snapshot } from './metrics'
 
-export type UserRole = 'admin' | 'viewer'
+// Custom roles can carry any name, so UserRole stays open beyond the built-ins.
+export type UserRole = 'admin' | 'operator' | 'viewer' | (string & {})
+
+export type Permission =
+  | 'users:manage'
+  | 'alerts:manage'
+  | 'alerts:silence'
+  | 'ports:kill'
+  | 'storage:scan'
+
+export interface RoleDefinition {
+  name: UserRole
+  description: string
+  permissions: Permission[]
+  built_in: boolean
+}
 
 export interface AuthUser {
   id: number
   username: string
   role: UserRole
+  two_factor_enabled?: boolean
 }
 
 export interface ServerInfo {
@@ -52,6 +68,73 @@ export interface WSMessage {
 export interface AuthMeResponse {
   user?: AuthUser
   auth_disabled?: boolean
+  permissions?: Permission[]
+  two_factor_enrollment_required?: boolean
+}
+
+export interface SessionInfo {
+  id: string
+  user_id: number
+  username: string
+  role: UserRole
+  ip: string
+  user_agent: string
+  created_at: string
+  last_seen_at: string
+  expires_at: string
+  max_expires_at: string
+  current: boolean
+}
+
+export interface LoginLockout {
+  kind: 'ip' | 'username'
+  key: string
+  failures: number
+  last_failure: string
+  retry_at?: string
+  locked_until?: string
+}
+
+export interface LoginResponse {
+  user?: AuthUser
+  permissions?: Permission[]
+  error?: string
+  retry_after_sec?: number
+  two_factor_required?: boolean
+  challenge?: string
+  expires_at?: string
+  two_factor_enrollment_required?: boolean
+}
+
+export type ApiTokenScope = 'metrics:read' | 'alerts:read' | 'alerts:write' | 'ports:kill'
+
+export interface ApiToken {
+  id: number
+  user_id: number
+  username: string
+  name: string
+  prefix: string
+  scopes: ApiTokenScope[]
+  expires_at?: string
+  last_used_at?: string
+  created_at: string
+}
+
+export interface ApiTokenCreated {
+  token: string
+  api_token: ApiToken
+}
+
+export interface TwoFactorStatus {
+  enabled: boolean
+  pending: boolean
+  recovery_codes_remaining: number
+}
+
+export interface TwoFactorSetup {
+  secret: string
+  otpauth_uri: string
+  qr_svg?: string
 }
 
 export interface UserAuditEntry {
@@ -0,0 +1,42 @@
+export type CheckKind = 'http' | 'tcp'
+
+export type CheckState = 'pending' | 'up' | 'down'
+
+export interface Check {
+  id: number
+  name: string
+  kind: CheckKind
+  target: string
+  enabled: boolean
+  interval_sec: number
+  timeout_sec: number
+  expect_status: number
+  body_contains: string
+  max_latency_ms: number
+  tls_expiry_days: number
+  fail_threshold: number
+  state: CheckState
+  state_since?: string
+  created_at: string
+  updated_at: string
+}
+
+export interface CheckResult {
+  id: number
+  check_id: number
+  ok: boolean
+  status_code?: number
+  latency_ms: number
+  error?: string
+  tls_expires_at?: string
+  checked_at: string
+}
+
+export interface CheckStatus extends Check {
+  last_result?: CheckResult
+  uptime_24h?: number
+  uptime_7d?: number
+  uptime_30d?: number
+}
+
+export type CheckInput = Partial<Omit<Check, 'id' | 'state' | 'state_since' | 'created_at' | 'updated_at'>>
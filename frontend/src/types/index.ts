@@ -4,3 +4,5 @@ export type * from './api'
 export type * from './alerts'
 export type * from './firewall'
 export type * from './packages'
+export type * from './checks'
+export type * from './watch'
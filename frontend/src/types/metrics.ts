@@ -22,6 +22,12 @@ export interface SwapMetrics {
   percent: number
 }
 
+export interface LoadMetrics {
+  load1: number
+  load5: number
+  load15: number
+}
+
 export interface DiskMetrics {
   mountpoint: string
   device: string
@@ -30,6 +36,15 @@ export interface DiskMetrics {
   used_bytes: number
   free_bytes: number
   percent: number
+  forecast?: DiskForecast
+}
+
+export interface DiskForecast {
+  growth_bytes_per_hour: number
+  hours_to_full?: number
+  full_at?: string
+  samples: number
+  window_sec: number
 }
 
 export interface DiskIOMetrics {
@@ -38,6 +53,8 @@ export interface DiskIOMetrics {
   write_bps: number
   read_ops: number
   write_ops: number
+  total_read: number
+  total_write: number
 }
 
 export interface NetMetrics {
@@ -48,12 +65,56 @@ export interface NetMetrics {
   total_sent: number
 }
 
+export interface ProcessInfo {
+  pid: number
+  ppid: number
+  name: string
+  user: string
+  cmdline: string
+  state: string
+  start_time: string
+  cpu_percent: number
+  rss_bytes: number
+  mem_percent: number
+  read_bps: number
+  write_bps: number
+}
+
+export interface ProcessList {
+  total: number
+  by_cpu: ProcessInfo[]
+  by_memory: ProcessInfo[]
+  by_io: ProcessInfo[]
+}
+
 export interface Snapshot {
   timestamp: string
   cpu: CPUMetrics
   memory: MemMetrics
   swap: SwapMetrics
+  load: LoadMetrics
   disks: DiskMetrics[]
   disk_io: DiskIOMetrics[]
   network: NetMetrics[]
+  processes?: ProcessList
+}
+
+export interface HistoryPoint {
+  t: string
+  avg: number
+  min: number
+  max: number
+}
+
+export interface HistorySeries {
+  name: string
+  points: HistoryPoint[]
+}
+
+export interface MetricsHistory {
+  from: string
+  to: string
+  step_sec: number
+  tier: string
+  series: HistorySeries[]
 }
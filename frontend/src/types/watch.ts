@@ -0,0 +1,27 @@
+export type WatchKind = 'process' | 'unit'
+
+export type WatchState = 'pending' | 'up' | 'down'
+
+export interface WatchTarget {
+  id: number
+  name: string
+  kind: WatchKind
+  pattern: string
+  match_cmdline: boolean
+  enabled: boolean
+  down_after_sec: number
+  state: WatchState
+  state_since?: string
+  created_at: string
+  updated_at: string
+}
+
+export interface WatchTargetStatus extends WatchTarget {
+  last_checked_at?: string
+  detail?: string
+  pids?: number[]
+  missing_since?: string
+  error?: string
+}
+
+export type WatchTargetInput = Partial<Pick<WatchTarget, 'name' | 'kind' | 'pattern' | 'match_cmdline' | 'enabled' | 'down_after_sec'>>
@@ -0,0 +1,201 @@
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DefaultNtfyServerURL is used when ntfy is enabled without a server URL.
+const DefaultNtfyServerURL = "https://ntfy.sh"
+
+// discordDescriptionLimit is Discord's embed description cap.
+const discordDescriptionLimit = 4096
+
+var ntfyTopicPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// chatRequest is one rendered POST to a chat service.
+type chatRequest struct {
+	URL     string
+	Headers map[string]string
+	Body    []byte
+}
+
+type chatSendFunc func(ctx context.Context, req chatRequest) error
+
+func (n *Notifier) sendChatDefault(ctx context.Context, in chatRequest) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, in.URL, bytes.NewReader(in.Body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "QuickVPS-Alerts")
+	for name, value := range in.Headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := n.httpClient.Do(req)
+	if err != nil {
+		// Chat webhook URLs embed their credentials; never echo them.
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return urlErr.Err
+		}
+		return err
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	_ = resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("send failed with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func slackRequest(webhookURL string, note Notification) (chatRequest, error) {
+	if webhookURL == "" {
+		return chatRequest{}, errors.New("slack webhook url is empty")
+	}
+	body, err := json.Marshal(map[string]any{
+		"text": note.Subject,
+		"attachments": []map[string]any{{
+			"color":  levelColor(note.Level),
+			"title":  note.Subject,
+			"text":   note.Message,
+			"footer": note.Host,
+			"ts":     note.Time.Unix(),
+		}},
+	})
+	return chatRequest{URL: webhookURL, Body: body}, err
+}
+
+func discordRequest(webhookURL string, note Notification) (chatRequest, error) {
+	if webhookURL == "" {
+		return chatRequest{}, errors.New("discord webhook url is empty")
+	}
+	description := note.Message
+	if len(description) > discordDescriptionLimit {
+		description = description[:discordDescriptionLimit-3] + "..."
+	}
+	color, _ := strconv.ParseInt(strings.TrimPrefix(levelColor(note.Level), "#"), 16, 32)
+	body, err := json.Marshal(map[string]any{
+		"embeds": []map[string]any{{
+			"title":       note.Subject,
+			"description": description,
+			"color":       color,
+			"timestamp":   note.Time.UTC().Format("2006-01-02T15:04:05Z"),
+			"footer":      map[string]string{"text": note.Host},
+		}},
+	})
+	return chatRequest{URL: webhookURL, Body: body}, err
+}
+
+// teamsRequest renders an Office 365 connector MessageCard.
+func teamsRequest(webhookURL string, note Notification) (chatRequest, error) {
+	if webhookURL == "" {
+		return chatRequest{}, errors.New("teams webhook url is empty")
+	}
+	body, err := json.Marshal(map[string]any{
+		"@type":      "MessageCard",
+		"@context":   "https://schema.org/extensions",
+		"themeColor": strings.TrimPrefix(levelColor(note.Level), "#"),
+		"summary":    note.Subject,
+		"title":      note.Subject,
+		// Teams renders markdown; a hard line break needs two trailing spaces.
+		"text": strings.ReplaceAll(note.Message, "\n", "  \n"),
+	})
+	return chatRequest{URL: webhookURL, Body: body}, err
+}
+
+// ntfyRequest publishes as JSON to the server root, which carries the topic
+// in the body.
+func ntfyRequest(serverURL string, topic string, token string, note Notification) (chatRequest, error) {
+	if topic == "" {
+		return chatRequest{}, errors.New("ntfy topic is empty")
+	}
+	if serverURL == "" {
+		serverURL = DefaultNtfyServerURL
+	}
+	priority, tag := 3, "information_source"
+	switch note.Level {
+	case LevelCritical:
+		priority, tag = 5, "rotating_light"
+	case LevelWarning:
+		priority, tag = 4, "warning"
+	case LevelFlapping:
+		priority, tag = 4, "repeat"
+	case LevelRecovery:
+		tag = "white_check_mark"
+	}
+	body, err := json.Marshal(map[string]any{
+		"topic":    topic,
+		"title":    note.Subject,
+		"message":  note.Message,
+		"priority": priority,
+		"tags":     []string{tag},
+	})
+	req := chatRequest{URL: strings.TrimRight(serverURL, "/"), Body: body}
+	if token != "" {
+		req.Headers = map[string]string{"Authorization": "Bearer " + token}
+	}
+	return req, err
+}
+
+func gotifyRequest(serverURL string, token string, note Notification) (chatRequest, error) {
+	if serverURL == "" {
+		return chatRequest{}, errors.New("gotify server url is empty")
+	}
+	if token == "" {
+		return chatRequest{}, errors.New("gotify app token is empty")
+	}
+	priority := 2
+	switch note.Level {
+	case LevelCritical:
+		priority = 8
+	case LevelWarning, LevelFlapping:
+		priority = 5
+	}
+	body, err := json.Marshal(map[string]any{
+		"title":    note.Subject,
+		"message":  note.Message,
+		"priority": priority,
+	})
+	return chatRequest{
+		URL:     strings.TrimRight(serverURL, "/") + "/message",
+		Headers: map[string]string{"X-Gotify-Key": token},
+		Body:    body,
+	}, err
+}
+
+func validateChatConfig(cfg Config) error {
+	if cfg.NtfyServerURL != "" && !isHTTPURL(cfg.NtfyServerURL) {
+		return errors.New("ntfy_server_url must be an http(s) url")
+	}
+	if cfg.NtfyTopic != "" && !ntfyTopicPattern.MatchString(cfg.NtfyTopic) {
+		return errors.New("ntfy_topic must be 1-64 letters, digits, '-' or '_'")
+	}
+	if cfg.GotifyServerURL != "" && !isHTTPURL(cfg.GotifyServerURL) {
+		return errors.New("gotify_server_url must be an http(s) url")
+	}
+	return nil
+}
+
+func validateChatWebhookURL(field string, raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("%s must be an https url", field)
+	}
+	return nil
+}
+
+func isHTTPURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
@@ -0,0 +1,137 @@
+package alerts
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type chatCapture struct {
+	Path   string
+	Header http.Header
+	Body   map[string]any
+}
+
+func TestChatChannelsNativeFormatting(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		captured = map[string]chatCapture{}
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, _ := io.ReadAll(r.Body)
+		var body map[string]any
+		_ = json.Unmarshal(raw, &body)
+		mu.Lock()
+		captured[r.URL.Path] = chatCapture{Path: r.URL.Path, Header: r.Header.Clone(), Body: body}
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	n := NewNotifier()
+	n.sleep = func(time.Duration) {}
+	n.httpClient = srv.Client()
+
+	cfg := DefaultConfig()
+	cfg.TelegramEnabled = false
+	cfg.EmailEnabled = false
+	cfg.SlackEnabled = true
+	cfg.DiscordEnabled = true
+	cfg.TeamsEnabled = true
+	cfg.NtfyEnabled = true
+	cfg.NtfyServerURL = srv.URL + "/ntfy/"
+	cfg.NtfyTopic = "vps-alerts"
+	cfg.GotifyEnabled = true
+	cfg.GotifyServerURL = srv.URL + "/gotify"
+	cfg.RetryDelaysSec = []int{0}
+
+	secrets := Secrets{
+		SlackWebhookURL:   srv.URL + "/slack",
+		DiscordWebhookURL: srv.URL + "/discord",
+		TeamsWebhookURL:   srv.URL + "/teams",
+		NtfyToken:         "tk_ntfy",
+		GotifyToken:       "gotify-app",
+	}
+	results := n.Notify(context.Background(), cfg, secrets, Notification{
+		Level:   LevelCritical,
+		Subject: "[QuickVPS] CPU CRITICAL",
+		Message: "cpu high",
+		Host:    "vps-1",
+		Time:    time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+	})
+	if len(results) != 5 {
+		t.Fatalf("results len = %d, want 5: %+v", len(results), results)
+	}
+	for _, res := range results {
+		if !res.Success {
+			t.Fatalf("channel %s failed: %+v", res.Channel, res)
+		}
+	}
+
+	slack := captured["/slack"].Body["attachments"].([]any)[0].(map[string]any)
+	if slack["color"] != levelColor(LevelCritical) || slack["text"] != "cpu high" {
+		t.Fatalf("slack attachment = %+v", slack)
+	}
+	discord := captured["/discord"].Body["embeds"].([]any)[0].(map[string]any)
+	if discord["color"].(float64) != 0xd93025 {
+		t.Fatalf("discord color = %v, want 0xd93025", discord["color"])
+	}
+	if captured["/teams"].Body["themeColor"] != "d93025" || captured["/teams"].Body["@type"] != "MessageCard" {
+		t.Fatalf("teams card = %+v", captured["/teams"].Body)
+	}
+	ntfy := captured["/ntfy"]
+	if ntfy.Body["topic"] != "vps-alerts" || ntfy.Body["priority"].(float64) != 5 {
+		t.Fatalf("ntfy body = %+v", ntfy.Body)
+	}
+	if ntfy.Header.Get("Authorization") != "Bearer tk_ntfy" {
+		t.Fatalf("ntfy auth = %q", ntfy.Header.Get("Authorization"))
+	}
+	gotify := captured["/gotify/message"]
+	if gotify.Header.Get("X-Gotify-Key") != "gotify-app" || gotify.Body["priority"].(float64) != 8 {
+		t.Fatalf("gotify request = %+v", gotify)
+	}
+}
+
+func TestChatChannelMissingSecretFailsWithoutSending(t *testing.T) {
+	n := NewNotifier()
+	n.sendChat = func(context.Context, chatRequest) error {
+		t.Fatal("sendChat should not be called")
+		return nil
+	}
+
+	cfg := DefaultConfig()
+	cfg.TelegramEnabled = false
+	cfg.EmailEnabled = false
+	cfg.SlackEnabled = true
+
+	results := n.Notify(context.Background(), cfg, Secrets{}, Notification{Level: LevelTest})
+	if len(results) != 1 || results[0].Success || results[0].ErrorMessage != "slack webhook url is empty" {
+		t.Fatalf("results = %+v", results)
+	}
+}
+
+func TestUpdateConfigChatSecretsMasked(t *testing.T) {
+	key := base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", 32)))
+	svc := newServiceWithKeyForTests(t, NewNotifier(), key)
+	enabled := true
+	view, err := svc.UpdateConfig(UpdateConfigInput{
+		SlackEnabled:    &enabled,
+		SlackWebhookURL: "https://hooks.slack.com/services/T000/B000/abcd1234",
+	})
+	if err != nil {
+		t.Fatalf("UpdateConfig() error = %v", err)
+	}
+	if !view.SlackEnabled || !view.HasSlackWebhook || view.SlackWebhookMask != "****1234" {
+		t.Fatalf("view = %+v", view)
+	}
+	if _, err := svc.UpdateConfig(UpdateConfigInput{DiscordWebhookURL: "http://insecure.example.com/hook"}); err == nil {
+		t.Fatal("UpdateConfig() should reject non-https discord url")
+	}
+}
@@ -4,73 +4,194 @@ import (
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
+	"strings"
 )
 
 var ErrMissingEncryptionKey = errors.New("missing QUICKVPS_ALERTS_KEY encryption key")
 
-type Cipher struct {
+// ErrUnknownKeyID is returned for ciphertext sealed under a key that is not in
+// the ring.
+var ErrUnknownKeyID = errors.New("ciphertext key is not in the key ring")
+
+// keyIDSeparator splits the key ID prefix from the base64 payload. Base64
+// never contains it, so ciphertext written before key rotation existed is
+// recognised by its absence.
+const keyIDSeparator = ":"
+
+type ringKey struct {
+	id  string
 	gcm cipher.AEAD
 }
 
+// Cipher is a key ring: it encrypts with the current key and decrypts with
+// any key it holds, picked by the key ID prefix of the ciphertext.
+type Cipher struct {
+	current  ringKey
+	previous []ringKey
+}
+
 func NewCipherFromBase64Key(base64Key string) (*Cipher, error) {
-	if base64Key == "" {
+	return NewKeyRing(base64Key)
+}
+
+// NewKeyRing builds a Cipher that encrypts with current and can still decrypt
+// secrets written under any of the previous keys.
+func NewKeyRing(current string, previous ...string) (*Cipher, error) {
+	if current == "" {
 		return nil, ErrMissingEncryptionKey
 	}
+	cur, err := parseRingKey(current)
+	if err != nil {
+		return nil, err
+	}
+	c := &Cipher{current: cur}
+	for i, p := range previous {
+		if p = strings.TrimSpace(p); p == "" {
+			continue
+		}
+		k, err := parseRingKey(p)
+		if err != nil {
+			return nil, fmt.Errorf("previous alert key %d: %w", i+1, err)
+		}
+		if k.id == cur.id {
+			continue
+		}
+		c.previous = append(c.previous, k)
+	}
+	return c, nil
+}
 
+func parseRingKey(base64Key string) (ringKey, error) {
 	key, err := base64.StdEncoding.DecodeString(base64Key)
 	if err != nil {
-		return nil, fmt.Errorf("decode alert key: %w", err)
+		return ringKey{}, fmt.Errorf("decode alert key: %w", err)
 	}
 	if len(key) != 32 {
-		return nil, fmt.Errorf("invalid alert key length: want 32 bytes, got %d", len(key))
+		return ringKey{}, fmt.Errorf("invalid alert key length: want 32 bytes, got %d", len(key))
 	}
 
 	block, err := aes.NewCipher(key)
 	if err != nil {
-		return nil, fmt.Errorf("new cipher: %w", err)
+		return ringKey{}, fmt.Errorf("new cipher: %w", err)
 	}
 	gcm, err := cipher.NewGCM(block)
 	if err != nil {
-		return nil, fmt.Errorf("new gcm: %w", err)
+		return ringKey{}, fmt.Errorf("new gcm: %w", err)
+	}
+
+	sum := sha256.Sum256(key)
+	return ringKey{id: hex.EncodeToString(sum[:4]), gcm: gcm}, nil
+}
+
+// KeyID identifies the current key: the first 8 hex digits of its SHA-256.
+func (c *Cipher) KeyID() string {
+	if c == nil {
+		return ""
+	}
+	return c.current.id
+}
+
+// PreviousKeyIDs lists the IDs of the decrypt-only keys.
+func (c *Cipher) PreviousKeyIDs() []string {
+	if c == nil {
+		return nil
+	}
+	ids := make([]string, 0, len(c.previous))
+	for _, k := range c.previous {
+		ids = append(ids, k.id)
 	}
+	return ids
+}
 
-	return &Cipher{gcm: gcm}, nil
+// CiphertextKeyID returns the key ID prefix of ciphertext, or "" for
+// ciphertext written before key IDs were recorded.
+func CiphertextKeyID(ciphertext string) string {
+	id, _, ok := strings.Cut(ciphertext, keyIDSeparator)
+	if !ok {
+		return ""
+	}
+	return id
 }
 
 func (c *Cipher) Encrypt(plaintext string) (string, error) {
 	if c == nil {
 		return "", ErrMissingEncryptionKey
 	}
-	nonce := make([]byte, c.gcm.NonceSize())
+	gcm := c.current.gcm
+	nonce := make([]byte, gcm.NonceSize())
 	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
 		return "", fmt.Errorf("read nonce: %w", err)
 	}
-	ciphertext := c.gcm.Seal(nil, nonce, []byte(plaintext), nil)
+	ciphertext := gcm.Seal(nil, nonce, []byte(plaintext), nil)
 	packed := append(nonce, ciphertext...)
-	return base64.StdEncoding.EncodeToString(packed), nil
+	return c.current.id + keyIDSeparator + base64.StdEncoding.EncodeToString(packed), nil
 }
 
 func (c *Cipher) Decrypt(ciphertext string) (string, error) {
 	if c == nil {
 		return "", ErrMissingEncryptionKey
 	}
-	packed, err := base64.StdEncoding.DecodeString(ciphertext)
+	id, payload, ok := strings.Cut(ciphertext, keyIDSeparator)
+	if !ok {
+		// Unprefixed ciphertext predates the key ring; try every key.
+		var lastErr error
+		for _, k := range c.keys() {
+			plain, err := openWith(k.gcm, ciphertext)
+			if err == nil {
+				return plain, nil
+			}
+			lastErr = err
+		}
+		return "", lastErr
+	}
+	for _, k := range c.keys() {
+		if k.id == id {
+			return openWith(k.gcm, payload)
+		}
+	}
+	return "", fmt.Errorf("%w: %s", ErrUnknownKeyID, id)
+}
+
+// Reencrypt returns ciphertext sealed under the current key. Ciphertext that
+// already is comes back unchanged.
+func (c *Cipher) Reencrypt(ciphertext string) (string, error) {
+	if c == nil {
+		return "", ErrMissingEncryptionKey
+	}
+	if CiphertextKeyID(ciphertext) == c.current.id {
+		return ciphertext, nil
+	}
+	plain, err := c.Decrypt(ciphertext)
+	if err != nil {
+		return "", err
+	}
+	return c.Encrypt(plain)
+}
+
+func (c *Cipher) keys() []ringKey {
+	return append([]ringKey{c.current}, c.previous...)
+}
+
+func openWith(gcm cipher.AEAD, payload string) (string, error) {
+	packed, err := base64.StdEncoding.DecodeString(payload)
 	if err != nil {
 		return "", fmt.Errorf("decode ciphertext: %w", err)
 	}
 
-	nonceSize := c.gcm.NonceSize()
+	nonceSize := gcm.NonceSize()
 	if len(packed) < nonceSize {
 		return "", errors.New("ciphertext too short")
 	}
 
 	nonce := packed[:nonceSize]
 	sealed := packed[nonceSize:]
-	plain, err := c.gcm.Open(nil, nonce, sealed, nil)
+	plain, err := gcm.Open(nil, nonce, sealed, nil)
 	if err != nil {
 		return "", fmt.Errorf("decrypt: %w", err)
 	}
@@ -2,6 +2,7 @@ package alerts
 
 import (
 	"encoding/base64"
+	"errors"
 	"strings"
 	"testing"
 )
@@ -43,3 +44,55 @@ func TestCipherInvalidKey(t *testing.T) {
 		t.Fatalf("expected error for short key")
 	}
 }
+
+func TestKeyRingDecryptsPreviousAndLegacyCiphertext(t *testing.T) {
+	oldKey := base64.StdEncoding.EncodeToString([]byte(strings.Repeat("o", 32)))
+	newKey := base64.StdEncoding.EncodeToString([]byte(strings.Repeat("n", 32)))
+
+	old, err := NewCipherFromBase64Key(oldKey)
+	if err != nil {
+		t.Fatalf("NewCipherFromBase64Key() error = %v", err)
+	}
+	oldCiphertext, err := old.Encrypt("old-secret")
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	if CiphertextKeyID(oldCiphertext) != old.KeyID() {
+		t.Fatalf("ciphertext %q lacks key ID %q", oldCiphertext, old.KeyID())
+	}
+	// Ciphertext written before key IDs existed has no prefix.
+	_, legacy, _ := strings.Cut(oldCiphertext, ":")
+
+	ring, err := NewKeyRing(newKey, oldKey)
+	if err != nil {
+		t.Fatalf("NewKeyRing() error = %v", err)
+	}
+	if ring.KeyID() == old.KeyID() || len(ring.PreviousKeyIDs()) != 1 {
+		t.Fatalf("ring ids = %q %v", ring.KeyID(), ring.PreviousKeyIDs())
+	}
+	for _, ct := range []string{oldCiphertext, legacy} {
+		plain, err := ring.Decrypt(ct)
+		if err != nil || plain != "old-secret" {
+			t.Fatalf("Decrypt(%q) = %q, %v", ct, plain, err)
+		}
+	}
+
+	rotated, err := ring.Reencrypt(oldCiphertext)
+	if err != nil {
+		t.Fatalf("Reencrypt() error = %v", err)
+	}
+	if CiphertextKeyID(rotated) != ring.KeyID() {
+		t.Fatalf("rotated key ID = %q, want %q", CiphertextKeyID(rotated), ring.KeyID())
+	}
+	if again, _ := ring.Reencrypt(rotated); again != rotated {
+		t.Fatalf("Reencrypt() rewrote ciphertext already under the current key")
+	}
+
+	newOnly, err := NewCipherFromBase64Key(newKey)
+	if err != nil {
+		t.Fatalf("NewCipherFromBase64Key() error = %v", err)
+	}
+	if _, err := newOnly.Decrypt(oldCiphertext); !errors.Is(err, ErrUnknownKeyID) {
+		t.Fatalf("Decrypt() error = %v, want ErrUnknownKeyID", err)
+	}
+}
@@ -0,0 +1,452 @@
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"sort"
+	"strings"
+	"time"
+
+	"quickvps/internal/firewall"
+	"quickvps/internal/history"
+	"quickvps/internal/metrics"
+	"quickvps/internal/packages"
+)
+
+const (
+	// digestCheckEvery is how often the digest worker looks for a due
+	// digest.
+	digestCheckEvery = 30 * time.Second
+	// digestCatchUp is how far back a missed digest time is still sent.
+	digestCatchUp      = 8 * 24 * time.Hour
+	digestTopDisks     = 5
+	digestTopUpdates   = 10
+	digestTopExposures = 10
+)
+
+var digestSections = []string{
+	DigestSectionResources,
+	DigestSectionAlerts,
+	DigestSectionDiskGrowth,
+	DigestSectionUpdates,
+	DigestSectionExposures,
+}
+
+// DigestSources supplies the data summarized by a digest. A nil source skips
+// its section.
+type DigestSources struct {
+	History   func(history.Query) (history.Result, error)
+	Updates   func() packages.UpdatesResult
+	Exposures func() ([]firewall.Exposure, error)
+}
+
+// SetDigestSources sets where the digest reads its data; main wires in the
+// metrics history, package updates and firewall audit.
+func (s *Service) SetDigestSources(src DigestSources) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.digestSources = src
+}
+
+func (p DigestPeriod) duration() time.Duration {
+	if p == DigestWeekly {
+		return 7 * 24 * time.Hour
+	}
+	return 24 * time.Hour
+}
+
+func validateDigest(cfg Config) error {
+	if cfg.DigestPeriod != DigestDaily && cfg.DigestPeriod != DigestWeekly {
+		return errors.New("digest_period must be daily or weekly")
+	}
+	if _, err := parseCron(cfg.DigestCron); err != nil {
+		return fmt.Errorf("digest_cron: %w", err)
+	}
+	if _, err := time.LoadLocation(cfg.DigestTimezone); err != nil {
+		return fmt.Errorf("unknown digest_timezone %q", cfg.DigestTimezone)
+	}
+	if len(cfg.DigestSections) == 0 {
+		return errors.New("digest_sections must not be empty")
+	}
+	for _, section := range cfg.DigestSections {
+		if !containsString(digestSections, section) {
+			return fmt.Errorf("unknown digest section %q", section)
+		}
+	}
+	return nil
+}
+
+func containsString(list []string, v string) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
+
+// digestDue reports whether a scheduled digest time has passed since the
+// last run, so a run missed while the process was busy or down is caught up
+// once. Only the latest missed time counts, never one before digestCatchUp.
+func (s *Service) digestDue(cfg Config, now time.Time) bool {
+	if !cfg.DigestEnabled {
+		return false
+	}
+	c, err := parseCron(cfg.DigestCron)
+	if err != nil {
+		return false
+	}
+	loc, err := time.LoadLocation(cfg.DigestTimezone)
+	if err != nil {
+		return false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	minute, ok := c.latest(s.lastDigestAt, now, loc, digestCatchUp)
+	if !ok {
+		return false
+	}
+	s.lastDigestAt = minute
+	return true
+}
+
+// runDigests sends the scheduled digest on its own goroutine so building it
+// never holds up evaluation.
+func (s *Service) runDigests(ctx context.Context) {
+	ticker := time.NewTicker(digestCheckEvery)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.runDigest(ctx, now.UTC())
+		}
+	}
+}
+
+func (s *Service) runDigest(ctx context.Context, now time.Time) {
+	s.mu.RLock()
+	cfg := s.cfg
+	s.mu.RUnlock()
+	if !s.digestDue(cfg, now) {
+		return
+	}
+	_, _ = s.SendDigest(ctx, now)
+}
+
+// BuildDigest summarizes the period ending at now according to the configured
+// sections.
+func (s *Service) BuildDigest(now time.Time) Digest {
+	s.mu.RLock()
+	cfg := s.cfg
+	src := s.digestSources
+	host := s.hostname
+	s.mu.RUnlock()
+
+	now = now.UTC()
+	d := Digest{
+		Host:   host,
+		Period: cfg.DigestPeriod,
+		From:   now.Add(-cfg.DigestPeriod.duration()),
+		To:     now,
+	}
+	sections := cfg.DigestSections
+
+	if src.History != nil && (containsString(sections, DigestSectionResources) || containsString(sections, DigestSectionDiskGrowth)) {
+		res, err := src.History(history.Query{
+			From:   d.From,
+			To:     d.To,
+			Series: []string{metrics.SeriesCPUPercent, metrics.SeriesMemoryPercent, metrics.SeriesDiskPercent, metrics.SeriesDiskUsedBytes},
+		})
+		if err != nil {
+			d.Errors = append(d.Errors, "metrics history: "+err.Error())
+		} else {
+			if containsString(sections, DigestSectionResources) {
+				d.Resources = digestResources(res.Series)
+			}
+			if containsString(sections, DigestSectionDiskGrowth) {
+				d.DiskGrowth = digestDiskGrowth(res.Series)
+			}
+		}
+	}
+
+	if containsString(sections, DigestSectionAlerts) {
+		counts, err := s.store.CountEventsByLevel(d.From, d.To)
+		if err != nil {
+			d.Errors = append(d.Errors, "alert history: "+err.Error())
+		} else {
+			a := &DigestAlerts{ByLevel: counts}
+			for level, n := range counts {
+				if level == LevelWarning || level == LevelCritical || level == LevelFlapping {
+					a.Fired += n
+				}
+			}
+			d.Alerts = a
+		}
+	}
+
+	if src.Updates != nil && containsString(sections, DigestSectionUpdates) {
+		res := src.Updates()
+		u := &DigestUpdates{Manager: string(res.Manager), Total: res.Total, Error: res.Error}
+		if u.Total == 0 {
+			u.Total = len(res.Updates)
+		}
+		for i, up := range res.Updates {
+			if i == digestTopUpdates {
+				break
+			}
+			u.Names = append(u.Names, up.Name)
+		}
+		d.Updates = u
+	}
+
+	if src.Exposures != nil && containsString(sections, DigestSectionExposures) {
+		list, err := src.Exposures()
+		e := &DigestExposures{Total: len(list)}
+		if err != nil {
+			e.Error = err.Error()
+		}
+		// Riskiest first; the total still counts every exposed port.
+		sort.SliceStable(list, func(i, j int) bool { return exposureRank(list[i].Risk) > exposureRank(list[j].Risk) })
+		for i, x := range list {
+			if i == digestTopExposures {
+				break
+			}
+			e.Items = append(e.Items, DigestExposure{Port: x.Port, Protocol: x.Protocol, Address: x.Address, Process: x.Process, Risk: x.Risk})
+		}
+		d.Exposures = e
+	}
+
+	d.Text = renderDigestText(d)
+	d.HTML = renderDigestHTML(d)
+	return d
+}
+
+func exposureRank(risk string) int {
+	switch strings.ToLower(risk) {
+	case "high":
+		return 3
+	case "medium":
+		return 2
+	case "low":
+		return 1
+	default:
+		return 0
+	}
+}
+
+// SendDigest builds a digest and sends it to every enabled channel. Email
+// gets the HTML version; the event is kept in history as LevelDigest.
+func (s *Service) SendDigest(ctx context.Context, now time.Time) (Event, error) {
+	s.mu.RLock()
+	cfg := s.cfg
+	secrets := s.secrets
+	s.mu.RUnlock()
+	if !cfg.anyChannelEnabled() {
+		return Event{}, errors.New("all channels are disabled")
+	}
+
+	d := s.BuildDigest(now)
+	event := Event{Level: LevelDigest, Message: d.Text, CreatedAt: d.To}
+	id, err := s.store.SaveEvent(event)
+	if err != nil {
+		return Event{}, err
+	}
+	note := Notification{
+		EventID: id,
+		Level:   LevelDigest,
+		Subject: digestSubject(d),
+		Message: d.Text,
+		HTML:    d.HTML,
+		Host:    d.Host,
+		Time:    d.To,
+	}
+	results := s.notifier.NotifyOnce(ctx, cfg, secrets, note)
+	results = s.enqueueFailed(cfg, note, results, d.To)
+	_ = s.store.UpdateEventChannels(id, results)
+
+	event.ID = id
+	event.Channels = results
+	return event, nil
+}
+
+func digestSubject(d Digest) string {
+	return fmt.Sprintf("[QuickVPS] %s digest for %s", titleCase(string(d.Period)), d.Host)
+}
+
+func titleCase(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+func digestResources(series []history.Series) []DigestResource {
+	var out []DigestResource
+	for _, s := range series {
+		base, label := metrics.SplitSeriesName(s.Name)
+		var name string
+		switch base {
+		case metrics.SeriesCPUPercent:
+			name = "CPU"
+		case metrics.SeriesMemoryPercent:
+			name = "Memory"
+		case metrics.SeriesDiskPercent:
+			name = "Disk " + label
+		default:
+			continue
+		}
+		if len(s.Points) == 0 {
+			continue
+		}
+		var sum, peak float64
+		for _, p := range s.Points {
+			sum += p.Avg
+			if p.Max > peak {
+				peak = p.Max
+			}
+		}
+		out = append(out, DigestResource{Series: s.Name, Name: name, Avg: sum / float64(len(s.Points)), Peak: peak})
+	}
+	sort.SliceStable(out, func(i, j int) bool { return resourceOrder(out[i].Series) < resourceOrder(out[j].Series) })
+	return out
+}
+
+func resourceOrder(series string) int {
+	base, _ := metrics.SplitSeriesName(series)
+	switch base {
+	case metrics.SeriesCPUPercent:
+		return 0
+	case metrics.SeriesMemoryPercent:
+		return 1
+	default:
+		return 2
+	}
+}
+
+func digestDiskGrowth(series []history.Series) []DiskGrowth {
+	var out []DiskGrowth
+	for _, s := range series {
+		base, mount := metrics.SplitSeriesName(s.Name)
+		if base != metrics.SeriesDiskUsedBytes || len(s.Points) < 2 {
+			continue
+		}
+		start, end := s.Points[0].Avg, s.Points[len(s.Points)-1].Avg
+		out = append(out, DiskGrowth{Mountpoint: mount, StartBytes: start, EndBytes: end, GrowthBytes: end - start})
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].GrowthBytes > out[j].GrowthBytes })
+	if len(out) > digestTopDisks {
+		out = out[:digestTopDisks]
+	}
+	return out
+}
+
+func formatSignedBytes(v float64) string {
+	if v < 0 {
+		return "-" + formatBytes(uint64(-v))
+	}
+	return "+" + formatBytes(uint64(v))
+}
+
+func renderDigestText(d Digest) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[QuickVPS][DIGEST] Host=%s %s %s - %s\n",
+		d.Host, strings.ToUpper(string(d.Period)), d.From.Format(time.RFC3339), d.To.Format(time.RFC3339))
+
+	if len(d.Resources) > 0 {
+		b.WriteString("\nResources (avg / peak):")
+		for _, r := range d.Resources {
+			fmt.Fprintf(&b, "\n- %s: %.1f%% / %.1f%%", r.Name, r.Avg, r.Peak)
+		}
+		b.WriteString("\n")
+	}
+	if d.Alerts != nil {
+		fmt.Fprintf(&b, "\nAlerts fired: %d (critical %d, warning %d, flapping %d, recovered %d)\n",
+			d.Alerts.Fired, d.Alerts.ByLevel[LevelCritical], d.Alerts.ByLevel[LevelWarning],
+			d.Alerts.ByLevel[LevelFlapping], d.Alerts.ByLevel[LevelRecovery])
+	}
+	if len(d.DiskGrowth) > 0 {
+		b.WriteString("\nTop disk growth:")
+		for _, g := range d.DiskGrowth {
+			fmt.Fprintf(&b, "\n- %s: %s (now %s)", g.Mountpoint, formatSignedBytes(g.GrowthBytes), formatBytes(uint64(g.EndBytes)))
+		}
+		b.WriteString("\n")
+	}
+	if d.Updates != nil {
+		if d.Updates.Error != "" {
+			fmt.Fprintf(&b, "\nPackage updates: unavailable (%s)\n", d.Updates.Error)
+		} else {
+			fmt.Fprintf(&b, "\nPackage updates pending: %d", d.Updates.Total)
+			if len(d.Updates.Names) > 0 {
+				fmt.Fprintf(&b, " (%s)", strings.Join(d.Updates.Names, ", "))
+			}
+			b.WriteString("\n")
+		}
+	}
+	if d.Exposures != nil {
+		if d.Exposures.Error != "" {
+			fmt.Fprintf(&b, "\nExposed ports: unavailable (%s)\n", d.Exposures.Error)
+		} else {
+			fmt.Fprintf(&b, "\nExposed ports: %d", d.Exposures.Total)
+			for _, x := range d.Exposures.Items {
+				fmt.Fprintf(&b, "\n- %s/%d on %s (%s) risk=%s", x.Protocol, x.Port, x.Address, x.Process, x.Risk)
+			}
+			b.WriteString("\n")
+		}
+	}
+	for _, e := range d.Errors {
+		fmt.Fprintf(&b, "\nNote: %s", e)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+var digestHTMLTemplate = template.Must(template.New("digest").Funcs(template.FuncMap{
+	"pct":    func(v float64) string { return fmt.Sprintf("%.1f%%", v) },
+	"bytes":  func(v float64) string { return formatBytes(uint64(v)) },
+	"growth": formatSignedBytes,
+	"level":  func(m map[Level]int, l string) int { return m[Level(l)] },
+	"upper":  func(p DigestPeriod) string { return strings.ToUpper(string(p)) },
+	"ts":     func(t time.Time) string { return t.Format("2006-01-02 15:04 MST") },
+}).Parse(`<!DOCTYPE html>
+<html><body style="font-family:Arial,Helvetica,sans-serif;color:#202124;margin:0;padding:16px;">
+<div style="border-left:6px solid #1a73e8;padding:12px 16px;background:#f8f9fa;">
+<h2 style="margin:0 0 4px 0;font-size:18px;">{{upper .Period}} digest &mdash; {{.Host}}</h2>
+<div style="color:#5f6368;font-size:13px;">{{ts .From}} &ndash; {{ts .To}}</div>
+</div>
+{{if .Resources}}<h3 style="font-size:15px;">Resources</h3>
+<table cellpadding="6" style="border-collapse:collapse;font-size:13px;">
+<tr style="background:#f1f3f4;"><th align="left">Metric</th><th align="right">Average</th><th align="right">Peak</th></tr>
+{{range .Resources}}<tr><td>{{.Name}}</td><td align="right">{{pct .Avg}}</td><td align="right">{{pct .Peak}}</td></tr>
+{{end}}</table>{{end}}
+{{with .Alerts}}<h3 style="font-size:15px;">Alerts</h3>
+<p style="font-size:13px;"><b>{{.Fired}}</b> fired: <span style="color:#d93025;">{{level .ByLevel "critical"}} critical</span>, <span style="color:#f29900;">{{level .ByLevel "warning"}} warning</span>, {{level .ByLevel "flapping"}} flapping, <span style="color:#188038;">{{level .ByLevel "recovery"}} recovered</span></p>{{end}}
+{{if .DiskGrowth}}<h3 style="font-size:15px;">Top disk growth</h3>
+<table cellpadding="6" style="border-collapse:collapse;font-size:13px;">
+<tr style="background:#f1f3f4;"><th align="left">Mountpoint</th><th align="right">Growth</th><th align="right">Used now</th></tr>
+{{range .DiskGrowth}}<tr><td>{{.Mountpoint}}</td><td align="right">{{growth .GrowthBytes}}</td><td align="right">{{bytes .EndBytes}}</td></tr>
+{{end}}</table>{{end}}
+{{with .Updates}}<h3 style="font-size:15px;">Package updates</h3>
+{{if .Error}}<p style="font-size:13px;color:#5f6368;">Unavailable: {{.Error}}</p>{{else}}<p style="font-size:13px;"><b>{{.Total}}</b> pending{{if .Names}}: {{range $i, $n := .Names}}{{if $i}}, {{end}}{{$n}}{{end}}{{end}}</p>{{end}}{{end}}
+{{with .Exposures}}<h3 style="font-size:15px;">Exposed ports</h3>
+{{if .Error}}<p style="font-size:13px;color:#5f6368;">Unavailable: {{.Error}}</p>{{else}}<p style="font-size:13px;"><b>{{.Total}}</b> exposed</p>
+{{if .Items}}<table cellpadding="6" style="border-collapse:collapse;font-size:13px;">
+<tr style="background:#f1f3f4;"><th align="left">Port</th><th align="left">Address</th><th align="left">Process</th><th align="left">Risk</th></tr>
+{{range .Items}}<tr><td>{{.Protocol}}/{{.Port}}</td><td>{{.Address}}</td><td>{{.Process}}</td><td>{{.Risk}}</td></tr>
+{{end}}</table>{{end}}{{end}}{{end}}
+{{range .Errors}}<p style="font-size:12px;color:#5f6368;">Note: {{.}}</p>{{end}}
+<p style="color:#5f6368;font-size:12px;margin-top:16px;">Sent by QuickVPS</p>
+</body></html>
+`))
+
+func renderDigestHTML(d Digest) string {
+	var buf bytes.Buffer
+	if err := digestHTMLTemplate.Execute(&buf, d); err != nil {
+		return ""
+	}
+	return buf.String()
+}
@@ -0,0 +1,208 @@
+package alerts
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"quickvps/internal/firewall"
+	"quickvps/internal/history"
+	"quickvps/internal/packages"
+)
+
+func digestTestSources() DigestSources {
+	return DigestSources{
+		History: func(q history.Query) (history.Result, error) {
+			return history.Result{Series: []history.Series{
+				{Name: "cpu.percent", Points: []history.Point{{Avg: 20, Max: 40}, {Avg: 40, Max: 95}}},
+				{Name: "memory.percent", Points: []history.Point{{Avg: 50, Max: 55}}},
+				{Name: "disk.percent:/", Points: []history.Point{{Avg: 70, Max: 71}}},
+				{Name: "disk.used_bytes:/", Points: []history.Point{{Avg: 10 << 30}, {Avg: 12 << 30}}},
+				{Name: "disk.used_bytes:/var", Points: []history.Point{{Avg: 5 << 30}, {Avg: 5 << 30}}},
+			}}, nil
+		},
+		Updates: func() packages.UpdatesResult {
+			return packages.UpdatesResult{Manager: packages.ManagerAPT, Total: 2, Updates: []packages.Update{{Name: "openssl"}, {Name: "curl"}}}
+		},
+		Exposures: func() ([]firewall.Exposure, error) {
+			return []firewall.Exposure{
+				{Port: 22, Protocol: "tcp", Address: "0.0.0.0", Process: "sshd", Risk: "low"},
+				{Port: 6379, Protocol: "tcp", Address: "0.0.0.0", Process: "redis-server", Risk: "high"},
+			}, nil
+		},
+	}
+}
+
+func TestBuildDigest(t *testing.T) {
+	svc := newServiceForTests(t, NewNotifier())
+	svc.SetDigestSources(digestTestSources())
+	now := time.Date(2026, 3, 2, 8, 0, 0, 0, time.UTC)
+	for _, e := range []Event{
+		{Level: LevelCritical, CreatedAt: now.Add(-2 * time.Hour)},
+		{Level: LevelWarning, CreatedAt: now.Add(-3 * time.Hour)},
+		{Level: LevelRecovery, CreatedAt: now.Add(-time.Hour)},
+		{Level: LevelTest, CreatedAt: now.Add(-time.Hour)},
+		{Level: LevelCritical, CreatedAt: now.Add(-48 * time.Hour)},
+	} {
+		if _, err := svc.store.SaveEvent(e); err != nil {
+			t.Fatalf("SaveEvent() error = %v", err)
+		}
+	}
+
+	d := svc.BuildDigest(now)
+	if !d.From.Equal(now.Add(-24*time.Hour)) || d.Period != DigestDaily {
+		t.Fatalf("period = %s from %s", d.Period, d.From)
+	}
+	if len(d.Resources) != 3 || d.Resources[0].Name != "CPU" || d.Resources[0].Avg != 30 || d.Resources[0].Peak != 95 {
+		t.Fatalf("resources = %+v", d.Resources)
+	}
+	if d.Alerts == nil || d.Alerts.Fired != 2 || d.Alerts.ByLevel[LevelRecovery] != 1 || d.Alerts.ByLevel[LevelTest] != 0 {
+		t.Fatalf("alerts = %+v", d.Alerts)
+	}
+	if len(d.DiskGrowth) != 2 || d.DiskGrowth[0].Mountpoint != "/" || d.DiskGrowth[0].GrowthBytes != 2<<30 {
+		t.Fatalf("disk growth = %+v", d.DiskGrowth)
+	}
+	if d.Updates == nil || d.Updates.Total != 2 || d.Exposures == nil || d.Exposures.Items[0].Port != 6379 {
+		t.Fatalf("updates = %+v, exposures = %+v", d.Updates, d.Exposures)
+	}
+	for _, want := range []string{"CPU: 30.0% / 95.0%", "Alerts fired: 2", "/: +2.0 GiB", "openssl, curl", "tcp/6379"} {
+		if !strings.Contains(d.Text, want) {
+			t.Errorf("text missing %q:\n%s", want, d.Text)
+		}
+	}
+	if !strings.Contains(d.HTML, "<table") || !strings.Contains(d.HTML, "redis-server") {
+		t.Fatalf("html = %s", d.HTML)
+	}
+
+	svc.cfg.DigestSections = []string{DigestSectionAlerts}
+	d = svc.BuildDigest(now)
+	if d.Resources != nil || d.DiskGrowth != nil || d.Updates != nil || d.Exposures != nil || d.Alerts == nil {
+		t.Fatalf("sections not honored: %+v", d)
+	}
+}
+
+func TestBuildDigestReportsSourceErrors(t *testing.T) {
+	svc := newServiceForTests(t, NewNotifier())
+	svc.SetDigestSources(DigestSources{
+		History: func(history.Query) (history.Result, error) { return history.Result{}, errors.New("db locked") },
+	})
+	d := svc.BuildDigest(time.Now())
+	if len(d.Errors) != 1 || !strings.Contains(d.Text, "db locked") {
+		t.Fatalf("errors = %v, text = %s", d.Errors, d.Text)
+	}
+}
+
+func TestSendDigestUsesHTMLEmail(t *testing.T) {
+	n := NewNotifier()
+	n.sleep = func(time.Duration) {}
+	var mail emailMessage
+	n.sendEmail = func(_ context.Context, _ smtpServer, msg emailMessage) error {
+		mail = msg
+		return nil
+	}
+	svc := newServiceForTests(t, n)
+	svc.SetDigestSources(digestTestSources())
+	svc.cfg.TelegramEnabled = false
+	svc.cfg.RecipientEmails = []string{"ops@example.com"}
+	svc.cfg.SMTPHost = "smtp.example.com"
+	svc.cfg.DigestPeriod = DigestWeekly
+
+	event, err := svc.SendDigest(context.Background(), time.Now())
+	if err != nil {
+		t.Fatalf("SendDigest() error = %v", err)
+	}
+	if event.Level != LevelDigest || len(event.Channels) != 1 || !event.Channels[0].Success {
+		t.Fatalf("event = %+v", event)
+	}
+	if !strings.Contains(mail.Subject, "Weekly digest") || !strings.Contains(mail.HTML, "<html>") || mail.Text != event.Message {
+		t.Fatalf("mail = %+v", mail)
+	}
+	counts, err := svc.store.CountEventsByLevel(time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+	if err != nil || len(counts) != 0 {
+		t.Fatalf("digest counted as an alert: %v %v", counts, err)
+	}
+}
+
+func TestDigestDue(t *testing.T) {
+	svc := newServiceForTests(t, NewNotifier())
+	cfg := svc.cfg
+	cfg.DigestEnabled = true
+	cfg.DigestCron = "30 9 * * 1"
+	cfg.DigestTimezone = "Europe/Berlin"
+
+	monday := time.Date(2026, 3, 2, 8, 30, 10, 0, time.UTC) // 09:30 in Berlin
+	svc.lastDigestAt = monday.Add(-24 * time.Hour)
+	if !svc.digestDue(cfg, monday) {
+		t.Fatal("expected digest due on Monday 09:30 Berlin")
+	}
+	if svc.digestDue(cfg, monday.Add(20*time.Second)) {
+		t.Fatal("digest ran twice in the same minute")
+	}
+	if svc.digestDue(cfg, monday.Add(24*time.Hour)) {
+		t.Fatal("digest due on Tuesday")
+	}
+	cfg.DigestEnabled = false
+	if svc.digestDue(cfg, monday.Add(7*24*time.Hour)) {
+		t.Fatal("disabled digest is due")
+	}
+}
+
+func TestDigestDueCatchesUpMissedMinute(t *testing.T) {
+	svc := newServiceForTests(t, NewNotifier())
+	cfg := svc.cfg
+	cfg.DigestEnabled = true
+	cfg.DigestCron = "0 8 * * *"
+	cfg.DigestTimezone = "UTC"
+
+	svc.lastDigestAt = time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC)
+	late := time.Date(2026, 3, 2, 8, 3, 0, 0, time.UTC)
+	if !svc.digestDue(cfg, late) {
+		t.Fatal("digest missed at 08:00 was not caught up at 08:03")
+	}
+	if !svc.lastDigestAt.Equal(time.Date(2026, 3, 2, 8, 0, 0, 0, time.UTC)) {
+		t.Fatalf("lastDigestAt = %v, want the scheduled minute", svc.lastDigestAt)
+	}
+	if svc.digestDue(cfg, late.Add(time.Hour)) {
+		t.Fatal("caught-up digest was sent twice")
+	}
+}
+
+func TestDigestScheduleChangeDoesNotFireForPastTimes(t *testing.T) {
+	svc := newServiceForTests(t, NewNotifier())
+	svc.lastDigestAt = time.Now().UTC().Add(-48 * time.Hour)
+	enabled := true
+	cron := "* * * * *"
+	if _, err := svc.UpdateConfig(UpdateConfigInput{DigestEnabled: &enabled, DigestCron: &cron}); err != nil {
+		t.Fatalf("UpdateConfig() error = %v", err)
+	}
+	svc.mu.RLock()
+	cfg := svc.cfg
+	svc.mu.RUnlock()
+	if svc.digestDue(cfg, svc.lastDigestAt) {
+		t.Fatal("digest due for a time before it was enabled")
+	}
+	if !svc.digestDue(cfg, svc.lastDigestAt.Add(time.Minute)) {
+		t.Fatal("digest not due on the next scheduled minute")
+	}
+}
+
+func TestValidateDigestConfig(t *testing.T) {
+	for name, mutate := range map[string]func(*Config){
+		"period":   func(c *Config) { c.DigestPeriod = "hourly" },
+		"cron":     func(c *Config) { c.DigestCron = "61 * * * *" },
+		"timezone": func(c *Config) { c.DigestTimezone = "Mars/Base" },
+		"empty":    func(c *Config) { c.DigestSections = nil },
+		"section":  func(c *Config) { c.DigestSections = []string{"weather"} },
+	} {
+		cfg := DefaultConfig()
+		mutate(&cfg)
+		if err := validateDigest(cfg); err == nil {
+			t.Errorf("%s: expected validation error", name)
+		}
+	}
+	if err := validateDigest(DefaultConfig()); err != nil {
+		t.Fatalf("default config error = %v", err)
+	}
+}
@@ -0,0 +1,219 @@
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// smtpServer is the resolved connection info for one delivery.
+type smtpServer struct {
+	Host     string
+	Port     int
+	Security SMTPSecurity
+	Username string
+	Password string
+	From     string
+}
+
+// emailMessage is one rendered alert mail. HTML is optional; when set the
+// mail is sent as multipart/alternative with Text as the fallback part.
+type emailMessage struct {
+	To      []string
+	Subject string
+	Text    string
+	HTML    string
+	SentAt  time.Time
+}
+
+type emailSendFunc func(ctx context.Context, server smtpServer, msg emailMessage) error
+
+func smtpServerFromConfig(cfg Config, secrets Secrets) smtpServer {
+	return smtpServer{
+		Host:     strings.TrimSpace(cfg.SMTPHost),
+		Port:     cfg.SMTPPort,
+		Security: cfg.SMTPSecurity,
+		Username: strings.TrimSpace(cfg.SMTPUsername),
+		Password: strings.TrimSpace(secrets.SMTPPassword),
+		From:     strings.TrimSpace(cfg.SMTPFrom),
+	}
+}
+
+func (n *Notifier) sendEmailDefault(ctx context.Context, server smtpServer, msg emailMessage) error {
+	if server.Host == "" {
+		return errors.New("smtp_host is empty")
+	}
+	if server.From == "" {
+		return errors.New("smtp_from is empty")
+	}
+	recipients := sanitizeStringSlice(msg.To)
+	if len(recipients) == 0 {
+		return errors.New("recipient_emails is empty")
+	}
+
+	addr := net.JoinHostPort(server.Host, strconv.Itoa(server.Port))
+	tlsConfig := &tls.Config{ServerName: server.Host, MinVersion: tls.VersionTLS12}
+	if n.tlsConfig != nil {
+		tlsConfig = n.tlsConfig.Clone()
+		tlsConfig.ServerName = server.Host
+	}
+
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	var (
+		conn net.Conn
+		err  error
+	)
+	if server.Security == SMTPSecurityTLS {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: tlsConfig}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("dial smtp: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	} else {
+		_ = conn.SetDeadline(time.Now().Add(30 * time.Second))
+	}
+
+	c, err := smtp.NewClient(conn, server.Host)
+	if err != nil {
+		_ = conn.Close()
+		return fmt.Errorf("smtp handshake: %w", err)
+	}
+	defer c.Close()
+
+	if server.Security == SMTPSecuritySTARTTLS {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			return errors.New("smtp server does not support STARTTLS")
+		}
+		if err := c.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("smtp starttls: %w", err)
+		}
+	}
+
+	if server.Username != "" {
+		// smtp.PlainAuth refuses to send credentials over an unencrypted
+		// connection unless the server is localhost.
+		if err := c.Auth(smtp.PlainAuth("", server.Username, server.Password, server.Host)); err != nil {
+			return fmt.Errorf("smtp auth: %w", err)
+		}
+	}
+
+	if err := c.Mail(server.From); err != nil {
+		return fmt.Errorf("smtp mail from: %w", err)
+	}
+	for _, rcpt := range recipients {
+		if err := c.Rcpt(rcpt); err != nil {
+			return fmt.Errorf("smtp rcpt %s: %w", rcpt, err)
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("smtp data: %w", err)
+	}
+	if _, err := w.Write(buildEmail(server.From, recipients, msg)); err != nil {
+		return fmt.Errorf("smtp write: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("smtp data close: %w", err)
+	}
+	return c.Quit()
+}
+
+func buildEmail(from string, to []string, msg emailMessage) []byte {
+	var b bytes.Buffer
+	b.WriteString("From: " + from + "\r\n")
+	b.WriteString("To: " + strings.Join(to, ", ") + "\r\n")
+	b.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", msg.Subject) + "\r\n")
+	b.WriteString("Date: " + msg.SentAt.Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+
+	if msg.HTML == "" {
+		b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+		b.WriteString("\r\n")
+		b.WriteString(normalizeCRLF(msg.Text))
+		return b.Bytes()
+	}
+
+	boundary := randomBoundary()
+	b.WriteString("Content-Type: multipart/alternative; boundary=\"" + boundary + "\"\r\n")
+	b.WriteString("\r\n")
+	b.WriteString("--" + boundary + "\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	b.WriteString(normalizeCRLF(msg.Text) + "\r\n")
+	b.WriteString("--" + boundary + "\r\n")
+	b.WriteString("Content-Type: text/html; charset=UTF-8\r\n\r\n")
+	b.WriteString(normalizeCRLF(msg.HTML) + "\r\n")
+	b.WriteString("--" + boundary + "--\r\n")
+	return b.Bytes()
+}
+
+// renderEmailHTML wraps the plain-text alert in a minimal HTML body with a
+// level-coloured header.
+func renderEmailHTML(level Level, subject string, text string) string {
+	return fmt.Sprintf(
+		`<html><body style="font-family:sans-serif">`+
+			`<h2 style="color:%s;margin:0 0 12px">%s</h2>`+
+			`<pre style="font-family:monospace;white-space:pre-wrap">%s</pre>`+
+			`</body></html>`,
+		levelColor(level),
+		html.EscapeString(subject),
+		html.EscapeString(text),
+	)
+}
+
+func levelColor(level Level) string {
+	switch level {
+	case LevelCritical:
+		return "#d93025"
+	case LevelWarning:
+		return "#f29900"
+	case LevelRecovery:
+		return "#188038"
+	case LevelFlapping:
+		return "#8e24aa"
+	default:
+		return "#1a73e8"
+	}
+}
+
+func normalizeCRLF(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	return strings.ReplaceAll(s, "\n", "\r\n")
+}
+
+func randomBoundary() string {
+	buf := make([]byte, 12)
+	if _, err := rand.Read(buf); err != nil {
+		return "quickvps-boundary"
+	}
+	return "quickvps-" + hex.EncodeToString(buf)
+}
+
+func validateSMTP(cfg Config) error {
+	switch cfg.SMTPSecurity {
+	case SMTPSecuritySTARTTLS, SMTPSecurityTLS, SMTPSecurityNone:
+	default:
+		return errors.New("smtp_security must be one of starttls, tls, none")
+	}
+	if cfg.SMTPPort <= 0 || cfg.SMTPPort > 65535 {
+		return errors.New("smtp_port must be between 1 and 65535")
+	}
+	if strings.ContainsAny(cfg.SMTPHost+cfg.SMTPFrom+cfg.SMTPUsername, "\r\n") {
+		return errors.New("smtp settings must not contain line breaks")
+	}
+	return nil
+}
@@ -0,0 +1,240 @@
+package alerts
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeSMTP is a minimal single-connection SMTP server that records what it
+// receives.
+type fakeSMTP struct {
+	listener net.Listener
+	tlsCfg   *tls.Config // enables STARTTLS when set and implicit is false
+	implicit bool
+
+	auth     string
+	mailFrom string
+	rcpts    []string
+	data     string
+	done     chan struct{}
+}
+
+func startFakeSMTP(t *testing.T, tlsCfg *tls.Config, implicit bool) *fakeSMTP {
+	t.Helper()
+	var (
+		ln  net.Listener
+		err error
+	)
+	if implicit {
+		ln, err = tls.Listen("tcp", "127.0.0.1:0", tlsCfg)
+	} else {
+		ln, err = net.Listen("tcp", "127.0.0.1:0")
+	}
+	if err != nil {
+		t.Fatalf("listen error = %v", err)
+	}
+	f := &fakeSMTP{listener: ln, tlsCfg: tlsCfg, implicit: implicit, done: make(chan struct{})}
+	t.Cleanup(func() { _ = ln.Close() })
+	go f.serve()
+	return f
+}
+
+func (f *fakeSMTP) port() int {
+	return f.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (f *fakeSMTP) serve() {
+	defer close(f.done)
+	conn, err := f.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	r := bufio.NewReader(conn)
+	reply := func(line string) { _, _ = conn.Write([]byte(line + "\r\n")) }
+	reply("220 fake ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch verb {
+		case "EHLO":
+			reply("250-fake")
+			if f.tlsCfg != nil && !f.implicit {
+				if _, ok := conn.(*tls.Conn); !ok {
+					reply("250-STARTTLS")
+				}
+			}
+			reply("250 AUTH PLAIN")
+		case "STARTTLS":
+			reply("220 ready")
+			tlsConn := tls.Server(conn, f.tlsCfg)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn = tlsConn
+			r = bufio.NewReader(conn)
+		case "AUTH":
+			parts := strings.Fields(line)
+			raw, _ := base64.StdEncoding.DecodeString(parts[len(parts)-1])
+			f.auth = string(raw)
+			reply("235 ok")
+		case "MAIL":
+			f.mailFrom = line
+			reply("250 ok")
+		case "RCPT":
+			f.rcpts = append(f.rcpts, line)
+			reply("250 ok")
+		case "DATA":
+			reply("354 go ahead")
+			var b strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				b.WriteString(l)
+			}
+			f.data = b.String()
+			reply("250 queued")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
+
+// testTLS returns a server config and a client config trusting it, borrowed
+// from httptest's self-signed certificate for 127.0.0.1.
+func testTLS(t *testing.T) (*tls.Config, *tls.Config) {
+	t.Helper()
+	srv := httptest.NewTLSServer(http.NotFoundHandler())
+	t.Cleanup(srv.Close)
+	client := srv.Client().Transport.(*http.Transport).TLSClientConfig.Clone()
+	return srv.TLS.Clone(), client
+}
+
+func TestSMTPSendPlainWithAuth(t *testing.T) {
+	fake := startFakeSMTP(t, nil, false)
+	n := NewNotifier()
+
+	err := n.sendEmail(context.Background(), smtpServer{
+		Host:     "127.0.0.1",
+		Port:     fake.port(),
+		Security: SMTPSecurityNone,
+		Username: "alerts",
+		Password: "secret",
+		From:     "alerts@example.com",
+	}, emailMessage{
+		To:      []string{"ops@example.com", " ", "dev@example.com"},
+		Subject: "[QuickVPS] CPU WARNING",
+		Text:    "cpu high",
+		SentAt:  time.Now(),
+	})
+	if err != nil {
+		t.Fatalf("sendEmail() error = %v", err)
+	}
+	<-fake.done
+
+	if fake.auth != "\x00alerts\x00secret" {
+		t.Fatalf("auth = %q", fake.auth)
+	}
+	if fake.mailFrom != "MAIL FROM:<alerts@example.com>" {
+		t.Fatalf("mail from = %q", fake.mailFrom)
+	}
+	if len(fake.rcpts) != 2 {
+		t.Fatalf("rcpts = %v, want 2", fake.rcpts)
+	}
+	if !strings.Contains(fake.data, "Content-Type: text/plain") || !strings.Contains(fake.data, "cpu high") {
+		t.Fatalf("data = %q", fake.data)
+	}
+}
+
+func TestSMTPSendSTARTTLSWithHTML(t *testing.T) {
+	serverTLS, clientTLS := testTLS(t)
+	fake := startFakeSMTP(t, serverTLS, false)
+	n := NewNotifier()
+	n.tlsConfig = clientTLS
+
+	cfg := DefaultConfig()
+	cfg.EmailHTML = true
+	cfg.TelegramEnabled = false
+	cfg.RecipientEmails = []string{"ops@example.com"}
+	cfg.SMTPHost = "127.0.0.1"
+	cfg.SMTPPort = fake.port()
+	cfg.SMTPUsername = "alerts"
+	cfg.SMTPFrom = "alerts@example.com"
+	cfg.RetryDelaysSec = []int{0}
+
+	results := n.Notify(context.Background(), cfg, Secrets{SMTPPassword: "secret"}, Notification{
+		Level:   LevelCritical,
+		Subject: "[QuickVPS] CPU CRITICAL",
+		Message: "cpu <very> high",
+		Time:    time.Now(),
+	})
+	if len(results) != 1 || !results[0].Success {
+		t.Fatalf("results = %+v, want one successful email", results)
+	}
+	<-fake.done
+
+	if !strings.Contains(fake.data, "multipart/alternative") {
+		t.Fatalf("data should be multipart: %q", fake.data)
+	}
+	if !strings.Contains(fake.data, "cpu &lt;very&gt; high") {
+		t.Fatalf("html part should be escaped: %q", fake.data)
+	}
+}
+
+func TestSMTPSendImplicitTLS(t *testing.T) {
+	serverTLS, clientTLS := testTLS(t)
+	fake := startFakeSMTP(t, serverTLS, true)
+	n := NewNotifier()
+	n.tlsConfig = clientTLS
+
+	err := n.sendEmail(context.Background(), smtpServer{
+		Host:     "127.0.0.1",
+		Port:     fake.port(),
+		Security: SMTPSecurityTLS,
+		From:     "alerts@example.com",
+	}, emailMessage{To: []string{"ops@example.com"}, Subject: "s", Text: "t", SentAt: time.Now()})
+	if err != nil {
+		t.Fatalf("sendEmail() error = %v", err)
+	}
+	<-fake.done
+	if fake.auth != "" {
+		t.Fatalf("auth should be skipped without username, got %q", fake.auth)
+	}
+}
+
+func TestSMTPRequiresSTARTTLSSupport(t *testing.T) {
+	fake := startFakeSMTP(t, nil, false)
+	n := NewNotifier()
+
+	err := n.sendEmail(context.Background(), smtpServer{
+		Host:     "127.0.0.1",
+		Port:     fake.port(),
+		Security: SMTPSecuritySTARTTLS,
+		From:     "alerts@example.com",
+	}, emailMessage{To: []string{"ops@example.com"}, Subject: "s", Text: "t", SentAt: time.Now()})
+	if err == nil || !strings.Contains(err.Error(), "STARTTLS") {
+		t.Fatalf("sendEmail() error = %v, want STARTTLS error", err)
+	}
+}
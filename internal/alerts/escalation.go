@@ -0,0 +1,228 @@
+package alerts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// escalationCheckEvery is how often Run looks for due escalation steps.
+	escalationCheckEvery  = 30 * time.Second
+	maxEscalationPolicies = 10
+	maxEscalationSteps    = 10
+	maxEscalationDelayMin = 7 * 24 * 60
+)
+
+// pendingEscalation is an escalation step waiting to be sent once the service
+// lock is released. step is 0-based.
+type pendingEscalation struct {
+	incident Incident
+	source   string
+	policy   EscalationPolicy
+	step     int
+}
+
+func sanitizeEscalationPolicies(in []EscalationPolicy) []EscalationPolicy {
+	out := make([]EscalationPolicy, 0, len(in))
+	for _, p := range in {
+		p.Name = strings.TrimSpace(p.Name)
+		p.Level = Level(strings.ToLower(strings.TrimSpace(string(p.Level))))
+		steps := make([]EscalationStep, 0, len(p.Steps))
+		for _, step := range p.Steps {
+			step.Channels = sanitizeChannels(step.Channels)
+			step.RecipientEmails = sanitizeStringSlice(step.RecipientEmails)
+			step.TelegramChatIDs = sanitizeStringSlice(step.TelegramChatIDs)
+			steps = append(steps, step)
+		}
+		p.Steps = steps
+		out = append(out, p)
+	}
+	return out
+}
+
+func validateEscalationPolicies(policies []EscalationPolicy) error {
+	if len(policies) > maxEscalationPolicies {
+		return fmt.Errorf("at most %d escalation policies are allowed", maxEscalationPolicies)
+	}
+	seen := make(map[string]bool, len(policies))
+	for _, p := range policies {
+		if p.Name == "" || len(p.Name) > 100 {
+			return errors.New("escalation policy name is required and must be at most 100 characters")
+		}
+		if seen[p.Name] {
+			return fmt.Errorf("duplicate escalation policy %q", p.Name)
+		}
+		seen[p.Name] = true
+		if p.Level != LevelWarning && p.Level != LevelCritical {
+			return fmt.Errorf("escalation policy %q: level must be warning or critical", p.Name)
+		}
+		if len(p.Steps) == 0 || len(p.Steps) > maxEscalationSteps {
+			return fmt.Errorf("escalation policy %q: must have 1 to %d steps", p.Name, maxEscalationSteps)
+		}
+		prev := 0
+		for i, step := range p.Steps {
+			if step.DelayMin <= prev || step.DelayMin > maxEscalationDelayMin {
+				return fmt.Errorf("escalation policy %q step %d: delay_min must be greater than the previous step and at most %d", p.Name, i+1, maxEscalationDelayMin)
+			}
+			prev = step.DelayMin
+			if len(step.Channels) == 0 {
+				return fmt.Errorf("escalation policy %q step %d: channels are required", p.Name, i+1)
+			}
+			if err := validateChannels(step.Channels); err != nil {
+				return fmt.Errorf("escalation policy %q step %d: %w", p.Name, i+1, err)
+			}
+			for _, addr := range step.RecipientEmails {
+				if !strings.Contains(addr, "@") || strings.ContainsAny(addr, "\r\n") {
+					return fmt.Errorf("escalation policy %q step %d: invalid email %q", p.Name, i+1, addr)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// nextEscalationStep returns the 0-based step of policy that is due for inc.
+func nextEscalationStep(inc Incident, policy EscalationPolicy, now time.Time) (int, bool) {
+	if levelRank(inc.Level) < levelRank(policy.Level) {
+		return 0, false
+	}
+	start := inc.OpenedAt
+	if policy.Level == LevelCritical {
+		if inc.CriticalAt == nil {
+			return 0, false
+		}
+		start = *inc.CriticalAt
+	}
+	done := inc.Escalations[policy.Name]
+	if done >= len(policy.Steps) {
+		return 0, false
+	}
+	if now.Sub(start) < time.Duration(policy.Steps[done].DelayMin)*time.Minute {
+		return 0, false
+	}
+	return done, true
+}
+
+// runEscalations sends the due escalation steps of unacknowledged incidents.
+// At most one step per policy and incident is sent per run, so steps missed
+// during downtime go out one check apart. Nothing escalates while alerts are
+// muted or in maintenance, and an incident whose source is no longer warning,
+// critical or flapping is resolved instead of escalated.
+func (s *Service) runEscalations(ctx context.Context, now time.Time) {
+	s.mu.Lock()
+	cfg := s.cfg
+	secrets := s.secrets
+	if len(cfg.EscalationPolicies) == 0 || s.status.Silenced {
+		s.mu.Unlock()
+		return
+	}
+	var due []pendingEscalation
+	for _, inc := range s.openIncidents {
+		if inc.AckedAt != nil {
+			continue
+		}
+		if !s.sourceActiveLocked(inc.RuleID) {
+			s.resolveIncidentLocked(inc.RuleID, now)
+			continue
+		}
+		changed := false
+		for _, policy := range cfg.EscalationPolicies {
+			step, ok := nextEscalationStep(*inc, policy, now)
+			if !ok {
+				continue
+			}
+			if inc.Escalations == nil {
+				inc.Escalations = make(map[string]int)
+			}
+			inc.Escalations[policy.Name] = step + 1
+			changed = true
+			due = append(due, pendingEscalation{incident: *inc, source: s.sourceNameLocked(inc.RuleID), policy: policy, step: step})
+		}
+		if changed {
+			_ = s.store.UpdateIncident(*inc)
+		}
+	}
+	s.mu.Unlock()
+
+	sort.SliceStable(due, func(i, j int) bool { return due[i].incident.ID < due[j].incident.ID })
+	for _, e := range due {
+		s.sendEscalation(ctx, cfg, secrets, e, now)
+	}
+}
+
+// sourceActiveLocked reports whether the evaluator of an alert source is
+// still warning, critical or flapping. Must be called with s.mu held.
+func (s *Service) sourceActiveLocked(ruleID int64) bool {
+	if ruleID == 0 {
+		return s.evaluator.active()
+	}
+	rt := s.ruleRuntimes[ruleID]
+	return rt != nil && rt.evaluator.active()
+}
+
+// sourceNameLocked names an alert source for messages. Must be called with
+// s.mu held.
+func (s *Service) sourceNameLocked(ruleID int64) string {
+	if ruleID == 0 {
+		return "CPU"
+	}
+	if idx := s.ruleIndex(ruleID); idx >= 0 {
+		return s.rules[idx].Name
+	}
+	return fmt.Sprintf("rule #%d", ruleID)
+}
+
+func (s *Service) sendEscalation(ctx context.Context, cfg Config, secrets Secrets, e pendingEscalation, now time.Time) {
+	inc := e.incident
+	step := e.policy.Steps[e.step]
+	ref := &EscalationRef{IncidentID: inc.ID, Policy: e.policy.Name, Step: e.step + 1}
+	msg := formatEscalationMessage(s.hostname, inc, e.source, e.policy, e.step, now)
+
+	id, err := s.store.SaveEvent(Event{
+		Level:      inc.Level,
+		Message:    msg,
+		RuleID:     inc.RuleID,
+		IncidentID: inc.ID,
+		Series:     inc.Series,
+		Escalation: ref,
+		CreatedAt:  now,
+	})
+	if err != nil {
+		return
+	}
+	note := Notification{
+		EventID:         id,
+		Level:           inc.Level,
+		Subject:         fmt.Sprintf("[QuickVPS] ESCALATION %d/%d: %s %s on %s", e.step+1, len(e.policy.Steps), strings.ToUpper(string(inc.Level)), e.source, s.hostname),
+		Message:         msg,
+		Host:            s.hostname,
+		Series:          inc.Series,
+		Time:            now,
+		RecipientEmails: step.RecipientEmails,
+		TelegramChatIDs: step.TelegramChatIDs,
+		Escalation:      ref,
+	}
+	results := s.notifier.NotifyOnce(ctx, cfg.withChannels(step.Channels), secrets, note)
+	results = s.enqueueFailed(cfg, note, results, now)
+	_ = s.store.UpdateEventChannels(id, results)
+}
+
+func formatEscalationMessage(hostname string, inc Incident, source string, policy EscalationPolicy, step int, now time.Time) string {
+	return fmt.Sprintf(
+		"[QuickVPS][ESCALATION] Host=%s Incident=#%d Level=%s Source=%q Series=%s Policy=%q Step=%d/%d Unacknowledged=%s Time=%s",
+		hostname,
+		inc.ID,
+		strings.ToUpper(string(inc.Level)),
+		source,
+		inc.Series,
+		policy.Name,
+		step+1,
+		len(policy.Steps),
+		now.Sub(inc.OpenedAt).Truncate(time.Second),
+		now.UTC().Format(time.RFC3339),
+	)
+}
@@ -0,0 +1,217 @@
+package alerts
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"quickvps/internal/metrics"
+)
+
+type sentLog struct {
+	telegram []string
+	email    [][]string
+}
+
+func newRoutingTestService(t *testing.T) (*Service, *sentLog) {
+	t.Helper()
+	sent := &sentLog{}
+	n := NewNotifier()
+	n.sleep = func(time.Duration) {}
+	n.sendTelegram = func(_ context.Context, _ string, _ []string, text string) error {
+		sent.telegram = append(sent.telegram, text)
+		return nil
+	}
+	n.sendEmail = func(_ context.Context, _ smtpServer, msg emailMessage) error {
+		sent.email = append(sent.email, msg.To)
+		return nil
+	}
+	svc := newServiceForTests(t, n)
+	svc.cfg.RecipientEmails = []string{"ops@example.com"}
+	svc.cfg.SMTPHost = "smtp.example.com"
+	return svc, sent
+}
+
+func dispatchCPU(svc *Service, level Level, now time.Time) {
+	snap := &metrics.Snapshot{Timestamp: now, CPU: metrics.CPUMetrics{TotalPercent: 95}}
+	svc.dispatch(context.Background(), pendingAlert{trigger: Trigger{Level: level, Value: 95}}, snap, svc.cfg, svc.secrets)
+}
+
+func TestRoutesLimitChannelsPerLevel(t *testing.T) {
+	svc, sent := newRoutingTestService(t)
+	svc.cfg.Routes = map[Level][]string{
+		LevelWarning:  {"telegram"},
+		LevelCritical: {"telegram", "email"},
+	}
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	dispatchCPU(svc, LevelWarning, now)
+	if len(sent.telegram) != 1 || len(sent.email) != 0 {
+		t.Fatalf("warning sent telegram=%d email=%d, want 1/0", len(sent.telegram), len(sent.email))
+	}
+	dispatchCPU(svc, LevelCritical, now.Add(time.Minute))
+	if len(sent.telegram) != 2 || len(sent.email) != 1 {
+		t.Fatalf("critical sent telegram=%d email=%d, want 2/1", len(sent.telegram), len(sent.email))
+	}
+	// Recovery has no route, so it goes to every enabled channel.
+	dispatchCPU(svc, LevelRecovery, now.Add(2*time.Minute))
+	if len(sent.telegram) != 3 || len(sent.email) != 2 {
+		t.Fatalf("recovery sent telegram=%d email=%d, want 3/2", len(sent.telegram), len(sent.email))
+	}
+}
+
+func TestEscalationPolicySteps(t *testing.T) {
+	svc, sent := newRoutingTestService(t)
+	svc.cfg.Routes = map[Level][]string{LevelWarning: {"telegram"}, LevelCritical: {"telegram"}}
+	svc.cfg.EscalationPolicies = []EscalationPolicy{{
+		Name:  "on-call",
+		Level: LevelCritical,
+		Steps: []EscalationStep{
+			{DelayMin: 10, Channels: []string{"email"}, RecipientEmails: []string{"lead@example.com"}},
+			{DelayMin: 30, Channels: []string{"email", "telegram"}},
+		},
+	}}
+	ctx := context.Background()
+	t0 := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	dispatchCPU(svc, LevelWarning, t0.Add(-20*time.Minute))
+	dispatchCPU(svc, LevelCritical, t0)
+	svc.evaluator.activeLevel = LevelCritical
+	if len(sent.email) != 0 {
+		t.Fatalf("critical went to email despite route: %v", sent.email)
+	}
+
+	// Delays count from when the incident turned critical, not when it opened.
+	svc.runEscalations(ctx, t0.Add(9*time.Minute))
+	if len(sent.email) != 0 {
+		t.Fatal("escalated before the first delay")
+	}
+	svc.runEscalations(ctx, t0.Add(10*time.Minute))
+	svc.runEscalations(ctx, t0.Add(11*time.Minute))
+	if len(sent.email) != 1 || sent.email[0][0] != "lead@example.com" {
+		t.Fatalf("step 1 emails = %v, want one to lead@example.com", sent.email)
+	}
+
+	events, err := svc.ListHistory(1, 0)
+	if err != nil {
+		t.Fatalf("ListHistory() error = %v", err)
+	}
+	esc := events[0].Escalation
+	if esc == nil || esc.Policy != "on-call" || esc.Step != 1 || esc.IncidentID != events[0].IncidentID || events[0].Level != LevelCritical {
+		t.Fatalf("escalation event = %+v / %+v", events[0], esc)
+	}
+
+	inc, err := svc.AcknowledgeIncident(esc.IncidentID, "alice", "")
+	if err != nil {
+		t.Fatalf("AcknowledgeIncident() error = %v", err)
+	}
+	if inc.Escalations["on-call"] != 1 {
+		t.Fatalf("incident escalations = %v", inc.Escalations)
+	}
+	svc.runEscalations(ctx, t0.Add(time.Hour))
+	if len(sent.email) != 1 {
+		t.Fatal("acknowledged incident escalated")
+	}
+
+	reloaded, err := NewService(svc.store, svc.notifier, "")
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	if got := reloaded.openIncidents[0]; got == nil || got.Escalations["on-call"] != 1 || got.CriticalAt == nil || !got.CriticalAt.Equal(t0) {
+		t.Fatalf("reloaded incident = %+v", got)
+	}
+}
+
+func TestNoEscalationAfterSilencedRecovery(t *testing.T) {
+	svc, sent := newRoutingTestService(t)
+	svc.cfg.Routes = map[Level][]string{LevelCritical: {"telegram"}}
+	svc.cfg.EscalationPolicies = []EscalationPolicy{{
+		Name:  "on-call",
+		Level: LevelCritical,
+		Steps: []EscalationStep{
+			{DelayMin: 10, Channels: []string{"email"}},
+			{DelayMin: 30, Channels: []string{"email"}, RecipientEmails: []string{"lead@example.com"}},
+		},
+	}}
+	ctx := context.Background()
+	t0 := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	svc.EvaluateSnapshot(ctx, cpuSnapshot(t0, 95))
+	svc.EvaluateSnapshot(ctx, cpuSnapshot(t0.Add(10*time.Minute), 95))
+	if len(sent.telegram) != 1 {
+		t.Fatalf("telegram = %d, want the critical alert", len(sent.telegram))
+	}
+
+	// CPU recovers inside a maintenance-like silence; the escalation steps
+	// fall due meanwhile.
+	mutedUntil := t0.Add(time.Hour)
+	svc.status.MutedUntil = &mutedUntil
+	svc.EvaluateSnapshot(ctx, cpuSnapshot(t0.Add(15*time.Minute), 10))
+	svc.EvaluateSnapshot(ctx, cpuSnapshot(t0.Add(20*time.Minute), 10))
+	svc.EvaluateSnapshot(ctx, cpuSnapshot(t0.Add(61*time.Minute), 10))
+	for i := 0; i < 3; i++ {
+		svc.runEscalations(ctx, t0.Add(time.Duration(62+i)*time.Minute))
+	}
+	if len(sent.email) != 0 {
+		t.Fatalf("escalated a recovered incident: %v", sent.email)
+	}
+
+	// An incident left open while its source is quiet is resolved, not
+	// escalated.
+	dispatchCPU(svc, LevelCritical, t0.Add(2*time.Hour))
+	svc.runEscalations(ctx, t0.Add(3*time.Hour))
+	if len(sent.email) != 0 {
+		t.Fatalf("escalated an incident of a quiet source: %v", sent.email)
+	}
+	if open, _ := svc.ListIncidents(IncidentOpen, 10, 0); len(open) != 0 {
+		t.Fatalf("open incidents = %+v, want none", open)
+	}
+}
+
+func TestEscalationConfigRoundTrip(t *testing.T) {
+	svc, _ := newRoutingTestService(t)
+	policies := []EscalationPolicy{{
+		Name:  " warn ",
+		Level: "WARNING",
+		Steps: []EscalationStep{{DelayMin: 15, Channels: []string{" Telegram "}, TelegramChatIDs: []string{"42"}}},
+	}}
+	if _, err := svc.UpdateConfig(UpdateConfigInput{
+		Routes:             map[Level][]string{LevelWarning: {"Slack", "telegram"}},
+		EscalationPolicies: policies,
+	}); err != nil {
+		t.Fatalf("UpdateConfig() error = %v", err)
+	}
+	cfg, err := svc.store.LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if got := cfg.Routes[LevelWarning]; len(got) != 2 || got[0] != "slack" {
+		t.Fatalf("routes = %v", cfg.Routes)
+	}
+	if len(cfg.EscalationPolicies) != 1 || cfg.EscalationPolicies[0].Name != "warn" || cfg.EscalationPolicies[0].Steps[0].Channels[0] != "telegram" {
+		t.Fatalf("policies = %+v", cfg.EscalationPolicies)
+	}
+}
+
+func TestValidateEscalation(t *testing.T) {
+	step := EscalationStep{DelayMin: 5, Channels: []string{"email"}}
+	for name, policies := range map[string][]EscalationPolicy{
+		"name":      {{Level: LevelCritical, Steps: []EscalationStep{step}}},
+		"duplicate": {{Name: "a", Level: LevelCritical, Steps: []EscalationStep{step}}, {Name: "a", Level: LevelWarning, Steps: []EscalationStep{step}}},
+		"level":     {{Name: "a", Level: LevelRecovery, Steps: []EscalationStep{step}}},
+		"no steps":  {{Name: "a", Level: LevelCritical}},
+		"order":     {{Name: "a", Level: LevelCritical, Steps: []EscalationStep{step, step}}},
+		"channel":   {{Name: "a", Level: LevelCritical, Steps: []EscalationStep{{DelayMin: 5, Channels: []string{"pager"}}}}},
+		"email":     {{Name: "a", Level: LevelCritical, Steps: []EscalationStep{{DelayMin: 5, Channels: []string{"email"}, RecipientEmails: []string{"nope"}}}}},
+	} {
+		if err := validateEscalationPolicies(policies); err == nil {
+			t.Errorf("%s: expected validation error", name)
+		}
+	}
+	if err := validateRoutes(map[Level][]string{LevelTest: {"email"}}); err == nil {
+		t.Error("expected error routing the test level")
+	}
+	if err := validateRoutes(map[Level][]string{LevelWarning: {"fax"}}); err == nil {
+		t.Error("expected error for unknown channel")
+	}
+}
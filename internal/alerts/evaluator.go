@@ -3,8 +3,11 @@ package alerts
 import "time"
 
 type Trigger struct {
-	Level      Level
-	CPUPercent float64
+	Level Level
+	Value float64
+	// Since is when the value crossed the threshold of Level (for recovery,
+	// when it fell below the recovery point). Zero when not applicable.
+	Since time.Time
 }
 
 type Evaluator struct {
@@ -16,6 +19,13 @@ type Evaluator struct {
 	lastWarningAt      *time.Time
 	lastCriticalAt     *time.Time
 	lastRecoveryAt     *time.Time
+
+	// Flap detection: state changes inside flapWindow are kept in
+	// transitions; flapThreshold of them mark the source as flapping.
+	flapWindow    time.Duration
+	flapThreshold int
+	transitions   []time.Time
+	flappingSince *time.Time
 }
 
 func NewEvaluator() *Evaluator {
@@ -29,6 +39,25 @@ func (e *Evaluator) State() Level {
 	return e.activeLevel
 }
 
+// active reports whether the source is warning, critical or flapping.
+func (e *Evaluator) active() bool {
+	return e.activeLevel != LevelNone || e.flappingSince != nil
+}
+
+// FlappingSince is set while the evaluator suppresses flapping transitions.
+func (e *Evaluator) FlappingSince() *time.Time {
+	return e.flappingSince
+}
+
+// SetFlapPolicy configures flap detection; a threshold below 2 disables it.
+func (e *Evaluator) SetFlapPolicy(window time.Duration, threshold int) {
+	if threshold < 2 || window <= 0 {
+		window, threshold = 0, 0
+	}
+	e.flapWindow = window
+	e.flapThreshold = threshold
+}
+
 func (e *Evaluator) LastWarningAt() *time.Time {
 	return e.lastWarningAt
 }
@@ -43,12 +72,89 @@ func (e *Evaluator) LastRecoveryAt() *time.Time {
 
 func (e *Evaluator) Evaluate(now time.Time, cpuPercent float64, cfg Config, silenced bool) []Trigger {
 	if !cfg.Enabled {
-		e.resetWindows()
-		e.activeLevel = LevelNone
+		e.Reset()
 		return nil
 	}
+	return e.EvaluateThresholds(now, cpuPercent, cfg.Thresholds(), silenced)
+}
+
+// Reset drops any in-progress windows and returns the evaluator to LevelNone
+// without emitting a recovery.
+func (e *Evaluator) Reset() {
+	e.resetWindows()
+	e.activeLevel = LevelNone
+	e.transitions = nil
+	e.flappingSince = nil
+}
+
+// EvaluateThresholds runs one sample through the warning/critical/recovery
+// state machine. Both the CPU config and every alert rule go through here.
+//
+// When the state changes flapThreshold times within flapWindow, a single
+// LevelFlapping trigger replaces the transition and later transitions are
+// held back. Once a whole window passes without a state change the source is
+// considered settled and the level it settled on (recovery for none) is
+// reported.
+func (e *Evaluator) EvaluateThresholds(now time.Time, value float64, th Thresholds, silenced bool) []Trigger {
+	before := e.activeLevel
+	// While flapping the state machine runs as if silenced, so suppressed
+	// transitions do not consume cooldowns or move the last-sent times.
+	triggers := e.step(now, value, th, silenced || e.flappingSince != nil)
+	if e.flapThreshold == 0 {
+		e.transitions = nil
+		e.flappingSince = nil
+		return triggers
+	}
 
-	if cpuPercent >= cfg.CriticalPercent {
+	if e.activeLevel != before {
+		e.transitions = append(e.transitions, now)
+	}
+	cutoff := now.Add(-e.flapWindow)
+	kept := e.transitions[:0]
+	for _, at := range e.transitions {
+		if at.After(cutoff) {
+			kept = append(kept, at)
+		}
+	}
+	e.transitions = kept
+
+	if e.flappingSince == nil {
+		if len(e.transitions) < e.flapThreshold {
+			return triggers
+		}
+		since := now
+		e.flappingSince = &since
+		if silenced {
+			return nil
+		}
+		e.markSent(LevelFlapping, now)
+		return []Trigger{{Level: LevelFlapping, Value: value, Since: e.transitions[0]}}
+	}
+
+	if len(e.transitions) > 0 {
+		return nil
+	}
+	e.flappingSince = nil
+	level := e.activeLevel
+	if level == LevelNone {
+		level = LevelRecovery
+	}
+	if silenced {
+		return nil
+	}
+	e.markSent(level, now)
+	trigger := Trigger{Level: level, Value: value}
+	switch {
+	case level == LevelCritical && e.aboveCriticalSince != nil:
+		trigger.Since = *e.aboveCriticalSince
+	case level == LevelWarning && e.aboveWarningSince != nil:
+		trigger.Since = *e.aboveWarningSince
+	}
+	return []Trigger{trigger}
+}
+
+func (e *Evaluator) step(now time.Time, value float64, th Thresholds, silenced bool) []Trigger {
+	if value >= th.Critical {
 		e.belowRecoverySince = nil
 		if e.aboveWarningSince == nil {
 			t := now
@@ -59,11 +165,11 @@ func (e *Evaluator) Evaluate(now time.Time, cpuPercent float64, cfg Config, sile
 			e.aboveCriticalSince = &t
 		}
 
-		if e.windowElapsed(e.aboveCriticalSince, now, cfg.CriticalForSec) && e.canSend(LevelCritical, now, cfg.CooldownSec) {
+		if e.windowElapsed(e.aboveCriticalSince, now, th.CriticalForSec) && e.canSend(LevelCritical, now, th.CooldownSec) {
 			if !silenced {
 				e.markSent(LevelCritical, now)
 				e.activeLevel = LevelCritical
-				return []Trigger{{Level: LevelCritical, CPUPercent: cpuPercent}}
+				return []Trigger{{Level: LevelCritical, Value: value, Since: *e.aboveCriticalSince}}
 			}
 			e.activeLevel = LevelCritical
 		}
@@ -72,20 +178,20 @@ func (e *Evaluator) Evaluate(now time.Time, cpuPercent float64, cfg Config, sile
 	}
 	e.aboveCriticalSince = nil
 
-	if cpuPercent >= cfg.WarningPercent {
+	if value >= th.Warning {
 		e.belowRecoverySince = nil
 		if e.aboveWarningSince == nil {
 			t := now
 			e.aboveWarningSince = &t
 		}
 
-		if e.windowElapsed(e.aboveWarningSince, now, cfg.WarningForSec) && e.canSend(LevelWarning, now, cfg.CooldownSec) {
+		if e.windowElapsed(e.aboveWarningSince, now, th.WarningForSec) && e.canSend(LevelWarning, now, th.CooldownSec) {
 			if !silenced {
 				e.markSent(LevelWarning, now)
 				if e.activeLevel != LevelCritical {
 					e.activeLevel = LevelWarning
 				}
-				return []Trigger{{Level: LevelWarning, CPUPercent: cpuPercent}}
+				return []Trigger{{Level: LevelWarning, Value: value, Since: *e.aboveWarningSince}}
 			}
 			if e.activeLevel == LevelNone {
 				e.activeLevel = LevelWarning
@@ -96,18 +202,19 @@ func (e *Evaluator) Evaluate(now time.Time, cpuPercent float64, cfg Config, sile
 	}
 	e.aboveWarningSince = nil
 
-	if e.activeLevel != LevelNone && cpuPercent < cfg.RecoveryPercent {
+	if e.activeLevel != LevelNone && value < th.Recovery {
 		if e.belowRecoverySince == nil {
 			t := now
 			e.belowRecoverySince = &t
 		}
 
-		if e.windowElapsed(e.belowRecoverySince, now, cfg.RecoveryForSec) && e.canSend(LevelRecovery, now, cfg.CooldownSec) {
+		if e.windowElapsed(e.belowRecoverySince, now, th.RecoveryForSec) && e.canSend(LevelRecovery, now, th.CooldownSec) {
 			if !silenced {
 				e.markSent(LevelRecovery, now)
+				since := *e.belowRecoverySince
 				e.activeLevel = LevelNone
 				e.resetWindows()
-				return []Trigger{{Level: LevelRecovery, CPUPercent: cpuPercent}}
+				return []Trigger{{Level: LevelRecovery, Value: value, Since: since}}
 			}
 			e.activeLevel = LevelNone
 			e.resetWindows()
@@ -119,6 +226,60 @@ func (e *Evaluator) Evaluate(now time.Time, cpuPercent float64, cfg Config, sile
 	return nil
 }
 
+// evaluatorState is the persisted form of an Evaluator.
+type evaluatorState struct {
+	ActiveLevel        Level               `json:"active_level"`
+	AboveWarningSince  *time.Time          `json:"above_warning_since,omitempty"`
+	AboveCriticalSince *time.Time          `json:"above_critical_since,omitempty"`
+	BelowRecoverySince *time.Time          `json:"below_recovery_since,omitempty"`
+	LastSent           map[Level]time.Time `json:"last_sent,omitempty"`
+	LastWarningAt      *time.Time          `json:"last_warning_at,omitempty"`
+	LastCriticalAt     *time.Time          `json:"last_critical_at,omitempty"`
+	LastRecoveryAt     *time.Time          `json:"last_recovery_at,omitempty"`
+	Transitions        []time.Time         `json:"transitions,omitempty"`
+	FlappingSince      *time.Time          `json:"flapping_since,omitempty"`
+}
+
+func (e *Evaluator) snapshot() evaluatorState {
+	return evaluatorState{
+		ActiveLevel:        e.activeLevel,
+		AboveWarningSince:  e.aboveWarningSince,
+		AboveCriticalSince: e.aboveCriticalSince,
+		BelowRecoverySince: e.belowRecoverySince,
+		LastSent:           e.lastSent,
+		LastWarningAt:      e.lastWarningAt,
+		LastCriticalAt:     e.lastCriticalAt,
+		LastRecoveryAt:     e.lastRecoveryAt,
+		Transitions:        e.transitions,
+		FlappingSince:      e.flappingSince,
+	}
+}
+
+// restore loads a persisted state. With stale set, samples are missing for
+// part of the in-progress windows, so they restart from the next sample; the
+// active level, cooldowns and flap history are kept.
+func (e *Evaluator) restore(st evaluatorState, stale bool) {
+	e.activeLevel = st.ActiveLevel
+	if e.activeLevel == "" {
+		e.activeLevel = LevelNone
+	}
+	e.aboveWarningSince = st.AboveWarningSince
+	e.aboveCriticalSince = st.AboveCriticalSince
+	e.belowRecoverySince = st.BelowRecoverySince
+	if stale {
+		e.resetWindows()
+	}
+	e.lastSent = make(map[Level]time.Time, len(st.LastSent))
+	for level, at := range st.LastSent {
+		e.lastSent[level] = at
+	}
+	e.lastWarningAt = st.LastWarningAt
+	e.lastCriticalAt = st.LastCriticalAt
+	e.lastRecoveryAt = st.LastRecoveryAt
+	e.transitions = append([]time.Time(nil), st.Transitions...)
+	e.flappingSince = st.FlappingSince
+}
+
 func (e *Evaluator) resetWindows() {
 	e.aboveWarningSince = nil
 	e.aboveCriticalSince = nil
@@ -0,0 +1,78 @@
+package alerts
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	// evaluatorStateMaxGap is the longest evaluation pause after which
+	// in-progress windows are still trusted on restore.
+	evaluatorStateMaxGap = 2 * time.Minute
+	// evaluatorStateHeartbeat refreshes updated_at of unchanged states so a
+	// restart can tell how long evaluation was down.
+	evaluatorStateHeartbeat = 30 * time.Second
+)
+
+// restoreEvaluators loads persisted evaluator state into the CPU evaluator
+// and the rule runtimes. It runs from NewService before the service is shared.
+func (s *Service) restoreEvaluators(stored map[int64]storedEvaluatorState, now time.Time) {
+	for ruleID, row := range stored {
+		var e *Evaluator
+		if ruleID == 0 {
+			e = s.evaluator
+		} else if rt := s.ruleRuntimes[ruleID]; rt != nil {
+			e = rt.evaluator
+		}
+		if e == nil {
+			continue
+		}
+		var st evaluatorState
+		if err := json.Unmarshal([]byte(row.StateJSON), &st); err != nil {
+			continue
+		}
+		e.restore(st, now.Sub(row.UpdatedAt) > evaluatorStateMaxGap)
+		s.savedStates[ruleID] = row.StateJSON
+	}
+
+	s.status.CurrentState = s.evaluator.State()
+	s.status.LastWarningAt = s.evaluator.LastWarningAt()
+	s.status.LastCriticalAt = s.evaluator.LastCriticalAt()
+	s.status.LastRecoveryAt = s.evaluator.LastRecoveryAt()
+	s.status.FlappingSince = s.evaluator.FlappingSince()
+}
+
+// persistEvaluatorsLocked writes every evaluator whose state changed since
+// the last save. Must be called with s.mu held.
+func (s *Service) persistEvaluatorsLocked(now time.Time) {
+	s.persistEvaluatorLocked(0, s.evaluator, now)
+	for ruleID, rt := range s.ruleRuntimes {
+		s.persistEvaluatorLocked(ruleID, rt.evaluator, now)
+	}
+	if now.Sub(s.stateTouchedAt) >= evaluatorStateHeartbeat {
+		if err := s.store.TouchEvaluatorStates(now); err == nil {
+			s.stateTouchedAt = now
+		}
+	}
+}
+
+func (s *Service) persistEvaluatorLocked(ruleID int64, e *Evaluator, now time.Time) {
+	raw, err := json.Marshal(e.snapshot())
+	if err != nil {
+		return
+	}
+	state := string(raw)
+	if saved, ok := s.savedStates[ruleID]; ok && saved == state {
+		return
+	}
+	if err := s.store.SaveEvaluatorState(ruleID, state, now); err == nil {
+		s.savedStates[ruleID] = state
+	}
+}
+
+// forgetEvaluatorLocked drops the persisted state of a rule that was edited
+// or deleted. Must be called with s.mu held.
+func (s *Service) forgetEvaluatorLocked(ruleID int64) {
+	_ = s.store.DeleteEvaluatorState(ruleID)
+	delete(s.savedStates, ruleID)
+}
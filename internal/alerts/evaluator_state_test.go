@@ -0,0 +1,108 @@
+package alerts
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"quickvps/internal/metrics"
+)
+
+func newStateTestService(t *testing.T) *Service {
+	t.Helper()
+	n := NewNotifier()
+	n.sleep = func(time.Duration) {}
+	n.sendTelegram = func(context.Context, string, []string, string) error { return nil }
+	svc := newServiceForTests(t, n)
+	svc.cfg.EmailEnabled = false
+	return svc
+}
+
+func feedCPU(svc *Service, at time.Time, cpu float64) {
+	svc.EvaluateSnapshot(context.Background(), &metrics.Snapshot{Timestamp: at, CPU: metrics.CPUMetrics{TotalPercent: cpu}})
+}
+
+func TestEvaluatorStateSurvivesRestart(t *testing.T) {
+	svc := newStateTestService(t)
+	base := time.Now().UTC().Add(-11 * time.Minute)
+	feedCPU(svc, base, 95)
+	criticalAt := base.Add(10 * time.Minute)
+	feedCPU(svc, criticalAt, 95)
+	if svc.evaluator.State() != LevelCritical {
+		t.Fatalf("state = %s, want critical", svc.evaluator.State())
+	}
+
+	reloaded, err := NewService(svc.store, svc.notifier, "")
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	e := reloaded.evaluator
+	if e.State() != LevelCritical || reloaded.Status(false).CurrentState != LevelCritical {
+		t.Fatalf("restored state = %s", e.State())
+	}
+	if e.aboveCriticalSince == nil || !e.aboveCriticalSince.Equal(base) {
+		t.Fatalf("aboveCriticalSince = %v, want %s", e.aboveCriticalSince, base)
+	}
+	if !e.lastSent[LevelCritical].Equal(criticalAt) {
+		t.Fatalf("lastSent[critical] = %s, want %s", e.lastSent[LevelCritical], criticalAt)
+	}
+
+	// The cooldown carries over and recovery still fires after the restart.
+	now := time.Now().UTC()
+	feedCPU(reloaded, now, 95)
+	feedCPU(reloaded, now.Add(time.Minute), 50)
+	feedCPU(reloaded, now.Add(6*time.Minute), 50)
+	events, _ := reloaded.ListHistory(10, 0)
+	if len(events) != 2 || events[0].Level != LevelRecovery || events[1].Level != LevelCritical {
+		t.Fatalf("events = %+v, want critical then recovery only", events)
+	}
+}
+
+func TestEvaluatorStateStaleWindowsRestart(t *testing.T) {
+	svc := newStateTestService(t)
+	base := time.Now().UTC().Add(-2 * time.Hour)
+	feedCPU(svc, base, 95)
+	feedCPU(svc, base.Add(10*time.Minute), 95)
+	feedCPU(svc, base.Add(11*time.Minute), 60)
+
+	reloaded, err := NewService(svc.store, svc.notifier, "")
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	e := reloaded.evaluator
+	if e.State() != LevelCritical {
+		t.Fatalf("state = %s, want critical kept across a long gap", e.State())
+	}
+	if e.belowRecoverySince != nil || e.aboveCriticalSince != nil || e.aboveWarningSince != nil {
+		t.Fatalf("stale windows were restored: %+v", e.snapshot())
+	}
+
+	// A fresh recovery window is needed before recovery is sent.
+	now := time.Now().UTC()
+	feedCPU(reloaded, now, 60)
+	if events, _ := reloaded.ListHistory(10, 0); len(events) != 1 {
+		t.Fatalf("recovery fired on a stale window: %+v", events)
+	}
+}
+
+func TestEditedRuleForgetsEvaluatorState(t *testing.T) {
+	svc := newStateTestService(t)
+	name, series, warning, critical, recovery := "memory", metrics.SeriesMemoryPercent, 80.0, 90.0, 70.0
+	rule, err := svc.CreateRule(RuleInput{Name: &name, Series: &series, Warning: &warning, Critical: &critical, Recovery: &recovery})
+	if err != nil {
+		t.Fatalf("CreateRule() error = %v", err)
+	}
+	svc.EvaluateSnapshot(context.Background(), &metrics.Snapshot{Timestamp: time.Now().UTC(), Memory: metrics.MemMetrics{Percent: 99}})
+	stored, _ := svc.store.LoadEvaluatorStates()
+	if _, ok := stored[rule.ID]; !ok {
+		t.Fatalf("rule state not persisted: %v", stored)
+	}
+
+	if err := svc.DeleteRule(rule.ID); err != nil {
+		t.Fatalf("DeleteRule() error = %v", err)
+	}
+	stored, _ = svc.store.LoadEvaluatorStates()
+	if _, ok := stored[rule.ID]; ok {
+		t.Fatal("deleted rule state should be removed")
+	}
+}
@@ -57,3 +57,78 @@ func TestEvaluatorSilencedSuppressesNotifications(t *testing.T) {
 		t.Fatalf("expected evaluator state to become critical while silenced, got %q", e.State())
 	}
 }
+
+func TestEvaluatorFlapDetection(t *testing.T) {
+	th := Thresholds{Warning: 75, WarningForSec: 1, Critical: 95, CriticalForSec: 1, Recovery: 70, RecoveryForSec: 1}
+	e := NewEvaluator()
+	e.SetFlapPolicy(10*time.Minute, 4)
+	now := time.Date(2026, 2, 20, 10, 0, 0, 0, time.UTC)
+
+	var levels []Level
+	feed := func(value float64, samples int) {
+		for i := 0; i < samples; i++ {
+			for _, trig := range e.EvaluateThresholds(now, value, th, false) {
+				levels = append(levels, trig.Level)
+			}
+			now = now.Add(30 * time.Second)
+		}
+	}
+
+	// Two full warning/recovery cycles: the fourth transition flaps.
+	for i := 0; i < 2; i++ {
+		feed(80, 2)
+		feed(60, 2)
+	}
+	want := []Level{LevelWarning, LevelRecovery, LevelWarning, LevelFlapping}
+	if len(levels) != len(want) {
+		t.Fatalf("levels = %v, want %v", levels, want)
+	}
+	for i := range want {
+		if levels[i] != want[i] {
+			t.Fatalf("levels = %v, want %v", levels, want)
+		}
+	}
+	if e.FlappingSince() == nil {
+		t.Fatal("expected evaluator to be flapping")
+	}
+
+	// Further oscillation is suppressed.
+	levels = nil
+	for i := 0; i < 3; i++ {
+		feed(80, 2)
+		feed(60, 2)
+	}
+	if len(levels) != 0 {
+		t.Fatalf("flapping transitions were sent: %v", levels)
+	}
+
+	// Settling above warning for a whole window reports the settled level once.
+	feed(80, 2)
+	if len(levels) != 0 {
+		t.Fatalf("unexpected trigger before settling: %v", levels)
+	}
+	feed(80, 20)
+	if len(levels) != 1 || levels[0] != LevelWarning || e.FlappingSince() != nil {
+		t.Fatalf("settle levels = %v, flapping since %v", levels, e.FlappingSince())
+	}
+}
+
+func TestEvaluatorFlapDetectionDisabled(t *testing.T) {
+	th := Thresholds{Warning: 75, WarningForSec: 1, Critical: 95, CriticalForSec: 1, Recovery: 70, RecoveryForSec: 1}
+	e := NewEvaluator()
+	e.SetFlapPolicy(10*time.Minute, 0)
+	now := time.Date(2026, 2, 20, 10, 0, 0, 0, time.UTC)
+
+	sent := 0
+	for i := 0; i < 24; i++ {
+		value := 80.0
+		if (i/2)%2 == 1 {
+			value = 60
+		}
+		sent += len(e.EvaluateThresholds(now, value, th, false))
+		now = now.Add(30 * time.Second)
+	}
+	if sent != 12 || e.FlappingSince() != nil {
+		t.Fatalf("sent = %d flapping = %v, want 12 transitions and no flapping", sent, e.FlappingSince())
+	}
+}
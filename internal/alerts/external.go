@@ -0,0 +1,102 @@
+package alerts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ExternalAlert is a state change detected outside the snapshot evaluators,
+// such as a failing synthetic check.
+type ExternalAlert struct {
+	Level Level
+	// Source names what changed state, e.g. `Check "api"`.
+	Source string
+	Series string
+	Value  float64
+	// ValueText is the human-readable value for templates.
+	ValueText string
+	// Detail is appended to the standard message prefix.
+	Detail string
+	Time   time.Time
+}
+
+// NotifyExternal saves a as an event and sends it over the channels routed
+// for its level, queueing failed deliveries in the outbox. While alerts are
+// muted or in maintenance the event is saved but not sent. External alerts do
+// not open incidents.
+func (s *Service) NotifyExternal(ctx context.Context, a ExternalAlert) (Event, error) {
+	if a.Level != LevelWarning && a.Level != LevelCritical && a.Level != LevelRecovery {
+		return Event{}, fmt.Errorf("external alert level must be warning, critical or recovery, got %q", a.Level)
+	}
+	if strings.TrimSpace(a.Source) == "" {
+		return Event{}, errors.New("external alert source is required")
+	}
+	now := a.Time
+	if now.IsZero() {
+		now = time.Now().UTC()
+	}
+
+	s.mu.RLock()
+	cfg := s.cfg
+	secrets := s.secrets
+	silenced := isSilencedAt(s.status.MutedUntil, now) || s.activeMaintenanceLocked(now) != nil
+	s.mu.RUnlock()
+
+	note := Notification{
+		Level:   a.Level,
+		Host:    s.hostname,
+		Series:  a.Series,
+		Value:   a.Value,
+		Time:    now,
+		Subject: fmt.Sprintf("[QuickVPS] %s %s", a.Source, strings.ToUpper(string(a.Level))),
+		Message: fmt.Sprintf("[QuickVPS][%s] Host=%s Source=%s %s Time=%s",
+			strings.ToUpper(string(a.Level)), s.hostname, a.Source, a.Detail, now.UTC().Format(time.RFC3339)),
+	}
+	s.renderTexts(&note, TemplateData{
+		Host:         s.hostname,
+		Level:        string(a.Level),
+		Rule:         a.Source,
+		Series:       a.Series,
+		Value:        a.Value,
+		ValueText:    a.ValueText,
+		DashboardURL: cfg.DashboardURL,
+		Time:         now,
+	})
+
+	if silenced {
+		id, err := s.store.SaveEvent(Event{Level: a.Level, Message: note.Message, Series: a.Series, Value: a.Value, CreatedAt: now})
+		if err != nil {
+			return Event{}, err
+		}
+		return Event{ID: id, Level: a.Level, Message: note.Message, Series: a.Series, Value: a.Value, CreatedAt: now}, nil
+	}
+	id, results, err := s.deliverUntracked(ctx, cfg, secrets, note, 0)
+	if err != nil {
+		return Event{}, err
+	}
+	return Event{ID: id, Level: a.Level, Message: note.Message, Series: a.Series, Value: a.Value, Channels: results, CreatedAt: now}, nil
+}
+
+// deliverUntracked saves note as an event without an incident and sends it
+// over the channels routed for its level.
+func (s *Service) deliverUntracked(ctx context.Context, cfg Config, secrets Secrets, note Notification, cpuPercent float64) (int64, []ChannelResult, error) {
+	id, err := s.store.SaveEvent(Event{
+		Level:      note.Level,
+		Message:    note.Message,
+		CPUPercent: cpuPercent,
+		Series:     note.Series,
+		Value:      note.Value,
+		CreatedAt:  note.Time,
+	})
+	note.EventID = id
+	results := s.notifier.NotifyOnce(ctx, cfg.routedFor(note.Level), secrets, note)
+	if err != nil {
+		return 0, results, err
+	}
+	results = s.enqueueFailed(cfg, note, results, note.Time)
+	_ = s.store.UpdateEventChannels(id, results)
+	return id, results, nil
+}
@@ -0,0 +1,51 @@
+package alerts
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNotifyExternal(t *testing.T) {
+	svc, sent := newRoutingTestService(t)
+	svc.cfg.Routes = map[Level][]string{LevelCritical: {"telegram"}}
+	ctx := context.Background()
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	ev, err := svc.NotifyExternal(ctx, ExternalAlert{
+		Level:  LevelCritical,
+		Source: `Check "web"`,
+		Series: "check:web",
+		Detail: "State=DOWN",
+		Time:   now,
+	})
+	if err != nil {
+		t.Fatalf("NotifyExternal() error = %v", err)
+	}
+	if len(sent.telegram) != 1 || len(sent.email) != 0 {
+		t.Fatalf("sent telegram=%d email=%d, want routed to telegram only", len(sent.telegram), len(sent.email))
+	}
+	if !strings.Contains(ev.Message, `Source=Check "web" State=DOWN`) || len(ev.Channels) != 1 {
+		t.Fatalf("event = %+v", ev)
+	}
+
+	// Muted: saved to history, not sent.
+	if _, err := svc.SetSilence(30); err != nil {
+		t.Fatalf("SetSilence() error = %v", err)
+	}
+	if _, err := svc.NotifyExternal(ctx, ExternalAlert{Level: LevelRecovery, Source: `Check "web"`, Series: "check:web"}); err != nil {
+		t.Fatalf("NotifyExternal() error = %v", err)
+	}
+	if len(sent.telegram) != 1 || len(sent.email) != 0 {
+		t.Fatal("sent while muted")
+	}
+	events, err := svc.ListHistory(10, 0)
+	if err != nil || len(events) != 2 || events[0].Level != LevelRecovery {
+		t.Fatalf("history = %+v, %v", events, err)
+	}
+
+	if _, err := svc.NotifyExternal(ctx, ExternalAlert{Level: LevelTest, Source: "x"}); err == nil {
+		t.Fatal("expected error for the test level")
+	}
+}
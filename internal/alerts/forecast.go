@@ -0,0 +1,167 @@
+package alerts
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"quickvps/internal/metrics"
+)
+
+// forecastHold is how long a mountpoint must stay inside (or outside) the
+// forecast horizon before a warning (or recovery) is sent, so a short burst
+// of writes does not page anyone.
+const forecastHold = 10 * time.Minute
+
+// forecastState is the disk-full forecast alert state of one mountpoint.
+type forecastState struct {
+	active bool
+	// notified is set when the warning was sent, so a recovery follows.
+	notified    bool
+	since       time.Time
+	pendingFrom *time.Time
+	hoursToFull *float64
+}
+
+type pendingForecast struct {
+	level Level
+	disk  metrics.DiskMetrics
+}
+
+// evaluateForecasts must be called with s.mu held.
+func (s *Service) evaluateForecasts(snap *metrics.Snapshot, silenced bool) []pendingForecast {
+	if !s.cfg.ForecastEnabled {
+		s.forecasts = make(map[string]*forecastState)
+		return nil
+	}
+	now := snap.Timestamp
+	horizon := float64(s.cfg.ForecastHorizonHours)
+
+	var pending []pendingForecast
+	for _, d := range snap.Disks {
+		st := s.forecasts[d.Mountpoint]
+		if st == nil {
+			st = &forecastState{}
+			s.forecasts[d.Mountpoint] = st
+		}
+		var hours *float64
+		if d.Forecast != nil {
+			hours = d.Forecast.HoursToFull
+		}
+		st.hoursToFull = hours
+		within := hours != nil && *hours <= horizon
+		if within == st.active {
+			st.pendingFrom = nil
+			// A warning held back by a silence goes out once it ends.
+			if st.active && !st.notified && !silenced {
+				st.notified = true
+				pending = append(pending, pendingForecast{level: LevelWarning, disk: d})
+			}
+			continue
+		}
+		if st.pendingFrom == nil {
+			at := now
+			st.pendingFrom = &at
+		}
+		if now.Sub(*st.pendingFrom) < forecastHold {
+			continue
+		}
+
+		st.active = within
+		st.pendingFrom = nil
+		st.since = now
+		switch {
+		case within && !silenced:
+			st.notified = true
+			pending = append(pending, pendingForecast{level: LevelWarning, disk: d})
+		case !within && st.notified:
+			st.notified = false
+			if !silenced {
+				pending = append(pending, pendingForecast{level: LevelRecovery, disk: d})
+			}
+		}
+	}
+	return pending
+}
+
+// forecastStatuses lists the mountpoints currently predicted to fill within
+// the horizon. Must be called with s.mu held.
+func (s *Service) forecastStatuses() []DiskForecastStatus {
+	out := make([]DiskForecastStatus, 0)
+	for mount, st := range s.forecasts {
+		if !st.active {
+			continue
+		}
+		out = append(out, DiskForecastStatus{Mountpoint: mount, Since: st.since, HoursToFull: st.hoursToFull})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Mountpoint < out[j].Mountpoint })
+	return out
+}
+
+func (s *Service) dispatchForecast(ctx context.Context, p pendingForecast, snap *metrics.Snapshot, cfg Config, secrets Secrets) {
+	now := snap.Timestamp
+	d := p.disk
+	series := metrics.SeriesName(metrics.SeriesDiskPercent, d.Mountpoint)
+
+	note := Notification{
+		Level:   p.level,
+		Host:    s.hostname,
+		Series:  series,
+		Value:   d.Percent,
+		Metrics: headlineMetrics(snap),
+		Time:    now,
+		Subject: fmt.Sprintf("[QuickVPS] Disk %s full forecast %s", d.Mountpoint, strings.ToUpper(string(p.level))),
+		Message: formatForecastMessage(p.level, s.hostname, d, cfg.ForecastHorizonHours, now),
+	}
+	valueText := fmt.Sprintf("%.2f%% used", d.Percent)
+	if d.Forecast != nil && d.Forecast.HoursToFull != nil {
+		valueText = "full in " + formatHours(*d.Forecast.HoursToFull)
+	}
+	s.renderTexts(&note, TemplateData{
+		Host:         s.hostname,
+		Level:        string(p.level),
+		Rule:         "Disk forecast " + d.Mountpoint,
+		Series:       series,
+		Value:        d.Percent,
+		ValueText:    valueText,
+		Metrics:      note.Metrics,
+		DashboardURL: cfg.DashboardURL,
+		Time:         now,
+	})
+
+	_, _, _ = s.deliverUntracked(ctx, cfg, secrets, note, snap.CPU.TotalPercent)
+}
+
+func formatForecastMessage(level Level, hostname string, d metrics.DiskMetrics, horizonHours int, now time.Time) string {
+	detail := fmt.Sprintf("not predicted to fill within %dh", horizonHours)
+	if f := d.Forecast; f != nil && f.HoursToFull != nil && f.FullAt != nil {
+		detail = fmt.Sprintf("predicted full in %s (at %s, growing %s/h)",
+			formatHours(*f.HoursToFull), f.FullAt.UTC().Format(time.RFC3339), formatBytes(uint64(f.GrowthBytesPerHour)))
+	}
+	return fmt.Sprintf(
+		"[QuickVPS][%s] Host=%s Disk=%s %s Used=%.2f%% Free=%s Time=%s",
+		strings.ToUpper(string(level)),
+		hostname,
+		d.Mountpoint,
+		detail,
+		d.Percent,
+		formatBytes(d.FreeBytes),
+		now.UTC().Format(time.RFC3339),
+	)
+}
+
+// formatHours renders a time to full like the dashboard: minutes under an
+// hour, hours under two days, days beyond.
+func formatHours(hours float64) string {
+	switch {
+	case hours < 1:
+		return fmt.Sprintf("%dm", max(1, int(math.Round(hours*60))))
+	case hours < 48:
+		return fmt.Sprintf("%dh", int(math.Round(hours)))
+	default:
+		return fmt.Sprintf("%dd", int(math.Round(hours/24)))
+	}
+}
@@ -0,0 +1,120 @@
+package alerts
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"quickvps/internal/metrics"
+)
+
+func forecastSnapshot(now time.Time, hoursToFull *float64) *metrics.Snapshot {
+	disk := metrics.DiskMetrics{Mountpoint: "/var", Percent: 80, FreeBytes: 10 << 30}
+	if hoursToFull != nil {
+		fullAt := now.Add(time.Duration(*hoursToFull * float64(time.Hour)))
+		disk.Forecast = &metrics.DiskForecast{GrowthBytesPerHour: 1 << 30, HoursToFull: hoursToFull, FullAt: &fullAt}
+	}
+	return &metrics.Snapshot{Timestamp: now, Disks: []metrics.DiskMetrics{disk}}
+}
+
+func TestDiskForecastAlert(t *testing.T) {
+	svc, sent := newRoutingTestService(t)
+	svc.cfg.ForecastHorizonHours = 24
+	ctx := context.Background()
+	t0 := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	soon, later := 10.0, 48.0
+
+	// Outside the horizon nothing happens.
+	svc.EvaluateSnapshot(ctx, forecastSnapshot(t0, &later))
+	svc.EvaluateSnapshot(ctx, forecastSnapshot(t0.Add(time.Minute), &soon))
+	svc.EvaluateSnapshot(ctx, forecastSnapshot(t0.Add(5*time.Minute), &soon))
+	if len(sent.telegram) != 0 {
+		t.Fatalf("warned before the hold elapsed: %v", sent.telegram)
+	}
+	svc.EvaluateSnapshot(ctx, forecastSnapshot(t0.Add(11*time.Minute), &soon))
+	if len(sent.telegram) != 1 || !strings.Contains(sent.telegram[0], "/var") {
+		t.Fatalf("telegram = %v, want one forecast warning", sent.telegram)
+	}
+	st := svc.Status(false)
+	if len(st.DiskForecasts) != 1 || st.DiskForecasts[0].Mountpoint != "/var" {
+		t.Fatalf("status forecasts = %+v", st.DiskForecasts)
+	}
+
+	events, err := svc.ListHistory(1, 0)
+	if err != nil {
+		t.Fatalf("ListHistory() error = %v", err)
+	}
+	if events[0].Level != LevelWarning || events[0].Series != "disk.percent:/var" || events[0].IncidentID != 0 {
+		t.Fatalf("event = %+v", events[0])
+	}
+
+	// Usage stops growing: recovery after the hold.
+	svc.EvaluateSnapshot(ctx, forecastSnapshot(t0.Add(20*time.Minute), nil))
+	svc.EvaluateSnapshot(ctx, forecastSnapshot(t0.Add(31*time.Minute), nil))
+	if len(sent.telegram) != 2 {
+		t.Fatalf("telegram = %v, want a recovery", sent.telegram)
+	}
+	if st := svc.Status(false); len(st.DiskForecasts) != 0 {
+		t.Fatalf("status forecasts after recovery = %+v", st.DiskForecasts)
+	}
+}
+
+func TestDiskForecastAlertSentAfterSilence(t *testing.T) {
+	svc, sent := newRoutingTestService(t)
+	svc.cfg.ForecastHorizonHours = 24
+	ctx := context.Background()
+	t0 := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	soon := 10.0
+	mutedUntil := t0.Add(15 * time.Minute)
+	svc.status.MutedUntil = &mutedUntil
+
+	svc.EvaluateSnapshot(ctx, forecastSnapshot(t0, &soon))
+	svc.EvaluateSnapshot(ctx, forecastSnapshot(t0.Add(11*time.Minute), &soon))
+	if len(sent.telegram) != 0 {
+		t.Fatalf("warned while silenced: %v", sent.telegram)
+	}
+	if st := svc.Status(false); len(st.DiskForecasts) != 1 {
+		t.Fatalf("status forecasts while silenced = %+v", st.DiskForecasts)
+	}
+
+	svc.EvaluateSnapshot(ctx, forecastSnapshot(t0.Add(16*time.Minute), &soon))
+	if len(sent.telegram) != 1 || !strings.Contains(sent.telegram[0], "/var") {
+		t.Fatalf("telegram = %v, want the held-back warning after the silence", sent.telegram)
+	}
+	svc.EvaluateSnapshot(ctx, forecastSnapshot(t0.Add(17*time.Minute), &soon))
+	if len(sent.telegram) != 1 {
+		t.Fatalf("telegram = %v, want the warning only once", sent.telegram)
+	}
+}
+
+func TestDiskForecastAlertDisabled(t *testing.T) {
+	svc, sent := newRoutingTestService(t)
+	svc.cfg.ForecastEnabled = false
+	ctx := context.Background()
+	t0 := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	soon := 1.0
+	for i := 0; i < 20; i++ {
+		svc.EvaluateSnapshot(ctx, forecastSnapshot(t0.Add(time.Duration(i)*time.Minute), &soon))
+	}
+	if len(sent.telegram) != 0 {
+		t.Fatalf("disabled forecast sent %v", sent.telegram)
+	}
+	horizon := 0
+	if _, err := svc.UpdateConfig(UpdateConfigInput{ForecastHorizonHours: &horizon}); err == nil {
+		t.Fatal("expected error for a zero horizon")
+	}
+}
+
+func TestFormatHours(t *testing.T) {
+	for hours, want := range map[float64]string{
+		0.25:                         "15m",
+		19.6:                         "20h",
+		24 * 7:                       "7d",
+		metrics.DiskForecastMaxHours: "365d",
+	} {
+		if got := formatHours(hours); got != want {
+			t.Errorf("formatHours(%v) = %q, want %q", hours, got, want)
+		}
+	}
+}
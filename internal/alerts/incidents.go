@@ -0,0 +1,199 @@
+package alerts
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	ErrIncidentNotFound     = errors.New("incident not found")
+	ErrIncidentResolved     = errors.New("incident is already resolved")
+	ErrIncidentAcknowledged = errors.New("incident is already acknowledged")
+)
+
+const maxAckNoteLen = 1000
+
+func levelRank(level Level) int {
+	switch level {
+	case LevelCritical:
+		return 2
+	case LevelWarning, LevelFlapping:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// trackIncident updates the incident of one alert source (a rule, or 0 for
+// the CPU config) for a trigger. It returns the incident ID to attach to the
+// event, and whether the notification is a repeat of an acknowledged incident
+// and should not be sent.
+func (s *Service) trackIncident(ruleID int64, series string, level Level, now time.Time) (int64, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	inc := s.openIncidents[ruleID]
+	switch level {
+	case LevelWarning, LevelCritical, LevelFlapping:
+		if inc == nil {
+			opened := Incident{
+				RuleID:     ruleID,
+				Series:     series,
+				Level:      level,
+				OpenedAt:   now,
+				EventCount: 1,
+			}
+			if level == LevelCritical {
+				opened.CriticalAt = &now
+			}
+			created, err := s.store.CreateIncident(opened)
+			if err != nil {
+				return 0, false
+			}
+			s.openIncidents[ruleID] = &created
+			return created.ID, false
+		}
+		escalated := levelRank(level) > levelRank(inc.Level)
+		if escalated {
+			inc.Level = level
+		}
+		if level == LevelCritical && inc.CriticalAt == nil {
+			criticalAt := now
+			inc.CriticalAt = &criticalAt
+		}
+		inc.EventCount++
+		_ = s.store.UpdateIncident(*inc)
+		return inc.ID, inc.AckedAt != nil && !escalated
+
+	case LevelRecovery:
+		if inc == nil {
+			return 0, false
+		}
+		resolvedAt := now
+		inc.ResolvedAt = &resolvedAt
+		inc.EventCount++
+		_ = s.store.UpdateIncident(*inc)
+		delete(s.openIncidents, ruleID)
+		return inc.ID, false
+	}
+	return 0, false
+}
+
+// settleIncidentLocked closes the open incident of a source whose evaluator
+// went back to LevelNone without a recovery trigger: it recovered while
+// silenced or in maintenance, alerting was turned off, or a flap settled
+// while silenced. Otherwise the next episode would attach to the stale
+// incident, and be suppressed if it was acknowledged. A recovery trigger is
+// left to dispatch, which resolves the incident with its event. Must be
+// called with s.mu held.
+func (s *Service) settleIncidentLocked(ruleID int64, wasActive bool, e *Evaluator, triggers []Trigger, now time.Time) {
+	if !wasActive || e.active() {
+		return
+	}
+	for _, t := range triggers {
+		if t.Level == LevelRecovery {
+			return
+		}
+	}
+	s.resolveIncidentLocked(ruleID, now)
+}
+
+// resolveIncidentLocked closes the open incident of a source without a
+// recovery event (a deleted, disabled or reset rule). Must be called with
+// s.mu held.
+func (s *Service) resolveIncidentLocked(ruleID int64, now time.Time) {
+	inc := s.openIncidents[ruleID]
+	if inc == nil {
+		return
+	}
+	resolvedAt := now
+	inc.ResolvedAt = &resolvedAt
+	_ = s.store.UpdateIncident(*inc)
+	delete(s.openIncidents, ruleID)
+}
+
+func (s *Service) ListIncidents(status IncidentStatus, limit int, beforeID int64) ([]Incident, error) {
+	incidents, err := s.store.ListIncidents(status, limit, beforeID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range incidents {
+		incidents[i].derive()
+	}
+	return incidents, nil
+}
+
+func (s *Service) GetIncident(id int64) (Incident, error) {
+	inc, err := s.store.GetIncident(id)
+	if err != nil {
+		return Incident{}, err
+	}
+	inc.derive()
+	return inc, nil
+}
+
+// AcknowledgeIncident marks an unresolved incident as handled by author.
+// Repeat notifications at the same or a lower level stop until it resolves.
+func (s *Service) AcknowledgeIncident(id int64, author string, note string) (Incident, error) {
+	note = strings.TrimSpace(note)
+	if len(note) > maxAckNoteLen {
+		return Incident{}, errors.New("note must be at most 1000 characters")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	inc, err := s.store.GetIncident(id)
+	if err != nil {
+		return Incident{}, err
+	}
+	if inc.ResolvedAt != nil {
+		return Incident{}, ErrIncidentResolved
+	}
+	if inc.AckedAt != nil {
+		return Incident{}, ErrIncidentAcknowledged
+	}
+	if open := s.openIncidents[inc.RuleID]; open != nil && open.ID == inc.ID {
+		// The in-memory copy has the latest level and event count.
+		inc = *open
+	}
+
+	ackedAt := time.Now().UTC()
+	inc.AckedAt = &ackedAt
+	inc.AckedBy = strings.TrimSpace(author)
+	inc.AckNote = note
+	if err := s.store.UpdateIncident(inc); err != nil {
+		return Incident{}, err
+	}
+	if open := s.openIncidents[inc.RuleID]; open != nil && open.ID == inc.ID {
+		*open = inc
+	}
+	inc.derive()
+	return inc, nil
+}
+
+// derive fills the computed status and durations.
+func (i *Incident) derive() {
+	switch {
+	case i.ResolvedAt != nil:
+		i.Status = IncidentResolved
+	case i.AckedAt != nil:
+		i.Status = IncidentAcknowledged
+	default:
+		i.Status = IncidentOpen
+	}
+	i.TimeToAckSec = secondsSince(i.OpenedAt, i.AckedAt)
+	i.TimeToResolveSec = secondsSince(i.OpenedAt, i.ResolvedAt)
+}
+
+func secondsSince(from time.Time, to *time.Time) *int64 {
+	if to == nil {
+		return nil
+	}
+	sec := int64(to.Sub(from).Seconds())
+	if sec < 0 {
+		sec = 0
+	}
+	return &sec
+}
@@ -0,0 +1,213 @@
+package alerts
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"quickvps/internal/metrics"
+)
+
+func TestIncidentLifecycle(t *testing.T) {
+	n := NewNotifier()
+	n.sleep = func(time.Duration) {}
+	sent := 0
+	n.sendTelegram = func(context.Context, string, []string, string) error {
+		sent++
+		return nil
+	}
+	svc := newServiceForTests(t, n)
+	svc.cfg.EmailEnabled = false
+
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	fire := func(level Level, at time.Time) {
+		snap := &metrics.Snapshot{Timestamp: at, CPU: metrics.CPUMetrics{TotalPercent: 90}}
+		svc.dispatch(context.Background(), pendingAlert{trigger: Trigger{Level: level, Value: 90}}, snap, svc.cfg, svc.secrets)
+	}
+
+	fire(LevelWarning, base)
+	fire(LevelWarning, base.Add(30*time.Minute))
+	if sent != 2 {
+		t.Fatalf("sent = %d, want 2 before ack", sent)
+	}
+	open, err := svc.ListIncidents(IncidentOpen, 10, 0)
+	if err != nil || len(open) != 1 {
+		t.Fatalf("ListIncidents(open) = %+v, %v", open, err)
+	}
+	if open[0].EventCount != 2 || open[0].Series != metrics.SeriesCPUPercent {
+		t.Fatalf("incident = %+v", open[0])
+	}
+
+	acked, err := svc.AcknowledgeIncident(open[0].ID, "alice", "looking into it")
+	if err != nil {
+		t.Fatalf("AcknowledgeIncident() error = %v", err)
+	}
+	if acked.Status != IncidentAcknowledged || acked.AckedBy != "alice" || acked.TimeToAckSec == nil {
+		t.Fatalf("acked = %+v", acked)
+	}
+	if _, err := svc.AcknowledgeIncident(open[0].ID, "bob", ""); !errors.Is(err, ErrIncidentAcknowledged) {
+		t.Fatalf("second ack error = %v, want ErrIncidentAcknowledged", err)
+	}
+
+	fire(LevelWarning, base.Add(time.Hour))
+	if sent != 2 {
+		t.Fatalf("sent = %d, acknowledged repeat should not notify", sent)
+	}
+	fire(LevelCritical, base.Add(2*time.Hour))
+	if sent != 3 {
+		t.Fatalf("sent = %d, escalation should notify", sent)
+	}
+
+	fire(LevelRecovery, base.Add(3*time.Hour))
+	if sent != 4 {
+		t.Fatalf("sent = %d, recovery should notify", sent)
+	}
+	inc, err := svc.GetIncident(open[0].ID)
+	if err != nil {
+		t.Fatalf("GetIncident() error = %v", err)
+	}
+	if inc.Status != IncidentResolved || inc.Level != LevelCritical || inc.EventCount != 5 {
+		t.Fatalf("resolved incident = %+v", inc)
+	}
+	if inc.TimeToResolveSec == nil || *inc.TimeToResolveSec != int64(3*time.Hour/time.Second) {
+		t.Fatalf("time_to_resolve_sec = %v, want 10800", inc.TimeToResolveSec)
+	}
+	if _, err := svc.AcknowledgeIncident(inc.ID, "alice", ""); !errors.Is(err, ErrIncidentResolved) {
+		t.Fatalf("ack resolved error = %v, want ErrIncidentResolved", err)
+	}
+
+	events, _ := svc.ListHistory(10, 0)
+	for _, e := range events {
+		if e.IncidentID != inc.ID {
+			t.Fatalf("event %d incident_id = %d, want %d", e.ID, e.IncidentID, inc.ID)
+		}
+	}
+
+	// The next warning opens a fresh incident.
+	fire(LevelWarning, base.Add(4*time.Hour))
+	if all, _ := svc.ListIncidents("", 10, 0); len(all) != 2 {
+		t.Fatalf("incidents = %d, want 2", len(all))
+	}
+}
+
+func TestOpenIncidentSurvivesRestart(t *testing.T) {
+	n := NewNotifier()
+	n.sleep = func(time.Duration) {}
+	n.sendTelegram = func(context.Context, string, []string, string) error { return nil }
+	svc := newServiceForTests(t, n)
+	svc.cfg.EmailEnabled = false
+
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	snap := &metrics.Snapshot{Timestamp: now, CPU: metrics.CPUMetrics{TotalPercent: 95}}
+	svc.dispatch(context.Background(), pendingAlert{trigger: Trigger{Level: LevelCritical, Value: 95}}, snap, svc.cfg, svc.secrets)
+
+	reloaded, err := NewService(svc.store, n, "")
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	inc := reloaded.openIncidents[0]
+	if inc == nil || inc.Level != LevelCritical || !inc.OpenedAt.Equal(now) {
+		t.Fatalf("restored incident = %+v", inc)
+	}
+}
+
+func TestDisablingRuleResolvesIncident(t *testing.T) {
+	n := NewNotifier()
+	n.sleep = func(time.Duration) {}
+	n.sendTelegram = func(context.Context, string, []string, string) error { return nil }
+	svc := newServiceForTests(t, n)
+	svc.cfg.EmailEnabled = false
+
+	name := "Var disk"
+	series := "disk.percent:/var"
+	warning, critical, recovery := 80.0, 90.0, 70.0
+	rule, err := svc.CreateRule(RuleInput{Name: &name, Series: &series, Warning: &warning, Critical: &critical, Recovery: &recovery})
+	if err != nil {
+		t.Fatalf("CreateRule() error = %v", err)
+	}
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	snap := &metrics.Snapshot{Timestamp: now, Disks: []metrics.DiskMetrics{{Mountpoint: "/var", Percent: 85}}}
+	svc.dispatch(context.Background(), pendingAlert{rule: &rule, trigger: Trigger{Level: LevelWarning, Value: 85}}, snap, svc.cfg, svc.secrets)
+	if open, _ := svc.ListIncidents(IncidentOpen, 10, 0); len(open) != 1 || open[0].RuleID != rule.ID {
+		t.Fatalf("open incidents = %+v, want one for the rule", open)
+	}
+
+	disabled := false
+	if _, err := svc.UpdateRule(rule.ID, RuleInput{Enabled: &disabled}); err != nil {
+		t.Fatalf("UpdateRule() error = %v", err)
+	}
+	if open, _ := svc.ListIncidents(IncidentOpen, 10, 0); len(open) != 0 {
+		t.Fatalf("open incidents after disabling = %+v, want none", open)
+	}
+	if resolved, _ := svc.ListIncidents(IncidentResolved, 10, 0); len(resolved) != 1 || resolved[0].RuleID != rule.ID {
+		t.Fatalf("resolved incidents = %+v, want the rule's incident", resolved)
+	}
+}
+
+func cpuSnapshot(at time.Time, percent float64) *metrics.Snapshot {
+	return &metrics.Snapshot{Timestamp: at, CPU: metrics.CPUMetrics{TotalPercent: percent}}
+}
+
+func TestSilencedRecoveryResolvesIncident(t *testing.T) {
+	svc, sent := newRoutingTestService(t)
+	ctx := context.Background()
+	t0 := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	svc.EvaluateSnapshot(ctx, cpuSnapshot(t0, 80))
+	svc.EvaluateSnapshot(ctx, cpuSnapshot(t0.Add(5*time.Minute), 80))
+	open, _ := svc.ListIncidents(IncidentOpen, 10, 0)
+	if len(sent.telegram) != 1 || len(open) != 1 {
+		t.Fatalf("sent = %d, open incidents = %+v, want one warning", len(sent.telegram), open)
+	}
+	if _, err := svc.AcknowledgeIncident(open[0].ID, "alice", ""); err != nil {
+		t.Fatalf("AcknowledgeIncident() error = %v", err)
+	}
+
+	// The value recovers inside a silence, so no recovery trigger is sent.
+	mutedUntil := t0.Add(30 * time.Minute)
+	svc.status.MutedUntil = &mutedUntil
+	svc.EvaluateSnapshot(ctx, cpuSnapshot(t0.Add(10*time.Minute), 10))
+	svc.EvaluateSnapshot(ctx, cpuSnapshot(t0.Add(15*time.Minute), 10))
+	if svc.evaluator.State() != LevelNone || len(sent.telegram) != 1 {
+		t.Fatalf("state = %s, sent = %d, want a silent recovery", svc.evaluator.State(), len(sent.telegram))
+	}
+	if inc, _ := svc.GetIncident(open[0].ID); inc.Status != IncidentResolved {
+		t.Fatalf("incident after silenced recovery = %+v, want resolved", inc)
+	}
+
+	// The next episode opens a new incident and is not suppressed by the
+	// acknowledgement of the old one.
+	svc.EvaluateSnapshot(ctx, cpuSnapshot(t0.Add(40*time.Minute), 80))
+	svc.EvaluateSnapshot(ctx, cpuSnapshot(t0.Add(45*time.Minute), 80))
+	if len(sent.telegram) != 2 {
+		t.Fatalf("sent = %d, want the second warning delivered", len(sent.telegram))
+	}
+	if all, _ := svc.ListIncidents("", 10, 0); len(all) != 2 {
+		t.Fatalf("incidents = %+v, want 2", all)
+	}
+}
+
+func TestDisablingCPUAlertResolvesIncident(t *testing.T) {
+	svc, _ := newRoutingTestService(t)
+	ctx := context.Background()
+	t0 := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	svc.EvaluateSnapshot(ctx, cpuSnapshot(t0, 80))
+	svc.EvaluateSnapshot(ctx, cpuSnapshot(t0.Add(5*time.Minute), 80))
+	if open, _ := svc.ListIncidents(IncidentOpen, 10, 0); len(open) != 1 {
+		t.Fatalf("open incidents = %+v, want one", open)
+	}
+
+	disabled := false
+	if _, err := svc.UpdateConfig(UpdateConfigInput{Enabled: &disabled}); err != nil {
+		t.Fatalf("UpdateConfig() error = %v", err)
+	}
+	svc.EvaluateSnapshot(ctx, cpuSnapshot(t0.Add(6*time.Minute), 80))
+	if open, _ := svc.ListIncidents(IncidentOpen, 10, 0); len(open) != 0 {
+		t.Fatalf("open incidents after disabling = %+v, want none", open)
+	}
+	if _, ok := svc.openIncidents[0]; ok {
+		t.Fatal("CPU incident still tracked as open")
+	}
+}
@@ -0,0 +1,85 @@
+package alerts
+
+// legacyKeyID labels ciphertext written before key IDs were recorded.
+const legacyKeyID = "legacy"
+
+// KeyStatus describes the encryption key ring and which keys the stored
+// secrets are sealed under.
+type KeyStatus struct {
+	CurrentKeyID   string   `json:"current_key_id,omitempty"`
+	PreviousKeyIDs []string `json:"previous_key_ids"`
+	// SecretsByKey counts stored secrets per key ID.
+	SecretsByKey map[string]int `json:"secrets_by_key"`
+	// PendingRotation is the number of secrets not yet under the current key.
+	PendingRotation int `json:"pending_rotation"`
+}
+
+// KeyStatus reports the key ring and the key IDs of the stored secrets.
+func (s *Service) KeyStatus() KeyStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.keyStatusLocked()
+}
+
+func (s *Service) keyStatusLocked() KeyStatus {
+	status := KeyStatus{
+		CurrentKeyID:   s.cipher.KeyID(),
+		PreviousKeyIDs: s.cipher.PreviousKeyIDs(),
+		SecretsByKey:   make(map[string]int),
+	}
+	if status.PreviousKeyIDs == nil {
+		status.PreviousKeyIDs = []string{}
+	}
+	for _, ciphertext := range s.secretRec.ciphertexts() {
+		id := CiphertextKeyID(ciphertext)
+		if id == "" {
+			id = legacyKeyID
+		}
+		status.SecretsByKey[id]++
+		if id != status.CurrentKeyID {
+			status.PendingRotation++
+		}
+	}
+	return status
+}
+
+// RotateSecrets re-encrypts every stored secret under the current key in a
+// single transaction and returns how many were rewritten. If any secret
+// cannot be decrypted with the key ring nothing is changed.
+func (s *Service) RotateSecrets() (int, KeyStatus, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.secretsWritable {
+		return 0, KeyStatus{}, ErrMissingEncryptionKey
+	}
+	n, err := s.store.ReencryptSecrets(s.cipher.Reencrypt)
+	if err != nil {
+		return 0, KeyStatus{}, err
+	}
+	rec, err := s.store.LoadSecretRecord()
+	if err != nil {
+		return 0, KeyStatus{}, err
+	}
+	s.secretRec = rec
+	return n, s.keyStatusLocked(), nil
+}
+
+func (r secretRecord) ciphertexts() []string {
+	out := make([]string, 0, 8)
+	for _, c := range []string{
+		r.TelegramTokenCipher,
+		r.SMTPPasswordCipher,
+		r.WebhookSecretsCipher,
+		r.SlackWebhookCipher,
+		r.DiscordWebhookCipher,
+		r.TeamsWebhookCipher,
+		r.NtfyTokenCipher,
+		r.GotifyTokenCipher,
+	} {
+		if c != "" {
+			out = append(out, c)
+		}
+	}
+	return out
+}
@@ -0,0 +1,104 @@
+package alerts
+
+import (
+	"encoding/base64"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRotateSecretsMovesSecretsToCurrentKey(t *testing.T) {
+	oldKey := base64.StdEncoding.EncodeToString([]byte(strings.Repeat("o", 32)))
+	newKey := base64.StdEncoding.EncodeToString([]byte(strings.Repeat("n", 32)))
+
+	store, err := NewStore(filepath.Join(t.TempDir(), "alerts.db"))
+	if err != nil {
+		t.Fatalf("NewStore() error = %v", err)
+	}
+	t.Cleanup(func() {
+		_ = store.Close()
+	})
+
+	svc, err := NewService(store, NewNotifier(), oldKey)
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	if _, err := svc.UpdateConfig(UpdateConfigInput{TelegramBotToken: "tg-token", NtfyToken: "ntfy-token"}); err != nil {
+		t.Fatalf("UpdateConfig() error = %v", err)
+	}
+	oldID := svc.KeyStatus().CurrentKeyID
+
+	// Restart with the new key, keeping the old one for decryption.
+	svc, err = NewService(store, NewNotifier(), newKey, oldKey)
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	if svc.secrets.TelegramBotToken != "tg-token" {
+		t.Fatalf("telegram token = %q, want it decrypted with the previous key", svc.secrets.TelegramBotToken)
+	}
+	status := svc.KeyStatus()
+	if status.PendingRotation != 2 || status.SecretsByKey[oldID] != 2 {
+		t.Fatalf("status before rotation = %+v", status)
+	}
+
+	n, status, err := svc.RotateSecrets()
+	if err != nil {
+		t.Fatalf("RotateSecrets() error = %v", err)
+	}
+	if n != 2 || status.PendingRotation != 0 || status.SecretsByKey[status.CurrentKeyID] != 2 {
+		t.Fatalf("RotateSecrets() = %d, %+v", n, status)
+	}
+
+	// The previous key is no longer needed.
+	svc, err = NewService(store, NewNotifier(), newKey)
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	if svc.secrets.TelegramBotToken != "tg-token" || svc.secrets.NtfyToken != "ntfy-token" {
+		t.Fatalf("secrets after rotation = %+v", svc.secrets)
+	}
+}
+
+func TestRotateSecretsWithoutPreviousKeyChangesNothing(t *testing.T) {
+	oldKey := base64.StdEncoding.EncodeToString([]byte(strings.Repeat("o", 32)))
+	newKey := base64.StdEncoding.EncodeToString([]byte(strings.Repeat("n", 32)))
+
+	store, err := NewStore(filepath.Join(t.TempDir(), "alerts.db"))
+	if err != nil {
+		t.Fatalf("NewStore() error = %v", err)
+	}
+	t.Cleanup(func() {
+		_ = store.Close()
+	})
+	svc, err := NewService(store, NewNotifier(), oldKey)
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	if _, err := svc.UpdateConfig(UpdateConfigInput{TelegramBotToken: "tg-token"}); err != nil {
+		t.Fatalf("UpdateConfig() error = %v", err)
+	}
+	before, err := store.LoadSecretRecord()
+	if err != nil {
+		t.Fatalf("LoadSecretRecord() error = %v", err)
+	}
+
+	svc, err = NewService(store, NewNotifier(), newKey)
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	if _, _, err := svc.RotateSecrets(); err == nil {
+		t.Fatalf("RotateSecrets() expected an error for an unknown key")
+	}
+	after, err := store.LoadSecretRecord()
+	if err != nil {
+		t.Fatalf("LoadSecretRecord() error = %v", err)
+	}
+	if after != before {
+		t.Fatalf("secrets changed after a failed rotation")
+	}
+
+	svc = newServiceForTests(t, NewNotifier())
+	if _, _, err := svc.RotateSecrets(); err != ErrMissingEncryptionKey {
+		t.Fatalf("RotateSecrets() without a key error = %v", err)
+	}
+}
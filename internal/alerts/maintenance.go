@@ -0,0 +1,306 @@
+package alerts
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	// Embedded zone data keeps window timezones working on hosts without
+	// /usr/share/zoneinfo (minimal containers).
+	_ "time/tzdata"
+)
+
+var ErrMaintenanceWindowNotFound = errors.New("maintenance window not found")
+
+const (
+	maxMaintenanceDuration = 24 * time.Hour
+	// maintenanceLookahead bounds the search for the next window start.
+	maintenanceLookahead = 35 * 24 * time.Hour
+)
+
+// maintenanceSchedule is a validated window ready for evaluation.
+type maintenanceSchedule struct {
+	window   MaintenanceWindow
+	loc      *time.Location
+	cron     *cronSchedule
+	weekdays [7]bool
+	startMin int // minute of day, weekly windows only
+	duration time.Duration
+}
+
+func defaultMaintenanceWindow() MaintenanceWindow {
+	return MaintenanceWindow{
+		Enabled:  true,
+		Kind:     MaintenanceWeekly,
+		Weekdays: []int{},
+		Timezone: "UTC",
+	}
+}
+
+func compileMaintenanceWindow(w MaintenanceWindow) (maintenanceSchedule, error) {
+	m := maintenanceSchedule{window: w}
+	if strings.TrimSpace(w.Name) == "" {
+		return m, errors.New("name is required")
+	}
+	if len(w.Name) > 100 {
+		return m, errors.New("name must be at most 100 characters")
+	}
+	loc, err := time.LoadLocation(w.Timezone)
+	if err != nil {
+		return m, fmt.Errorf("unknown timezone %q", w.Timezone)
+	}
+	m.loc = loc
+
+	switch w.Kind {
+	case MaintenanceWeekly:
+		if len(w.Weekdays) == 0 {
+			return m, errors.New("weekdays is required for weekly windows")
+		}
+		for _, d := range w.Weekdays {
+			if d < 0 || d > 6 {
+				return m, errors.New("weekdays must be 0 (Sunday) to 6 (Saturday)")
+			}
+			m.weekdays[d] = true
+		}
+		start, err := parseClock(w.StartTime)
+		if err != nil {
+			return m, fmt.Errorf("start_time: %w", err)
+		}
+		end, err := parseClock(w.EndTime)
+		if err != nil {
+			return m, fmt.Errorf("end_time: %w", err)
+		}
+		if start == end {
+			return m, errors.New("start_time and end_time must differ")
+		}
+		m.startMin = start
+		span := end - start
+		if span < 0 {
+			span += 24 * 60
+		}
+		m.duration = time.Duration(span) * time.Minute
+
+	case MaintenanceCron:
+		c, err := parseCron(w.Cron)
+		if err != nil {
+			return m, err
+		}
+		m.cron = c
+		m.duration = time.Duration(w.DurationMin) * time.Minute
+		if m.duration <= 0 || m.duration > maxMaintenanceDuration {
+			return m, errors.New("duration_min must be between 1 and 1440")
+		}
+
+	default:
+		return m, errors.New("kind must be weekly or cron")
+	}
+	return m, nil
+}
+
+// parseClock parses "HH:MM" into minutes since midnight.
+func parseClock(raw string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(raw))
+	if err != nil {
+		return 0, errors.New("must be HH:MM")
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// hourMatches lets the next-start search skip whole hours.
+func (m maintenanceSchedule) hourMatches(local time.Time) bool {
+	if m.cron != nil {
+		return m.cron.hour&(1<<uint(local.Hour())) != 0
+	}
+	return local.Hour() == m.startMin/60
+}
+
+func (m maintenanceSchedule) startsAt(t time.Time) bool {
+	local := t.In(m.loc)
+	if m.cron != nil {
+		return m.cron.matches(local)
+	}
+	return m.weekdays[local.Weekday()] && local.Hour()*60+local.Minute() == m.startMin
+}
+
+// activeAt returns the start of the run covering now, if any.
+func (m maintenanceSchedule) activeAt(now time.Time) (time.Time, bool) {
+	minute := now.Truncate(time.Minute)
+	for back := time.Duration(0); back < m.duration; back += time.Minute {
+		start := minute.Add(-back)
+		if m.startsAt(start) && now.Before(start.Add(m.duration)) {
+			return start, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// nextAfter returns the first run start strictly after now.
+func (m maintenanceSchedule) nextAfter(now time.Time) (time.Time, bool) {
+	t := now.Truncate(time.Minute).Add(time.Minute)
+	limit := now.Add(maintenanceLookahead)
+	for t.Before(limit) {
+		local := t.In(m.loc)
+		if !m.hourMatches(local) {
+			t = t.Add(time.Duration(60-local.Minute()) * time.Minute)
+			continue
+		}
+		if m.startsAt(t) {
+			return t, true
+		}
+		t = t.Add(time.Minute)
+	}
+	return time.Time{}, false
+}
+
+func (s *Service) ListMaintenanceWindows() []MaintenanceWindow {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]MaintenanceWindow, 0, len(s.maintenance))
+	for _, m := range s.maintenance {
+		out = append(out, m.window)
+	}
+	return out
+}
+
+func (s *Service) CreateMaintenanceWindow(in MaintenanceWindowInput) (MaintenanceWindow, error) {
+	w := defaultMaintenanceWindow()
+	applyMaintenanceInput(&w, in)
+	if _, err := compileMaintenanceWindow(w); err != nil {
+		return MaintenanceWindow{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	created, err := s.store.CreateMaintenanceWindow(w)
+	if err != nil {
+		return MaintenanceWindow{}, err
+	}
+	compiled, err := compileMaintenanceWindow(created)
+	if err != nil {
+		return MaintenanceWindow{}, err
+	}
+	s.maintenance = append(s.maintenance, compiled)
+	return created, nil
+}
+
+func (s *Service) UpdateMaintenanceWindow(id int64, in MaintenanceWindowInput) (MaintenanceWindow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	idx := s.maintenanceIndex(id)
+	if idx < 0 {
+		return MaintenanceWindow{}, ErrMaintenanceWindowNotFound
+	}
+	w := s.maintenance[idx].window
+	applyMaintenanceInput(&w, in)
+	if _, err := compileMaintenanceWindow(w); err != nil {
+		return MaintenanceWindow{}, err
+	}
+
+	updated, err := s.store.UpdateMaintenanceWindow(w)
+	if err != nil {
+		return MaintenanceWindow{}, err
+	}
+	compiled, err := compileMaintenanceWindow(updated)
+	if err != nil {
+		return MaintenanceWindow{}, err
+	}
+	s.maintenance[idx] = compiled
+	return updated, nil
+}
+
+func (s *Service) DeleteMaintenanceWindow(id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	idx := s.maintenanceIndex(id)
+	if idx < 0 {
+		return ErrMaintenanceWindowNotFound
+	}
+	if err := s.store.DeleteMaintenanceWindow(id); err != nil {
+		return err
+	}
+	s.maintenance = append(s.maintenance[:idx], s.maintenance[idx+1:]...)
+	return nil
+}
+
+func (s *Service) maintenanceIndex(id int64) int {
+	for i, m := range s.maintenance {
+		if m.window.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// activeMaintenanceLocked must be called with s.mu held.
+func (s *Service) activeMaintenanceLocked(now time.Time) *MaintenanceOccurrence {
+	var active *MaintenanceOccurrence
+	for _, m := range s.maintenance {
+		if !m.window.Enabled {
+			continue
+		}
+		start, ok := m.activeAt(now)
+		if !ok {
+			continue
+		}
+		// With overlapping windows report the one that lasts longest.
+		occ := &MaintenanceOccurrence{WindowID: m.window.ID, Name: m.window.Name, Start: start.UTC(), End: start.Add(m.duration).UTC()}
+		if active == nil || occ.End.After(active.End) {
+			active = occ
+		}
+	}
+	return active
+}
+
+// nextMaintenanceLocked must be called with s.mu held.
+func (s *Service) nextMaintenanceLocked(now time.Time) *MaintenanceOccurrence {
+	var next *MaintenanceOccurrence
+	for _, m := range s.maintenance {
+		if !m.window.Enabled {
+			continue
+		}
+		start, ok := m.nextAfter(now)
+		if !ok {
+			continue
+		}
+		if next == nil || start.Before(next.Start) {
+			next = &MaintenanceOccurrence{WindowID: m.window.ID, Name: m.window.Name, Start: start.UTC(), End: start.Add(m.duration).UTC()}
+		}
+	}
+	return next
+}
+
+func applyMaintenanceInput(w *MaintenanceWindow, in MaintenanceWindowInput) {
+	if in.Name != nil {
+		w.Name = strings.TrimSpace(*in.Name)
+	}
+	if in.Enabled != nil {
+		w.Enabled = *in.Enabled
+	}
+	if in.Kind != nil {
+		w.Kind = MaintenanceKind(strings.ToLower(strings.TrimSpace(string(*in.Kind))))
+	}
+	if in.Weekdays != nil {
+		w.Weekdays = append([]int{}, in.Weekdays...)
+	}
+	if in.StartTime != nil {
+		w.StartTime = strings.TrimSpace(*in.StartTime)
+	}
+	if in.EndTime != nil {
+		w.EndTime = strings.TrimSpace(*in.EndTime)
+	}
+	if in.Cron != nil {
+		w.Cron = strings.Join(strings.Fields(*in.Cron), " ")
+	}
+	if in.DurationMin != nil {
+		w.DurationMin = *in.DurationMin
+	}
+	if in.Timezone != nil {
+		w.Timezone = strings.TrimSpace(*in.Timezone)
+		if w.Timezone == "" {
+			w.Timezone = "UTC"
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package alerts
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseCron(t *testing.T) {
+	c, err := parseCron("*/15 2-4 * * 1,7")
+	if err != nil {
+		t.Fatalf("parseCron() error = %v", err)
+	}
+	monday := time.Date(2026, 3, 2, 3, 30, 0, 0, time.UTC)
+	sunday := time.Date(2026, 3, 1, 2, 45, 0, 0, time.UTC)
+	tuesday := time.Date(2026, 3, 3, 3, 30, 0, 0, time.UTC)
+	if !c.matches(monday) || !c.matches(sunday) {
+		t.Fatal("expected Monday 03:30 and Sunday 02:45 to match")
+	}
+	if c.matches(tuesday) || c.matches(monday.Add(time.Minute)) || c.matches(monday.Add(2*time.Hour)) {
+		t.Fatal("unexpected match")
+	}
+
+	// Both day fields restricted: either may match.
+	c, _ = parseCron("0 0 1 * 0")
+	if !c.matches(time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)) || !c.matches(time.Date(2026, 4, 5, 0, 0, 0, 0, time.UTC)) {
+		t.Fatal("expected day-of-month OR day-of-week semantics")
+	}
+
+	for _, bad := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "*/0 * * * *", "5-1 * * * *", "a * * * *"} {
+		if _, err := parseCron(bad); err == nil {
+			t.Errorf("parseCron(%q) should fail", bad)
+		}
+	}
+}
+
+func TestWeeklyMaintenanceWindowAcrossMidnight(t *testing.T) {
+	m, err := compileMaintenanceWindow(MaintenanceWindow{
+		Name:      "Saturday backup",
+		Enabled:   true,
+		Kind:      MaintenanceWeekly,
+		Weekdays:  []int{6},
+		StartTime: "23:00",
+		EndTime:   "01:30",
+		Timezone:  "Europe/Berlin",
+	})
+	if err != nil {
+		t.Fatalf("compileMaintenanceWindow() error = %v", err)
+	}
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+
+	start, ok := m.activeAt(time.Date(2026, 3, 8, 0, 45, 0, 0, berlin))
+	if !ok || !start.Equal(time.Date(2026, 3, 7, 23, 0, 0, 0, berlin)) {
+		t.Fatalf("activeAt(Sunday 00:45) = %s, %v; want Saturday 23:00", start, ok)
+	}
+	if _, ok := m.activeAt(time.Date(2026, 3, 8, 1, 30, 0, 0, berlin)); ok {
+		t.Fatal("window should end at 01:30")
+	}
+	if _, ok := m.activeAt(time.Date(2026, 3, 7, 22, 59, 0, 0, berlin)); ok {
+		t.Fatal("window should not be active before 23:00")
+	}
+
+	next, ok := m.nextAfter(time.Date(2026, 3, 8, 12, 0, 0, 0, berlin))
+	if !ok || !next.Equal(time.Date(2026, 3, 14, 23, 0, 0, 0, berlin)) {
+		t.Fatalf("nextAfter() = %s, %v; want next Saturday 23:00", next, ok)
+	}
+}
+
+func TestCompileMaintenanceWindowValidation(t *testing.T) {
+	valid := MaintenanceWindow{Name: "w", Kind: MaintenanceCron, Cron: "0 3 * * *", DurationMin: 30, Timezone: "UTC"}
+	if _, err := compileMaintenanceWindow(valid); err != nil {
+		t.Fatalf("valid window error = %v", err)
+	}
+	cases := map[string]func(*MaintenanceWindow){
+		"name":     func(w *MaintenanceWindow) { w.Name = " " },
+		"timezone": func(w *MaintenanceWindow) { w.Timezone = "Mars/Olympus" },
+		"kind":     func(w *MaintenanceWindow) { w.Kind = "monthly" },
+		"cron":     func(w *MaintenanceWindow) { w.Cron = "0 3 * *" },
+		"duration": func(w *MaintenanceWindow) { w.DurationMin = 0 },
+		"weekdays": func(w *MaintenanceWindow) { w.Kind = MaintenanceWeekly; w.StartTime = "01:00"; w.EndTime = "02:00" },
+		"clock": func(w *MaintenanceWindow) {
+			w.Kind = MaintenanceWeekly
+			w.Weekdays = []int{1}
+			w.StartTime = "25:00"
+			w.EndTime = "02:00"
+		},
+	}
+	for name, mutate := range cases {
+		w := valid
+		mutate(&w)
+		if _, err := compileMaintenanceWindow(w); err == nil {
+			t.Errorf("%s: expected validation error", name)
+		}
+	}
+}
+
+func TestMaintenanceWindowSilencesAlerts(t *testing.T) {
+	svc := newServiceForTests(t, NewNotifier())
+
+	name, kind, cron, duration := "always", MaintenanceCron, "* * * * *", 5
+	w, err := svc.CreateMaintenanceWindow(MaintenanceWindowInput{Name: &name, Kind: &kind, Cron: &cron, DurationMin: &duration})
+	if err != nil {
+		t.Fatalf("CreateMaintenanceWindow() error = %v", err)
+	}
+	st := svc.Status(false)
+	if !st.Silenced || st.ActiveMaintenance == nil || st.ActiveMaintenance.WindowID != w.ID {
+		t.Fatalf("status = %+v, want silenced by window %d", st, w.ID)
+	}
+	if st.NextMaintenance == nil {
+		t.Fatal("expected next maintenance occurrence")
+	}
+
+	disabled := false
+	if _, err := svc.UpdateMaintenanceWindow(w.ID, MaintenanceWindowInput{Enabled: &disabled}); err != nil {
+		t.Fatalf("UpdateMaintenanceWindow() error = %v", err)
+	}
+	if st := svc.Status(false); st.Silenced || st.ActiveMaintenance != nil {
+		t.Fatalf("status = %+v, disabled window should not silence", st)
+	}
+
+	reloaded, err := NewService(svc.store, NewNotifier(), "")
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	if got := reloaded.ListMaintenanceWindows(); len(got) != 1 || got[0].Enabled {
+		t.Fatalf("reloaded windows = %+v", got)
+	}
+
+	if err := svc.DeleteMaintenanceWindow(w.ID); err != nil {
+		t.Fatalf("DeleteMaintenanceWindow() error = %v", err)
+	}
+	if err := svc.DeleteMaintenanceWindow(w.ID); !errors.Is(err, ErrMaintenanceWindowNotFound) {
+		t.Fatalf("DeleteMaintenanceWindow(again) error = %v", err)
+	}
+}
@@ -3,24 +3,66 @@ package alerts
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
-	"net/smtp"
 	"strings"
 	"time"
 )
 
 type telegramSendFunc func(ctx context.Context, token string, chatIDs []string, text string) error
 
-type emailSendFunc func(from string, appPassword string, to []string, subject string, body string) error
+// Notification is one alert fanned out to every enabled channel. Message is
+// the plain-text body; Subject is used where the channel has one (email).
+// It is stored as JSON in the outbox so a delivery can be replayed.
+type Notification struct {
+	EventID   int64              `json:"event_id"`
+	Level     Level              `json:"level"`
+	Subject   string             `json:"subject"`
+	Message   string             `json:"message"`
+	Host      string             `json:"host"`
+	Series    string             `json:"series,omitempty"`
+	Value     float64            `json:"value"`
+	Metrics   map[string]float64 `json:"metrics,omitempty"`
+	Processes *TopProcesses      `json:"processes,omitempty"`
+	Time      time.Time          `json:"time"`
+	// Texts holds the subject and message rendered from a notification
+	// template, by channel. Channels without an entry use Subject/Message.
+	Texts map[string]NotificationText `json:"texts,omitempty"`
+	// HTML is a ready-made email body (the digest); it is sent even when
+	// Config.EmailHTML is off.
+	HTML string `json:"html,omitempty"`
+	// RecipientEmails and TelegramChatIDs, when set, replace the configured
+	// recipients (escalation steps).
+	RecipientEmails []string       `json:"recipient_emails,omitempty"`
+	TelegramChatIDs []string       `json:"telegram_chat_ids,omitempty"`
+	Escalation      *EscalationRef `json:"escalation,omitempty"`
+}
+
+type NotificationText struct {
+	Subject string `json:"subject"`
+	Message string `json:"message"`
+}
+
+// forChannel returns the notification as one channel should send it.
+func (note Notification) forChannel(channel string) Notification {
+	if text, ok := note.Texts[channel]; ok {
+		note.Subject = text.Subject
+		note.Message = text.Message
+	}
+	return note
+}
 
 type Notifier struct {
 	httpClient   *http.Client
+	tlsConfig    *tls.Config // nil uses the system roots (tests inject their own)
 	sleep        func(time.Duration)
 	sendTelegram telegramSendFunc
 	sendEmail    emailSendFunc
+	sendWebhook  webhookSendFunc
+	sendChat     chatSendFunc
 }
 
 func NewNotifier() *Notifier {
@@ -29,53 +71,171 @@ func NewNotifier() *Notifier {
 		sleep:      time.Sleep,
 	}
 	n.sendTelegram = n.sendTelegramDefault
-	n.sendEmail = sendEmailDefault
+	n.sendEmail = n.sendEmailDefault
+	n.sendWebhook = n.sendWebhookDefault
+	n.sendChat = n.sendChatDefault
 	return n
 }
 
-func (n *Notifier) Notify(ctx context.Context, cfg Config, secrets Secrets, level Level, message string) []ChannelResult {
-	results := make([]ChannelResult, 0, 2)
-
+// Notify sends note to every enabled channel, retrying each in-process
+// with cfg.RetryDelaysSec. It is meant for interactive sends (test alerts)
+// whose failures are reported rather than queued.
+func (n *Notifier) Notify(ctx context.Context, cfg Config, secrets Secrets, note Notification) []ChannelResult {
 	delays := cfg.RetryDelaysSec
 	if len(delays) == 0 {
 		delays = []int{1, 5, 15}
 	}
+	return n.notify(ctx, delays, cfg, secrets, note)
+}
+
+// NotifyOnce makes a single attempt on every enabled channel. Alerts are sent
+// this way so a failure reaches the outbox, and survives a restart, without
+// waiting out in-process retries.
+func (n *Notifier) NotifyOnce(ctx context.Context, cfg Config, secrets Secrets, note Notification) []ChannelResult {
+	return n.notify(ctx, []int{0}, cfg, secrets, note)
+}
+
+func (n *Notifier) notify(ctx context.Context, delays []int, cfg Config, secrets Secrets, note Notification) []ChannelResult {
+	deliveries := n.deliveries(ctx, cfg, secrets, note)
+	results := make([]ChannelResult, 0, len(deliveries))
+	for _, d := range deliveries {
+		if d.err != nil {
+			d.result.ErrorMessage = d.err.Error()
+			results = append(results, d.result)
+			continue
+		}
+		results = append(results, n.deliver(ctx, delays, d.result, d.send))
+	}
+	return results
+}
+
+// Redeliver makes a single attempt on one channel of a notification, as
+// identified by the ChannelResult it produced earlier. ok is false when that
+// channel is no longer enabled or configured.
+func (n *Notifier) Redeliver(ctx context.Context, cfg Config, secrets Secrets, note Notification, channel string, targetKey string) (res ChannelResult, ok bool) {
+	for _, d := range n.deliveries(ctx, cfg, secrets, note) {
+		if d.result.Channel != channel || d.result.targetKey != targetKey {
+			continue
+		}
+		if d.err != nil {
+			d.result.Attempts = 1
+			d.result.ErrorMessage = d.err.Error()
+			return d.result, true
+		}
+		return n.deliver(ctx, []int{0}, d.result, d.send), true
+	}
+	return ChannelResult{}, false
+}
+
+// delivery is one channel/target of a notification. err is set when the
+// channel is enabled but cannot be sent (missing credentials, bad payload).
+type delivery struct {
+	result ChannelResult
+	send   func() error
+	err    error
+}
+
+func (n *Notifier) deliveries(ctx context.Context, cfg Config, secrets Secrets, note Notification) []delivery {
+	var out []delivery
 
 	if cfg.TelegramEnabled {
-		res := ChannelResult{Channel: "telegram"}
-		err := n.retry(ctx, delays, func() error {
-			return n.sendTelegram(ctx, strings.TrimSpace(secrets.TelegramBotToken), cfg.TelegramChatIDs, message)
-		}, &res)
-		if err != nil {
-			res.Success = false
-			res.ErrorMessage = err.Error()
-		} else {
-			res.Success = true
+		message := note.forChannel("telegram").Message
+		chatIDs := cfg.TelegramChatIDs
+		if len(note.TelegramChatIDs) > 0 {
+			chatIDs = note.TelegramChatIDs
 		}
-		results = append(results, res)
+		out = append(out, delivery{result: ChannelResult{Channel: "telegram"}, send: func() error {
+			return n.sendTelegram(ctx, strings.TrimSpace(secrets.TelegramBotToken), chatIDs, message)
+		}})
 	}
 
 	if cfg.EmailEnabled {
-		res := ChannelResult{Channel: "email"}
-		err := n.retry(ctx, delays, func() error {
-			return n.sendEmail(
-				strings.TrimSpace(secrets.GmailAddress),
-				strings.TrimSpace(secrets.GmailAppPassword),
-				cfg.RecipientEmails,
-				fmt.Sprintf("[QuickVPS] CPU %s", strings.ToUpper(string(level))),
-				message,
-			)
-		}, &res)
-		if err != nil {
-			res.Success = false
-			res.ErrorMessage = err.Error()
-		} else {
-			res.Success = true
+		server := smtpServerFromConfig(cfg, secrets)
+		mail := note.forChannel("email")
+		msg := emailMessage{
+			To:      cfg.RecipientEmails,
+			Subject: mail.Subject,
+			Text:    mail.Message,
+			SentAt:  note.Time,
+		}
+		if len(note.RecipientEmails) > 0 {
+			msg.To = note.RecipientEmails
 		}
-		results = append(results, res)
+		switch {
+		case note.HTML != "":
+			msg.HTML = note.HTML
+		case cfg.EmailHTML:
+			msg.HTML = renderEmailHTML(note.Level, mail.Subject, mail.Message)
+		}
+		out = append(out, delivery{result: ChannelResult{Channel: "email"}, send: func() error {
+			return n.sendEmail(ctx, server, msg)
+		}})
 	}
 
-	return results
+	chats := []struct {
+		channel string
+		enabled bool
+		build   func(Notification) (chatRequest, error)
+	}{
+		{"slack", cfg.SlackEnabled, func(note Notification) (chatRequest, error) {
+			return slackRequest(strings.TrimSpace(secrets.SlackWebhookURL), note)
+		}},
+		{"discord", cfg.DiscordEnabled, func(note Notification) (chatRequest, error) {
+			return discordRequest(strings.TrimSpace(secrets.DiscordWebhookURL), note)
+		}},
+		{"teams", cfg.TeamsEnabled, func(note Notification) (chatRequest, error) {
+			return teamsRequest(strings.TrimSpace(secrets.TeamsWebhookURL), note)
+		}},
+		{"ntfy", cfg.NtfyEnabled, func(note Notification) (chatRequest, error) {
+			return ntfyRequest(cfg.NtfyServerURL, cfg.NtfyTopic, strings.TrimSpace(secrets.NtfyToken), note)
+		}},
+		{"gotify", cfg.GotifyEnabled, func(note Notification) (chatRequest, error) {
+			return gotifyRequest(cfg.GotifyServerURL, strings.TrimSpace(secrets.GotifyToken), note)
+		}},
+	}
+	for _, chat := range chats {
+		if !chat.enabled {
+			continue
+		}
+		req, err := chat.build(note.forChannel(chat.channel))
+		out = append(out, delivery{result: ChannelResult{Channel: chat.channel}, err: err, send: func() error {
+			return n.sendChat(ctx, req)
+		}})
+	}
+
+	if cfg.WebhookEnabled && len(cfg.Webhooks) > 0 {
+		body, err := json.Marshal(webhookPayload(note.forChannel("webhook")))
+		for _, w := range cfg.Webhooks {
+			sec := secrets.Webhooks[w.ID]
+			target := w.withSecret(sec)
+			res := ChannelResult{Channel: "webhook", Target: redactWebhookURL(target.URL), targetKey: w.ID}
+			targetErr := err
+			if target.URL == "" && targetErr == nil {
+				res.Target = ""
+				targetErr = errors.New("webhook url is empty")
+			}
+			out = append(out, delivery{
+				result: res,
+				err:    targetErr,
+				send: func() error {
+					return n.sendWebhook(ctx, target, sec.Secret, body)
+				},
+			})
+		}
+	}
+
+	return out
+}
+
+// deliver runs send with retries and records the outcome in res.
+func (n *Notifier) deliver(ctx context.Context, delays []int, res ChannelResult, send func() error) ChannelResult {
+	if err := n.retry(ctx, delays, send, &res); err != nil {
+		res.Success = false
+		res.ErrorMessage = err.Error()
+	} else {
+		res.Success = true
+	}
+	return res
 }
 
 func (n *Notifier) retry(ctx context.Context, delays []int, fn func() error, res *ChannelResult) error {
@@ -155,43 +315,3 @@ func (n *Notifier) sendTelegramDefault(ctx context.Context, token string, chatID
 
 	return nil
 }
-
-func sendEmailDefault(from string, appPassword string, to []string, subject string, body string) error {
-	from = strings.TrimSpace(from)
-	appPassword = strings.TrimSpace(appPassword)
-	if from == "" {
-		return errors.New("gmail_address is empty")
-	}
-	if appPassword == "" {
-		return errors.New("gmail_app_password is empty")
-	}
-
-	recipients := make([]string, 0, len(to))
-	for _, raw := range to {
-		email := strings.TrimSpace(raw)
-		if email != "" {
-			recipients = append(recipients, email)
-		}
-	}
-	if len(recipients) == 0 {
-		return errors.New("recipient_emails is empty")
-	}
-
-	host := "smtp.gmail.com"
-	addr := host + ":587"
-	auth := smtp.PlainAuth("", from, appPassword, host)
-
-	msg := bytes.Buffer{}
-	msg.WriteString("From: " + from + "\r\n")
-	msg.WriteString("To: " + strings.Join(recipients, ",") + "\r\n")
-	msg.WriteString("Subject: " + subject + "\r\n")
-	msg.WriteString("MIME-Version: 1.0\r\n")
-	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
-	msg.WriteString("\r\n")
-	msg.WriteString(body)
-
-	if err := smtp.SendMail(addr, auth, from, recipients, msg.Bytes()); err != nil {
-		return fmt.Errorf("send gmail smtp: %w", err)
-	}
-	return nil
-}
@@ -26,7 +26,7 @@ func TestNotifierRetrySuccessAfterFailures(t *testing.T) {
 	cfg.TelegramChatIDs = []string{"123"}
 	cfg.RetryDelaysSec = []int{0, 0, 0}
 
-	results := n.Notify(context.Background(), cfg, Secrets{TelegramBotToken: "token"}, LevelCritical, "test")
+	results := n.Notify(context.Background(), cfg, Secrets{TelegramBotToken: "token"}, Notification{Level: LevelCritical, Message: "test"})
 	if len(results) != 1 {
 		t.Fatalf("results len = %d, want 1", len(results))
 	}
@@ -44,7 +44,7 @@ func TestNotifierPartialFailure(t *testing.T) {
 	n.sendTelegram = func(_ context.Context, _ string, _ []string, _ string) error {
 		return nil
 	}
-	n.sendEmail = func(_ string, _ string, _ []string, _ string, _ string) error {
+	n.sendEmail = func(_ context.Context, _ smtpServer, _ emailMessage) error {
 		return errors.New("smtp down")
 	}
 
@@ -55,7 +55,7 @@ func TestNotifierPartialFailure(t *testing.T) {
 	cfg.RecipientEmails = []string{"ops@example.com"}
 	cfg.RetryDelaysSec = []int{0}
 
-	results := n.Notify(context.Background(), cfg, Secrets{TelegramBotToken: "token", GmailAddress: "noreply@example.com", GmailAppPassword: "pass"}, LevelWarning, "test")
+	results := n.Notify(context.Background(), cfg, Secrets{TelegramBotToken: "token", SMTPPassword: "pass"}, Notification{Level: LevelWarning, Subject: "[QuickVPS] CPU WARNING", Message: "test"})
 	if len(results) != 2 {
 		t.Fatalf("results len = %d, want 2", len(results))
 	}
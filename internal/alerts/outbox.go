@@ -0,0 +1,230 @@
+package alerts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrOutboxNotFound = errors.New("outbox item not found")
+
+const (
+	// outboxBaseDelay doubles with every attempt past the configured retry
+	// delays, up to outboxMaxDelay.
+	outboxBaseDelay = 30 * time.Second
+	outboxMaxDelay  = time.Hour
+	// DefaultOutboxMaxAgeHours is how long a delivery is retried before it
+	// is given up, unless Config.OutboxMaxAgeHours says otherwise.
+	DefaultOutboxMaxAgeHours = 24
+	maxOutboxMaxAgeHours     = 30 * 24
+)
+
+// outboxRecord is an OutboxItem plus what is needed to replay it.
+type outboxRecord struct {
+	OutboxItem
+	TargetKey string
+	Note      Notification
+}
+
+func outboxBackoff(attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+	if attempts > 12 {
+		return outboxMaxDelay
+	}
+	d := outboxBaseDelay << (attempts - 1)
+	if d > outboxMaxDelay {
+		return outboxMaxDelay
+	}
+	return d
+}
+
+// outboxDelay is the wait after the given number of failed attempts: the
+// configured retry delays first, then exponential backoff.
+func outboxDelay(retryDelays []int, attempts int) time.Duration {
+	if attempts >= 1 && attempts <= len(retryDelays) {
+		return time.Duration(retryDelays[attempts-1]) * time.Second
+	}
+	return outboxBackoff(attempts - len(retryDelays))
+}
+
+// enqueueFailed writes every failed result to the outbox and marks it queued.
+// It runs right after the first attempt, so the outbox worker owns every
+// retry and a restart loses nothing.
+func (s *Service) enqueueFailed(cfg Config, note Notification, results []ChannelResult, now time.Time) []ChannelResult {
+	queued := false
+	for i, res := range results {
+		if res.Success {
+			continue
+		}
+		_, err := s.store.EnqueueOutbox(outboxRecord{
+			OutboxItem: OutboxItem{
+				EventID:       note.EventID,
+				Channel:       res.Channel,
+				Target:        res.Target,
+				Level:         note.Level,
+				Attempts:      res.Attempts,
+				NextAttemptAt: now.Add(outboxDelay(cfg.RetryDelaysSec, res.Attempts)),
+				LastError:     res.ErrorMessage,
+				CreatedAt:     now,
+			},
+			TargetKey: res.targetKey,
+			Note:      note,
+		})
+		if err == nil {
+			results[i].Queued = true
+			queued = true
+		}
+	}
+	if queued {
+		// Wake the worker; a short first retry delay may be due before
+		// its next check.
+		select {
+		case s.outboxWake <- struct{}{}:
+		default:
+		}
+	}
+	return results
+}
+
+func (s *Service) ListOutbox() ([]OutboxItem, error) {
+	recs, err := s.store.ListOutbox()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]OutboxItem, 0, len(recs))
+	for _, rec := range recs {
+		out = append(out, rec.OutboxItem)
+	}
+	return out, nil
+}
+
+// runOutbox is the outbox worker, run on its own goroutine so a slow
+// redelivery never holds up alert evaluation. It wakes when a delivery is
+// queued, when the earliest pending one falls due and at least every
+// outboxEvery.
+func (s *Service) runOutbox(ctx context.Context) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		case <-s.outboxWake:
+		}
+		wait := s.outboxEvery
+		if next, ok := s.processOutbox(ctx, time.Now().UTC()); ok {
+			wait = min(wait, max(time.Until(next), 0))
+		}
+		timer.Reset(wait)
+	}
+}
+
+// processOutbox retries every delivery that is due at now. It returns when
+// the earliest delivery still pending is due.
+func (s *Service) processOutbox(ctx context.Context, now time.Time) (time.Time, bool) {
+	s.outboxMu.Lock()
+	defer s.outboxMu.Unlock()
+
+	recs, err := s.store.ListOutbox()
+	if err != nil {
+		return time.Time{}, false
+	}
+	for _, rec := range recs {
+		if ctx.Err() != nil {
+			return time.Time{}, false
+		}
+		if rec.NextAttemptAt.After(now) {
+			continue
+		}
+		_, _ = s.attemptOutbox(ctx, rec, now, true)
+	}
+
+	recs, err = s.store.ListOutbox()
+	if err != nil || len(recs) == 0 {
+		return time.Time{}, false
+	}
+	next := recs[0].NextAttemptAt
+	for _, rec := range recs[1:] {
+		if rec.NextAttemptAt.Before(next) {
+			next = rec.NextAttemptAt
+		}
+	}
+	return next, true
+}
+
+// RetryOutbox attempts one delivery immediately, regardless of its schedule.
+func (s *Service) RetryOutbox(ctx context.Context, id int64) (ChannelResult, error) {
+	s.outboxMu.Lock()
+	defer s.outboxMu.Unlock()
+
+	rec, err := s.store.GetOutbox(id)
+	if err != nil {
+		return ChannelResult{}, err
+	}
+	return s.attemptOutbox(ctx, rec, time.Now().UTC(), false)
+}
+
+// DropOutbox abandons a pending delivery and records it as failed on the event.
+func (s *Service) DropOutbox(id int64) error {
+	s.outboxMu.Lock()
+	defer s.outboxMu.Unlock()
+
+	rec, err := s.store.GetOutbox(id)
+	if err != nil {
+		return err
+	}
+	if err := s.store.DeleteOutbox(id); err != nil {
+		return err
+	}
+	return s.store.UpdateEventChannel(rec.EventID, ChannelResult{
+		Channel:      rec.Channel,
+		Target:       rec.Target,
+		Attempts:     rec.Attempts,
+		ErrorMessage: "dropped from outbox: " + rec.LastError,
+	})
+}
+
+// attemptOutbox must be called with s.outboxMu held. Expired deliveries are
+// only given up when expire is set, so a manual retry never drops an item.
+func (s *Service) attemptOutbox(ctx context.Context, rec outboxRecord, now time.Time, expire bool) (ChannelResult, error) {
+	s.mu.RLock()
+	cfg := s.cfg
+	secrets := s.secrets
+	s.mu.RUnlock()
+
+	res, ok := s.notifier.Redeliver(ctx, cfg, secrets, rec.Note, rec.Channel, rec.TargetKey)
+	if !ok {
+		res = ChannelResult{
+			Channel:      rec.Channel,
+			Target:       rec.Target,
+			ErrorMessage: "channel is no longer enabled",
+		}
+		if err := s.store.DeleteOutbox(rec.ID); err != nil {
+			return ChannelResult{}, err
+		}
+		res.Attempts = rec.Attempts
+		return res, s.store.UpdateEventChannel(rec.EventID, res)
+	}
+	res.Attempts += rec.Attempts
+
+	maxAge := cfg.outboxMaxAge()
+	if res.Success || (expire && now.Sub(rec.CreatedAt) >= maxAge) {
+		if !res.Success {
+			res.ErrorMessage = fmt.Sprintf("gave up after %s: %s", maxAge, res.ErrorMessage)
+		}
+		if err := s.store.DeleteOutbox(rec.ID); err != nil {
+			return ChannelResult{}, err
+		}
+		return res, s.store.UpdateEventChannel(rec.EventID, res)
+	}
+
+	res.Queued = true
+	if err := s.store.RescheduleOutbox(rec.ID, res.Attempts, now.Add(outboxDelay(cfg.RetryDelaysSec, res.Attempts)), res.ErrorMessage); err != nil {
+		return ChannelResult{}, err
+	}
+	return res, s.store.UpdateEventChannel(rec.EventID, res)
+}
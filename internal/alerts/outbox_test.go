@@ -0,0 +1,194 @@
+package alerts
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"quickvps/internal/metrics"
+)
+
+func TestOutboxRetriesUntilDelivered(t *testing.T) {
+	n := NewNotifier()
+	n.sleep = func(time.Duration) { t.Fatal("alert delivery slept in-process") }
+	telegramDown := true
+	n.sendTelegram = func(context.Context, string, []string, string) error {
+		if telegramDown {
+			return errors.New("network unreachable")
+		}
+		return nil
+	}
+	svc := newServiceForTests(t, n)
+	svc.cfg.EmailEnabled = false
+	svc.cfg.RetryDelaysSec = []int{60}
+
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	snap := &metrics.Snapshot{Timestamp: base, CPU: metrics.CPUMetrics{TotalPercent: 95}}
+	svc.dispatch(context.Background(), pendingAlert{trigger: Trigger{Level: LevelCritical, Value: 95}}, snap, svc.cfg, svc.secrets)
+
+	items, err := svc.ListOutbox()
+	if err != nil {
+		t.Fatalf("ListOutbox() error = %v", err)
+	}
+	if len(items) != 1 || items[0].Channel != "telegram" || items[0].Attempts != 1 {
+		t.Fatalf("outbox = %+v, want one telegram item", items)
+	}
+	// The first retry follows the configured delays, later ones back off.
+	if !items[0].NextAttemptAt.Equal(base.Add(time.Minute)) {
+		t.Fatalf("next attempt = %s, want %s", items[0].NextAttemptAt, base.Add(time.Minute))
+	}
+	events, _ := svc.ListHistory(1, 0)
+	if len(events[0].Channels) != 1 || !events[0].Channels[0].Queued {
+		t.Fatalf("event channels = %+v, want queued telegram", events[0].Channels)
+	}
+
+	// Not due yet: nothing happens.
+	svc.processOutbox(context.Background(), base.Add(time.Second))
+	items, _ = svc.ListOutbox()
+	if items[0].Attempts != 1 {
+		t.Fatalf("attempts = %d, want 1 before the item is due", items[0].Attempts)
+	}
+
+	svc.processOutbox(context.Background(), base.Add(time.Hour))
+	items, _ = svc.ListOutbox()
+	if len(items) != 1 || items[0].Attempts != 2 || items[0].LastError != "network unreachable" {
+		t.Fatalf("outbox = %+v, want one item with 2 attempts", items)
+	}
+	if want := base.Add(time.Hour + outboxBackoff(1)); !items[0].NextAttemptAt.Equal(want) {
+		t.Fatalf("next attempt = %s, want %s", items[0].NextAttemptAt, want)
+	}
+
+	telegramDown = false
+	res, err := svc.RetryOutbox(context.Background(), items[0].ID)
+	if err != nil || !res.Success {
+		t.Fatalf("RetryOutbox() = %+v, %v", res, err)
+	}
+	if items, _ = svc.ListOutbox(); len(items) != 0 {
+		t.Fatalf("outbox should be empty after delivery, got %+v", items)
+	}
+	events, _ = svc.ListHistory(1, 0)
+	ch := events[0].Channels[0]
+	if !ch.Success || ch.Queued || ch.Attempts != 3 {
+		t.Fatalf("event channel = %+v, want delivered after 3 attempts", ch)
+	}
+}
+
+func TestOutboxGivesUpAfterMaxAgeAndDrop(t *testing.T) {
+	n := NewNotifier()
+	n.sleep = func(time.Duration) {}
+	n.sendTelegram = func(context.Context, string, []string, string) error {
+		return errors.New("bad gateway")
+	}
+	svc := newServiceForTests(t, n)
+	svc.cfg.EmailEnabled = false
+	svc.cfg.RetryDelaysSec = []int{0}
+	svc.cfg.OutboxMaxAgeHours = 2
+
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	for _, level := range []Level{LevelWarning, LevelCritical} {
+		snap := &metrics.Snapshot{Timestamp: base, CPU: metrics.CPUMetrics{TotalPercent: 90}}
+		svc.dispatch(context.Background(), pendingAlert{trigger: Trigger{Level: level, Value: 90}}, snap, svc.cfg, svc.secrets)
+	}
+	items, _ := svc.ListOutbox()
+	if len(items) != 2 {
+		t.Fatalf("outbox len = %d, want 2", len(items))
+	}
+
+	if err := svc.DropOutbox(items[1].ID); err != nil {
+		t.Fatalf("DropOutbox() error = %v", err)
+	}
+	if err := svc.DropOutbox(items[1].ID); !errors.Is(err, ErrOutboxNotFound) {
+		t.Fatalf("DropOutbox(again) error = %v, want ErrOutboxNotFound", err)
+	}
+
+	svc.processOutbox(context.Background(), base.Add(time.Hour))
+	if remaining, _ := svc.ListOutbox(); len(remaining) != 1 {
+		t.Fatalf("outbox = %+v, want the item kept before the max age", remaining)
+	}
+	svc.processOutbox(context.Background(), base.Add(2*time.Hour+time.Minute))
+	if remaining, _ := svc.ListOutbox(); len(remaining) != 0 {
+		t.Fatalf("outbox = %+v, want expired item removed", remaining)
+	}
+
+	events, _ := svc.ListHistory(10, 0)
+	for _, e := range events {
+		ch := e.Channels[0]
+		if ch.Success || ch.Queued {
+			t.Fatalf("event %d channel = %+v, want final failure", e.ID, ch)
+		}
+		want := "gave up after 2h0m0s"
+		if e.Level == LevelCritical {
+			want = "dropped"
+		}
+		if !strings.Contains(ch.ErrorMessage, want) {
+			t.Fatalf("event %d error = %q, want %q", e.ID, ch.ErrorMessage, want)
+		}
+	}
+}
+
+func TestOutboxWorkerRetriesQueuedDelivery(t *testing.T) {
+	n := NewNotifier()
+	var (
+		mu       sync.Mutex
+		attempts int
+	)
+	n.sendTelegram = func(context.Context, string, []string, string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts++
+		if attempts == 1 {
+			return errors.New("network unreachable")
+		}
+		return nil
+	}
+	svc := newServiceForTests(t, n)
+	svc.cfg.EmailEnabled = false
+	svc.cfg.RetryDelaysSec = []int{0}
+	svc.outboxEvery = time.Hour
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go svc.runOutbox(ctx)
+
+	snap := &metrics.Snapshot{Timestamp: time.Now().UTC(), CPU: metrics.CPUMetrics{TotalPercent: 95}}
+	svc.dispatch(ctx, pendingAlert{trigger: Trigger{Level: LevelCritical, Value: 95}}, snap, svc.cfg, svc.secrets)
+
+	// Queuing wakes the worker, which retries without waiting for its
+	// hourly check.
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		events, _ := svc.ListHistory(1, 0)
+		if len(events) == 1 && len(events[0].Channels) == 1 && events[0].Channels[0].Success {
+			if events[0].Channels[0].Attempts != 2 {
+				t.Fatalf("channel = %+v, want delivered on the second attempt", events[0].Channels[0])
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("delivery not retried by the worker: %+v", events)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if items, _ := svc.ListOutbox(); len(items) != 0 {
+		t.Fatalf("outbox = %+v, want empty", items)
+	}
+}
+
+func TestOutboxMaxAgeConfig(t *testing.T) {
+	svc := newServiceForTests(t, NewNotifier())
+	hours := 72
+	view, err := svc.UpdateConfig(UpdateConfigInput{OutboxMaxAgeHours: &hours})
+	if err != nil || view.OutboxMaxAgeHours != 72 {
+		t.Fatalf("UpdateConfig() = %d, %v", view.OutboxMaxAgeHours, err)
+	}
+	if cfg, _ := svc.store.LoadConfig(); cfg.OutboxMaxAgeHours != 72 {
+		t.Fatalf("stored outbox_max_age_hours = %d", cfg.OutboxMaxAgeHours)
+	}
+	hours = 0
+	if _, err := svc.UpdateConfig(UpdateConfigInput{OutboxMaxAgeHours: &hours}); err == nil {
+		t.Fatal("expected error for a zero max age")
+	}
+}
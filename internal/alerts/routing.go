@@ -0,0 +1,104 @@
+package alerts
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Channels are the notification channel names, as used in ChannelResult,
+// routes, escalation steps and templates.
+var Channels = []string{"telegram", "email", "slack", "discord", "teams", "ntfy", "gotify", "webhook"}
+
+// RoutableLevels are the levels Config.Routes can restrict.
+var RoutableLevels = []Level{LevelWarning, LevelCritical, LevelRecovery, LevelFlapping}
+
+func (c *Config) channelFlag(channel string) *bool {
+	switch channel {
+	case "telegram":
+		return &c.TelegramEnabled
+	case "email":
+		return &c.EmailEnabled
+	case "slack":
+		return &c.SlackEnabled
+	case "discord":
+		return &c.DiscordEnabled
+	case "teams":
+		return &c.TeamsEnabled
+	case "ntfy":
+		return &c.NtfyEnabled
+	case "gotify":
+		return &c.GotifyEnabled
+	case "webhook":
+		return &c.WebhookEnabled
+	}
+	return nil
+}
+
+// withChannels returns the config with every channel outside channels
+// disabled. Channels that are disabled stay disabled.
+func (c Config) withChannels(channels []string) Config {
+	for _, channel := range Channels {
+		if !containsString(channels, channel) {
+			*c.channelFlag(channel) = false
+		}
+	}
+	return c
+}
+
+// routedFor applies the route of level, if any.
+func (c Config) routedFor(level Level) Config {
+	channels, ok := c.Routes[level]
+	if !ok {
+		return c
+	}
+	return c.withChannels(channels)
+}
+
+func sanitizeChannels(in []string) []string {
+	out := make([]string, 0, len(in))
+	for _, raw := range in {
+		channel := strings.ToLower(strings.TrimSpace(raw))
+		if channel != "" && !containsString(out, channel) {
+			out = append(out, channel)
+		}
+	}
+	return out
+}
+
+func sanitizeRoutes(in map[Level][]string) map[Level][]string {
+	out := make(map[Level][]string, len(in))
+	for level, channels := range in {
+		out[Level(strings.ToLower(strings.TrimSpace(string(level))))] = sanitizeChannels(channels)
+	}
+	return out
+}
+
+func validateChannels(channels []string) error {
+	for _, channel := range channels {
+		if !containsString(Channels, channel) {
+			return fmt.Errorf("unknown channel %q", channel)
+		}
+	}
+	return nil
+}
+
+func validateRoutes(routes map[Level][]string) error {
+	for level, channels := range routes {
+		if !isRoutableLevel(level) {
+			return fmt.Errorf("routes: level %q cannot be routed", level)
+		}
+		if err := validateChannels(channels); err != nil {
+			return fmt.Errorf("routes %s: %w", level, err)
+		}
+	}
+	return nil
+}
+
+func isRoutableLevel(level Level) bool {
+	for _, l := range RoutableLevels {
+		if l == level {
+			return true
+		}
+	}
+	return false
+}
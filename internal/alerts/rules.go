@@ -0,0 +1,276 @@
+package alerts
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"quickvps/internal/metrics"
+)
+
+var ErrRuleNotFound = errors.New("alert rule not found")
+
+// ruleRuntime is the in-memory evaluation state of one rule.
+type ruleRuntime struct {
+	evaluator       *Evaluator
+	lastValue       *float64
+	lastEvaluatedAt *time.Time
+}
+
+// pendingAlert is a trigger waiting to be dispatched once the service lock is
+// released. rule is nil for the built-in CPU alert.
+type pendingAlert struct {
+	rule    *Rule
+	trigger Trigger
+}
+
+func defaultRule() Rule {
+	return Rule{
+		Enabled: true,
+		Thresholds: Thresholds{
+			WarningForSec:  300,
+			CriticalForSec: 600,
+			RecoveryForSec: 300,
+			CooldownSec:    1800,
+		},
+	}
+}
+
+func (s *Service) ListRules() []Rule {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]Rule(nil), s.rules...)
+}
+
+func (s *Service) CreateRule(in RuleInput) (Rule, error) {
+	rule := defaultRule()
+	applyRuleInput(&rule, in)
+	if err := validateRule(rule); err != nil {
+		return Rule{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	created, err := s.store.CreateRule(rule)
+	if err != nil {
+		return Rule{}, err
+	}
+	s.rules = append(s.rules, created)
+	s.ruleRuntimes[created.ID] = &ruleRuntime{evaluator: NewEvaluator()}
+	return created, nil
+}
+
+func (s *Service) UpdateRule(id int64, in RuleInput) (Rule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	idx := s.ruleIndex(id)
+	if idx < 0 {
+		return Rule{}, ErrRuleNotFound
+	}
+	rule := s.rules[idx]
+	applyRuleInput(&rule, in)
+	if err := validateRule(rule); err != nil {
+		return Rule{}, err
+	}
+
+	updated, err := s.store.UpdateRule(rule)
+	if err != nil {
+		return Rule{}, err
+	}
+	s.rules[idx] = updated
+	// New thresholds or a new series make the old windows meaningless.
+	s.ruleRuntimes[id] = &ruleRuntime{evaluator: NewEvaluator()}
+	s.forgetEvaluatorLocked(id)
+	// The fresh evaluator starts at LevelNone and never sends the recovery
+	// that would close the old incident; a new one opens if the rule fires
+	// again.
+	s.resolveIncidentLocked(id, time.Now().UTC())
+	return updated, nil
+}
+
+func (s *Service) DeleteRule(id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	idx := s.ruleIndex(id)
+	if idx < 0 {
+		return ErrRuleNotFound
+	}
+	if err := s.store.DeleteRule(id); err != nil {
+		return err
+	}
+	s.rules = append(s.rules[:idx], s.rules[idx+1:]...)
+	delete(s.ruleRuntimes, id)
+	s.forgetEvaluatorLocked(id)
+	s.resolveIncidentLocked(id, time.Now().UTC())
+	return nil
+}
+
+func (s *Service) ruleIndex(id int64) int {
+	for i, r := range s.rules {
+		if r.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// evaluateRules must be called with s.mu held.
+func (s *Service) evaluateRules(snap *metrics.Snapshot, silenced bool) []pendingAlert {
+	if len(s.rules) == 0 {
+		return nil
+	}
+
+	now := snap.Timestamp
+	values := snap.Series()
+	var pending []pendingAlert
+	for i := range s.rules {
+		rule := s.rules[i]
+		rt := s.ruleRuntimes[rule.ID]
+		if rt == nil {
+			rt = &ruleRuntime{evaluator: NewEvaluator()}
+			s.ruleRuntimes[rule.ID] = rt
+		}
+		if !rule.Enabled {
+			rt.evaluator.Reset()
+			s.resolveIncidentLocked(rule.ID, now)
+			continue
+		}
+		// A series can disappear (unmounted disk, removed interface); keep
+		// the current windows until it comes back.
+		value, ok := values[rule.Series]
+		if !ok {
+			continue
+		}
+		evaluatedAt := now
+		rt.lastValue = &value
+		rt.lastEvaluatedAt = &evaluatedAt
+
+		rt.evaluator.SetFlapPolicy(s.cfg.flapWindow(), s.cfg.FlapThreshold)
+		wasActive := rt.evaluator.active()
+		triggers := rt.evaluator.EvaluateThresholds(now, value, rule.Thresholds, silenced)
+		s.settleIncidentLocked(rule.ID, wasActive, rt.evaluator, triggers, now)
+		for _, trigger := range triggers {
+			pending = append(pending, pendingAlert{rule: &rule, trigger: trigger})
+		}
+	}
+	return pending
+}
+
+// ruleStatuses must be called with s.mu held.
+func (s *Service) ruleStatuses() []RuleStatus {
+	out := make([]RuleStatus, 0, len(s.rules))
+	for _, rule := range s.rules {
+		st := RuleStatus{
+			RuleID: rule.ID,
+			Name:   rule.Name,
+			Series: rule.Series,
+			State:  LevelNone,
+		}
+		if rt := s.ruleRuntimes[rule.ID]; rt != nil {
+			st.State = rt.evaluator.State()
+			st.LastValue = rt.lastValue
+			st.LastEvaluatedAt = rt.lastEvaluatedAt
+			st.FlappingSince = rt.evaluator.FlappingSince()
+		}
+		out = append(out, st)
+	}
+	return out
+}
+
+func applyRuleInput(r *Rule, in RuleInput) {
+	if in.Name != nil {
+		r.Name = strings.TrimSpace(*in.Name)
+	}
+	if in.Enabled != nil {
+		r.Enabled = *in.Enabled
+	}
+	if in.Series != nil {
+		r.Series = strings.TrimSpace(*in.Series)
+	}
+	if in.Warning != nil {
+		r.Warning = *in.Warning
+	}
+	if in.WarningForSec != nil {
+		r.WarningForSec = *in.WarningForSec
+	}
+	if in.Critical != nil {
+		r.Critical = *in.Critical
+	}
+	if in.CriticalForSec != nil {
+		r.CriticalForSec = *in.CriticalForSec
+	}
+	if in.Recovery != nil {
+		r.Recovery = *in.Recovery
+	}
+	if in.RecoveryForSec != nil {
+		r.RecoveryForSec = *in.RecoveryForSec
+	}
+	if in.CooldownSec != nil {
+		r.CooldownSec = *in.CooldownSec
+	}
+	if r.Name == "" {
+		r.Name = r.Series
+	}
+}
+
+func validateRule(r Rule) error {
+	if r.Series == "" {
+		return errors.New("series is required")
+	}
+	if !metrics.KnownSeries(r.Series) {
+		return fmt.Errorf("unknown series %q", r.Series)
+	}
+	if len(r.Name) > 100 {
+		return errors.New("name must be at most 100 characters")
+	}
+	if r.Recovery < 0 {
+		return errors.New("recovery must be >= 0")
+	}
+	if r.Warning >= r.Critical {
+		return errors.New("warning must be lower than critical")
+	}
+	if r.Recovery >= r.Warning {
+		return errors.New("recovery must be lower than warning")
+	}
+	base, _ := metrics.SplitSeriesName(r.Series)
+	if strings.HasSuffix(base, ".percent") && r.Critical > 100 {
+		return errors.New("critical must be <= 100 for percent series")
+	}
+	if r.WarningForSec <= 0 || r.CriticalForSec <= 0 || r.RecoveryForSec <= 0 {
+		return errors.New("window seconds must be > 0")
+	}
+	if r.CooldownSec < 0 {
+		return errors.New("cooldown_sec must be >= 0")
+	}
+	return nil
+}
+
+func formatRuleMessage(level Level, hostname string, rule Rule, value float64, now time.Time) string {
+	return fmt.Sprintf(
+		"[QuickVPS][%s] Host=%s Rule=%q %s=%s Time=%s",
+		strings.ToUpper(string(level)),
+		hostname,
+		rule.Name,
+		rule.Series,
+		formatSeriesValue(rule.Series, value),
+		now.UTC().Format(time.RFC3339),
+	)
+}
+
+func formatSeriesValue(series string, value float64) string {
+	base, _ := metrics.SplitSeriesName(series)
+	switch {
+	case strings.HasSuffix(base, ".percent"):
+		return fmt.Sprintf("%.2f%%", value)
+	case strings.HasSuffix(base, "_bps"):
+		return formatBytes(uint64(value)) + "/s"
+	case strings.HasSuffix(base, "_bytes"):
+		return formatBytes(uint64(value))
+	default:
+		return fmt.Sprintf("%.2f", value)
+	}
+}
@@ -27,13 +27,38 @@ type Service struct {
 	secretRec secretRecord
 	status    Status
 
-	evaluator      *Evaluator
+	evaluator    *Evaluator
+	rules        []Rule
+	ruleRuntimes map[int64]*ruleRuntime
+	// openIncidents holds the unresolved incident per source (rule ID, 0 for CPU).
+	openIncidents map[int64]*Incident
+	maintenance   []maintenanceSchedule
+	templates     map[templateKey]compiledTemplate
+	// savedStates is the last persisted evaluator state JSON per source.
+	savedStates    map[int64]string
+	stateTouchedAt time.Time
 	historyDays    int
 	cleanupEvery   time.Duration
 	lastCleanupRun time.Time
+
+	outboxMu    sync.Mutex
+	outboxEvery time.Duration
+	// outboxWake tells the outbox worker a delivery was queued.
+	outboxWake chan struct{}
+
+	// forecasts is the disk-full forecast alert state per mountpoint.
+	forecasts map[string]*forecastState
+
+	digestSources DigestSources
+	// lastDigestAt is the schedule minute of the last digest run; it starts
+	// at the newest stored digest, or at startup if there is none.
+	lastDigestAt time.Time
 }
 
-func NewService(store *Store, notifier *Notifier, base64Key string) (*Service, error) {
+// NewService loads the alerting state. Secrets are encrypted with base64Key;
+// previousKeys are only used to decrypt secrets not yet moved to it by
+// RotateSecrets.
+func NewService(store *Store, notifier *Notifier, base64Key string, previousKeys ...string) (*Service, error) {
 	if store == nil {
 		return nil, ErrServiceNotConfigured
 	}
@@ -55,35 +80,106 @@ func NewService(store *Store, notifier *Notifier, base64Key string) (*Service, e
 		c               *Cipher
 		secretsWritable bool
 		secrets         Secrets
+		legacyWebhooks  map[string]string
 	)
 	if base64Key != "" {
-		parsed, err := NewCipherFromBase64Key(base64Key)
+		parsed, err := NewKeyRing(base64Key, previousKeys...)
 		if err != nil {
 			return nil, err
 		}
 		c = parsed
 		secretsWritable = true
 
-		if rec.TelegramTokenCipher != "" {
-			plain, err := c.Decrypt(rec.TelegramTokenCipher)
+		decryptInto(c, rec.TelegramTokenCipher, &secrets.TelegramBotToken)
+		decryptInto(c, rec.SMTPPasswordCipher, &secrets.SMTPPassword)
+		decryptInto(c, rec.SlackWebhookCipher, &secrets.SlackWebhookURL)
+		decryptInto(c, rec.DiscordWebhookCipher, &secrets.DiscordWebhookURL)
+		decryptInto(c, rec.TeamsWebhookCipher, &secrets.TeamsWebhookURL)
+		decryptInto(c, rec.NtfyTokenCipher, &secrets.NtfyToken)
+		decryptInto(c, rec.GotifyTokenCipher, &secrets.GotifyToken)
+		if rec.WebhookSecretsCipher != "" {
+			plain, err := c.Decrypt(rec.WebhookSecretsCipher)
 			if err == nil {
-				secrets.TelegramBotToken = plain
+				secrets.Webhooks, legacyWebhooks = decodeWebhookSecrets(plain)
 			}
 		}
-		if rec.GmailPasswordCipher != "" {
-			plain, err := c.Decrypt(rec.GmailPasswordCipher)
-			if err == nil {
-				secrets.GmailAppPassword = plain
-			}
+	}
+	if secrets.Webhooks == nil {
+		secrets.Webhooks = make(map[string]WebhookSecret)
+	}
+	// Webhooks saved with their URL and header values in plain text are
+	// encrypted now; without the key they are only moved in memory.
+	if moved := migrateLegacyWebhooks(&cfg, secrets.Webhooks, legacyWebhooks); len(moved) > 0 && secretsWritable {
+		cipher, err := encodeWebhookSecrets(c, secrets.Webhooks)
+		if err != nil {
+			return nil, err
+		}
+		if err := store.MigrateWebhooks(cfg.Webhooks, cipher, moved); err != nil {
+			return nil, err
 		}
+		rec.WebhookSecretsCipher = cipher
 	}
-	secrets.GmailAddress = rec.GmailAddress
 
 	mutedUntil, err := store.GetMutedUntil()
 	if err != nil {
 		return nil, err
 	}
 
+	rules, err := store.ListRules()
+	if err != nil {
+		return nil, err
+	}
+	ruleRuntimes := make(map[int64]*ruleRuntime, len(rules))
+	for _, r := range rules {
+		ruleRuntimes[r.ID] = &ruleRuntime{evaluator: NewEvaluator()}
+	}
+
+	windows, err := store.ListMaintenanceWindows()
+	if err != nil {
+		return nil, err
+	}
+	maintenance := make([]maintenanceSchedule, 0, len(windows))
+	for _, w := range windows {
+		// A window whose timezone vanished from the zone database is skipped
+		// rather than failing startup.
+		if compiled, err := compileMaintenanceWindow(w); err == nil {
+			maintenance = append(maintenance, compiled)
+		}
+	}
+
+	storedTemplates, err := store.ListTemplates()
+	if err != nil {
+		return nil, err
+	}
+	templates := make(map[templateKey]compiledTemplate, len(storedTemplates))
+	for _, t := range storedTemplates {
+		if compiled, err := compileTemplate(t); err == nil {
+			templates[templateKey{t.Channel, t.Level}] = compiled
+		}
+	}
+
+	storedStates, err := store.LoadEvaluatorStates()
+	if err != nil {
+		return nil, err
+	}
+
+	unresolved, err := store.ListUnresolvedIncidents()
+	if err != nil {
+		return nil, err
+	}
+	openIncidents := make(map[int64]*Incident, len(unresolved))
+	for i := range unresolved {
+		openIncidents[unresolved[i].RuleID] = &unresolved[i]
+	}
+
+	lastDigestAt, err := store.LastEventAt(LevelDigest)
+	if err != nil {
+		return nil, err
+	}
+	if lastDigestAt.IsZero() {
+		lastDigestAt = time.Now().UTC()
+	}
+
 	hostname, _ := os.Hostname()
 	if strings.TrimSpace(hostname) == "" {
 		hostname = "unknown-host"
@@ -103,10 +199,21 @@ func NewService(store *Store, notifier *Notifier, base64Key string) (*Service, e
 			MutedUntil:   mutedUntil,
 			Silenced:     isSilencedAt(mutedUntil, time.Now()),
 		},
-		evaluator:    NewEvaluator(),
-		historyDays:  DefaultHistoryRetentionDays,
-		cleanupEvery: time.Hour,
-	}
+		evaluator:     NewEvaluator(),
+		rules:         rules,
+		ruleRuntimes:  ruleRuntimes,
+		openIncidents: openIncidents,
+		maintenance:   maintenance,
+		templates:     templates,
+		savedStates:   make(map[int64]string, len(storedStates)),
+		historyDays:   DefaultHistoryRetentionDays,
+		cleanupEvery:  time.Hour,
+		outboxEvery:   30 * time.Second,
+		outboxWake:    make(chan struct{}, 1),
+		forecasts:     make(map[string]*forecastState),
+		lastDigestAt:  lastDigestAt,
+	}
+	s.restoreEvaluators(storedStates, time.Now().UTC())
 	return s, nil
 }
 
@@ -114,8 +221,15 @@ func (s *Service) Run(ctx context.Context, sub <-chan *metrics.Snapshot) {
 	if sub == nil {
 		return
 	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go s.runOutbox(ctx)
+	go s.runDigests(ctx)
+
 	cleanupTicker := time.NewTicker(s.cleanupEvery)
 	defer cleanupTicker.Stop()
+	escalationTicker := time.NewTicker(escalationCheckEvery)
+	defer escalationTicker.Stop()
 
 	for {
 		select {
@@ -123,6 +237,8 @@ func (s *Service) Run(ctx context.Context, sub <-chan *metrics.Snapshot) {
 			return
 		case <-cleanupTicker.C:
 			s.cleanupOldEvents()
+		case now := <-escalationTicker.C:
+			s.runEscalations(ctx, now.UTC())
 		case snap, ok := <-sub:
 			if !ok {
 				return
@@ -145,26 +261,120 @@ func (s *Service) EvaluateSnapshot(ctx context.Context, snap *metrics.Snapshot)
 	mutedUntil := s.status.MutedUntil
 	s.status.LastEvaluatedAt = now
 	s.status.LastCPUPercent = cpuPercent
-	s.status.Silenced = isSilencedAt(mutedUntil, now)
+	s.status.Silenced = isSilencedAt(mutedUntil, now) || s.activeMaintenanceLocked(now) != nil
 
+	var pending []pendingAlert
+	s.evaluator.SetFlapPolicy(cfg.flapWindow(), cfg.FlapThreshold)
+	wasActive := s.evaluator.active()
 	triggers := s.evaluator.Evaluate(now, cpuPercent, cfg, s.status.Silenced)
+	s.settleIncidentLocked(0, wasActive, s.evaluator, triggers, now)
+	for _, trigger := range triggers {
+		pending = append(pending, pendingAlert{trigger: trigger})
+	}
 	s.status.CurrentState = s.evaluator.State()
 	s.status.LastWarningAt = s.evaluator.LastWarningAt()
 	s.status.LastCriticalAt = s.evaluator.LastCriticalAt()
 	s.status.LastRecoveryAt = s.evaluator.LastRecoveryAt()
+	s.status.FlappingSince = s.evaluator.FlappingSince()
+	pending = append(pending, s.evaluateRules(snap, s.status.Silenced)...)
+	forecasts := s.evaluateForecasts(snap, s.status.Silenced)
+	s.persistEvaluatorsLocked(now)
 	s.mu.Unlock()
 
-	for _, trigger := range triggers {
-		s.dispatch(ctx, trigger.Level, trigger.CPUPercent, cfg, secrets, now)
+	for _, p := range pending {
+		s.dispatch(ctx, p, snap, cfg, secrets)
+	}
+	for _, p := range forecasts {
+		s.dispatchForecast(ctx, p, snap, cfg, secrets)
 	}
 }
 
-func (s *Service) dispatch(ctx context.Context, level Level, cpuPercent float64, cfg Config, secrets Secrets, now time.Time) {
-	message := formatAlertMessage(level, s.hostname, cpuPercent, now)
-	results := s.notifier.Notify(ctx, cfg, secrets, level, message)
-	id, err := s.store.SaveEvent(level, message, cpuPercent, results, now)
+func (s *Service) dispatch(ctx context.Context, p pendingAlert, snap *metrics.Snapshot, cfg Config, secrets Secrets) {
+	now := snap.Timestamp
+	level := p.trigger.Level
+
+	var offenders *TopProcesses
+	if level == LevelWarning || level == LevelCritical {
+		offenders = topOffenders(snap.Processes)
+	}
+
+	note := Notification{
+		Level:     level,
+		Host:      s.hostname,
+		Value:     p.trigger.Value,
+		Metrics:   headlineMetrics(snap),
+		Processes: offenders,
+		Time:      now,
+	}
+	var ruleID int64
+	ruleName, thresholds := "CPU", cfg.Thresholds()
+	if p.rule == nil {
+		note.Series = metrics.SeriesCPUPercent
+		note.Subject = fmt.Sprintf("[QuickVPS] CPU %s", strings.ToUpper(string(level)))
+		note.Message = formatAlertMessage(level, s.hostname, p.trigger.Value, now)
+	} else {
+		ruleID = p.rule.ID
+		ruleName, thresholds = p.rule.Name, p.rule.Thresholds
+		note.Series = p.rule.Series
+		note.Subject = fmt.Sprintf("[QuickVPS] %s %s", p.rule.Name, strings.ToUpper(string(level)))
+		note.Message = formatRuleMessage(level, s.hostname, *p.rule, p.trigger.Value, now)
+	}
+	note.Message += formatTopProcesses(offenders)
+
+	var duration time.Duration
+	if !p.trigger.Since.IsZero() {
+		duration = now.Sub(p.trigger.Since)
+	}
+	s.renderTexts(&note, TemplateData{
+		Host:         s.hostname,
+		Level:        string(level),
+		Rule:         ruleName,
+		Series:       note.Series,
+		Value:        p.trigger.Value,
+		ValueText:    formatSeriesValue(note.Series, p.trigger.Value),
+		Thresholds:   thresholds,
+		Metrics:      note.Metrics,
+		Duration:     duration,
+		DurationText: duration.Round(time.Second).String(),
+		DashboardURL: cfg.DashboardURL,
+		Time:         now,
+		Processes:    offenders,
+	})
+
+	incidentID, acknowledged := s.trackIncident(ruleID, note.Series, level, now)
+	id, err := s.store.SaveEvent(Event{
+		Level:      level,
+		Message:    note.Message,
+		CPUPercent: snap.CPU.TotalPercent,
+		RuleID:     ruleID,
+		IncidentID: incidentID,
+		Series:     note.Series,
+		Value:      note.Value,
+		Processes:  offenders,
+		CreatedAt:  now,
+	})
+	if acknowledged {
+		// Someone is on it; keep the event in history without notifying.
+		return
+	}
+	note.EventID = id
+	results := s.notifier.NotifyOnce(ctx, cfg.routedFor(note.Level), secrets, note)
 	if err == nil {
-		_ = id
+		results = s.enqueueFailed(cfg, note, results, now)
+		_ = s.store.UpdateEventChannels(id, results)
+	}
+}
+
+// headlineMetrics is the small set of host metrics attached to structured
+// notifications (webhooks) alongside the triggering series.
+func headlineMetrics(snap *metrics.Snapshot) map[string]float64 {
+	return map[string]float64{
+		metrics.SeriesCPUPercent:    snap.CPU.TotalPercent,
+		metrics.SeriesMemoryPercent: snap.Memory.Percent,
+		metrics.SeriesSwapPercent:   snap.Swap.Percent,
+		metrics.SeriesLoad1:         snap.Load.Load1,
+		metrics.SeriesLoad5:         snap.Load.Load5,
+		metrics.SeriesLoad15:        snap.Load.Load15,
 	}
 }
 
@@ -175,17 +385,28 @@ func (s *Service) TriggerTest(ctx context.Context) (Event, error) {
 	hostname := s.hostname
 	s.mu.RUnlock()
 
-	if !cfg.TelegramEnabled && !cfg.EmailEnabled {
-		return Event{}, errors.New("both channels are disabled")
+	if !cfg.anyChannelEnabled() {
+		return Event{}, errors.New("all channels are disabled")
 	}
 
 	now := time.Now().UTC()
 	msg := fmt.Sprintf("[QuickVPS][TEST] Host=%s Time=%s", hostname, now.Format(time.RFC3339))
-	results := s.notifier.Notify(ctx, cfg, secrets, LevelTest, msg)
-	id, err := s.store.SaveEvent(LevelTest, msg, 0, results, now)
+	id, err := s.store.SaveEvent(Event{Level: LevelTest, Message: msg, CreatedAt: now})
 	if err != nil {
 		return Event{}, err
 	}
+	// Test sends are interactive, so failures are reported, not queued.
+	results := s.notifier.Notify(ctx, cfg, secrets, Notification{
+		EventID: id,
+		Level:   LevelTest,
+		Subject: "[QuickVPS] CPU TEST",
+		Message: msg,
+		Host:    hostname,
+		Time:    now,
+	})
+	if err := s.store.UpdateEventChannels(id, results); err != nil {
+		return Event{}, err
+	}
 	return Event{
 		ID:         id,
 		Level:      LevelTest,
@@ -199,18 +420,36 @@ func (s *Service) TriggerTest(ctx context.Context) (Event, error) {
 func (s *Service) ConfigView(readOnly bool) ConfigView {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
+	return s.configViewLocked(readOnly)
+}
 
-	view := ConfigView{
-		Config:            s.cfg,
-		HasTelegramToken:  strings.TrimSpace(s.secretRec.TelegramTokenCipher) != "",
-		TelegramTokenMask: maskSecret(s.secrets.TelegramBotToken, strings.TrimSpace(s.secretRec.TelegramTokenCipher) != ""),
-		HasGmailPassword:  strings.TrimSpace(s.secretRec.GmailPasswordCipher) != "",
-		GmailPasswordMask: maskSecret(s.secrets.GmailAppPassword, strings.TrimSpace(s.secretRec.GmailPasswordCipher) != ""),
-		GmailAddress:      strings.TrimSpace(s.secretRec.GmailAddress),
-		SecretsWritable:   s.secretsWritable,
-		ReadOnly:          readOnly,
-	}
-	return view
+// configViewLocked must be called with s.mu held.
+func (s *Service) configViewLocked(readOnly bool) ConfigView {
+	hasSMTPPassword := strings.TrimSpace(s.secretRec.SMTPPasswordCipher) != ""
+	smtpPasswordMask := maskSecret(s.secrets.SMTPPassword, hasSMTPPassword)
+	return ConfigView{
+		Config:             s.cfg,
+		HasTelegramToken:   strings.TrimSpace(s.secretRec.TelegramTokenCipher) != "",
+		TelegramTokenMask:  maskSecret(s.secrets.TelegramBotToken, strings.TrimSpace(s.secretRec.TelegramTokenCipher) != ""),
+		HasSMTPPassword:    hasSMTPPassword,
+		SMTPPasswordMask:   smtpPasswordMask,
+		HasGmailPassword:   hasSMTPPassword,
+		GmailPasswordMask:  smtpPasswordMask,
+		GmailAddress:       s.cfg.SMTPFrom,
+		HasSlackWebhook:    s.secretRec.SlackWebhookCipher != "",
+		SlackWebhookMask:   maskSecret(s.secrets.SlackWebhookURL, s.secretRec.SlackWebhookCipher != ""),
+		HasDiscordWebhook:  s.secretRec.DiscordWebhookCipher != "",
+		DiscordWebhookMask: maskSecret(s.secrets.DiscordWebhookURL, s.secretRec.DiscordWebhookCipher != ""),
+		HasTeamsWebhook:    s.secretRec.TeamsWebhookCipher != "",
+		TeamsWebhookMask:   maskSecret(s.secrets.TeamsWebhookURL, s.secretRec.TeamsWebhookCipher != ""),
+		HasNtfyToken:       s.secretRec.NtfyTokenCipher != "",
+		NtfyTokenMask:      maskSecret(s.secrets.NtfyToken, s.secretRec.NtfyTokenCipher != ""),
+		HasGotifyToken:     s.secretRec.GotifyTokenCipher != "",
+		GotifyTokenMask:    maskSecret(s.secrets.GotifyToken, s.secretRec.GotifyTokenCipher != ""),
+		Webhooks:           webhookViews(s.cfg.Webhooks, s.secrets.Webhooks),
+		SecretsWritable:    s.secretsWritable,
+		ReadOnly:           readOnly,
+	}
 }
 
 func (s *Service) UpdateConfig(in UpdateConfigInput) (ConfigView, error) {
@@ -260,46 +499,151 @@ func (s *Service) UpdateConfig(in UpdateConfigInput) (ConfigView, error) {
 	if in.RetryDelaysSec != nil {
 		cfg.RetryDelaysSec = sanitizeRetryDelays(in.RetryDelaysSec)
 	}
-
-	if err := validateConfig(cfg); err != nil {
-		return ConfigView{}, err
+	if in.OutboxMaxAgeHours != nil {
+		cfg.OutboxMaxAgeHours = *in.OutboxMaxAgeHours
 	}
-
-	if strings.TrimSpace(in.TelegramBotToken) != "" {
+	if in.FlapWindowSec != nil {
+		cfg.FlapWindowSec = *in.FlapWindowSec
+	}
+	if in.FlapThreshold != nil {
+		cfg.FlapThreshold = *in.FlapThreshold
+	}
+	if in.DashboardURL != nil {
+		cfg.DashboardURL = strings.TrimRight(strings.TrimSpace(*in.DashboardURL), "/")
+	}
+	if in.DigestEnabled != nil {
+		cfg.DigestEnabled = *in.DigestEnabled
+	}
+	if in.DigestPeriod != nil {
+		cfg.DigestPeriod = DigestPeriod(strings.ToLower(strings.TrimSpace(string(*in.DigestPeriod))))
+	}
+	if in.DigestCron != nil {
+		cfg.DigestCron = strings.Join(strings.Fields(*in.DigestCron), " ")
+	}
+	if in.DigestTimezone != nil {
+		cfg.DigestTimezone = strings.TrimSpace(*in.DigestTimezone)
+	}
+	if in.DigestSections != nil {
+		cfg.DigestSections = sanitizeStringSlice(in.DigestSections)
+	}
+	if in.Routes != nil {
+		cfg.Routes = sanitizeRoutes(in.Routes)
+	}
+	if in.ForecastEnabled != nil {
+		cfg.ForecastEnabled = *in.ForecastEnabled
+	}
+	if in.ForecastHorizonHours != nil {
+		cfg.ForecastHorizonHours = *in.ForecastHorizonHours
+	}
+	if in.EscalationPolicies != nil {
+		cfg.EscalationPolicies = sanitizeEscalationPolicies(in.EscalationPolicies)
+	}
+	if address := strings.TrimSpace(in.GmailAddress); address != "" && address != cfg.SMTPFrom {
+		cfg.SMTPHost = "smtp.gmail.com"
+		cfg.SMTPPort = 587
+		cfg.SMTPSecurity = SMTPSecuritySTARTTLS
+		cfg.SMTPUsername = address
+		cfg.SMTPFrom = address
+	}
+	if in.SMTPHost != nil {
+		cfg.SMTPHost = strings.TrimSpace(*in.SMTPHost)
+	}
+	if in.SMTPPort != nil {
+		cfg.SMTPPort = *in.SMTPPort
+	}
+	if in.SMTPSecurity != nil {
+		cfg.SMTPSecurity = SMTPSecurity(strings.ToLower(strings.TrimSpace(string(*in.SMTPSecurity))))
+	}
+	if in.SMTPUsername != nil {
+		cfg.SMTPUsername = strings.TrimSpace(*in.SMTPUsername)
+	}
+	if in.SMTPFrom != nil {
+		cfg.SMTPFrom = strings.TrimSpace(*in.SMTPFrom)
+	}
+	if in.EmailHTML != nil {
+		cfg.EmailHTML = *in.EmailHTML
+	}
+	if in.SlackEnabled != nil {
+		cfg.SlackEnabled = *in.SlackEnabled
+	}
+	if in.DiscordEnabled != nil {
+		cfg.DiscordEnabled = *in.DiscordEnabled
+	}
+	if in.TeamsEnabled != nil {
+		cfg.TeamsEnabled = *in.TeamsEnabled
+	}
+	if in.NtfyEnabled != nil {
+		cfg.NtfyEnabled = *in.NtfyEnabled
+	}
+	if in.NtfyServerURL != nil {
+		cfg.NtfyServerURL = strings.TrimSpace(*in.NtfyServerURL)
+	}
+	if in.NtfyTopic != nil {
+		cfg.NtfyTopic = strings.TrimSpace(*in.NtfyTopic)
+	}
+	if in.GotifyEnabled != nil {
+		cfg.GotifyEnabled = *in.GotifyEnabled
+	}
+	if in.GotifyServerURL != nil {
+		cfg.GotifyServerURL = strings.TrimSpace(*in.GotifyServerURL)
+	}
+	if in.WebhookEnabled != nil {
+		cfg.WebhookEnabled = *in.WebhookEnabled
+	}
+	if in.Webhooks != nil {
+		// Webhook URLs and header values are stored encrypted only.
 		if !s.secretsWritable {
 			return ConfigView{}, ErrMissingEncryptionKey
 		}
-		cipher, err := s.cipher.Encrypt(strings.TrimSpace(in.TelegramBotToken))
+		webhooks, webhookSecrets, err := mergeWebhooks(in.Webhooks, secrets.Webhooks)
 		if err != nil {
 			return ConfigView{}, err
 		}
-		rec.TelegramTokenCipher = cipher
-		secrets.TelegramBotToken = strings.TrimSpace(in.TelegramBotToken)
-	}
-	if in.ClearTelegramBotToken {
-		rec.TelegramTokenCipher = ""
-		secrets.TelegramBotToken = ""
+		cipher, err := encodeWebhookSecrets(s.cipher, webhookSecrets)
+		if err != nil {
+			return ConfigView{}, err
+		}
+		cfg.Webhooks = webhooks
+		secrets.Webhooks = webhookSecrets
+		rec.WebhookSecretsCipher = cipher
 	}
 
-	if strings.TrimSpace(in.GmailAddress) != "" {
-		rec.GmailAddress = strings.TrimSpace(in.GmailAddress)
-		secrets.GmailAddress = rec.GmailAddress
+	if err := validateConfig(cfg); err != nil {
+		return ConfigView{}, err
 	}
 
-	if strings.TrimSpace(in.GmailAppPassword) != "" {
-		if !s.secretsWritable {
-			return ConfigView{}, ErrMissingEncryptionKey
+	smtpPassword := in.SMTPPassword
+	if strings.TrimSpace(smtpPassword) == "" {
+		smtpPassword = in.GmailAppPassword
+	}
+	for field, value := range map[string]string{
+		"slack_webhook_url":   in.SlackWebhookURL,
+		"discord_webhook_url": in.DiscordWebhookURL,
+		"teams_webhook_url":   in.TeamsWebhookURL,
+	} {
+		if value = strings.TrimSpace(value); value != "" {
+			if err := validateChatWebhookURL(field, value); err != nil {
+				return ConfigView{}, err
+			}
 		}
-		cipher, err := s.cipher.Encrypt(strings.TrimSpace(in.GmailAppPassword))
-		if err != nil {
+	}
+	for _, u := range []struct {
+		value  string
+		clear  bool
+		cipher *string
+		plain  *string
+	}{
+		{in.TelegramBotToken, in.ClearTelegramBotToken, &rec.TelegramTokenCipher, &secrets.TelegramBotToken},
+		{smtpPassword, in.ClearSMTPPassword || in.ClearGmailAppPassword, &rec.SMTPPasswordCipher, &secrets.SMTPPassword},
+		{in.SlackWebhookURL, in.ClearSlackWebhookURL, &rec.SlackWebhookCipher, &secrets.SlackWebhookURL},
+		{in.DiscordWebhookURL, in.ClearDiscordWebhookURL, &rec.DiscordWebhookCipher, &secrets.DiscordWebhookURL},
+		{in.TeamsWebhookURL, in.ClearTeamsWebhookURL, &rec.TeamsWebhookCipher, &secrets.TeamsWebhookURL},
+		{in.NtfyToken, in.ClearNtfyToken, &rec.NtfyTokenCipher, &secrets.NtfyToken},
+		{in.GotifyToken, in.ClearGotifyToken, &rec.GotifyTokenCipher, &secrets.GotifyToken},
+	} {
+		if err := s.applySecret(u.value, u.clear, u.cipher, u.plain); err != nil {
 			return ConfigView{}, err
 		}
-		rec.GmailPasswordCipher = cipher
-		secrets.GmailAppPassword = strings.TrimSpace(in.GmailAppPassword)
-	}
-	if in.ClearGmailAppPassword {
-		rec.GmailPasswordCipher = ""
-		secrets.GmailAppPassword = ""
 	}
 
 	if err := s.store.SaveConfig(cfg); err != nil {
@@ -309,28 +653,58 @@ func (s *Service) UpdateConfig(in UpdateConfigInput) (ConfigView, error) {
 		return ConfigView{}, err
 	}
 
+	// A new schedule fires from now on, not for times before it was set.
+	if cfg.DigestEnabled && (!s.cfg.DigestEnabled || cfg.DigestCron != s.cfg.DigestCron || cfg.DigestTimezone != s.cfg.DigestTimezone) {
+		s.lastDigestAt = time.Now().UTC()
+	}
 	s.cfg = cfg
 	s.secrets = secrets
 	s.secretRec = rec
 
-	return ConfigView{
-		Config:            cfg,
-		HasTelegramToken:  rec.TelegramTokenCipher != "",
-		TelegramTokenMask: maskSecret(secrets.TelegramBotToken, rec.TelegramTokenCipher != ""),
-		HasGmailPassword:  rec.GmailPasswordCipher != "",
-		GmailPasswordMask: maskSecret(secrets.GmailAppPassword, rec.GmailPasswordCipher != ""),
-		GmailAddress:      rec.GmailAddress,
-		SecretsWritable:   s.secretsWritable,
-		ReadOnly:          false,
-	}, nil
+	return s.configViewLocked(false), nil
+}
+
+// applySecret encrypts a new value into cipher/plain; clear wipes both.
+// An empty value leaves the stored secret untouched.
+func (s *Service) applySecret(value string, clear bool, cipher *string, plain *string) error {
+	if value = strings.TrimSpace(value); value != "" {
+		if !s.secretsWritable {
+			return ErrMissingEncryptionKey
+		}
+		encrypted, err := s.cipher.Encrypt(value)
+		if err != nil {
+			return err
+		}
+		*cipher = encrypted
+		*plain = value
+	}
+	if clear {
+		*cipher = ""
+		*plain = ""
+	}
+	return nil
+}
+
+func decryptInto(c *Cipher, cipher string, dst *string) {
+	if cipher == "" {
+		return
+	}
+	if plain, err := c.Decrypt(cipher); err == nil {
+		*dst = plain
+	}
 }
 
 func (s *Service) Status(readOnly bool) Status {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	out := s.status
+	now := time.Now().UTC()
+	out.Rules = s.ruleStatuses()
+	out.DiskForecasts = s.forecastStatuses()
 	out.ReadOnly = readOnly
-	out.Silenced = isSilencedAt(s.status.MutedUntil, time.Now())
+	out.ActiveMaintenance = s.activeMaintenanceLocked(now)
+	out.NextMaintenance = s.nextMaintenanceLocked(now)
+	out.Silenced = isSilencedAt(s.status.MutedUntil, now) || out.ActiveMaintenance != nil
 	return out
 }
 
@@ -400,6 +774,57 @@ func formatAlertMessage(level Level, hostname string, cpuPercent float64, now ti
 	)
 }
 
+// topOffenders returns nil when the process collector is disabled.
+func topOffenders(list *metrics.ProcessList) *TopProcesses {
+	if list == nil {
+		return nil
+	}
+	out := &TopProcesses{
+		ByCPU:    append([]metrics.ProcessInfo(nil), list.ByCPU...),
+		ByMemory: append([]metrics.ProcessInfo(nil), list.ByMemory...),
+	}
+	if len(out.ByCPU) > alertTopProcesses {
+		out.ByCPU = out.ByCPU[:alertTopProcesses]
+	}
+	if len(out.ByMemory) > alertTopProcesses {
+		out.ByMemory = out.ByMemory[:alertTopProcesses]
+	}
+	return out
+}
+
+func formatTopProcesses(top *TopProcesses) string {
+	if top == nil {
+		return ""
+	}
+	var b strings.Builder
+	if len(top.ByCPU) > 0 {
+		b.WriteString("\nTop CPU:")
+		for _, p := range top.ByCPU {
+			fmt.Fprintf(&b, "\n- %.1f%% %s (pid %d, %s)", p.CPUPercent, p.Name, p.PID, p.User)
+		}
+	}
+	if len(top.ByMemory) > 0 {
+		b.WriteString("\nTop memory:")
+		for _, p := range top.ByMemory {
+			fmt.Fprintf(&b, "\n- %s (%.1f%%) %s (pid %d, %s)", formatBytes(p.RSSBytes), p.MemPercent, p.Name, p.PID, p.User)
+		}
+	}
+	return b.String()
+}
+
+func formatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := uint64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
 func validateConfig(cfg Config) error {
 	if cfg.WarningPercent <= 0 || cfg.WarningPercent > 100 {
 		return errors.New("warning_percent must be > 0 and <= 100")
@@ -422,7 +847,34 @@ func validateConfig(cfg Config) error {
 	if cfg.CooldownSec < 0 {
 		return errors.New("cooldown_sec must be >= 0")
 	}
-	return nil
+	if cfg.OutboxMaxAgeHours < 1 || cfg.OutboxMaxAgeHours > maxOutboxMaxAgeHours {
+		return fmt.Errorf("outbox_max_age_hours must be between 1 and %d", maxOutboxMaxAgeHours)
+	}
+	if cfg.FlapThreshold != 0 && (cfg.FlapThreshold < 2 || cfg.FlapThreshold > 100) {
+		return errors.New("flap_threshold must be 0 (disabled) or between 2 and 100")
+	}
+	if cfg.FlapThreshold != 0 && (cfg.FlapWindowSec < 60 || cfg.FlapWindowSec > 86400) {
+		return errors.New("flap_window_sec must be between 60 and 86400")
+	}
+	if cfg.DashboardURL != "" && !isHTTPURL(cfg.DashboardURL) {
+		return errors.New("dashboard_url must be an http(s) URL")
+	}
+	if err := validateDigest(cfg); err != nil {
+		return err
+	}
+	if cfg.ForecastHorizonHours < 1 || cfg.ForecastHorizonHours > 720 {
+		return errors.New("forecast_horizon_hours must be between 1 and 720")
+	}
+	if err := validateRoutes(cfg.Routes); err != nil {
+		return err
+	}
+	if err := validateEscalationPolicies(cfg.EscalationPolicies); err != nil {
+		return err
+	}
+	if err := validateSMTP(cfg); err != nil {
+		return err
+	}
+	return validateChatConfig(cfg)
 }
 
 func sanitizeStringSlice(items []string) []string {
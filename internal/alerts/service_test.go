@@ -0,0 +1,154 @@
+package alerts
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"quickvps/internal/metrics"
+)
+
+func newServiceForTests(t *testing.T, n *Notifier) *Service {
+	t.Helper()
+	return newServiceWithKeyForTests(t, n, "")
+}
+
+func newServiceWithKeyForTests(t *testing.T, n *Notifier, key string) *Service {
+	t.Helper()
+	store, err := NewStore(filepath.Join(t.TempDir(), "alerts.db"))
+	if err != nil {
+		t.Fatalf("NewStore() error = %v", err)
+	}
+	t.Cleanup(func() {
+		_ = store.Close()
+	})
+	svc, err := NewService(store, n, key)
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	return svc
+}
+
+func TestServiceCriticalAlertIncludesTopProcesses(t *testing.T) {
+	n := NewNotifier()
+	n.sleep = func(_ time.Duration) {}
+	var sent []string
+	n.sendTelegram = func(_ context.Context, _ string, _ []string, text string) error {
+		sent = append(sent, text)
+		return nil
+	}
+	svc := newServiceForTests(t, n)
+	svc.cfg.EmailEnabled = false
+
+	procs := make([]metrics.ProcessInfo, 0, 7)
+	for i := 0; i < 7; i++ {
+		procs = append(procs, metrics.ProcessInfo{
+			PID:        100 + i,
+			Name:       "worker",
+			User:       "app",
+			CPUPercent: float64(70 - i),
+			RSSBytes:   uint64(512-i) << 20,
+			MemPercent: 12.5,
+		})
+	}
+	base := time.Date(2026, 2, 20, 10, 0, 0, 0, time.UTC)
+	for _, at := range []time.Time{base, base.Add(10 * time.Minute)} {
+		svc.EvaluateSnapshot(context.Background(), &metrics.Snapshot{
+			Timestamp: at,
+			CPU:       metrics.CPUMetrics{TotalPercent: 95},
+			Processes: &metrics.ProcessList{Total: 7, ByCPU: procs, ByMemory: procs},
+		})
+	}
+
+	if len(sent) != 1 {
+		t.Fatalf("sent %d messages, want 1", len(sent))
+	}
+	for _, want := range []string{"[CRITICAL]", "Top CPU:", "- 70.0% worker (pid 100, app)", "Top memory:", "- 512.0 MiB (12.5%) worker (pid 100, app)"} {
+		if !strings.Contains(sent[0], want) {
+			t.Fatalf("message missing %q:\n%s", want, sent[0])
+		}
+	}
+	if strings.Contains(sent[0], "pid 105") {
+		t.Fatalf("message should be capped at %d processes:\n%s", alertTopProcesses, sent[0])
+	}
+
+	events, err := svc.ListHistory(10, 0)
+	if err != nil {
+		t.Fatalf("ListHistory() error = %v", err)
+	}
+	if len(events) != 1 || events[0].Processes == nil {
+		t.Fatalf("events = %+v, want one event with processes", events)
+	}
+	if got := len(events[0].Processes.ByCPU); got != alertTopProcesses {
+		t.Fatalf("stored ByCPU len = %d, want %d", got, alertTopProcesses)
+	}
+	if events[0].Processes.ByMemory[0].PID != 100 {
+		t.Fatalf("stored ByMemory[0] = %+v", events[0].Processes.ByMemory[0])
+	}
+}
+
+func TestServiceRuleFiresPerSeries(t *testing.T) {
+	n := NewNotifier()
+	n.sleep = func(_ time.Duration) {}
+	var subjects []string
+	n.sendEmail = func(_ context.Context, _ smtpServer, msg emailMessage) error {
+		subjects = append(subjects, msg.Subject)
+		return nil
+	}
+	svc := newServiceForTests(t, n)
+	svc.cfg.TelegramEnabled = false
+
+	name := "Var disk"
+	series := "disk.percent:/var"
+	warning, critical, recovery := 80.0, 90.0, 70.0
+	warnFor := int64(60)
+	rule, err := svc.CreateRule(RuleInput{Name: &name, Series: &series, Warning: &warning, Critical: &critical, Recovery: &recovery, WarningForSec: &warnFor})
+	if err != nil {
+		t.Fatalf("CreateRule() error = %v", err)
+	}
+
+	snapAt := func(at time.Time, varPercent float64) *metrics.Snapshot {
+		return &metrics.Snapshot{
+			Timestamp: at,
+			CPU:       metrics.CPUMetrics{TotalPercent: 5},
+			Disks: []metrics.DiskMetrics{
+				{Mountpoint: "/", Percent: 99},
+				{Mountpoint: "/var", Percent: varPercent},
+			},
+		}
+	}
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	svc.EvaluateSnapshot(context.Background(), snapAt(base, 85))
+	svc.EvaluateSnapshot(context.Background(), snapAt(base.Add(time.Minute), 86))
+
+	if len(subjects) != 1 || subjects[0] != "[QuickVPS] Var disk WARNING" {
+		t.Fatalf("subjects = %v, want one Var disk warning", subjects)
+	}
+	status := svc.Status(false)
+	if len(status.Rules) != 1 || status.Rules[0].State != LevelWarning || *status.Rules[0].LastValue != 86 {
+		t.Fatalf("rule status = %+v", status.Rules)
+	}
+	if status.CurrentState != LevelNone {
+		t.Fatalf("CPU state = %s, want none", status.CurrentState)
+	}
+
+	events, err := svc.ListHistory(10, 0)
+	if err != nil {
+		t.Fatalf("ListHistory() error = %v", err)
+	}
+	if len(events) != 1 || events[0].RuleID != rule.ID || events[0].Series != series || events[0].Value != 86 {
+		t.Fatalf("events = %+v", events)
+	}
+	if !strings.Contains(events[0].Message, `Rule="Var disk" disk.percent:/var=86.00%`) {
+		t.Fatalf("message = %q", events[0].Message)
+	}
+
+	if err := svc.DeleteRule(rule.ID); err != nil {
+		t.Fatalf("DeleteRule() error = %v", err)
+	}
+	if _, err := svc.UpdateRule(rule.ID, RuleInput{}); err != ErrRuleNotFound {
+		t.Fatalf("UpdateRule(deleted) error = %v, want ErrRuleNotFound", err)
+	}
+}
@@ -5,8 +5,11 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
+	"quickvps/internal/metrics"
+
 	_ "modernc.org/sqlite"
 )
 
@@ -16,8 +19,14 @@ type Store struct {
 
 type secretRecord struct {
 	TelegramTokenCipher string
-	GmailAddress        string
-	GmailPasswordCipher string
+	SMTPPasswordCipher  string
+	// WebhookSecretsCipher is the encrypted JSON object of URL -> secret.
+	WebhookSecretsCipher string
+	SlackWebhookCipher   string
+	DiscordWebhookCipher string
+	TeamsWebhookCipher   string
+	NtfyTokenCipher      string
+	GotifyTokenCipher    string
 }
 
 func NewStore(path string) (*Store, error) {
@@ -42,6 +51,11 @@ func NewStore(path string) (*Store, error) {
 		return nil, err
 	}
 
+	if err := s.migrateGmailToSMTP(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
 	return s, nil
 }
 
@@ -69,6 +83,22 @@ CREATE TABLE IF NOT EXISTS alert_settings (
   recipient_emails TEXT NOT NULL DEFAULT '[]',
   telegram_chat_ids TEXT NOT NULL DEFAULT '[]',
   retry_delays_sec TEXT NOT NULL DEFAULT '[1,5,15]',
+  webhook_enabled INTEGER NOT NULL DEFAULT 0,
+  webhooks_json TEXT NOT NULL DEFAULT '[]',
+  smtp_host TEXT NOT NULL DEFAULT '',
+  smtp_port INTEGER NOT NULL DEFAULT 587,
+  smtp_security TEXT NOT NULL DEFAULT 'starttls',
+  smtp_username TEXT NOT NULL DEFAULT '',
+  smtp_from TEXT NOT NULL DEFAULT '',
+  email_html INTEGER NOT NULL DEFAULT 0,
+  slack_enabled INTEGER NOT NULL DEFAULT 0,
+  discord_enabled INTEGER NOT NULL DEFAULT 0,
+  teams_enabled INTEGER NOT NULL DEFAULT 0,
+  ntfy_enabled INTEGER NOT NULL DEFAULT 0,
+  ntfy_server_url TEXT NOT NULL DEFAULT 'https://ntfy.sh',
+  ntfy_topic TEXT NOT NULL DEFAULT '',
+  gotify_enabled INTEGER NOT NULL DEFAULT 0,
+  gotify_server_url TEXT NOT NULL DEFAULT '',
   updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
 );
 
@@ -77,6 +107,13 @@ CREATE TABLE IF NOT EXISTS alert_secrets (
   telegram_token_cipher TEXT NOT NULL DEFAULT '',
   gmail_address TEXT NOT NULL DEFAULT '',
   gmail_password_cipher TEXT NOT NULL DEFAULT '',
+  webhook_secrets_cipher TEXT NOT NULL DEFAULT '',
+  smtp_password_cipher TEXT NOT NULL DEFAULT '',
+  slack_webhook_cipher TEXT NOT NULL DEFAULT '',
+  discord_webhook_cipher TEXT NOT NULL DEFAULT '',
+  teams_webhook_cipher TEXT NOT NULL DEFAULT '',
+  ntfy_token_cipher TEXT NOT NULL DEFAULT '',
+  gotify_token_cipher TEXT NOT NULL DEFAULT '',
   updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
 );
 
@@ -86,11 +123,93 @@ CREATE TABLE IF NOT EXISTS alert_events (
   message TEXT NOT NULL,
   cpu_percent REAL NOT NULL,
   channels_json TEXT NOT NULL DEFAULT '[]',
+  processes_json TEXT NOT NULL DEFAULT '',
+  rule_id INTEGER NOT NULL DEFAULT 0,
+  series TEXT NOT NULL DEFAULT '',
+  value REAL NOT NULL DEFAULT 0,
+  incident_id INTEGER NOT NULL DEFAULT 0,
+  escalation_policy TEXT NOT NULL DEFAULT '',
+  escalation_step INTEGER NOT NULL DEFAULT 0,
   created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
 );
 
 CREATE INDEX IF NOT EXISTS idx_alert_events_created_at ON alert_events(created_at DESC);
 
+CREATE TABLE IF NOT EXISTS alert_rules (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  name TEXT NOT NULL,
+  enabled INTEGER NOT NULL DEFAULT 1,
+  series TEXT NOT NULL,
+  warning_value REAL NOT NULL,
+  warning_for_sec INTEGER NOT NULL,
+  critical_value REAL NOT NULL,
+  critical_for_sec INTEGER NOT NULL,
+  recovery_value REAL NOT NULL,
+  recovery_for_sec INTEGER NOT NULL,
+  cooldown_sec INTEGER NOT NULL,
+  created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS alert_maintenance_windows (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  name TEXT NOT NULL,
+  enabled INTEGER NOT NULL DEFAULT 1,
+  kind TEXT NOT NULL,
+  weekdays_json TEXT NOT NULL DEFAULT '[]',
+  start_time TEXT NOT NULL DEFAULT '',
+  end_time TEXT NOT NULL DEFAULT '',
+  cron TEXT NOT NULL DEFAULT '',
+  duration_min INTEGER NOT NULL DEFAULT 0,
+  timezone TEXT NOT NULL DEFAULT 'UTC',
+  created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS alert_incidents (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  rule_id INTEGER NOT NULL DEFAULT 0,
+  series TEXT NOT NULL,
+  level TEXT NOT NULL,
+  opened_at DATETIME NOT NULL,
+  acked_at DATETIME NULL,
+  acked_by TEXT NOT NULL DEFAULT '',
+  ack_note TEXT NOT NULL DEFAULT '',
+  resolved_at DATETIME NULL,
+  event_count INTEGER NOT NULL DEFAULT 0,
+  critical_at DATETIME NULL,
+  escalations_json TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS alert_outbox (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  event_id INTEGER NOT NULL,
+  channel TEXT NOT NULL,
+  target TEXT NOT NULL DEFAULT '',
+  target_key TEXT NOT NULL DEFAULT '',
+  level TEXT NOT NULL,
+  notification_json TEXT NOT NULL,
+  attempts INTEGER NOT NULL DEFAULT 0,
+  next_attempt_at DATETIME NOT NULL,
+  last_error TEXT NOT NULL DEFAULT '',
+  created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS alert_evaluator_state (
+  rule_id INTEGER PRIMARY KEY,
+  state_json TEXT NOT NULL,
+  updated_at DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS alert_templates (
+  channel TEXT NOT NULL,
+  level TEXT NOT NULL,
+  subject TEXT NOT NULL DEFAULT '',
+  body TEXT NOT NULL,
+  updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (channel, level)
+);
+
 CREATE TABLE IF NOT EXISTS alert_silence (
   id INTEGER PRIMARY KEY CHECK(id = 1),
   muted_until DATETIME NULL,
@@ -100,6 +219,128 @@ CREATE TABLE IF NOT EXISTS alert_silence (
 	if _, err := s.db.Exec(schema); err != nil {
 		return fmt.Errorf("migrate alert tables: %w", err)
 	}
+	// Columns added after the first release; older databases lack them.
+	for _, col := range []struct{ table, name, definition string }{
+		{"alert_events", "processes_json", `TEXT NOT NULL DEFAULT ''`},
+		{"alert_events", "rule_id", `INTEGER NOT NULL DEFAULT 0`},
+		{"alert_events", "series", `TEXT NOT NULL DEFAULT ''`},
+		{"alert_events", "value", `REAL NOT NULL DEFAULT 0`},
+		{"alert_settings", "webhook_enabled", `INTEGER NOT NULL DEFAULT 0`},
+		{"alert_settings", "webhooks_json", `TEXT NOT NULL DEFAULT '[]'`},
+		{"alert_secrets", "webhook_secrets_cipher", `TEXT NOT NULL DEFAULT ''`},
+		{"alert_settings", "smtp_host", `TEXT NOT NULL DEFAULT ''`},
+		{"alert_settings", "smtp_port", `INTEGER NOT NULL DEFAULT 587`},
+		{"alert_settings", "smtp_security", `TEXT NOT NULL DEFAULT 'starttls'`},
+		{"alert_settings", "smtp_username", `TEXT NOT NULL DEFAULT ''`},
+		{"alert_settings", "smtp_from", `TEXT NOT NULL DEFAULT ''`},
+		{"alert_settings", "email_html", `INTEGER NOT NULL DEFAULT 0`},
+		{"alert_secrets", "smtp_password_cipher", `TEXT NOT NULL DEFAULT ''`},
+		{"alert_settings", "slack_enabled", `INTEGER NOT NULL DEFAULT 0`},
+		{"alert_settings", "discord_enabled", `INTEGER NOT NULL DEFAULT 0`},
+		{"alert_settings", "teams_enabled", `INTEGER NOT NULL DEFAULT 0`},
+		{"alert_settings", "ntfy_enabled", `INTEGER NOT NULL DEFAULT 0`},
+		{"alert_settings", "ntfy_server_url", `TEXT NOT NULL DEFAULT 'https://ntfy.sh'`},
+		{"alert_settings", "ntfy_topic", `TEXT NOT NULL DEFAULT ''`},
+		{"alert_settings", "gotify_enabled", `INTEGER NOT NULL DEFAULT 0`},
+		{"alert_settings", "gotify_server_url", `TEXT NOT NULL DEFAULT ''`},
+		{"alert_secrets", "slack_webhook_cipher", `TEXT NOT NULL DEFAULT ''`},
+		{"alert_secrets", "discord_webhook_cipher", `TEXT NOT NULL DEFAULT ''`},
+		{"alert_secrets", "teams_webhook_cipher", `TEXT NOT NULL DEFAULT ''`},
+		{"alert_secrets", "ntfy_token_cipher", `TEXT NOT NULL DEFAULT ''`},
+		{"alert_secrets", "gotify_token_cipher", `TEXT NOT NULL DEFAULT ''`},
+		{"alert_events", "incident_id", `INTEGER NOT NULL DEFAULT 0`},
+		{"alert_settings", "flap_window_sec", `INTEGER NOT NULL DEFAULT 3600`},
+		{"alert_settings", "flap_threshold", `INTEGER NOT NULL DEFAULT 4`},
+		{"alert_settings", "dashboard_url", `TEXT NOT NULL DEFAULT ''`},
+		{"alert_settings", "digest_enabled", `INTEGER NOT NULL DEFAULT 0`},
+		{"alert_settings", "digest_period", `TEXT NOT NULL DEFAULT 'daily'`},
+		{"alert_settings", "digest_cron", `TEXT NOT NULL DEFAULT '0 8 * * *'`},
+		{"alert_settings", "digest_timezone", `TEXT NOT NULL DEFAULT 'UTC'`},
+		{"alert_settings", "digest_sections_json", `TEXT NOT NULL DEFAULT '[]'`},
+		{"alert_settings", "routes_json", `TEXT NOT NULL DEFAULT ''`},
+		{"alert_settings", "escalation_policies_json", `TEXT NOT NULL DEFAULT '[]'`},
+		{"alert_settings", "forecast_enabled", `INTEGER NOT NULL DEFAULT 1`},
+		{"alert_settings", "forecast_horizon_hours", `INTEGER NOT NULL DEFAULT 24`},
+		{"alert_settings", "outbox_max_age_hours", `INTEGER NOT NULL DEFAULT 24`},
+		{"alert_events", "escalation_policy", `TEXT NOT NULL DEFAULT ''`},
+		{"alert_events", "escalation_step", `INTEGER NOT NULL DEFAULT 0`},
+		{"alert_incidents", "critical_at", `DATETIME NULL`},
+		{"alert_incidents", "escalations_json", `TEXT NOT NULL DEFAULT ''`},
+	} {
+		if err := s.addColumnIfMissing(col.table, col.name, col.definition); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateGmailToSMTP turns a Gmail-only email setup into the equivalent SMTP
+// settings once, then clears the legacy columns. The password ciphertext is
+// copied as is, so no encryption key is needed.
+func (s *Store) migrateGmailToSMTP() error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin gmail migration: %w", err)
+	}
+	defer tx.Rollback()
+
+	var address, passwordCipher string
+	err = tx.QueryRow(`SELECT gmail_address, gmail_password_cipher FROM alert_secrets WHERE id = 1`).Scan(&address, &passwordCipher)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("load gmail settings: %w", err)
+	}
+	if address == "" && passwordCipher == "" {
+		return nil
+	}
+
+	if _, err := tx.Exec(`
+UPDATE alert_settings
+SET smtp_host = 'smtp.gmail.com', smtp_port = 587, smtp_security = 'starttls',
+    smtp_username = ?, smtp_from = ?
+WHERE id = 1 AND smtp_host = ''
+`, address, address); err != nil {
+		return fmt.Errorf("migrate gmail settings: %w", err)
+	}
+	if _, err := tx.Exec(`
+UPDATE alert_secrets
+SET smtp_password_cipher = CASE WHEN smtp_password_cipher = '' THEN gmail_password_cipher ELSE smtp_password_cipher END,
+    gmail_address = '', gmail_password_cipher = ''
+WHERE id = 1
+`); err != nil {
+		return fmt.Errorf("migrate gmail secrets: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit gmail migration: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) addColumnIfMissing(table, column, definition string) error {
+	rows, err := s.db.Query(`SELECT name FROM pragma_table_info(?)`, table)
+	if err != nil {
+		return fmt.Errorf("inspect %s columns: %w", table, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return fmt.Errorf("scan %s column: %w", table, err)
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate %s columns: %w", table, err)
+	}
+	rows.Close()
+
+	if _, err := s.db.Exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, table, column, definition)); err != nil {
+		return fmt.Errorf("add %s.%s column: %w", table, column, err)
+	}
 	return nil
 }
 
@@ -155,8 +396,13 @@ ON CONFLICT(id) DO NOTHING
 
 func (s *Store) LoadConfig() (Config, error) {
 	cfg := DefaultConfig()
-	var recipientJSON, chatJSON, retryJSON string
-	var enabled, telegramEnabled, emailEnabled int
+	var recipientJSON, chatJSON, retryJSON, webhooksJSON, smtpSecurity string
+	var enabled, telegramEnabled, emailEnabled, webhookEnabled, emailHTML int
+	var slackEnabled, discordEnabled, teamsEnabled, ntfyEnabled, gotifyEnabled int
+	var digestEnabled int
+	var digestPeriod, digestSectionsJSON string
+	var routesJSON, escalationJSON string
+	var forecastEnabled int
 
 	err := s.db.QueryRow(`
 SELECT
@@ -172,7 +418,36 @@ SELECT
   email_enabled,
   recipient_emails,
   telegram_chat_ids,
-  retry_delays_sec
+  retry_delays_sec,
+  webhook_enabled,
+  webhooks_json,
+  smtp_host,
+  smtp_port,
+  smtp_security,
+  smtp_username,
+  smtp_from,
+  email_html,
+  slack_enabled,
+  discord_enabled,
+  teams_enabled,
+  ntfy_enabled,
+  ntfy_server_url,
+  ntfy_topic,
+  gotify_enabled,
+  gotify_server_url,
+  flap_window_sec,
+  flap_threshold,
+  dashboard_url,
+  digest_enabled,
+  digest_period,
+  digest_cron,
+  digest_timezone,
+  digest_sections_json,
+  routes_json,
+  escalation_policies_json,
+  forecast_enabled,
+  forecast_horizon_hours,
+  outbox_max_age_hours
 FROM alert_settings
 WHERE id = 1
 `).Scan(
@@ -189,6 +464,35 @@ WHERE id = 1
 		&recipientJSON,
 		&chatJSON,
 		&retryJSON,
+		&webhookEnabled,
+		&webhooksJSON,
+		&cfg.SMTPHost,
+		&cfg.SMTPPort,
+		&smtpSecurity,
+		&cfg.SMTPUsername,
+		&cfg.SMTPFrom,
+		&emailHTML,
+		&slackEnabled,
+		&discordEnabled,
+		&teamsEnabled,
+		&ntfyEnabled,
+		&cfg.NtfyServerURL,
+		&cfg.NtfyTopic,
+		&gotifyEnabled,
+		&cfg.GotifyServerURL,
+		&cfg.FlapWindowSec,
+		&cfg.FlapThreshold,
+		&cfg.DashboardURL,
+		&digestEnabled,
+		&digestPeriod,
+		&cfg.DigestCron,
+		&cfg.DigestTimezone,
+		&digestSectionsJSON,
+		&routesJSON,
+		&escalationJSON,
+		&forecastEnabled,
+		&cfg.ForecastHorizonHours,
+		&cfg.OutboxMaxAgeHours,
 	)
 	if err != nil {
 		return cfg, fmt.Errorf("load alert config: %w", err)
@@ -197,12 +501,30 @@ WHERE id = 1
 	cfg.Enabled = enabled == 1
 	cfg.TelegramEnabled = telegramEnabled == 1
 	cfg.EmailEnabled = emailEnabled == 1
+	cfg.WebhookEnabled = webhookEnabled == 1
+	cfg.Webhooks = decodeWebhooks(webhooksJSON)
+	cfg.SMTPSecurity = SMTPSecurity(smtpSecurity)
+	cfg.EmailHTML = emailHTML == 1
+	cfg.SlackEnabled = slackEnabled == 1
+	cfg.DiscordEnabled = discordEnabled == 1
+	cfg.TeamsEnabled = teamsEnabled == 1
+	cfg.NtfyEnabled = ntfyEnabled == 1
+	cfg.GotifyEnabled = gotifyEnabled == 1
 	cfg.RecipientEmails = decodeStringSlice(recipientJSON)
 	cfg.TelegramChatIDs = decodeStringSlice(chatJSON)
 	cfg.RetryDelaysSec = decodeIntSlice(retryJSON)
 	if len(cfg.RetryDelaysSec) == 0 {
 		cfg.RetryDelaysSec = []int{1, 5, 15}
 	}
+	cfg.DigestEnabled = digestEnabled == 1
+	cfg.DigestPeriod = DigestPeriod(digestPeriod)
+	cfg.DigestSections = decodeStringSlice(digestSectionsJSON)
+	if len(cfg.DigestSections) == 0 {
+		cfg.DigestSections = append([]string(nil), digestSections...)
+	}
+	cfg.Routes = decodeRoutes(routesJSON)
+	cfg.EscalationPolicies = decodeEscalationPolicies(escalationJSON)
+	cfg.ForecastEnabled = forecastEnabled == 1
 
 	return cfg, nil
 }
@@ -224,6 +546,35 @@ SET
   recipient_emails = ?,
   telegram_chat_ids = ?,
   retry_delays_sec = ?,
+  webhook_enabled = ?,
+  webhooks_json = ?,
+  smtp_host = ?,
+  smtp_port = ?,
+  smtp_security = ?,
+  smtp_username = ?,
+  smtp_from = ?,
+  email_html = ?,
+  slack_enabled = ?,
+  discord_enabled = ?,
+  teams_enabled = ?,
+  ntfy_enabled = ?,
+  ntfy_server_url = ?,
+  ntfy_topic = ?,
+  gotify_enabled = ?,
+  gotify_server_url = ?,
+  flap_window_sec = ?,
+  flap_threshold = ?,
+  dashboard_url = ?,
+  digest_enabled = ?,
+  digest_period = ?,
+  digest_cron = ?,
+  digest_timezone = ?,
+  digest_sections_json = ?,
+  routes_json = ?,
+  escalation_policies_json = ?,
+  forecast_enabled = ?,
+  forecast_horizon_hours = ?,
+  outbox_max_age_hours = ?,
   updated_at = CURRENT_TIMESTAMP
 WHERE id = 1
 `,
@@ -240,6 +591,35 @@ WHERE id = 1
 		mustJSON(cfg.RecipientEmails),
 		mustJSON(cfg.TelegramChatIDs),
 		mustJSON(cfg.RetryDelaysSec),
+		boolToInt(cfg.WebhookEnabled),
+		mustJSON(webhooksOrEmpty(cfg.Webhooks)),
+		cfg.SMTPHost,
+		cfg.SMTPPort,
+		string(cfg.SMTPSecurity),
+		cfg.SMTPUsername,
+		cfg.SMTPFrom,
+		boolToInt(cfg.EmailHTML),
+		boolToInt(cfg.SlackEnabled),
+		boolToInt(cfg.DiscordEnabled),
+		boolToInt(cfg.TeamsEnabled),
+		boolToInt(cfg.NtfyEnabled),
+		cfg.NtfyServerURL,
+		cfg.NtfyTopic,
+		boolToInt(cfg.GotifyEnabled),
+		cfg.GotifyServerURL,
+		cfg.FlapWindowSec,
+		cfg.FlapThreshold,
+		cfg.DashboardURL,
+		boolToInt(cfg.DigestEnabled),
+		string(cfg.DigestPeriod),
+		cfg.DigestCron,
+		cfg.DigestTimezone,
+		mustJSON(cfg.DigestSections),
+		mustJSON(cfg.Routes),
+		mustJSON(escalationPoliciesOrEmpty(cfg.EscalationPolicies)),
+		boolToInt(cfg.ForecastEnabled),
+		cfg.ForecastHorizonHours,
+		cfg.OutboxMaxAgeHours,
 	)
 	if err != nil {
 		return fmt.Errorf("save alert config: %w", err)
@@ -250,10 +630,27 @@ WHERE id = 1
 func (s *Store) LoadSecretRecord() (secretRecord, error) {
 	var rec secretRecord
 	err := s.db.QueryRow(`
-SELECT telegram_token_cipher, gmail_address, gmail_password_cipher
+SELECT
+  telegram_token_cipher,
+  smtp_password_cipher,
+  webhook_secrets_cipher,
+  slack_webhook_cipher,
+  discord_webhook_cipher,
+  teams_webhook_cipher,
+  ntfy_token_cipher,
+  gotify_token_cipher
 FROM alert_secrets
 WHERE id = 1
-`).Scan(&rec.TelegramTokenCipher, &rec.GmailAddress, &rec.GmailPasswordCipher)
+`).Scan(
+		&rec.TelegramTokenCipher,
+		&rec.SMTPPasswordCipher,
+		&rec.WebhookSecretsCipher,
+		&rec.SlackWebhookCipher,
+		&rec.DiscordWebhookCipher,
+		&rec.TeamsWebhookCipher,
+		&rec.NtfyTokenCipher,
+		&rec.GotifyTokenCipher,
+	)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return rec, nil
@@ -268,22 +665,139 @@ func (s *Store) SaveSecretRecord(rec secretRecord) error {
 UPDATE alert_secrets
 SET
   telegram_token_cipher = ?,
-  gmail_address = ?,
-  gmail_password_cipher = ?,
+  smtp_password_cipher = ?,
+  webhook_secrets_cipher = ?,
+  slack_webhook_cipher = ?,
+  discord_webhook_cipher = ?,
+  teams_webhook_cipher = ?,
+  ntfy_token_cipher = ?,
+  gotify_token_cipher = ?,
   updated_at = CURRENT_TIMESTAMP
 WHERE id = 1
-`, rec.TelegramTokenCipher, rec.GmailAddress, rec.GmailPasswordCipher)
+`,
+		rec.TelegramTokenCipher,
+		rec.SMTPPasswordCipher,
+		rec.WebhookSecretsCipher,
+		rec.SlackWebhookCipher,
+		rec.DiscordWebhookCipher,
+		rec.TeamsWebhookCipher,
+		rec.NtfyTokenCipher,
+		rec.GotifyTokenCipher,
+	)
 	if err != nil {
 		return fmt.Errorf("save alert secrets: %w", err)
 	}
 	return nil
 }
 
-func (s *Store) SaveEvent(level Level, message string, cpuPercent float64, channels []ChannelResult, createdAt time.Time) (int64, error) {
+// secretCipherColumns are the alert_secrets columns holding ciphertext.
+var secretCipherColumns = []string{
+	"telegram_token_cipher",
+	"smtp_password_cipher",
+	"webhook_secrets_cipher",
+	"slack_webhook_cipher",
+	"discord_webhook_cipher",
+	"teams_webhook_cipher",
+	"ntfy_token_cipher",
+	"gotify_token_cipher",
+}
+
+// ReencryptSecrets rewrites every stored ciphertext through fn in one
+// transaction and returns how many values changed. Any error from fn rolls
+// the whole rewrite back, so secrets never end up split across keys by a
+// half-finished rotation.
+func (s *Store) ReencryptSecrets(fn func(ciphertext string) (string, error)) (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("begin secret re-encryption: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	values := make([]string, len(secretCipherColumns))
+	dest := make([]any, len(values))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	err = tx.QueryRow(`SELECT ` + strings.Join(secretCipherColumns, ", ") + ` FROM alert_secrets WHERE id = 1`).Scan(dest...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("load alert secrets: %w", err)
+	}
+
+	changed := 0
+	assignments := make([]string, len(secretCipherColumns))
+	args := make([]any, len(values))
+	for i, value := range values {
+		assignments[i] = secretCipherColumns[i] + " = ?"
+		args[i] = value
+		if value == "" {
+			continue
+		}
+		next, err := fn(value)
+		if err != nil {
+			return 0, fmt.Errorf("re-encrypt %s: %w", secretCipherColumns[i], err)
+		}
+		if next != value {
+			args[i] = next
+			changed++
+		}
+	}
+	if changed == 0 {
+		return 0, nil
+	}
+
+	if _, err := tx.Exec(`UPDATE alert_secrets SET `+strings.Join(assignments, ", ")+`, updated_at = CURRENT_TIMESTAMP WHERE id = 1`, args...); err != nil {
+		return 0, fmt.Errorf("save re-encrypted alert secrets: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit secret re-encryption: %w", err)
+	}
+	return changed, nil
+}
+
+// MigrateWebhooks stores webhooks whose URLs and header values moved into
+// secretsCipher, and re-keys their pending outbox deliveries from the old
+// URL to the new ID, in one transaction so a crash cannot leave the config
+// pointing at secrets that were never written.
+func (s *Store) MigrateWebhooks(webhooks []Webhook, secretsCipher string, outboxKeys map[string]string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin webhook migration: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	if _, err := tx.Exec(`UPDATE alert_settings SET webhooks_json = ?, updated_at = CURRENT_TIMESTAMP WHERE id = 1`, mustJSON(webhooksOrEmpty(webhooks))); err != nil {
+		return fmt.Errorf("save migrated webhooks: %w", err)
+	}
+	if _, err := tx.Exec(`UPDATE alert_secrets SET webhook_secrets_cipher = ?, updated_at = CURRENT_TIMESTAMP WHERE id = 1`, secretsCipher); err != nil {
+		return fmt.Errorf("save migrated webhook secrets: %w", err)
+	}
+	for oldKey, newKey := range outboxKeys {
+		if _, err := tx.Exec(`UPDATE alert_outbox SET target_key = ? WHERE channel = 'webhook' AND target_key = ?`, newKey, oldKey); err != nil {
+			return fmt.Errorf("re-key webhook outbox: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit webhook migration: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) SaveEvent(e Event) (int64, error) {
+	processesJSON := ""
+	if e.Processes != nil {
+		processesJSON = mustJSON(e.Processes)
+	}
+	escalationPolicy, escalationStep := "", 0
+	if e.Escalation != nil {
+		escalationPolicy, escalationStep = e.Escalation.Policy, e.Escalation.Step
+	}
 	result, err := s.db.Exec(`
-INSERT INTO alert_events(level, message, cpu_percent, channels_json, created_at)
-VALUES (?, ?, ?, ?, ?)
-`, string(level), message, cpuPercent, mustJSON(channels), createdAt.UTC())
+INSERT INTO alert_events(level, message, cpu_percent, channels_json, processes_json, rule_id, incident_id, series, value, escalation_policy, escalation_step, created_at)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+`, string(e.Level), e.Message, e.CPUPercent, mustJSON(e.Channels), processesJSON, e.RuleID, e.IncidentID, e.Series, e.Value, escalationPolicy, escalationStep, e.CreatedAt.UTC())
 	if err != nil {
 		return 0, fmt.Errorf("save alert event: %w", err)
 	}
@@ -295,6 +809,148 @@ VALUES (?, ?, ?, ?, ?)
 	return id, nil
 }
 
+// UpdateEventChannels records delivery results once notifications finish;
+// events are saved first so channels can reference their ID.
+func (s *Store) UpdateEventChannels(id int64, channels []ChannelResult) error {
+	if _, err := s.db.Exec(`UPDATE alert_events SET channels_json = ? WHERE id = ?`, mustJSON(channels), id); err != nil {
+		return fmt.Errorf("update alert event channels: %w", err)
+	}
+	return nil
+}
+
+// UpdateEventChannel replaces the result for one channel/target of an event,
+// used when an outbox delivery finishes.
+func (s *Store) UpdateEventChannel(id int64, res ChannelResult) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin event channel update: %w", err)
+	}
+	defer tx.Rollback()
+
+	var chJSON string
+	if err := tx.QueryRow(`SELECT channels_json FROM alert_events WHERE id = ?`, id).Scan(&chJSON); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			// The event was cleaned up while the delivery was pending.
+			return nil
+		}
+		return fmt.Errorf("load alert event channels: %w", err)
+	}
+	channels := decodeChannelResults(chJSON)
+	replaced := false
+	for i, ch := range channels {
+		if ch.Channel == res.Channel && ch.Target == res.Target {
+			channels[i] = res
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		channels = append(channels, res)
+	}
+	if _, err := tx.Exec(`UPDATE alert_events SET channels_json = ? WHERE id = ?`, mustJSON(channels), id); err != nil {
+		return fmt.Errorf("update alert event channel: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit event channel update: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) EnqueueOutbox(rec outboxRecord) (int64, error) {
+	result, err := s.db.Exec(`
+INSERT INTO alert_outbox(event_id, channel, target, target_key, level, notification_json, attempts, next_attempt_at, last_error, created_at)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+`,
+		rec.EventID, rec.Channel, rec.Target, rec.TargetKey, string(rec.Level), mustJSON(rec.Note),
+		rec.Attempts, rec.NextAttemptAt.UTC(), rec.LastError, rec.CreatedAt.UTC(),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("enqueue alert outbox: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("enqueue alert outbox last insert id: %w", err)
+	}
+	return id, nil
+}
+
+const outboxColumns = `id, event_id, channel, target, target_key, level, notification_json,
+  attempts, next_attempt_at, last_error, created_at`
+
+// ListOutbox returns every pending delivery, oldest first.
+func (s *Store) ListOutbox() ([]outboxRecord, error) {
+	rows, err := s.db.Query(`SELECT ` + outboxColumns + ` FROM alert_outbox ORDER BY id ASC`)
+	if err != nil {
+		return nil, fmt.Errorf("list alert outbox: %w", err)
+	}
+	defer rows.Close()
+
+	out := make([]outboxRecord, 0)
+	for rows.Next() {
+		rec, err := scanOutbox(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, rec)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate alert outbox: %w", err)
+	}
+	return out, nil
+}
+
+func (s *Store) GetOutbox(id int64) (outboxRecord, error) {
+	rec, err := scanOutbox(s.db.QueryRow(`SELECT `+outboxColumns+` FROM alert_outbox WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return outboxRecord{}, ErrOutboxNotFound
+	}
+	return rec, err
+}
+
+func (s *Store) RescheduleOutbox(id int64, attempts int, next time.Time, lastError string) error {
+	_, err := s.db.Exec(`
+UPDATE alert_outbox SET attempts = ?, next_attempt_at = ?, last_error = ? WHERE id = ?
+`, attempts, next.UTC(), lastError, id)
+	if err != nil {
+		return fmt.Errorf("reschedule alert outbox: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) DeleteOutbox(id int64) error {
+	result, err := s.db.Exec(`DELETE FROM alert_outbox WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("delete alert outbox: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrOutboxNotFound
+	}
+	return nil
+}
+
+func scanOutbox(row rowScanner) (outboxRecord, error) {
+	var (
+		rec      outboxRecord
+		level    string
+		noteJSON string
+	)
+	err := row.Scan(
+		&rec.ID, &rec.EventID, &rec.Channel, &rec.Target, &rec.TargetKey, &level, &noteJSON,
+		&rec.Attempts, &rec.NextAttemptAt, &rec.LastError, &rec.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return outboxRecord{}, err
+		}
+		return outboxRecord{}, fmt.Errorf("scan alert outbox: %w", err)
+	}
+	rec.Level = Level(level)
+	if err := json.Unmarshal([]byte(noteJSON), &rec.Note); err != nil {
+		return outboxRecord{}, fmt.Errorf("decode alert outbox notification: %w", err)
+	}
+	return rec, nil
+}
+
 func (s *Store) ListEvents(limit int, beforeID int64) ([]Event, error) {
 	if limit <= 0 {
 		limit = 50
@@ -304,7 +960,7 @@ func (s *Store) ListEvents(limit int, beforeID int64) ([]Event, error) {
 	}
 
 	query := `
-SELECT id, level, message, cpu_percent, channels_json, created_at
+SELECT id, level, message, cpu_percent, channels_json, processes_json, rule_id, incident_id, series, value, escalation_policy, escalation_step, created_at
 FROM alert_events
 `
 	args := make([]any, 0, 2)
@@ -324,15 +980,27 @@ FROM alert_events
 	events := make([]Event, 0, limit)
 	for rows.Next() {
 		var (
-			e      Event
-			level  string
-			chJSON string
+			e        Event
+			level    string
+			chJSON   string
+			procJSON string
+			policy   string
+			step     int
 		)
-		if err := rows.Scan(&e.ID, &level, &e.Message, &e.CPUPercent, &chJSON, &e.CreatedAt); err != nil {
+		if err := rows.Scan(&e.ID, &level, &e.Message, &e.CPUPercent, &chJSON, &procJSON, &e.RuleID, &e.IncidentID, &e.Series, &e.Value, &policy, &step, &e.CreatedAt); err != nil {
 			return nil, fmt.Errorf("scan alert event: %w", err)
 		}
 		e.Level = Level(level)
 		e.Channels = decodeChannelResults(chJSON)
+		e.Processes = decodeTopProcesses(procJSON)
+		if step > 0 {
+			e.Escalation = &EscalationRef{IncidentID: e.IncidentID, Policy: policy, Step: step}
+		}
+		if e.Series == "" && e.Level != LevelTest && e.Level != LevelDigest {
+			// Events recorded before rules existed were always CPU alerts.
+			e.Series = metrics.SeriesCPUPercent
+			e.Value = e.CPUPercent
+		}
 		events = append(events, e)
 	}
 	if err := rows.Err(); err != nil {
@@ -342,6 +1010,398 @@ FROM alert_events
 	return events, nil
 }
 
+const ruleColumns = `id, name, enabled, series,
+  warning_value, warning_for_sec, critical_value, critical_for_sec,
+  recovery_value, recovery_for_sec, cooldown_sec, created_at, updated_at`
+
+func (s *Store) ListRules() ([]Rule, error) {
+	rows, err := s.db.Query(`SELECT ` + ruleColumns + ` FROM alert_rules ORDER BY id ASC`)
+	if err != nil {
+		return nil, fmt.Errorf("list alert rules: %w", err)
+	}
+	defer rows.Close()
+
+	rules := make([]Rule, 0)
+	for rows.Next() {
+		r, err := scanRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate alert rules: %w", err)
+	}
+	return rules, nil
+}
+
+func (s *Store) GetRule(id int64) (Rule, error) {
+	r, err := scanRule(s.db.QueryRow(`SELECT `+ruleColumns+` FROM alert_rules WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Rule{}, ErrRuleNotFound
+	}
+	return r, err
+}
+
+func (s *Store) CreateRule(r Rule) (Rule, error) {
+	result, err := s.db.Exec(`
+INSERT INTO alert_rules(
+  name, enabled, series,
+  warning_value, warning_for_sec, critical_value, critical_for_sec,
+  recovery_value, recovery_for_sec, cooldown_sec
+)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+`,
+		r.Name, boolToInt(r.Enabled), r.Series,
+		r.Warning, r.WarningForSec, r.Critical, r.CriticalForSec,
+		r.Recovery, r.RecoveryForSec, r.CooldownSec,
+	)
+	if err != nil {
+		return Rule{}, fmt.Errorf("create alert rule: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return Rule{}, fmt.Errorf("create alert rule last insert id: %w", err)
+	}
+	return s.GetRule(id)
+}
+
+func (s *Store) UpdateRule(r Rule) (Rule, error) {
+	result, err := s.db.Exec(`
+UPDATE alert_rules
+SET
+  name = ?,
+  enabled = ?,
+  series = ?,
+  warning_value = ?,
+  warning_for_sec = ?,
+  critical_value = ?,
+  critical_for_sec = ?,
+  recovery_value = ?,
+  recovery_for_sec = ?,
+  cooldown_sec = ?,
+  updated_at = CURRENT_TIMESTAMP
+WHERE id = ?
+`,
+		r.Name, boolToInt(r.Enabled), r.Series,
+		r.Warning, r.WarningForSec, r.Critical, r.CriticalForSec,
+		r.Recovery, r.RecoveryForSec, r.CooldownSec,
+		r.ID,
+	)
+	if err != nil {
+		return Rule{}, fmt.Errorf("update alert rule: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return Rule{}, ErrRuleNotFound
+	}
+	return s.GetRule(r.ID)
+}
+
+func (s *Store) DeleteRule(id int64) error {
+	result, err := s.db.Exec(`DELETE FROM alert_rules WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("delete alert rule: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrRuleNotFound
+	}
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanRule(row rowScanner) (Rule, error) {
+	var (
+		r       Rule
+		enabled int
+	)
+	err := row.Scan(
+		&r.ID, &r.Name, &enabled, &r.Series,
+		&r.Warning, &r.WarningForSec, &r.Critical, &r.CriticalForSec,
+		&r.Recovery, &r.RecoveryForSec, &r.CooldownSec,
+		&r.CreatedAt, &r.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Rule{}, err
+		}
+		return Rule{}, fmt.Errorf("scan alert rule: %w", err)
+	}
+	r.Enabled = enabled == 1
+	return r, nil
+}
+
+const maintenanceColumns = `id, name, enabled, kind, weekdays_json, start_time, end_time,
+  cron, duration_min, timezone, created_at, updated_at`
+
+func (s *Store) ListMaintenanceWindows() ([]MaintenanceWindow, error) {
+	rows, err := s.db.Query(`SELECT ` + maintenanceColumns + ` FROM alert_maintenance_windows ORDER BY id ASC`)
+	if err != nil {
+		return nil, fmt.Errorf("list maintenance windows: %w", err)
+	}
+	defer rows.Close()
+
+	windows := make([]MaintenanceWindow, 0)
+	for rows.Next() {
+		w, err := scanMaintenanceWindow(rows)
+		if err != nil {
+			return nil, err
+		}
+		windows = append(windows, w)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate maintenance windows: %w", err)
+	}
+	return windows, nil
+}
+
+func (s *Store) GetMaintenanceWindow(id int64) (MaintenanceWindow, error) {
+	w, err := scanMaintenanceWindow(s.db.QueryRow(`SELECT `+maintenanceColumns+` FROM alert_maintenance_windows WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return MaintenanceWindow{}, ErrMaintenanceWindowNotFound
+	}
+	return w, err
+}
+
+func (s *Store) CreateMaintenanceWindow(w MaintenanceWindow) (MaintenanceWindow, error) {
+	result, err := s.db.Exec(`
+INSERT INTO alert_maintenance_windows(name, enabled, kind, weekdays_json, start_time, end_time, cron, duration_min, timezone)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+`,
+		w.Name, boolToInt(w.Enabled), string(w.Kind), mustJSON(w.Weekdays),
+		w.StartTime, w.EndTime, w.Cron, w.DurationMin, w.Timezone,
+	)
+	if err != nil {
+		return MaintenanceWindow{}, fmt.Errorf("create maintenance window: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return MaintenanceWindow{}, fmt.Errorf("create maintenance window last insert id: %w", err)
+	}
+	return s.GetMaintenanceWindow(id)
+}
+
+func (s *Store) UpdateMaintenanceWindow(w MaintenanceWindow) (MaintenanceWindow, error) {
+	result, err := s.db.Exec(`
+UPDATE alert_maintenance_windows
+SET
+  name = ?,
+  enabled = ?,
+  kind = ?,
+  weekdays_json = ?,
+  start_time = ?,
+  end_time = ?,
+  cron = ?,
+  duration_min = ?,
+  timezone = ?,
+  updated_at = CURRENT_TIMESTAMP
+WHERE id = ?
+`,
+		w.Name, boolToInt(w.Enabled), string(w.Kind), mustJSON(w.Weekdays),
+		w.StartTime, w.EndTime, w.Cron, w.DurationMin, w.Timezone,
+		w.ID,
+	)
+	if err != nil {
+		return MaintenanceWindow{}, fmt.Errorf("update maintenance window: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return MaintenanceWindow{}, ErrMaintenanceWindowNotFound
+	}
+	return s.GetMaintenanceWindow(w.ID)
+}
+
+func (s *Store) DeleteMaintenanceWindow(id int64) error {
+	result, err := s.db.Exec(`DELETE FROM alert_maintenance_windows WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("delete maintenance window: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrMaintenanceWindowNotFound
+	}
+	return nil
+}
+
+func scanMaintenanceWindow(row rowScanner) (MaintenanceWindow, error) {
+	var (
+		w            MaintenanceWindow
+		enabled      int
+		kind         string
+		weekdaysJSON string
+	)
+	err := row.Scan(
+		&w.ID, &w.Name, &enabled, &kind, &weekdaysJSON, &w.StartTime, &w.EndTime,
+		&w.Cron, &w.DurationMin, &w.Timezone, &w.CreatedAt, &w.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return MaintenanceWindow{}, err
+		}
+		return MaintenanceWindow{}, fmt.Errorf("scan maintenance window: %w", err)
+	}
+	w.Enabled = enabled == 1
+	w.Kind = MaintenanceKind(kind)
+	w.Weekdays = decodeIntSlice(weekdaysJSON)
+	if w.Weekdays == nil {
+		w.Weekdays = []int{}
+	}
+	return w, nil
+}
+
+const incidentColumns = `id, rule_id, series, level, opened_at, acked_at, acked_by, ack_note, resolved_at, event_count, critical_at, escalations_json`
+
+func (s *Store) CreateIncident(inc Incident) (Incident, error) {
+	result, err := s.db.Exec(`
+INSERT INTO alert_incidents(rule_id, series, level, opened_at, event_count, critical_at)
+VALUES (?, ?, ?, ?, ?, ?)
+`, inc.RuleID, inc.Series, string(inc.Level), inc.OpenedAt.UTC(), inc.EventCount, nullableTime(inc.CriticalAt))
+	if err != nil {
+		return Incident{}, fmt.Errorf("create alert incident: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return Incident{}, fmt.Errorf("create alert incident last insert id: %w", err)
+	}
+	return s.GetIncident(id)
+}
+
+func (s *Store) UpdateIncident(inc Incident) error {
+	_, err := s.db.Exec(`
+UPDATE alert_incidents
+SET level = ?, acked_at = ?, acked_by = ?, ack_note = ?, resolved_at = ?, event_count = ?, critical_at = ?, escalations_json = ?
+WHERE id = ?
+`, string(inc.Level), nullableTime(inc.AckedAt), inc.AckedBy, inc.AckNote, nullableTime(inc.ResolvedAt), inc.EventCount,
+		nullableTime(inc.CriticalAt), encodeEscalations(inc.Escalations), inc.ID)
+	if err != nil {
+		return fmt.Errorf("update alert incident: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) GetIncident(id int64) (Incident, error) {
+	inc, err := scanIncident(s.db.QueryRow(`SELECT `+incidentColumns+` FROM alert_incidents WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Incident{}, ErrIncidentNotFound
+	}
+	return inc, err
+}
+
+// ListIncidents returns incidents newest first; an empty status lists all.
+func (s *Store) ListIncidents(status IncidentStatus, limit int, beforeID int64) ([]Incident, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	if limit > 500 {
+		limit = 500
+	}
+
+	query := `SELECT ` + incidentColumns + ` FROM alert_incidents WHERE 1 = 1 `
+	args := make([]any, 0, 2)
+	switch status {
+	case IncidentOpen:
+		query += `AND resolved_at IS NULL AND acked_at IS NULL `
+	case IncidentAcknowledged:
+		query += `AND resolved_at IS NULL AND acked_at IS NOT NULL `
+	case IncidentResolved:
+		query += `AND resolved_at IS NOT NULL `
+	}
+	if beforeID > 0 {
+		query += `AND id < ? `
+		args = append(args, beforeID)
+	}
+	query += `ORDER BY id DESC LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list alert incidents: %w", err)
+	}
+	defer rows.Close()
+
+	incidents := make([]Incident, 0)
+	for rows.Next() {
+		inc, err := scanIncident(rows)
+		if err != nil {
+			return nil, err
+		}
+		incidents = append(incidents, inc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate alert incidents: %w", err)
+	}
+	return incidents, nil
+}
+
+// ListUnresolvedIncidents returns the incidents still open or acknowledged,
+// at most one per alert source.
+func (s *Store) ListUnresolvedIncidents() ([]Incident, error) {
+	rows, err := s.db.Query(`SELECT ` + incidentColumns + ` FROM alert_incidents WHERE resolved_at IS NULL ORDER BY id ASC`)
+	if err != nil {
+		return nil, fmt.Errorf("list unresolved alert incidents: %w", err)
+	}
+	defer rows.Close()
+
+	incidents := make([]Incident, 0)
+	for rows.Next() {
+		inc, err := scanIncident(rows)
+		if err != nil {
+			return nil, err
+		}
+		incidents = append(incidents, inc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate unresolved alert incidents: %w", err)
+	}
+	return incidents, nil
+}
+
+func scanIncident(row rowScanner) (Incident, error) {
+	var (
+		inc                Incident
+		level              string
+		ackedAt, resolveAt sql.NullTime
+		criticalAt         sql.NullTime
+		escalationsJSON    string
+	)
+	err := row.Scan(
+		&inc.ID, &inc.RuleID, &inc.Series, &level, &inc.OpenedAt,
+		&ackedAt, &inc.AckedBy, &inc.AckNote, &resolveAt, &inc.EventCount,
+		&criticalAt, &escalationsJSON,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Incident{}, err
+		}
+		return Incident{}, fmt.Errorf("scan alert incident: %w", err)
+	}
+	inc.Level = Level(level)
+	inc.OpenedAt = inc.OpenedAt.UTC()
+	if ackedAt.Valid {
+		t := ackedAt.Time.UTC()
+		inc.AckedAt = &t
+	}
+	if resolveAt.Valid {
+		t := resolveAt.Time.UTC()
+		inc.ResolvedAt = &t
+	}
+	if criticalAt.Valid {
+		t := criticalAt.Time.UTC()
+		inc.CriticalAt = &t
+	}
+	if escalationsJSON != "" {
+		_ = json.Unmarshal([]byte(escalationsJSON), &inc.Escalations)
+	}
+	return inc, nil
+}
+
+func nullableTime(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return t.UTC()
+}
+
 func (s *Store) CleanupEvents(retentionDays int) error {
 	if retentionDays <= 0 {
 		retentionDays = DefaultHistoryRetentionDays
@@ -353,6 +1413,46 @@ func (s *Store) CleanupEvents(retentionDays int) error {
 	return nil
 }
 
+// CountEventsByLevel counts alert events created in [from, to), ignoring
+// test notifications, digests and escalation steps.
+func (s *Store) CountEventsByLevel(from, to time.Time) (map[Level]int, error) {
+	rows, err := s.db.Query(`
+SELECT level, COUNT(*)
+FROM alert_events
+WHERE created_at >= ? AND created_at < ? AND level NOT IN (?, ?) AND escalation_step = 0
+GROUP BY level
+`, from.UTC(), to.UTC(), string(LevelTest), string(LevelDigest))
+	if err != nil {
+		return nil, fmt.Errorf("count alert events: %w", err)
+	}
+	defer rows.Close()
+
+	out := make(map[Level]int)
+	for rows.Next() {
+		var level string
+		var n int
+		if err := rows.Scan(&level, &n); err != nil {
+			return nil, fmt.Errorf("scan alert event count: %w", err)
+		}
+		out[Level(level)] = n
+	}
+	return out, rows.Err()
+}
+
+// LastEventAt returns when the newest event of level was created, or the
+// zero time if there is none.
+func (s *Store) LastEventAt(level Level) (time.Time, error) {
+	var at time.Time
+	err := s.db.QueryRow(`SELECT created_at FROM alert_events WHERE level = ? ORDER BY created_at DESC LIMIT 1`, string(level)).Scan(&at)
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("last alert event: %w", err)
+	}
+	return at.UTC(), nil
+}
+
 func (s *Store) SetMutedUntil(until *time.Time) error {
 	var v any
 	if until != nil {
@@ -384,6 +1484,116 @@ func (s *Store) GetMutedUntil() (*time.Time, error) {
 	return &t, nil
 }
 
+func (s *Store) ListTemplates() ([]NotificationTemplate, error) {
+	rows, err := s.db.Query(`SELECT channel, level, subject, body, updated_at FROM alert_templates ORDER BY channel, level`)
+	if err != nil {
+		return nil, fmt.Errorf("list templates: %w", err)
+	}
+	defer rows.Close()
+
+	out := make([]NotificationTemplate, 0)
+	for rows.Next() {
+		var (
+			t     NotificationTemplate
+			level string
+		)
+		if err := rows.Scan(&t.Channel, &level, &t.Subject, &t.Body, &t.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("scan template: %w", err)
+		}
+		t.Level = Level(level)
+		out = append(out, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate templates: %w", err)
+	}
+	return out, nil
+}
+
+func (s *Store) SaveTemplate(t NotificationTemplate) (NotificationTemplate, error) {
+	t.UpdatedAt = time.Now().UTC()
+	_, err := s.db.Exec(`
+INSERT INTO alert_templates(channel, level, subject, body, updated_at)
+VALUES (?, ?, ?, ?, ?)
+ON CONFLICT(channel, level) DO UPDATE SET
+  subject = excluded.subject,
+  body = excluded.body,
+  updated_at = excluded.updated_at
+`, t.Channel, string(t.Level), t.Subject, t.Body, t.UpdatedAt)
+	if err != nil {
+		return NotificationTemplate{}, fmt.Errorf("save template: %w", err)
+	}
+	return t, nil
+}
+
+func (s *Store) DeleteTemplate(channel string, level Level) error {
+	result, err := s.db.Exec(`DELETE FROM alert_templates WHERE channel = ? AND level = ?`, channel, string(level))
+	if err != nil {
+		return fmt.Errorf("delete template: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrTemplateNotFound
+	}
+	return nil
+}
+
+// storedEvaluatorState is one alert_evaluator_state row. UpdatedAt is the
+// last time the service evaluated that source, not only the last change.
+type storedEvaluatorState struct {
+	StateJSON string
+	UpdatedAt time.Time
+}
+
+func (s *Store) SaveEvaluatorState(ruleID int64, stateJSON string, at time.Time) error {
+	_, err := s.db.Exec(`
+INSERT INTO alert_evaluator_state(rule_id, state_json, updated_at)
+VALUES (?, ?, ?)
+ON CONFLICT(rule_id) DO UPDATE SET state_json = excluded.state_json, updated_at = excluded.updated_at
+`, ruleID, stateJSON, at.UTC())
+	if err != nil {
+		return fmt.Errorf("save evaluator state: %w", err)
+	}
+	return nil
+}
+
+// TouchEvaluatorStates records that evaluation is still running.
+func (s *Store) TouchEvaluatorStates(at time.Time) error {
+	if _, err := s.db.Exec(`UPDATE alert_evaluator_state SET updated_at = ?`, at.UTC()); err != nil {
+		return fmt.Errorf("touch evaluator state: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) LoadEvaluatorStates() (map[int64]storedEvaluatorState, error) {
+	rows, err := s.db.Query(`SELECT rule_id, state_json, updated_at FROM alert_evaluator_state`)
+	if err != nil {
+		return nil, fmt.Errorf("load evaluator state: %w", err)
+	}
+	defer rows.Close()
+
+	out := make(map[int64]storedEvaluatorState)
+	for rows.Next() {
+		var (
+			ruleID int64
+			st     storedEvaluatorState
+		)
+		if err := rows.Scan(&ruleID, &st.StateJSON, &st.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("scan evaluator state: %w", err)
+		}
+		out[ruleID] = st
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate evaluator state: %w", err)
+	}
+	return out, nil
+}
+
+func (s *Store) DeleteEvaluatorState(ruleID int64) error {
+	if _, err := s.db.Exec(`DELETE FROM alert_evaluator_state WHERE rule_id = ?`, ruleID); err != nil {
+		return fmt.Errorf("delete evaluator state: %w", err)
+	}
+	return nil
+}
+
 func boolToInt(v bool) int {
 	if v {
 		return 1
@@ -431,3 +1641,68 @@ func decodeChannelResults(v string) []ChannelResult {
 	}
 	return out
 }
+
+func decodeTopProcesses(v string) *TopProcesses {
+	if v == "" {
+		return nil
+	}
+	var out TopProcesses
+	if err := json.Unmarshal([]byte(v), &out); err != nil {
+		return nil
+	}
+	return &out
+}
+
+func decodeWebhooks(v string) []Webhook {
+	if v == "" {
+		return nil
+	}
+	var out []Webhook
+	if err := json.Unmarshal([]byte(v), &out); err != nil {
+		return nil
+	}
+	return out
+}
+
+func webhooksOrEmpty(v []Webhook) []Webhook {
+	if v == nil {
+		return []Webhook{}
+	}
+	return v
+}
+
+func decodeRoutes(v string) map[Level][]string {
+	out := make(map[Level][]string)
+	if v == "" {
+		return out
+	}
+	if err := json.Unmarshal([]byte(v), &out); err != nil || out == nil {
+		return make(map[Level][]string)
+	}
+	return out
+}
+
+func decodeEscalationPolicies(v string) []EscalationPolicy {
+	if v == "" {
+		return []EscalationPolicy{}
+	}
+	var out []EscalationPolicy
+	if err := json.Unmarshal([]byte(v), &out); err != nil || out == nil {
+		return []EscalationPolicy{}
+	}
+	return out
+}
+
+func escalationPoliciesOrEmpty(v []EscalationPolicy) []EscalationPolicy {
+	if v == nil {
+		return []EscalationPolicy{}
+	}
+	return v
+}
+
+func encodeEscalations(v map[string]int) string {
+	if len(v) == 0 {
+		return ""
+	}
+	return mustJSON(v)
+}
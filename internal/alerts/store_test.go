@@ -0,0 +1,104 @@
+package alerts
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStoreMigratesLegacyEventsTable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "alerts.db")
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("sql.Open() error = %v", err)
+	}
+	_, err = db.Exec(`
+CREATE TABLE alert_events (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  level TEXT NOT NULL,
+  message TEXT NOT NULL,
+  cpu_percent REAL NOT NULL,
+  channels_json TEXT NOT NULL DEFAULT '[]',
+  created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+INSERT INTO alert_events(level, message, cpu_percent) VALUES ('critical', 'old', 91);
+`)
+	if err != nil {
+		t.Fatalf("create legacy table error = %v", err)
+	}
+	_ = db.Close()
+
+	store, err := NewStore(path)
+	if err != nil {
+		t.Fatalf("NewStore() error = %v", err)
+	}
+	t.Cleanup(func() {
+		_ = store.Close()
+	})
+
+	if _, err := store.SaveEvent(Event{Level: LevelWarning, Message: "new", CPUPercent: 80, Processes: &TopProcesses{}, CreatedAt: time.Now()}); err != nil {
+		t.Fatalf("SaveEvent() error = %v", err)
+	}
+	events, err := store.ListEvents(10, 0)
+	if err != nil {
+		t.Fatalf("ListEvents() error = %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("events len = %d, want 2", len(events))
+	}
+	if events[0].Processes == nil {
+		t.Fatalf("new event should carry processes")
+	}
+	if events[1].Message != "old" || events[1].Processes != nil {
+		t.Fatalf("legacy event = %+v, want message old without processes", events[1])
+	}
+}
+
+func TestStoreMigratesGmailToSMTP(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "alerts.db")
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("sql.Open() error = %v", err)
+	}
+	_, err = db.Exec(`
+CREATE TABLE alert_secrets (
+  id INTEGER PRIMARY KEY CHECK(id = 1),
+  telegram_token_cipher TEXT NOT NULL DEFAULT '',
+  gmail_address TEXT NOT NULL DEFAULT '',
+  gmail_password_cipher TEXT NOT NULL DEFAULT '',
+  updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+INSERT INTO alert_secrets(id, gmail_address, gmail_password_cipher) VALUES (1, 'me@gmail.com', 'cipher-blob');
+`)
+	if err != nil {
+		t.Fatalf("create legacy table error = %v", err)
+	}
+	_ = db.Close()
+
+	store, err := NewStore(path)
+	if err != nil {
+		t.Fatalf("NewStore() error = %v", err)
+	}
+	t.Cleanup(func() {
+		_ = store.Close()
+	})
+
+	cfg, err := store.LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if cfg.SMTPHost != "smtp.gmail.com" || cfg.SMTPPort != 587 || cfg.SMTPSecurity != SMTPSecuritySTARTTLS {
+		t.Fatalf("smtp server = %s:%d %s, want smtp.gmail.com:587 starttls", cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPSecurity)
+	}
+	if cfg.SMTPUsername != "me@gmail.com" || cfg.SMTPFrom != "me@gmail.com" {
+		t.Fatalf("smtp user/from = %q/%q, want gmail address", cfg.SMTPUsername, cfg.SMTPFrom)
+	}
+	rec, err := store.LoadSecretRecord()
+	if err != nil {
+		t.Fatalf("LoadSecretRecord() error = %v", err)
+	}
+	if rec.SMTPPasswordCipher != "cipher-blob" {
+		t.Fatalf("smtp password cipher = %q, want copied gmail cipher", rec.SMTPPasswordCipher)
+	}
+}
@@ -0,0 +1,293 @@
+package alerts
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"quickvps/internal/metrics"
+)
+
+var ErrTemplateNotFound = errors.New("template not found")
+
+// TemplateAny in a template's channel or level matches every channel or
+// level. A more specific template wins: channel+level, channel+any,
+// any+level, then any+any.
+const TemplateAny = "*"
+
+const (
+	maxTemplateSubjectLen = 200
+	maxTemplateBodyLen    = 4000
+)
+
+// TemplateChannels are the channel names a template can target.
+var TemplateChannels = append([]string{TemplateAny}, Channels...)
+
+// TemplateLevels are the levels a template can target.
+var TemplateLevels = []Level{TemplateAny, LevelWarning, LevelCritical, LevelRecovery, LevelFlapping}
+
+// TemplateData is what notification templates can reference, e.g.
+// {{.Host}}, {{.ValueText}} or {{.Thresholds.Critical}}.
+type TemplateData struct {
+	Host  string
+	Level string
+	// Rule is the rule name, or "CPU" for the built-in CPU alert.
+	Rule       string
+	Series     string
+	Value      float64
+	ValueText  string
+	Thresholds Thresholds
+	Metrics    map[string]float64
+	// Duration is how long the value has been past the threshold of this
+	// level (below the recovery point for recovery).
+	Duration     time.Duration
+	DurationText string
+	DashboardURL string
+	Time         time.Time
+	Processes    *TopProcesses
+}
+
+var templateFuncs = template.FuncMap{
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"bytes": func(v float64) string { return formatBytes(uint64(v)) },
+}
+
+type compiledTemplate struct {
+	NotificationTemplate
+	subject *template.Template
+	body    *template.Template
+}
+
+type templateKey struct {
+	channel string
+	level   Level
+}
+
+func compileTemplate(t NotificationTemplate) (compiledTemplate, error) {
+	c := compiledTemplate{NotificationTemplate: t}
+	if !validTemplateChannel(t.Channel) {
+		return c, fmt.Errorf("unknown channel %q", t.Channel)
+	}
+	if !validTemplateLevel(t.Level) {
+		return c, fmt.Errorf("unknown level %q", t.Level)
+	}
+	if strings.TrimSpace(t.Body) == "" {
+		return c, errors.New("body is required")
+	}
+	if len(t.Subject) > maxTemplateSubjectLen {
+		return c, fmt.Errorf("subject must be at most %d characters", maxTemplateSubjectLen)
+	}
+	if len(t.Body) > maxTemplateBodyLen {
+		return c, fmt.Errorf("body must be at most %d characters", maxTemplateBodyLen)
+	}
+
+	var err error
+	if c.subject, err = template.New("subject").Funcs(templateFuncs).Option("missingkey=error").Parse(t.Subject); err != nil {
+		return c, fmt.Errorf("subject: %w", err)
+	}
+	if c.body, err = template.New("body").Funcs(templateFuncs).Option("missingkey=error").Parse(t.Body); err != nil {
+		return c, fmt.Errorf("body: %w", err)
+	}
+	// Unknown fields only fail at execution time, so try the sample data.
+	if _, err := c.render(sampleTemplateData(DefaultConfig(), "example-host", levelOrWarning(t.Level))); err != nil {
+		return c, err
+	}
+	return c, nil
+}
+
+func (c compiledTemplate) render(data TemplateData) (NotificationText, error) {
+	var subject, body strings.Builder
+	if err := c.subject.Execute(&subject, data); err != nil {
+		return NotificationText{}, fmt.Errorf("subject: %w", err)
+	}
+	if err := c.body.Execute(&body, data); err != nil {
+		return NotificationText{}, fmt.Errorf("body: %w", err)
+	}
+	return NotificationText{
+		Subject: strings.Join(strings.Fields(subject.String()), " "),
+		Message: strings.TrimSpace(body.String()),
+	}, nil
+}
+
+func validTemplateChannel(channel string) bool {
+	for _, c := range TemplateChannels {
+		if c == channel {
+			return true
+		}
+	}
+	return false
+}
+
+func validTemplateLevel(level Level) bool {
+	for _, l := range TemplateLevels {
+		if l == level {
+			return true
+		}
+	}
+	return false
+}
+
+func levelOrWarning(level Level) Level {
+	if level == TemplateAny {
+		return LevelWarning
+	}
+	return level
+}
+
+// sampleTemplateData is a plausible CPU alert used to validate and preview
+// templates.
+func sampleTemplateData(cfg Config, host string, level Level) TemplateData {
+	th := cfg.Thresholds()
+	value := th.Critical + 4
+	window := time.Duration(th.CriticalForSec) * time.Second
+	switch level {
+	case LevelWarning:
+		value = (th.Warning + th.Critical) / 2
+		window = time.Duration(th.WarningForSec) * time.Second
+	case LevelRecovery:
+		value = th.Recovery - 10
+		window = time.Duration(th.RecoveryForSec) * time.Second
+	}
+	now := time.Now().UTC().Truncate(time.Second)
+	return TemplateData{
+		Host:       host,
+		Level:      string(level),
+		Rule:       "CPU",
+		Series:     metrics.SeriesCPUPercent,
+		Value:      value,
+		ValueText:  formatSeriesValue(metrics.SeriesCPUPercent, value),
+		Thresholds: th,
+		Metrics: map[string]float64{
+			metrics.SeriesCPUPercent:    value,
+			metrics.SeriesMemoryPercent: 62.5,
+			metrics.SeriesSwapPercent:   3.1,
+			metrics.SeriesLoad1:         3.42,
+			metrics.SeriesLoad5:         2.87,
+			metrics.SeriesLoad15:        2.1,
+		},
+		Duration:     window,
+		DurationText: window.String(),
+		DashboardURL: cfg.DashboardURL,
+		Time:         now,
+		Processes: &TopProcesses{
+			ByCPU:    []metrics.ProcessInfo{{PID: 4242, Name: "backup.sh", User: "root", CPUPercent: 87.5}},
+			ByMemory: []metrics.ProcessInfo{{PID: 1337, Name: "postgres", User: "postgres", MemPercent: 21.4, RSSBytes: 512 << 20}},
+		},
+	}
+}
+
+func (s *Service) ListTemplates() []NotificationTemplate {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]NotificationTemplate, 0, len(s.templates))
+	for _, channel := range TemplateChannels {
+		for _, level := range TemplateLevels {
+			if c, ok := s.templates[templateKey{channel, level}]; ok {
+				out = append(out, c.NotificationTemplate)
+			}
+		}
+	}
+	return out
+}
+
+func (s *Service) SaveTemplate(channel string, level Level, in TemplateInput) (NotificationTemplate, error) {
+	compiled, err := compileTemplate(NotificationTemplate{
+		Channel: strings.ToLower(strings.TrimSpace(channel)),
+		Level:   Level(strings.ToLower(strings.TrimSpace(string(level)))),
+		Subject: in.Subject,
+		Body:    in.Body,
+	})
+	if err != nil {
+		return NotificationTemplate{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	saved, err := s.store.SaveTemplate(compiled.NotificationTemplate)
+	if err != nil {
+		return NotificationTemplate{}, err
+	}
+	compiled.NotificationTemplate = saved
+	s.templates[templateKey{saved.Channel, saved.Level}] = compiled
+	return saved, nil
+}
+
+func (s *Service) DeleteTemplate(channel string, level Level) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := templateKey{channel, level}
+	if _, ok := s.templates[key]; !ok {
+		return ErrTemplateNotFound
+	}
+	if err := s.store.DeleteTemplate(channel, level); err != nil {
+		return err
+	}
+	delete(s.templates, key)
+	return nil
+}
+
+// PreviewTemplate renders an unsaved template against sample data built from
+// the current config.
+func (s *Service) PreviewTemplate(in TemplatePreviewInput) (NotificationText, error) {
+	compiled, err := compileTemplate(NotificationTemplate{
+		Channel: strings.ToLower(strings.TrimSpace(in.Channel)),
+		Level:   Level(strings.ToLower(strings.TrimSpace(string(in.Level)))),
+		Subject: in.Subject,
+		Body:    in.Body,
+	})
+	if err != nil {
+		return NotificationText{}, err
+	}
+	s.mu.RLock()
+	data := sampleTemplateData(s.cfg, s.hostname, levelOrWarning(compiled.Level))
+	s.mu.RUnlock()
+	return compiled.render(data)
+}
+
+// templateLocked finds the most specific template for a channel and level.
+// Must be called with s.mu held.
+func (s *Service) templateLocked(channel string, level Level) (compiledTemplate, bool) {
+	for _, key := range []templateKey{
+		{channel, level},
+		{channel, TemplateAny},
+		{TemplateAny, level},
+		{TemplateAny, TemplateAny},
+	} {
+		if c, ok := s.templates[key]; ok {
+			return c, true
+		}
+	}
+	return compiledTemplate{}, false
+}
+
+// renderTexts fills note.Texts for every channel that has a template. A
+// template that fails to render falls back to the built-in text.
+func (s *Service) renderTexts(note *Notification, data TemplateData) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if len(s.templates) == 0 {
+		return
+	}
+	for _, channel := range TemplateChannels[1:] {
+		c, ok := s.templateLocked(channel, note.Level)
+		if !ok {
+			continue
+		}
+		text, err := c.render(data)
+		if err != nil || text.Message == "" {
+			continue
+		}
+		if text.Subject == "" {
+			text.Subject = note.Subject
+		}
+		if note.Texts == nil {
+			note.Texts = make(map[string]NotificationText)
+		}
+		note.Texts[channel] = text
+	}
+}
@@ -0,0 +1,107 @@
+package alerts
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"quickvps/internal/metrics"
+)
+
+func TestCompileTemplateValidation(t *testing.T) {
+	for name, tmpl := range map[string]NotificationTemplate{
+		"syntax":        {Channel: "telegram", Level: LevelWarning, Body: "{{.Host"},
+		"unknown field": {Channel: "telegram", Level: LevelWarning, Body: "{{.Hostname}}"},
+		"unknown func":  {Channel: "telegram", Level: LevelWarning, Body: "{{shout .Host}}"},
+		"channel":       {Channel: "pager", Level: LevelWarning, Body: "x"},
+		"level":         {Channel: "telegram", Level: "info", Body: "x"},
+		"empty body":    {Channel: "telegram", Level: LevelWarning, Body: "  "},
+		"long subject":  {Channel: "email", Level: LevelWarning, Subject: strings.Repeat("x", 201), Body: "x"},
+	} {
+		if _, err := compileTemplate(tmpl); err == nil {
+			t.Errorf("%s: expected compile error", name)
+		}
+	}
+	if _, err := compileTemplate(NotificationTemplate{
+		Channel: TemplateAny,
+		Level:   TemplateAny,
+		Subject: "{{upper .Level}} on {{.Host}}",
+		Body:    `{{.Rule}} {{.ValueText}} > {{.Thresholds.Warning}} for {{.DurationText}} {{index .Metrics "memory.percent"}} {{.DashboardURL}}`,
+	}); err != nil {
+		t.Fatalf("valid template error = %v", err)
+	}
+}
+
+func TestTemplatesRenderPerChannel(t *testing.T) {
+	n := NewNotifier()
+	n.sleep = func(time.Duration) {}
+	var telegramText string
+	n.sendTelegram = func(_ context.Context, _ string, _ []string, text string) error {
+		telegramText = text
+		return nil
+	}
+	var mail emailMessage
+	n.sendEmail = func(_ context.Context, _ smtpServer, msg emailMessage) error {
+		mail = msg
+		return nil
+	}
+	svc := newServiceForTests(t, n)
+	svc.cfg.RecipientEmails = []string{"ops@example.com"}
+	svc.cfg.SMTPHost = "smtp.example.com"
+	svc.cfg.DashboardURL = "https://vps.example.com"
+
+	if _, err := svc.SaveTemplate("telegram", LevelCritical, TemplateInput{
+		Body: "{{.Host}} {{.Rule}} at {{.ValueText}} (critical {{.Thresholds.Critical}}) for {{.DurationText}} {{.DashboardURL}}",
+	}); err != nil {
+		t.Fatalf("SaveTemplate(telegram) error = %v", err)
+	}
+	if _, err := svc.SaveTemplate(TemplateAny, TemplateAny, TemplateInput{Subject: "{{upper .Level}}: {{.Host}}", Body: "generic {{.Level}}"}); err != nil {
+		t.Fatalf("SaveTemplate(*) error = %v", err)
+	}
+
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	snap := &metrics.Snapshot{Timestamp: now, CPU: metrics.CPUMetrics{TotalPercent: 93.5}}
+	svc.dispatch(context.Background(), pendingAlert{trigger: Trigger{Level: LevelCritical, Value: 93.5, Since: now.Add(-12 * time.Minute)}}, snap, svc.cfg, svc.secrets)
+
+	want := svc.hostname + " CPU at 93.50% (critical 85) for 12m0s https://vps.example.com"
+	if telegramText != want {
+		t.Fatalf("telegram text = %q, want %q", telegramText, want)
+	}
+	if mail.Subject != "CRITICAL: "+svc.hostname || mail.Text != "generic critical" {
+		t.Fatalf("email = %q / %q, want the catch-all template", mail.Subject, mail.Text)
+	}
+	events, _ := svc.ListHistory(1, 0)
+	if !strings.HasPrefix(events[0].Message, "[QuickVPS][CRITICAL]") {
+		t.Fatalf("history message = %q, want the built-in text", events[0].Message)
+	}
+
+	if err := svc.DeleteTemplate(TemplateAny, TemplateAny); err != nil {
+		t.Fatalf("DeleteTemplate() error = %v", err)
+	}
+	if err := svc.DeleteTemplate(TemplateAny, TemplateAny); !errors.Is(err, ErrTemplateNotFound) {
+		t.Fatalf("DeleteTemplate(again) error = %v", err)
+	}
+	reloaded, err := NewService(svc.store, n, "")
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	if got := reloaded.ListTemplates(); len(got) != 1 || got[0].Channel != "telegram" {
+		t.Fatalf("reloaded templates = %+v", got)
+	}
+}
+
+func TestPreviewTemplate(t *testing.T) {
+	svc := newServiceForTests(t, NewNotifier())
+	out, err := svc.PreviewTemplate(TemplatePreviewInput{Channel: "slack", Level: LevelRecovery, Subject: "{{.Level}}", Body: "{{.Host}} back to {{.ValueText}}"})
+	if err != nil {
+		t.Fatalf("PreviewTemplate() error = %v", err)
+	}
+	if out.Subject != "recovery" || out.Message != svc.hostname+" back to 60.00%" {
+		t.Fatalf("preview = %+v", out)
+	}
+	if _, err := svc.PreviewTemplate(TemplatePreviewInput{Channel: "slack", Level: LevelWarning, Body: "{{.Nope}}"}); err == nil {
+		t.Fatal("expected preview error for unknown field")
+	}
+}
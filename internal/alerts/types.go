@@ -1,6 +1,10 @@
 package alerts
 
-import "time"
+import (
+	"time"
+
+	"quickvps/internal/metrics"
+)
 
 type Level string
 
@@ -9,40 +13,336 @@ const (
 	LevelWarning  Level = "warning"
 	LevelCritical Level = "critical"
 	LevelRecovery Level = "recovery"
+	// LevelFlapping is sent once when a source keeps changing state; further
+	// transitions are suppressed until it settles.
+	LevelFlapping Level = "flapping"
 	LevelTest     Level = "test"
+	// LevelDigest marks the scheduled health digest in the event history.
+	LevelDigest Level = "digest"
 )
 
 type Config struct {
-	Enabled         bool     `json:"enabled"`
-	WarningPercent  float64  `json:"warning_percent"`
-	WarningForSec   int64    `json:"warning_for_sec"`
-	CriticalPercent float64  `json:"critical_percent"`
-	CriticalForSec  int64    `json:"critical_for_sec"`
-	RecoveryPercent float64  `json:"recovery_percent"`
-	RecoveryForSec  int64    `json:"recovery_for_sec"`
-	CooldownSec     int64    `json:"cooldown_sec"`
-	TelegramEnabled bool     `json:"telegram_enabled"`
-	EmailEnabled    bool     `json:"email_enabled"`
-	RecipientEmails []string `json:"recipient_emails"`
-	TelegramChatIDs []string `json:"telegram_chat_ids"`
-	RetryDelaysSec  []int    `json:"retry_delays_sec"`
+	Enabled         bool         `json:"enabled"`
+	WarningPercent  float64      `json:"warning_percent"`
+	WarningForSec   int64        `json:"warning_for_sec"`
+	CriticalPercent float64      `json:"critical_percent"`
+	CriticalForSec  int64        `json:"critical_for_sec"`
+	RecoveryPercent float64      `json:"recovery_percent"`
+	RecoveryForSec  int64        `json:"recovery_for_sec"`
+	CooldownSec     int64        `json:"cooldown_sec"`
+	TelegramEnabled bool         `json:"telegram_enabled"`
+	EmailEnabled    bool         `json:"email_enabled"`
+	RecipientEmails []string     `json:"recipient_emails"`
+	TelegramChatIDs []string     `json:"telegram_chat_ids"`
+	SMTPHost        string       `json:"smtp_host"`
+	SMTPPort        int          `json:"smtp_port"`
+	SMTPSecurity    SMTPSecurity `json:"smtp_security"`
+	SMTPUsername    string       `json:"smtp_username"`
+	SMTPFrom        string       `json:"smtp_from"`
+	EmailHTML       bool         `json:"email_html"`
+	SlackEnabled    bool         `json:"slack_enabled"`
+	DiscordEnabled  bool         `json:"discord_enabled"`
+	TeamsEnabled    bool         `json:"teams_enabled"`
+	NtfyEnabled     bool         `json:"ntfy_enabled"`
+	NtfyServerURL   string       `json:"ntfy_server_url"`
+	NtfyTopic       string       `json:"ntfy_topic"`
+	GotifyEnabled   bool         `json:"gotify_enabled"`
+	GotifyServerURL string       `json:"gotify_server_url"`
+	WebhookEnabled  bool         `json:"webhook_enabled"`
+	Webhooks        []Webhook    `json:"webhooks"`
+	// RetryDelaysSec are the waits between the outbox retries of a failed
+	// delivery, after which the delay doubles; OutboxMaxAgeHours is how
+	// long a delivery is retried before it is given up.
+	RetryDelaysSec    []int `json:"retry_delays_sec"`
+	OutboxMaxAgeHours int   `json:"outbox_max_age_hours"`
+	// FlapThreshold state changes within FlapWindowSec mark an alert source
+	// as flapping; 0 disables flap detection.
+	FlapWindowSec int64 `json:"flap_window_sec"`
+	FlapThreshold int   `json:"flap_threshold"`
+	// DashboardURL is the public address of this QuickVPS instance, linked
+	// from notification templates.
+	DashboardURL string `json:"dashboard_url"`
+	// The health digest is sent whenever DigestCron matches in
+	// DigestTimezone and covers the DigestPeriod before that.
+	DigestEnabled  bool         `json:"digest_enabled"`
+	DigestPeriod   DigestPeriod `json:"digest_period"`
+	DigestCron     string       `json:"digest_cron"`
+	DigestTimezone string       `json:"digest_timezone"`
+	DigestSections []string     `json:"digest_sections"`
+	// Routes limits the channels each level is sent to; a level without an
+	// entry goes to every enabled channel.
+	Routes             map[Level][]string `json:"routes"`
+	EscalationPolicies []EscalationPolicy `json:"escalation_policies"`
+	// A warning is sent when a mountpoint's usage trend (see
+	// metrics.DiskForecast) reaches full within ForecastHorizonHours.
+	ForecastEnabled      bool `json:"forecast_enabled"`
+	ForecastHorizonHours int  `json:"forecast_horizon_hours"`
+}
+
+func (c Config) flapWindow() time.Duration {
+	return time.Duration(c.FlapWindowSec) * time.Second
+}
+
+func (c Config) outboxMaxAge() time.Duration {
+	if c.OutboxMaxAgeHours <= 0 {
+		return DefaultOutboxMaxAgeHours * time.Hour
+	}
+	return time.Duration(c.OutboxMaxAgeHours) * time.Hour
+}
+
+func (c Config) anyChannelEnabled() bool {
+	return c.TelegramEnabled || c.EmailEnabled || c.WebhookEnabled ||
+		c.SlackEnabled || c.DiscordEnabled || c.TeamsEnabled || c.NtfyEnabled || c.GotifyEnabled
+}
+
+type DigestPeriod string
+
+const (
+	DigestDaily  DigestPeriod = "daily"
+	DigestWeekly DigestPeriod = "weekly"
+)
+
+// Digest sections, see Config.DigestSections.
+const (
+	DigestSectionResources  = "resources"
+	DigestSectionAlerts     = "alerts"
+	DigestSectionDiskGrowth = "disk_growth"
+	DigestSectionUpdates    = "updates"
+	DigestSectionExposures  = "exposures"
+)
+
+// EscalationPolicy re-notifies about an incident that stays unacknowledged.
+// It applies to incidents at Level (warning or critical) or above; step
+// delays count from when the incident reached that level.
+type EscalationPolicy struct {
+	Name  string           `json:"name"`
+	Level Level            `json:"level"`
+	Steps []EscalationStep `json:"steps"`
+}
+
+// EscalationStep sends to Channels once the incident has been unacknowledged
+// for DelayMin minutes. Channels must also be enabled in the config; the
+// recipient lists, when set, replace the configured email recipients and
+// Telegram chats for this step.
+type EscalationStep struct {
+	DelayMin        int      `json:"delay_min"`
+	Channels        []string `json:"channels"`
+	RecipientEmails []string `json:"recipient_emails,omitempty"`
+	TelegramChatIDs []string `json:"telegram_chat_ids,omitempty"`
+}
+
+// EscalationRef identifies the escalation step an event or notification
+// belongs to. Step is 1-based.
+type EscalationRef struct {
+	IncidentID int64  `json:"incident_id"`
+	Policy     string `json:"policy"`
+	Step       int    `json:"step"`
+}
+
+// SMTPSecurity selects how the SMTP connection is protected.
+type SMTPSecurity string
+
+const (
+	SMTPSecuritySTARTTLS SMTPSecurity = "starttls"
+	SMTPSecurityTLS      SMTPSecurity = "tls"
+	SMTPSecurityNone     SMTPSecurity = "none"
+)
+
+// Webhook is one outbound JSON POST target. Its URL and header values often
+// carry credentials, so they are kept encrypted in Secrets.Webhooks under ID
+// and the config only stores the ID and header names.
+type Webhook struct {
+	ID string `json:"id"`
+	// URL and header values are only set in configs saved before they were
+	// encrypted; NewService moves them into the secrets.
+	URL     string          `json:"url,omitempty"`
+	Headers []WebhookHeader `json:"headers"`
+}
+
+type WebhookHeader struct {
+	Name  string `json:"name"`
+	Value string `json:"value,omitempty"`
+}
+
+// WebhookSecret is the encrypted part of a Webhook.
+type WebhookSecret struct {
+	URL string `json:"url"`
+	// Headers maps a header name to its value.
+	Headers map[string]string `json:"headers,omitempty"`
+	// Secret is the HMAC-SHA256 signing secret.
+	Secret string `json:"secret,omitempty"`
+}
+
+// WebhookInput is one webhook target in a config update. ID keeps an
+// existing webhook, whose URL, header values and signing secret stay as they
+// are unless given; a webhook without ID is new and needs a URL.
+type WebhookInput struct {
+	ID          string          `json:"id"`
+	URL         string          `json:"url"`
+	Headers     []WebhookHeader `json:"headers"`
+	Secret      string          `json:"secret"`
+	ClearSecret bool            `json:"clear_secret"`
+}
+
+// WebhookView is a Webhook as served by the API, credentials masked.
+type WebhookView struct {
+	ID         string              `json:"id"`
+	URLMask    string              `json:"url_mask"`
+	Headers    []WebhookHeaderView `json:"headers"`
+	HasSecret  bool                `json:"has_secret"`
+	SecretMask string              `json:"secret_mask"`
+}
+
+type WebhookHeaderView struct {
+	Name      string `json:"name"`
+	HasValue  bool   `json:"has_value"`
+	ValueMask string `json:"value_mask"`
+}
+
+// Thresholds are the trigger points of one warning/critical/recovery state
+// machine. Values are in the unit of the watched series.
+type Thresholds struct {
+	Warning        float64 `json:"warning"`
+	WarningForSec  int64   `json:"warning_for_sec"`
+	Critical       float64 `json:"critical"`
+	CriticalForSec int64   `json:"critical_for_sec"`
+	Recovery       float64 `json:"recovery"`
+	RecoveryForSec int64   `json:"recovery_for_sec"`
+	CooldownSec    int64   `json:"cooldown_sec"`
+}
+
+func (c Config) Thresholds() Thresholds {
+	return Thresholds{
+		Warning:        c.WarningPercent,
+		WarningForSec:  c.WarningForSec,
+		Critical:       c.CriticalPercent,
+		CriticalForSec: c.CriticalForSec,
+		Recovery:       c.RecoveryPercent,
+		RecoveryForSec: c.RecoveryForSec,
+		CooldownSec:    c.CooldownSec,
+	}
+}
+
+// Rule watches one snapshot series (see metrics.Snapshot.Series), e.g.
+// "memory.percent", "disk.percent:/var" or "net.recv_bps:eth0".
+type Rule struct {
+	ID      int64  `json:"id"`
+	Name    string `json:"name"`
+	Enabled bool   `json:"enabled"`
+	Series  string `json:"series"`
+	Thresholds
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type RuleInput struct {
+	Name           *string  `json:"name"`
+	Enabled        *bool    `json:"enabled"`
+	Series         *string  `json:"series"`
+	Warning        *float64 `json:"warning"`
+	WarningForSec  *int64   `json:"warning_for_sec"`
+	Critical       *float64 `json:"critical"`
+	CriticalForSec *int64   `json:"critical_for_sec"`
+	Recovery       *float64 `json:"recovery"`
+	RecoveryForSec *int64   `json:"recovery_for_sec"`
+	CooldownSec    *int64   `json:"cooldown_sec"`
+}
+
+type MaintenanceKind string
+
+const (
+	// MaintenanceWeekly runs from StartTime to EndTime on each of Weekdays;
+	// an EndTime before StartTime ends on the next day.
+	MaintenanceWeekly MaintenanceKind = "weekly"
+	// MaintenanceCron starts whenever Cron matches and lasts DurationMin.
+	MaintenanceCron MaintenanceKind = "cron"
+)
+
+// MaintenanceWindow is a recurring period during which alerts are silenced.
+// Times are wall-clock times in Timezone (an IANA name, default UTC).
+type MaintenanceWindow struct {
+	ID          int64           `json:"id"`
+	Name        string          `json:"name"`
+	Enabled     bool            `json:"enabled"`
+	Kind        MaintenanceKind `json:"kind"`
+	Weekdays    []int           `json:"weekdays"`
+	StartTime   string          `json:"start_time"`
+	EndTime     string          `json:"end_time"`
+	Cron        string          `json:"cron"`
+	DurationMin int             `json:"duration_min"`
+	Timezone    string          `json:"timezone"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+}
+
+type MaintenanceWindowInput struct {
+	Name        *string          `json:"name"`
+	Enabled     *bool            `json:"enabled"`
+	Kind        *MaintenanceKind `json:"kind"`
+	Weekdays    []int            `json:"weekdays"`
+	StartTime   *string          `json:"start_time"`
+	EndTime     *string          `json:"end_time"`
+	Cron        *string          `json:"cron"`
+	DurationMin *int             `json:"duration_min"`
+	Timezone    *string          `json:"timezone"`
+}
+
+// MaintenanceOccurrence is one concrete run of a maintenance window.
+type MaintenanceOccurrence struct {
+	WindowID int64     `json:"window_id"`
+	Name     string    `json:"name"`
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+}
+
+type RuleStatus struct {
+	RuleID          int64      `json:"rule_id"`
+	Name            string     `json:"name"`
+	Series          string     `json:"series"`
+	State           Level      `json:"state"`
+	LastValue       *float64   `json:"last_value,omitempty"`
+	LastEvaluatedAt *time.Time `json:"last_evaluated_at,omitempty"`
+	FlappingSince   *time.Time `json:"flapping_since,omitempty"`
 }
 
 type Secrets struct {
 	TelegramBotToken string `json:"telegram_bot_token,omitempty"`
-	GmailAddress     string `json:"gmail_address,omitempty"`
-	GmailAppPassword string `json:"gmail_app_password,omitempty"`
+	SMTPPassword     string `json:"smtp_password,omitempty"`
+	// Chat webhook URLs embed their credentials, so they are secrets too.
+	SlackWebhookURL   string `json:"slack_webhook_url,omitempty"`
+	DiscordWebhookURL string `json:"discord_webhook_url,omitempty"`
+	TeamsWebhookURL   string `json:"teams_webhook_url,omitempty"`
+	NtfyToken         string `json:"ntfy_token,omitempty"`
+	GotifyToken       string `json:"gotify_token,omitempty"`
+	// Webhooks holds the URL, header values and signing secret of each
+	// webhook by Webhook.ID.
+	Webhooks map[string]WebhookSecret `json:"webhooks,omitempty"`
 }
 
 type ConfigView struct {
 	Config
 	HasTelegramToken  bool   `json:"has_telegram_token"`
 	TelegramTokenMask string `json:"telegram_token_mask"`
-	HasGmailPassword  bool   `json:"has_gmail_password"`
-	GmailPasswordMask string `json:"gmail_password_mask"`
-	GmailAddress      string `json:"gmail_address"`
-	SecretsWritable   bool   `json:"secrets_writable"`
-	ReadOnly          bool   `json:"read_only"`
+	HasSMTPPassword   bool   `json:"has_smtp_password"`
+	SMTPPasswordMask  string `json:"smtp_password_mask"`
+	// Deprecated Gmail aliases of the SMTP fields, kept for older clients.
+	HasGmailPassword   bool   `json:"has_gmail_password"`
+	GmailPasswordMask  string `json:"gmail_password_mask"`
+	GmailAddress       string `json:"gmail_address"`
+	HasSlackWebhook    bool   `json:"has_slack_webhook"`
+	SlackWebhookMask   string `json:"slack_webhook_mask"`
+	HasDiscordWebhook  bool   `json:"has_discord_webhook"`
+	DiscordWebhookMask string `json:"discord_webhook_mask"`
+	HasTeamsWebhook    bool   `json:"has_teams_webhook"`
+	TeamsWebhookMask   string `json:"teams_webhook_mask"`
+	HasNtfyToken       bool   `json:"has_ntfy_token"`
+	NtfyTokenMask      string `json:"ntfy_token_mask"`
+	HasGotifyToken     bool   `json:"has_gotify_token"`
+	GotifyTokenMask    string `json:"gotify_token_mask"`
+	// Webhooks replaces Config.Webhooks with masked URLs and header values.
+	Webhooks        []WebhookView `json:"webhooks"`
+	SecretsWritable bool          `json:"secrets_writable"`
+	ReadOnly        bool          `json:"read_only"`
 }
 
 type UpdateConfigInput struct {
@@ -55,36 +355,234 @@ type UpdateConfigInput struct {
 	RecoveryForSec  *int64   `json:"recovery_for_sec"`
 	CooldownSec     *int64   `json:"cooldown_sec"`
 
-	TelegramEnabled *bool    `json:"telegram_enabled"`
-	EmailEnabled    *bool    `json:"email_enabled"`
-	RecipientEmails []string `json:"recipient_emails"`
-	TelegramChatIDs []string `json:"telegram_chat_ids"`
-	RetryDelaysSec  []int    `json:"retry_delays_sec"`
+	TelegramEnabled      *bool              `json:"telegram_enabled"`
+	EmailEnabled         *bool              `json:"email_enabled"`
+	RecipientEmails      []string           `json:"recipient_emails"`
+	TelegramChatIDs      []string           `json:"telegram_chat_ids"`
+	SMTPHost             *string            `json:"smtp_host"`
+	SMTPPort             *int               `json:"smtp_port"`
+	SMTPSecurity         *SMTPSecurity      `json:"smtp_security"`
+	SMTPUsername         *string            `json:"smtp_username"`
+	SMTPFrom             *string            `json:"smtp_from"`
+	EmailHTML            *bool              `json:"email_html"`
+	SlackEnabled         *bool              `json:"slack_enabled"`
+	DiscordEnabled       *bool              `json:"discord_enabled"`
+	TeamsEnabled         *bool              `json:"teams_enabled"`
+	NtfyEnabled          *bool              `json:"ntfy_enabled"`
+	NtfyServerURL        *string            `json:"ntfy_server_url"`
+	NtfyTopic            *string            `json:"ntfy_topic"`
+	GotifyEnabled        *bool              `json:"gotify_enabled"`
+	GotifyServerURL      *string            `json:"gotify_server_url"`
+	WebhookEnabled       *bool              `json:"webhook_enabled"`
+	Webhooks             []WebhookInput     `json:"webhooks"`
+	RetryDelaysSec       []int              `json:"retry_delays_sec"`
+	OutboxMaxAgeHours    *int               `json:"outbox_max_age_hours"`
+	FlapWindowSec        *int64             `json:"flap_window_sec"`
+	FlapThreshold        *int               `json:"flap_threshold"`
+	DashboardURL         *string            `json:"dashboard_url"`
+	DigestEnabled        *bool              `json:"digest_enabled"`
+	DigestPeriod         *DigestPeriod      `json:"digest_period"`
+	DigestCron           *string            `json:"digest_cron"`
+	DigestTimezone       *string            `json:"digest_timezone"`
+	DigestSections       []string           `json:"digest_sections"`
+	Routes               map[Level][]string `json:"routes"`
+	EscalationPolicies   []EscalationPolicy `json:"escalation_policies"`
+	ForecastEnabled      *bool              `json:"forecast_enabled"`
+	ForecastHorizonHours *int               `json:"forecast_horizon_hours"`
 
 	TelegramBotToken      string `json:"telegram_bot_token"`
 	ClearTelegramBotToken bool   `json:"clear_telegram_bot_token"`
-	GmailAddress          string `json:"gmail_address"`
-	GmailAppPassword      string `json:"gmail_app_password"`
-	ClearGmailAppPassword bool   `json:"clear_gmail_app_password"`
+	SMTPPassword          string `json:"smtp_password"`
+	ClearSMTPPassword     bool   `json:"clear_smtp_password"`
+	// Deprecated: a Gmail address switches SMTP to smtp.gmail.com:587
+	// STARTTLS with that address as username and sender; the app password
+	// fields are aliases of the SMTP password fields.
+	GmailAddress           string `json:"gmail_address"`
+	GmailAppPassword       string `json:"gmail_app_password"`
+	ClearGmailAppPassword  bool   `json:"clear_gmail_app_password"`
+	SlackWebhookURL        string `json:"slack_webhook_url"`
+	ClearSlackWebhookURL   bool   `json:"clear_slack_webhook_url"`
+	DiscordWebhookURL      string `json:"discord_webhook_url"`
+	ClearDiscordWebhookURL bool   `json:"clear_discord_webhook_url"`
+	TeamsWebhookURL        string `json:"teams_webhook_url"`
+	ClearTeamsWebhookURL   bool   `json:"clear_teams_webhook_url"`
+	NtfyToken              string `json:"ntfy_token"`
+	ClearNtfyToken         bool   `json:"clear_ntfy_token"`
+	GotifyToken            string `json:"gotify_token"`
+	ClearGotifyToken       bool   `json:"clear_gotify_token"`
 }
 
 type Status struct {
-	CurrentState    Level      `json:"current_state"`
-	LastCPUPercent  float64    `json:"last_cpu_percent"`
-	LastEvaluatedAt time.Time  `json:"last_evaluated_at"`
-	MutedUntil      *time.Time `json:"muted_until,omitempty"`
-	Silenced        bool       `json:"silenced"`
-	LastWarningAt   *time.Time `json:"last_warning_at,omitempty"`
-	LastCriticalAt  *time.Time `json:"last_critical_at,omitempty"`
-	LastRecoveryAt  *time.Time `json:"last_recovery_at,omitempty"`
-	ReadOnly        bool       `json:"read_only"`
+	CurrentState    Level        `json:"current_state"`
+	LastCPUPercent  float64      `json:"last_cpu_percent"`
+	LastEvaluatedAt time.Time    `json:"last_evaluated_at"`
+	MutedUntil      *time.Time   `json:"muted_until,omitempty"`
+	Silenced        bool         `json:"silenced"`
+	LastWarningAt   *time.Time   `json:"last_warning_at,omitempty"`
+	LastCriticalAt  *time.Time   `json:"last_critical_at,omitempty"`
+	LastRecoveryAt  *time.Time   `json:"last_recovery_at,omitempty"`
+	FlappingSince   *time.Time   `json:"flapping_since,omitempty"`
+	Rules           []RuleStatus `json:"rules"`
+	// ActiveMaintenance is set while a maintenance window silences alerts;
+	// NextMaintenance is the earliest upcoming window start.
+	ActiveMaintenance *MaintenanceOccurrence `json:"active_maintenance,omitempty"`
+	NextMaintenance   *MaintenanceOccurrence `json:"next_maintenance,omitempty"`
+	// DiskForecasts lists mountpoints predicted to fill within the horizon.
+	DiskForecasts []DiskForecastStatus `json:"disk_forecasts"`
+	ReadOnly      bool                 `json:"read_only"`
+}
+
+type DiskForecastStatus struct {
+	Mountpoint  string    `json:"mountpoint"`
+	Since       time.Time `json:"since"`
+	HoursToFull *float64  `json:"hours_to_full,omitempty"`
 }
 
 type ChannelResult struct {
 	Channel      string `json:"channel"`
+	Target       string `json:"target,omitempty"`
 	Success      bool   `json:"success"`
 	Attempts     int    `json:"attempts"`
 	ErrorMessage string `json:"error_message,omitempty"`
+	// Queued is set while a failed delivery waits in the outbox.
+	Queued bool `json:"queued,omitempty"`
+	// targetKey identifies the target when the redacted Target is not
+	// unique (the full webhook URL); it is never serialized.
+	targetKey string
+}
+
+// TopProcesses is the offender list captured when a warning or critical
+// alert fires.
+type TopProcesses struct {
+	ByCPU    []metrics.ProcessInfo `json:"by_cpu"`
+	ByMemory []metrics.ProcessInfo `json:"by_memory"`
+}
+
+// NotificationTemplate is a text/template pair that replaces the built-in
+// subject and message for one channel and level (see TemplateAny).
+type NotificationTemplate struct {
+	Channel   string    `json:"channel"`
+	Level     Level     `json:"level"`
+	Subject   string    `json:"subject"`
+	Body      string    `json:"body"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type TemplateInput struct {
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+type TemplatePreviewInput struct {
+	Channel string `json:"channel"`
+	Level   Level  `json:"level"`
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+// Digest is a summary of the host over one digest period. Sections that are
+// not configured stay empty.
+type Digest struct {
+	Host       string           `json:"host"`
+	Period     DigestPeriod     `json:"period"`
+	From       time.Time        `json:"from"`
+	To         time.Time        `json:"to"`
+	Resources  []DigestResource `json:"resources,omitempty"`
+	Alerts     *DigestAlerts    `json:"alerts,omitempty"`
+	DiskGrowth []DiskGrowth     `json:"disk_growth,omitempty"`
+	Updates    *DigestUpdates   `json:"updates,omitempty"`
+	Exposures  *DigestExposures `json:"exposures,omitempty"`
+	// Errors lists data sources that could not be read.
+	Errors []string `json:"errors,omitempty"`
+	Text   string   `json:"text"`
+	HTML   string   `json:"html"`
+}
+
+// DigestResource is the average and peak of one percent series.
+type DigestResource struct {
+	Series string  `json:"series"`
+	Name   string  `json:"name"`
+	Avg    float64 `json:"avg"`
+	Peak   float64 `json:"peak"`
+}
+
+type DigestAlerts struct {
+	// Fired counts warning, critical and flapping events.
+	Fired   int           `json:"fired"`
+	ByLevel map[Level]int `json:"by_level"`
+}
+
+type DiskGrowth struct {
+	Mountpoint  string  `json:"mountpoint"`
+	StartBytes  float64 `json:"start_bytes"`
+	EndBytes    float64 `json:"end_bytes"`
+	GrowthBytes float64 `json:"growth_bytes"`
+}
+
+type DigestUpdates struct {
+	Manager string `json:"manager,omitempty"`
+	Total   int    `json:"total"`
+	// Names holds the first few pending packages.
+	Names []string `json:"names,omitempty"`
+	Error string   `json:"error,omitempty"`
+}
+
+type DigestExposures struct {
+	Total int              `json:"total"`
+	Items []DigestExposure `json:"items,omitempty"`
+	Error string           `json:"error,omitempty"`
+}
+
+type DigestExposure struct {
+	Port     int    `json:"port"`
+	Protocol string `json:"protocol"`
+	Address  string `json:"address"`
+	Process  string `json:"process"`
+	Risk     string `json:"risk"`
+}
+
+type IncidentStatus string
+
+const (
+	IncidentOpen         IncidentStatus = "open"
+	IncidentAcknowledged IncidentStatus = "acknowledged"
+	IncidentResolved     IncidentStatus = "resolved"
+)
+
+// Incident groups the warning/critical events of one alert source (a rule,
+// or the CPU config when RuleID is 0) from the first trigger to recovery.
+type Incident struct {
+	ID         int64          `json:"id"`
+	RuleID     int64          `json:"rule_id,omitempty"`
+	Series     string         `json:"series"`
+	Level      Level          `json:"level"`
+	Status     IncidentStatus `json:"status"`
+	OpenedAt   time.Time      `json:"opened_at"`
+	AckedAt    *time.Time     `json:"acked_at,omitempty"`
+	AckedBy    string         `json:"acked_by,omitempty"`
+	AckNote    string         `json:"ack_note,omitempty"`
+	ResolvedAt *time.Time     `json:"resolved_at,omitempty"`
+	EventCount int            `json:"event_count"`
+	// CriticalAt is when the incident first reached critical.
+	CriticalAt *time.Time `json:"critical_at,omitempty"`
+	// Escalations counts the executed steps per escalation policy name.
+	Escalations map[string]int `json:"escalations,omitempty"`
+	// TimeToAckSec and TimeToResolveSec are measured from OpenedAt.
+	TimeToAckSec     *int64 `json:"time_to_ack_sec,omitempty"`
+	TimeToResolveSec *int64 `json:"time_to_resolve_sec,omitempty"`
+}
+
+// OutboxItem is a failed delivery waiting for another attempt.
+type OutboxItem struct {
+	ID            int64     `json:"id"`
+	EventID       int64     `json:"event_id"`
+	Channel       string    `json:"channel"`
+	Target        string    `json:"target,omitempty"`
+	Level         Level     `json:"level"`
+	Attempts      int       `json:"attempts"`
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+	LastError     string    `json:"last_error,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
 }
 
 type Event struct {
@@ -92,24 +590,48 @@ type Event struct {
 	Level      Level           `json:"level"`
 	Message    string          `json:"message"`
 	CPUPercent float64         `json:"cpu_percent"`
+	RuleID     int64           `json:"rule_id,omitempty"`
+	IncidentID int64           `json:"incident_id,omitempty"`
+	Series     string          `json:"series"`
+	Value      float64         `json:"value"`
 	Channels   []ChannelResult `json:"channels"`
-	CreatedAt  time.Time       `json:"created_at"`
+	Processes  *TopProcesses   `json:"processes,omitempty"`
+	// Escalation is set on events recording an escalation step.
+	Escalation *EscalationRef `json:"escalation,omitempty"`
+	CreatedAt  time.Time      `json:"created_at"`
 }
 
 const DefaultHistoryRetentionDays = 30
 
+// alertTopProcesses is how many processes per ranking go into an alert.
+const alertTopProcesses = 5
+
 func DefaultConfig() Config {
 	return Config{
-		Enabled:         true,
-		WarningPercent:  75,
-		WarningForSec:   300,
-		CriticalPercent: 85,
-		CriticalForSec:  600,
-		RecoveryPercent: 70,
-		RecoveryForSec:  300,
-		CooldownSec:     1800,
-		TelegramEnabled: true,
-		EmailEnabled:    true,
-		RetryDelaysSec:  []int{1, 5, 15},
+		Enabled:              true,
+		WarningPercent:       75,
+		WarningForSec:        300,
+		CriticalPercent:      85,
+		CriticalForSec:       600,
+		RecoveryPercent:      70,
+		RecoveryForSec:       300,
+		CooldownSec:          1800,
+		TelegramEnabled:      true,
+		EmailEnabled:         true,
+		SMTPPort:             587,
+		SMTPSecurity:         SMTPSecuritySTARTTLS,
+		NtfyServerURL:        DefaultNtfyServerURL,
+		RetryDelaysSec:       []int{1, 5, 15},
+		OutboxMaxAgeHours:    DefaultOutboxMaxAgeHours,
+		FlapWindowSec:        3600,
+		FlapThreshold:        4,
+		DigestPeriod:         DigestDaily,
+		DigestCron:           "0 8 * * *",
+		DigestTimezone:       "UTC",
+		DigestSections:       append([]string(nil), digestSections...),
+		Routes:               map[Level][]string{},
+		EscalationPolicies:   []EscalationPolicy{},
+		ForecastEnabled:      true,
+		ForecastHorizonHours: 24,
 	}
 }
@@ -0,0 +1,286 @@
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// WebhookSignatureHeader carries "sha256=<hex HMAC of the raw body>" when the
+// target has a signing secret.
+const WebhookSignatureHeader = "X-QuickVPS-Signature"
+
+const maxWebhooks = 10
+
+type webhookSendFunc func(ctx context.Context, target Webhook, secret string, body []byte) error
+
+// WebhookPayload is the JSON body POSTed to every webhook target.
+type WebhookPayload struct {
+	EventID   int64              `json:"event_id"`
+	Level     Level              `json:"level"`
+	Message   string             `json:"message"`
+	Host      string             `json:"host"`
+	Series    string             `json:"series,omitempty"`
+	Value     float64            `json:"value"`
+	Metrics   map[string]float64 `json:"metrics,omitempty"`
+	Processes *TopProcesses      `json:"processes,omitempty"`
+	// Escalation is set when the payload is an escalation step.
+	Escalation *EscalationRef `json:"escalation,omitempty"`
+	Timestamp  time.Time      `json:"timestamp"`
+}
+
+func webhookPayload(note Notification) WebhookPayload {
+	return WebhookPayload{
+		EventID:    note.EventID,
+		Level:      note.Level,
+		Message:    note.Message,
+		Host:       note.Host,
+		Series:     note.Series,
+		Value:      note.Value,
+		Metrics:    note.Metrics,
+		Processes:  note.Processes,
+		Escalation: note.Escalation,
+		Timestamp:  note.Time.UTC(),
+	}
+}
+
+func signWebhookBody(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (n *Notifier) sendWebhookDefault(ctx context.Context, target Webhook, secret string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for _, h := range target.Headers {
+		req.Header.Set(h.Name, h.Value)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "QuickVPS-Webhook")
+	if secret != "" {
+		req.Header.Set(WebhookSignatureHeader, signWebhookBody(secret, body))
+	}
+
+	resp, err := n.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	_ = resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook send failed with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// redactWebhookURL drops credentials and the query string, which often carry
+// tokens, before a URL is stored in ChannelResult.
+func redactWebhookURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "invalid-url"
+	}
+	return u.Scheme + "://" + u.Host + u.Path
+}
+
+// mergeWebhooks applies a webhook list update to the current secrets. It
+// returns the config entries and the secrets of exactly the listed webhooks,
+// so removed webhooks lose their URL, header values and signing secret.
+func mergeWebhooks(items []WebhookInput, current map[string]WebhookSecret) ([]Webhook, map[string]WebhookSecret, error) {
+	if len(items) > maxWebhooks {
+		return nil, nil, fmt.Errorf("at most %d webhooks are allowed", maxWebhooks)
+	}
+	webhooks := make([]Webhook, 0, len(items))
+	secrets := make(map[string]WebhookSecret, len(items))
+	seen := make(map[string]bool, len(items))
+	for _, item := range items {
+		id := strings.TrimSpace(item.ID)
+		var sec WebhookSecret
+		if id == "" {
+			id = newWebhookID()
+		} else {
+			existing, ok := current[id]
+			if !ok {
+				return nil, nil, fmt.Errorf("unknown webhook id %q", id)
+			}
+			if _, dup := secrets[id]; dup {
+				return nil, nil, fmt.Errorf("duplicate webhook id %q", id)
+			}
+			sec = existing
+		}
+
+		if rawURL := strings.TrimSpace(item.URL); rawURL != "" {
+			u, err := url.Parse(rawURL)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return nil, nil, fmt.Errorf("invalid webhook url %q", redactWebhookURL(rawURL))
+			}
+			sec.URL = rawURL
+		}
+		if sec.URL == "" {
+			return nil, nil, errors.New("webhook url is required")
+		}
+		if seen[sec.URL] {
+			return nil, nil, fmt.Errorf("duplicate webhook url %q", redactWebhookURL(sec.URL))
+		}
+		seen[sec.URL] = true
+
+		headers := make([]WebhookHeader, 0, len(item.Headers))
+		values := make(map[string]string, len(item.Headers))
+		for _, h := range item.Headers {
+			name := strings.TrimSpace(h.Name)
+			if name == "" {
+				continue
+			}
+			if !validHeaderName(name) || strings.ContainsAny(h.Value, "\r\n") {
+				return nil, nil, fmt.Errorf("invalid webhook header %q", name)
+			}
+			if strings.EqualFold(name, WebhookSignatureHeader) || strings.EqualFold(name, "Content-Type") {
+				return nil, nil, fmt.Errorf("webhook header %q is set by QuickVPS", name)
+			}
+			// An empty value keeps the one already stored for the header.
+			value := strings.TrimSpace(h.Value)
+			if value == "" {
+				value = sec.Headers[name]
+			}
+			headers = append(headers, WebhookHeader{Name: name})
+			values[name] = value
+		}
+		sec.Headers = values
+		if secret := strings.TrimSpace(item.Secret); secret != "" {
+			sec.Secret = secret
+		}
+		if item.ClearSecret {
+			sec.Secret = ""
+		}
+
+		webhooks = append(webhooks, Webhook{ID: id, Headers: headers})
+		secrets[id] = sec
+	}
+	return webhooks, secrets, nil
+}
+
+func newWebhookID() string {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return fmt.Sprintf("wh%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(buf)
+}
+
+// withSecret returns the webhook with its URL and header values filled in.
+func (w Webhook) withSecret(sec WebhookSecret) Webhook {
+	out := Webhook{ID: w.ID, URL: sec.URL, Headers: make([]WebhookHeader, 0, len(w.Headers))}
+	for _, h := range w.Headers {
+		out.Headers = append(out.Headers, WebhookHeader{Name: h.Name, Value: sec.Headers[h.Name]})
+	}
+	return out
+}
+
+// webhookViews masks the webhook URLs, header values and signing secrets.
+func webhookViews(webhooks []Webhook, secrets map[string]WebhookSecret) []WebhookView {
+	out := make([]WebhookView, 0, len(webhooks))
+	for _, w := range webhooks {
+		sec := secrets[w.ID]
+		view := WebhookView{
+			ID:         w.ID,
+			URLMask:    maskSecret(sec.URL, true),
+			Headers:    make([]WebhookHeaderView, 0, len(w.Headers)),
+			HasSecret:  sec.Secret != "",
+			SecretMask: maskSecret(sec.Secret, sec.Secret != ""),
+		}
+		for _, h := range w.Headers {
+			value := sec.Headers[h.Name]
+			view.Headers = append(view.Headers, WebhookHeaderView{
+				Name:      h.Name,
+				HasValue:  value != "",
+				ValueMask: maskSecret(value, value != ""),
+			})
+		}
+		out = append(out, view)
+	}
+	return out
+}
+
+func validHeaderName(name string) bool {
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case strings.ContainsRune("!#$%&'*+-.^_`|~", r):
+		default:
+			return false
+		}
+	}
+	return name != ""
+}
+
+// decodeWebhookSecrets reads the decrypted webhook secrets. Before URLs and
+// header values were encrypted the blob only mapped a URL to its signing
+// secret; that form is returned as legacy for migrateLegacyWebhooks.
+func decodeWebhookSecrets(plain string) (secrets map[string]WebhookSecret, legacy map[string]string) {
+	secrets = map[string]WebhookSecret{}
+	if plain == "" {
+		return secrets, nil
+	}
+	if err := json.Unmarshal([]byte(plain), &secrets); err == nil {
+		return secrets, nil
+	}
+	secrets = map[string]WebhookSecret{}
+	if err := json.Unmarshal([]byte(plain), &legacy); err != nil {
+		return secrets, nil
+	}
+	return secrets, legacy
+}
+
+func encodeWebhookSecrets(c *Cipher, secrets map[string]WebhookSecret) (string, error) {
+	if len(secrets) == 0 {
+		return "", nil
+	}
+	if c == nil {
+		return "", ErrMissingEncryptionKey
+	}
+	raw, err := json.Marshal(secrets)
+	if err != nil {
+		return "", errors.New("encode webhook secrets")
+	}
+	return c.Encrypt(string(raw))
+}
+
+// migrateLegacyWebhooks moves the URL and header values of webhooks saved
+// in plain text into secrets, under a new ID, together with their signing
+// secret from legacy. It returns the new ID of every moved URL.
+func migrateLegacyWebhooks(cfg *Config, secrets map[string]WebhookSecret, legacy map[string]string) map[string]string {
+	moved := make(map[string]string)
+	for i, w := range cfg.Webhooks {
+		if w.ID != "" {
+			continue
+		}
+		sec := WebhookSecret{URL: w.URL, Secret: legacy[w.URL]}
+		headers := make([]WebhookHeader, 0, len(w.Headers))
+		for _, h := range w.Headers {
+			if sec.Headers == nil {
+				sec.Headers = make(map[string]string, len(w.Headers))
+			}
+			sec.Headers[h.Name] = h.Value
+			headers = append(headers, WebhookHeader{Name: h.Name})
+		}
+		id := newWebhookID()
+		secrets[id] = sec
+		cfg.Webhooks[i] = Webhook{ID: id, Headers: headers}
+		moved[w.URL] = id
+	}
+	return moved
+}
@@ -0,0 +1,214 @@
+package alerts
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWebhookDeliverySignedWithHeaders(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		bodies   [][]byte
+		sigs     []string
+		apiKeys  []string
+		failNext = true
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		if failNext {
+			failNext = false
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		bodies = append(bodies, body)
+		sigs = append(sigs, r.Header.Get(WebhookSignatureHeader))
+		apiKeys = append(apiKeys, r.Header.Get("X-Api-Key"))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	store, err := NewStore(filepath.Join(t.TempDir(), "alerts.db"))
+	if err != nil {
+		t.Fatalf("NewStore() error = %v", err)
+	}
+	t.Cleanup(func() {
+		_ = store.Close()
+	})
+	n := NewNotifier()
+	n.sleep = func(_ time.Duration) {}
+	key := base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", 32)))
+	svc, err := NewService(store, n, key)
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+
+	hookURL := srv.URL + "/hook?token=abc"
+	enabled, disabled := true, false
+	view, err := svc.UpdateConfig(UpdateConfigInput{
+		TelegramEnabled: &disabled,
+		EmailEnabled:    &disabled,
+		WebhookEnabled:  &enabled,
+		Webhooks: []WebhookInput{{
+			URL:     hookURL,
+			Headers: []WebhookHeader{{Name: "X-Api-Key", Value: "api-key-1"}},
+			Secret:  "shared-secret",
+		}},
+	})
+	if err != nil {
+		t.Fatalf("UpdateConfig() error = %v", err)
+	}
+	if len(view.Webhooks) != 1 {
+		t.Fatalf("view webhooks = %+v", view.Webhooks)
+	}
+	hook := view.Webhooks[0]
+	if hook.ID == "" || hook.URLMask != "****=abc" || !hook.HasSecret || hook.SecretMask != "****cret" {
+		t.Fatalf("webhook view = %+v", hook)
+	}
+	if len(hook.Headers) != 1 || hook.Headers[0].Name != "X-Api-Key" || !hook.Headers[0].HasValue || hook.Headers[0].ValueMask != "****ey-1" {
+		t.Fatalf("webhook header view = %+v", hook.Headers)
+	}
+	raw, _ := json.Marshal(view)
+	if strings.Contains(string(raw), "token=abc") || strings.Contains(string(raw), "api-key-1") {
+		t.Fatalf("config view leaks webhook credentials: %s", raw)
+	}
+	var stored string
+	if err := store.db.QueryRow(`SELECT webhooks_json FROM alert_settings WHERE id = 1`).Scan(&stored); err != nil {
+		t.Fatalf("read webhooks_json error = %v", err)
+	}
+	if strings.Contains(stored, "token=abc") || strings.Contains(stored, "api-key-1") {
+		t.Fatalf("webhooks_json stores credentials in plain text: %s", stored)
+	}
+
+	if _, err := svc.UpdateConfig(UpdateConfigInput{Webhooks: []WebhookInput{{URL: "ftp://example.com"}}}); err == nil {
+		t.Fatalf("UpdateConfig(ftp url) should fail")
+	}
+	if _, err := svc.UpdateConfig(UpdateConfigInput{Webhooks: []WebhookInput{{ID: "missing"}}}); err == nil {
+		t.Fatalf("UpdateConfig(unknown id) should fail")
+	}
+
+	event, err := svc.TriggerTest(context.Background())
+	if err != nil {
+		t.Fatalf("TriggerTest() error = %v", err)
+	}
+	if len(event.Channels) != 1 || !event.Channels[0].Success || event.Channels[0].Attempts != 2 {
+		t.Fatalf("channels = %+v, want one webhook success after retry", event.Channels)
+	}
+	if event.Channels[0].Target != srv.URL+"/hook" {
+		t.Fatalf("target = %q, query string should be redacted", event.Channels[0].Target)
+	}
+
+	if len(bodies) != 1 {
+		t.Fatalf("delivered %d bodies, want 1", len(bodies))
+	}
+	if sigs[0] != signWebhookBody("shared-secret", bodies[0]) || apiKeys[0] != "api-key-1" {
+		t.Fatalf("signature/header = %q/%q", sigs[0], apiKeys[0])
+	}
+	var payload WebhookPayload
+	if err := json.Unmarshal(bodies[0], &payload); err != nil {
+		t.Fatalf("payload decode error = %v", err)
+	}
+	if payload.EventID != event.ID || payload.Level != LevelTest {
+		t.Fatalf("payload = %+v, want event id %d", payload, event.ID)
+	}
+
+	events, err := svc.ListHistory(1, 0)
+	if err != nil || len(events) != 1 || len(events[0].Channels) != 1 {
+		t.Fatalf("stored event channels = %+v err=%v", events, err)
+	}
+
+	// Secrets survive a restart, are kept when the webhook is listed by ID
+	// only, and are dropped with the webhook.
+	reloaded, err := NewService(store, n, key)
+	if err != nil {
+		t.Fatalf("NewService(reload) error = %v", err)
+	}
+	if got := reloaded.secrets.Webhooks[hook.ID]; got.URL != hookURL || got.Secret != "shared-secret" || got.Headers["X-Api-Key"] != "api-key-1" {
+		t.Fatalf("reloaded secrets = %+v", reloaded.secrets.Webhooks)
+	}
+	view, err = reloaded.UpdateConfig(UpdateConfigInput{Webhooks: []WebhookInput{{ID: hook.ID, Headers: []WebhookHeader{{Name: "X-Api-Key"}}}}})
+	if err != nil || len(view.Webhooks) != 1 || view.Webhooks[0].SecretMask != "****cret" || view.Webhooks[0].Headers[0].ValueMask != "****ey-1" {
+		t.Fatalf("UpdateConfig(keep by id) view = %+v err=%v", view.Webhooks, err)
+	}
+	view, err = reloaded.UpdateConfig(UpdateConfigInput{Webhooks: []WebhookInput{}})
+	if err != nil || len(view.Webhooks) != 0 || len(reloaded.secrets.Webhooks) != 0 {
+		t.Fatalf("UpdateConfig(no webhooks) view = %+v secrets = %v err=%v", view.Webhooks, reloaded.secrets.Webhooks, err)
+	}
+}
+
+func TestLegacyPlainTextWebhooksAreEncrypted(t *testing.T) {
+	store, err := NewStore(filepath.Join(t.TempDir(), "alerts.db"))
+	if err != nil {
+		t.Fatalf("NewStore() error = %v", err)
+	}
+	t.Cleanup(func() {
+		_ = store.Close()
+	})
+	key := base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", 32)))
+	ring, err := NewKeyRing(key)
+	if err != nil {
+		t.Fatalf("NewKeyRing() error = %v", err)
+	}
+
+	hookURL := "https://hooks.example.com/in?token=abc"
+	legacyHooks := `[{"url":"` + hookURL + `","headers":[{"name":"Authorization","value":"Bearer t0ken"}]}]`
+	legacySecrets, err := ring.Encrypt(`{"` + hookURL + `":"shared-secret"}`)
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	if _, err := store.db.Exec(`UPDATE alert_settings SET webhooks_json = ? WHERE id = 1`, legacyHooks); err != nil {
+		t.Fatalf("write legacy webhooks error = %v", err)
+	}
+	if _, err := store.db.Exec(`UPDATE alert_secrets SET webhook_secrets_cipher = ? WHERE id = 1`, legacySecrets); err != nil {
+		t.Fatalf("write legacy secrets error = %v", err)
+	}
+	if _, err := store.EnqueueOutbox(outboxRecord{
+		OutboxItem: OutboxItem{EventID: 1, Channel: "webhook", Level: LevelWarning, NextAttemptAt: time.Now(), CreatedAt: time.Now()},
+		TargetKey:  hookURL,
+	}); err != nil {
+		t.Fatalf("EnqueueOutbox() error = %v", err)
+	}
+
+	svc, err := NewService(store, NewNotifier(), key)
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	if len(svc.cfg.Webhooks) != 1 || svc.cfg.Webhooks[0].ID == "" {
+		t.Fatalf("migrated webhooks = %+v", svc.cfg.Webhooks)
+	}
+	id := svc.cfg.Webhooks[0].ID
+	if got := svc.secrets.Webhooks[id]; got.URL != hookURL || got.Secret != "shared-secret" || got.Headers["Authorization"] != "Bearer t0ken" {
+		t.Fatalf("migrated secrets = %+v", svc.secrets.Webhooks)
+	}
+
+	var stored string
+	if err := store.db.QueryRow(`SELECT webhooks_json FROM alert_settings WHERE id = 1`).Scan(&stored); err != nil {
+		t.Fatalf("read webhooks_json error = %v", err)
+	}
+	if strings.Contains(stored, "token=abc") || strings.Contains(stored, "t0ken") {
+		t.Fatalf("webhooks_json still holds credentials: %s", stored)
+	}
+	recs, err := store.ListOutbox()
+	if err != nil || len(recs) != 1 || recs[0].TargetKey != id {
+		t.Fatalf("outbox = %+v err=%v, want target key %q", recs, err, id)
+	}
+
+	// A restart reads the migrated form without moving anything again.
+	reloaded, err := NewService(store, NewNotifier(), key)
+	if err != nil {
+		t.Fatalf("NewService(reload) error = %v", err)
+	}
+	if len(reloaded.cfg.Webhooks) != 1 || reloaded.cfg.Webhooks[0].ID != id || reloaded.secrets.Webhooks[id].URL != hookURL {
+		t.Fatalf("reloaded webhooks = %+v / %+v", reloaded.cfg.Webhooks, reloaded.secrets.Webhooks)
+	}
+}
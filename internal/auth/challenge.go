@@ -0,0 +1,73 @@
+package auth
+
+import "time"
+
+const (
+	// loginChallengeTTL is how long the second login step stays open after
+	// the password was accepted.
+	loginChallengeTTL = 5 * time.Minute
+	// loginChallengeAttempts is how many wrong codes a challenge survives.
+	loginChallengeAttempts = 5
+)
+
+// loginChallenge is a password-verified login waiting for its second factor.
+type loginChallenge struct {
+	user      User
+	expiresAt time.Time
+	failures  int
+}
+
+// CreateLoginChallenge records that user passed the password step and returns
+// the token the second step must present. Challenges live in memory only.
+func (m *SessionManager) CreateLoginChallenge(user User) (string, time.Time, error) {
+	token, err := randomToken(32)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	now := time.Now()
+	expiresAt := now.Add(loginChallengeTTL)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for t, c := range m.challenges {
+		if now.After(c.expiresAt) {
+			delete(m.challenges, t)
+		}
+	}
+	m.challenges[token] = &loginChallenge{user: user, expiresAt: expiresAt}
+	return token, expiresAt, nil
+}
+
+// LoginChallenge returns the user of an open challenge.
+func (m *SessionManager) LoginChallenge(token string) (User, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c, ok := m.challenges[token]
+	if !ok {
+		return User{}, false
+	}
+	if time.Now().After(c.expiresAt) {
+		delete(m.challenges, token)
+		return User{}, false
+	}
+	return c.user, true
+}
+
+// FailLoginChallenge counts a wrong code and closes the challenge after too
+// many, so the password step has to be repeated.
+func (m *SessionManager) FailLoginChallenge(token string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if c, ok := m.challenges[token]; ok {
+		c.failures++
+		if c.failures >= loginChallengeAttempts {
+			delete(m.challenges, token)
+		}
+	}
+}
+
+func (m *SessionManager) DeleteLoginChallenge(token string) {
+	m.mu.Lock()
+	delete(m.challenges, token)
+	m.mu.Unlock()
+}
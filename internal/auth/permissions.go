@@ -0,0 +1,348 @@
+package auth
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+var (
+	ErrInvalidPermission = errors.New("invalid permission")
+	ErrRoleExists        = errors.New("role already exists")
+	ErrRoleInUse         = errors.New("role is assigned to users")
+	ErrBuiltinRole       = errors.New("built-in roles cannot be changed")
+)
+
+// Permission names an action beyond read access. Every signed-in user can
+// read; permissions gate the rest.
+type Permission string
+
+const (
+	// PermUsersManage covers users, roles, the audit log, the 2FA policy and
+	// other users' API tokens.
+	PermUsersManage Permission = "users:manage"
+	// PermAlertsManage covers alert config, rules, templates, maintenance,
+	// outbox, digests, key rotation, synthetic checks and the watch list.
+	PermAlertsManage Permission = "alerts:manage"
+	// PermAlertsSilence covers muting alerts and acknowledging incidents.
+	PermAlertsSilence Permission = "alerts:silence"
+	PermPortsKill     Permission = "ports:kill"
+	PermStorageScan   Permission = "storage:scan"
+)
+
+// AllPermissions lists every permission, in display order.
+var AllPermissions = []Permission{
+	PermUsersManage,
+	PermAlertsManage,
+	PermAlertsSilence,
+	PermPortsKill,
+	PermStorageScan,
+}
+
+// RoleDefinition is a role and the permissions it grants.
+type RoleDefinition struct {
+	Name        Role         `json:"name"`
+	Description string       `json:"description"`
+	Permissions []Permission `json:"permissions"`
+	BuiltIn     bool         `json:"built_in"`
+}
+
+// builtinRoles cannot be edited or deleted. Admin always holds every
+// permission, including ones added later.
+var builtinRoles = []RoleDefinition{
+	{Name: RoleAdmin, Description: "Full access", Permissions: AllPermissions, BuiltIn: true},
+	{
+		Name:        RoleOperator,
+		Description: "Day-to-day operations without user management",
+		Permissions: []Permission{PermAlertsSilence, PermPortsKill, PermStorageScan},
+		BuiltIn:     true,
+	},
+	{Name: RoleViewer, Description: "Read-only access", Permissions: []Permission{}, BuiltIn: true},
+}
+
+var roleNameRe = regexp.MustCompile(`^[a-z][a-z0-9_-]{2,31}$`)
+
+func builtinRole(name Role) (RoleDefinition, bool) {
+	for _, r := range builtinRoles {
+		if r.Name == name {
+			return r, true
+		}
+	}
+	return RoleDefinition{}, false
+}
+
+func normalizePermissions(perms []Permission) ([]Permission, error) {
+	out := make([]Permission, 0, len(perms))
+	for _, p := range perms {
+		p = Permission(strings.ToLower(strings.TrimSpace(string(p))))
+		if !slices.Contains(AllPermissions, p) {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidPermission, p)
+		}
+		if !slices.Contains(out, p) {
+			out = append(out, p)
+		}
+	}
+	slices.Sort(out)
+	return out, nil
+}
+
+// loadRoles fills the in-memory custom role cache from the roles table.
+func (s *Store) loadRoles() error {
+	rows, err := s.db.Query(`SELECT name, description, permissions FROM roles ORDER BY name ASC`)
+	if err != nil {
+		return fmt.Errorf("list roles: %w", err)
+	}
+	defer rows.Close()
+
+	roles := make(map[Role]RoleDefinition)
+	for rows.Next() {
+		var (
+			def   RoleDefinition
+			perms string
+		)
+		if err := rows.Scan(&def.Name, &def.Description, &perms); err != nil {
+			return fmt.Errorf("scan role: %w", err)
+		}
+		def.Permissions = splitPermissions(perms)
+		roles[def.Name] = def
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate roles: %w", err)
+	}
+
+	s.mu.Lock()
+	s.roles = roles
+	s.mu.Unlock()
+	return nil
+}
+
+// resolveRole normalizes role and checks that it is built in or defined.
+func (s *Store) resolveRole(role Role) (Role, error) {
+	if clean, err := normalizeRole(role); err == nil {
+		return clean, nil
+	}
+	clean := Role(strings.ToLower(strings.TrimSpace(string(role))))
+	s.mu.RLock()
+	_, ok := s.roles[clean]
+	s.mu.RUnlock()
+	if !ok {
+		return "", ErrInvalidRole
+	}
+	return clean, nil
+}
+
+// HasPermission reports whether role grants perm. Unknown roles grant nothing.
+func (s *Store) HasPermission(role Role, perm Permission) bool {
+	return slices.Contains(s.RolePermissions(role), perm)
+}
+
+// RolePermissions returns the permissions granted by role.
+func (s *Store) RolePermissions(role Role) []Permission {
+	if def, ok := builtinRole(role); ok {
+		return def.Permissions
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if def, ok := s.roles[role]; ok {
+		return def.Permissions
+	}
+	return []Permission{}
+}
+
+// ListRoles returns the built-in roles followed by the custom ones.
+func (s *Store) ListRoles() []RoleDefinition {
+	out := slices.Clone(builtinRoles)
+	s.mu.RLock()
+	custom := make([]RoleDefinition, 0, len(s.roles))
+	for _, def := range s.roles {
+		custom = append(custom, def)
+	}
+	s.mu.RUnlock()
+	slices.SortFunc(custom, func(a, b RoleDefinition) int { return strings.Compare(string(a.Name), string(b.Name)) })
+	return append(out, custom...)
+}
+
+func (s *Store) GetRole(name Role) (RoleDefinition, error) {
+	if def, ok := builtinRole(name); ok {
+		return def, nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	def, ok := s.roles[name]
+	if !ok {
+		return RoleDefinition{}, ErrNotFound
+	}
+	return def, nil
+}
+
+// CreateRole defines a custom role.
+func (s *Store) CreateRole(name Role, description string, perms []Permission) (RoleDefinition, error) {
+	name = Role(strings.ToLower(strings.TrimSpace(string(name))))
+	if !roleNameRe.MatchString(string(name)) {
+		return RoleDefinition{}, ErrInvalidRole
+	}
+	if _, ok := builtinRole(name); ok {
+		return RoleDefinition{}, ErrRoleExists
+	}
+	cleanPerms, err := normalizePermissions(perms)
+	if err != nil {
+		return RoleDefinition{}, err
+	}
+	def := RoleDefinition{Name: name, Description: strings.TrimSpace(description), Permissions: cleanPerms}
+
+	if _, err := s.db.Exec(`INSERT INTO roles (name, description, permissions) VALUES (?, ?, ?)`,
+		string(def.Name), def.Description, joinPermissions(def.Permissions)); err != nil {
+		if strings.Contains(strings.ToLower(err.Error()), "unique") {
+			return RoleDefinition{}, ErrRoleExists
+		}
+		return RoleDefinition{}, fmt.Errorf("insert role: %w", err)
+	}
+	if err := s.loadRoles(); err != nil {
+		return RoleDefinition{}, err
+	}
+	return def, nil
+}
+
+// UpdateRole changes the description and/or permissions of a custom role.
+// Sessions resolve permissions on every request, so changes apply at once.
+func (s *Store) UpdateRole(name Role, description *string, perms []Permission) (RoleDefinition, error) {
+	if _, ok := builtinRole(name); ok {
+		return RoleDefinition{}, ErrBuiltinRole
+	}
+	def, err := s.GetRole(name)
+	if err != nil {
+		return RoleDefinition{}, err
+	}
+	if description != nil {
+		def.Description = strings.TrimSpace(*description)
+	}
+	if perms != nil {
+		if def.Permissions, err = normalizePermissions(perms); err != nil {
+			return RoleDefinition{}, err
+		}
+	}
+	if _, err := s.db.Exec(`UPDATE roles SET description = ?, permissions = ? WHERE name = ?`,
+		def.Description, joinPermissions(def.Permissions), string(name)); err != nil {
+		return RoleDefinition{}, fmt.Errorf("update role: %w", err)
+	}
+	if err := s.loadRoles(); err != nil {
+		return RoleDefinition{}, err
+	}
+	return def, nil
+}
+
+// DeleteRole removes a custom role that no user holds.
+func (s *Store) DeleteRole(name Role) error {
+	if _, ok := builtinRole(name); ok {
+		return ErrBuiltinRole
+	}
+	var users int
+	if err := s.db.QueryRow(`SELECT COUNT(1) FROM users WHERE role = ?`, string(name)).Scan(&users); err != nil {
+		return fmt.Errorf("count role users: %w", err)
+	}
+	if users > 0 {
+		return ErrRoleInUse
+	}
+	res, err := s.db.Exec(`DELETE FROM roles WHERE name = ?`, string(name))
+	if err != nil {
+		return fmt.Errorf("delete role: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+	return s.loadRoles()
+}
+
+// dropRoleCheck rebuilds a table created with CHECK(role IN ('admin',
+// 'viewer')), which would reject any other role. SQLite cannot drop a
+// constraint, so the table is recreated from its stored definition minus the
+// CHECK and every row is copied across.
+func (s *Store) dropRoleCheck(table string) error {
+	var current string
+	err := s.db.QueryRow(`SELECT sql FROM sqlite_master WHERE type = 'table' AND name = ?`, table).Scan(&current)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("inspect %s table: %w", table, err)
+	}
+	if !roleCheckRe.MatchString(current) {
+		return nil
+	}
+
+	newTable := table + "_new"
+	createSQL := roleCheckRe.ReplaceAllString(current, "")
+	createSQL = regexp.MustCompile(`^CREATE TABLE\s+(IF NOT EXISTS\s+)?"?`+table+`"?`).
+		ReplaceAllLiteralString(createSQL, "CREATE TABLE "+newTable)
+
+	columns, err := s.tableColumns(table)
+	if err != nil {
+		return err
+	}
+	cols := strings.Join(columns, ", ")
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin %s migration: %w", table, err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	for _, stmt := range []string{
+		`DROP TABLE IF EXISTS ` + newTable,
+		createSQL,
+		fmt.Sprintf(`INSERT INTO %s (%s) SELECT %s FROM %s`, newTable, cols, cols, table),
+		`DROP TABLE ` + table,
+		fmt.Sprintf(`ALTER TABLE %s RENAME TO %s`, newTable, table),
+	} {
+		if _, err := tx.Exec(stmt); err != nil {
+			return fmt.Errorf("rebuild %s table: %w", table, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit %s migration: %w", table, err)
+	}
+	return nil
+}
+
+var roleCheckRe = regexp.MustCompile(`(?i)\s*CHECK\s*\(\s*role\s+IN\s*\([^)]*\)\s*\)`)
+
+func (s *Store) tableColumns(table string) ([]string, error) {
+	rows, err := s.db.Query(`SELECT name FROM pragma_table_info(?)`, table)
+	if err != nil {
+		return nil, fmt.Errorf("inspect %s columns: %w", table, err)
+	}
+	defer rows.Close()
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("scan %s column: %w", table, err)
+		}
+		names = append(names, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate %s columns: %w", table, err)
+	}
+	return names, nil
+}
+
+func joinPermissions(perms []Permission) string {
+	parts := make([]string, len(perms))
+	for i, p := range perms {
+		parts[i] = string(p)
+	}
+	return strings.Join(parts, ",")
+}
+
+func splitPermissions(raw string) []Permission {
+	perms := make([]Permission, 0, 4)
+	for _, p := range strings.Split(raw, ",") {
+		if p != "" {
+			perms = append(perms, Permission(p))
+		}
+	}
+	return perms
+}
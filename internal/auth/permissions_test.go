@@ -0,0 +1,214 @@
+package auth
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestBuiltinRolePermissions(t *testing.T) {
+	store := newTestStore(t)
+
+	for _, perm := range AllPermissions {
+		if !store.HasPermission(RoleAdmin, perm) {
+			t.Fatalf("admin lacks %s", perm)
+		}
+		if store.HasPermission(RoleViewer, perm) {
+			t.Fatalf("viewer has %s", perm)
+		}
+	}
+	for _, perm := range []Permission{PermAlertsSilence, PermPortsKill, PermStorageScan} {
+		if !store.HasPermission(RoleOperator, perm) {
+			t.Fatalf("operator lacks %s", perm)
+		}
+	}
+	if store.HasPermission(RoleOperator, PermUsersManage) || store.HasPermission(RoleOperator, PermAlertsManage) {
+		t.Fatalf("operator permissions too broad: %v", store.RolePermissions(RoleOperator))
+	}
+	if store.HasPermission("ghost", PermPortsKill) {
+		t.Fatalf("unknown role granted a permission")
+	}
+
+	if _, err := store.CreateUser("ops", "secret123", RoleOperator); err != nil {
+		t.Fatalf("CreateUser(operator) error = %v", err)
+	}
+}
+
+func TestCustomRoleLifecycle(t *testing.T) {
+	store := newTestStore(t)
+
+	if _, err := store.CreateRole("auditor", "", []Permission{"root"}); !errors.Is(err, ErrInvalidPermission) {
+		t.Fatalf("CreateRole() unknown permission error = %v", err)
+	}
+	if _, err := store.CreateRole("Bad Name", "", nil); !errors.Is(err, ErrInvalidRole) {
+		t.Fatalf("CreateRole() invalid name error = %v", err)
+	}
+	if _, err := store.CreateRole("operator", "", nil); !errors.Is(err, ErrRoleExists) {
+		t.Fatalf("CreateRole() built-in name error = %v", err)
+	}
+
+	role, err := store.CreateRole("oncall", "Pager rotation", []Permission{PermAlertsSilence, "ALERTS:SILENCE"})
+	if err != nil {
+		t.Fatalf("CreateRole() error = %v", err)
+	}
+	if len(role.Permissions) != 1 || role.BuiltIn {
+		t.Fatalf("role = %+v", role)
+	}
+	if _, err := store.CreateRole("oncall", "", nil); !errors.Is(err, ErrRoleExists) {
+		t.Fatalf("CreateRole() duplicate error = %v", err)
+	}
+
+	user, err := store.CreateUser("pager", "secret123", "oncall")
+	if err != nil {
+		t.Fatalf("CreateUser(oncall) error = %v", err)
+	}
+	if !store.HasPermission(user.Role, PermAlertsSilence) || store.HasPermission(user.Role, PermPortsKill) {
+		t.Fatalf("oncall permissions = %v", store.RolePermissions(user.Role))
+	}
+
+	if _, err := store.UpdateRole("oncall", nil, []Permission{PermAlertsSilence, PermPortsKill}); err != nil {
+		t.Fatalf("UpdateRole() error = %v", err)
+	}
+	if !store.HasPermission("oncall", PermPortsKill) {
+		t.Fatalf("UpdateRole() did not take effect")
+	}
+	if _, err := store.UpdateRole(RoleAdmin, nil, nil); !errors.Is(err, ErrBuiltinRole) {
+		t.Fatalf("UpdateRole(admin) error = %v", err)
+	}
+
+	roles := store.ListRoles()
+	if len(roles) != 4 || roles[3].Name != "oncall" {
+		t.Fatalf("ListRoles() = %+v", roles)
+	}
+
+	if err := store.DeleteRole("oncall"); !errors.Is(err, ErrRoleInUse) {
+		t.Fatalf("DeleteRole() in use error = %v", err)
+	}
+	role2 := RoleViewer
+	if _, err := store.UpdateUser(user.ID, &role2, nil); err != nil {
+		t.Fatalf("UpdateUser() error = %v", err)
+	}
+	if err := store.DeleteRole("oncall"); err != nil {
+		t.Fatalf("DeleteRole() error = %v", err)
+	}
+	if _, err := store.GetRole("oncall"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("GetRole() after delete error = %v", err)
+	}
+	if _, err := store.CreateUser("late", "secret123", "oncall"); !errors.Is(err, ErrInvalidRole) {
+		t.Fatalf("CreateUser() deleted role error = %v", err)
+	}
+}
+
+func TestCustomRolesSurviveReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "auth-test.db")
+	store, err := NewStore(path)
+	if err != nil {
+		t.Fatalf("NewStore() error = %v", err)
+	}
+	if _, err := store.CreateRole("scanner", "", []Permission{PermStorageScan}); err != nil {
+		t.Fatalf("CreateRole() error = %v", err)
+	}
+	store.Close()
+
+	store, err = NewStore(path)
+	if err != nil {
+		t.Fatalf("NewStore() reopen error = %v", err)
+	}
+	defer store.Close()
+	if !store.HasPermission("scanner", PermStorageScan) {
+		t.Fatalf("custom role lost on reopen")
+	}
+}
+
+func TestMigrateDropsLegacyRoleCheck(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "legacy.db")
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("open legacy db: %v", err)
+	}
+	if _, err := db.Exec(`
+CREATE TABLE users (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  username TEXT NOT NULL UNIQUE,
+  password_hash TEXT NOT NULL,
+  role TEXT NOT NULL CHECK(role IN ('admin', 'viewer')),
+  created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE TABLE sessions (
+	token TEXT PRIMARY KEY,
+	user_id INTEGER NOT NULL,
+	username TEXT NOT NULL,
+	role TEXT NOT NULL CHECK(role IN ('admin', 'viewer')),
+	expires_at DATETIME NOT NULL,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+INSERT INTO users (id, username, password_hash, role) VALUES (7, 'root', 'x', 'admin'), (9, 'guest', 'y', 'viewer');
+`); err != nil {
+		t.Fatalf("seed legacy db: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO sessions (token, user_id, username, role, expires_at) VALUES ('tok', 7, 'root', 'admin', ?)`,
+		time.Now().Add(time.Hour).UTC()); err != nil {
+		t.Fatalf("seed legacy session: %v", err)
+	}
+	db.Close()
+
+	store, err := NewStore(path)
+	if err != nil {
+		t.Fatalf("NewStore() error = %v", err)
+	}
+	defer store.Close()
+
+	users, err := store.ListUsers()
+	if err != nil || len(users) != 2 || users[0].ID != 7 || users[1].Username != "guest" {
+		t.Fatalf("ListUsers() = %+v err=%v", users, err)
+	}
+	if session, ok, err := store.GetSession("tok"); err != nil || !ok || session.UserID != 7 {
+		t.Fatalf("GetSession() = %+v ok=%v err=%v", session, ok, err)
+	}
+	// The public ID of a legacy session is backfilled.
+	if session, ok, err := store.GetSessionByID(sessionID("tok")); err != nil || !ok || session.Token != "tok" {
+		t.Fatalf("GetSessionByID() = %+v ok=%v err=%v", session, ok, err)
+	}
+
+	role := RoleOperator
+	if _, err := store.UpdateUser(9, &role, nil); err != nil {
+		t.Fatalf("UpdateUser(operator) error = %v", err)
+	}
+	if err := store.SaveSession(Session{Token: "op", UserID: 9, Username: "guest", Role: RoleOperator, ExpiresAt: time.Now().Add(time.Hour)}); err != nil {
+		t.Fatalf("SaveSession(operator) error = %v", err)
+	}
+	// Legacy rows have no client or last-seen columns filled in.
+	if sessions, err := store.ListSessions(0); err != nil || len(sessions) != 2 || sessions[1].CreatedAt.IsZero() {
+		t.Fatalf("ListSessions() = %+v err=%v", sessions, err)
+	}
+
+	var indexes []string
+	rows, err := store.db.Query(`SELECT name FROM sqlite_master WHERE type = 'index' AND tbl_name = 'sessions' AND name LIKE 'idx_%'`)
+	if err != nil {
+		t.Fatalf("list indexes: %v", err)
+	}
+	for rows.Next() {
+		var name string
+		rows.Scan(&name)
+		indexes = append(indexes, name)
+	}
+	rows.Close()
+	if !slices.Contains(indexes, "idx_sessions_user_id") || !slices.Contains(indexes, "idx_sessions_expires_at") || !slices.Contains(indexes, "idx_sessions_id") {
+		t.Fatalf("session indexes = %v", indexes)
+	}
+
+	// A second open must leave the rebuilt tables alone.
+	store.Close()
+	store, err = NewStore(path)
+	if err != nil {
+		t.Fatalf("NewStore() reopen error = %v", err)
+	}
+	defer store.Close()
+	if user, err := store.GetUserByID(9); err != nil || user.Role != RoleOperator {
+		t.Fatalf("GetUserByID() = %+v err=%v", user, err)
+	}
+}
@@ -2,24 +2,46 @@ package auth
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
+	"slices"
 	"sync"
 	"time"
 )
 
+// sessionTouchInterval is how often a session in use has its last-seen time
+// and sliding expiry written back.
+const sessionTouchInterval = time.Minute
+
 type Session struct {
-	Token     string
-	UserID    int64
-	Username  string
-	Role      Role
-	ExpiresAt time.Time
+	// Token is the cookie secret and is never sent in API responses; ID
+	// identifies the session instead.
+	Token     string    `json:"-"`
+	ID        string    `json:"id"`
+	UserID    int64     `json:"user_id"`
+	Username  string    `json:"username"`
+	Role      Role      `json:"role"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"user_agent"`
+	CreatedAt time.Time `json:"created_at"`
+	// LastSeenAt is updated at most once per sessionTouchInterval.
+	LastSeenAt time.Time `json:"last_seen_at"`
+	// ExpiresAt slides forward with use; MaxExpiresAt is the absolute limit
+	// it never passes.
+	ExpiresAt    time.Time `json:"expires_at"`
+	MaxExpiresAt time.Time `json:"max_expires_at"`
 }
 
 type SessionManager struct {
-	mu       sync.RWMutex
-	ttl      time.Duration
-	store    *Store
-	sessions map[string]Session
+	mu sync.RWMutex
+	// ttl is the idle timeout; maxLifetime caps a session however active.
+	ttl         time.Duration
+	maxLifetime time.Duration
+	store       *Store
+	sessions    map[string]Session
+	// challenges are logins waiting for their second factor.
+	challenges map[string]*loginChallenge
+	throttle   *loginThrottle
 }
 
 func NewSessionManager(ttl time.Duration, store *Store) *SessionManager {
@@ -27,29 +49,72 @@ func NewSessionManager(ttl time.Duration, store *Store) *SessionManager {
 		ttl = 24 * time.Hour
 	}
 	return &SessionManager{
-		ttl:      ttl,
-		store:    store,
-		sessions: make(map[string]Session),
+		ttl:         ttl,
+		maxLifetime: 7 * 24 * time.Hour,
+		store:       store,
+		sessions:    make(map[string]Session),
+		challenges:  make(map[string]*loginChallenge),
+		throttle:    newLoginThrottle(),
+	}
+}
+
+// SetMaxLifetime sets the absolute session lifetime. It is never shorter than
+// the idle timeout.
+func (m *SessionManager) SetMaxLifetime(d time.Duration) {
+	m.mu.Lock()
+	m.maxLifetime = max(d, m.ttl)
+	m.mu.Unlock()
+}
+
+// sessionID derives the public ID of a session from its token, so the ID
+// needs no column of its own and reveals nothing about the token.
+func sessionID(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:8])
+}
+
+// withLimits fills the derived fields of a session loaded from the store or
+// built by hand.
+func (m *SessionManager) withLimits(s Session) Session {
+	s.ID = sessionID(s.Token)
+	if s.LastSeenAt.IsZero() {
+		s.LastSeenAt = s.CreatedAt
 	}
+	if !s.CreatedAt.IsZero() {
+		s.MaxExpiresAt = s.CreatedAt.Add(m.maxLifetime)
+	}
+	return s
+}
+
+func expired(s Session, now time.Time) bool {
+	return now.After(s.ExpiresAt) || (!s.MaxExpiresAt.IsZero() && now.After(s.MaxExpiresAt))
 }
 
-func (m *SessionManager) Create(user User) (Session, error) {
+// Create starts a session for user, recording the client it signed in from.
+func (m *SessionManager) Create(user User, ip, userAgent string) (Session, error) {
 	token, err := randomToken(32)
 	if err != nil {
 		return Session{}, err
 	}
 
 	now := time.Now()
-	s := Session{
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := m.withLimits(Session{
 		Token:     token,
 		UserID:    user.ID,
 		Username:  user.Username,
 		Role:      user.Role,
-		ExpiresAt: now.Add(m.ttl),
+		IP:        ip,
+		UserAgent: userAgent,
+		CreatedAt: now,
+	})
+	s.ExpiresAt = now.Add(m.ttl)
+	if s.ExpiresAt.After(s.MaxExpiresAt) {
+		s.ExpiresAt = s.MaxExpiresAt
 	}
 
-	m.mu.Lock()
-	defer m.mu.Unlock()
 	m.sessions[token] = s
 	if m.store != nil {
 		if err := m.store.SaveSession(s); err != nil {
@@ -61,42 +126,148 @@ func (m *SessionManager) Create(user User) (Session, error) {
 	return s, nil
 }
 
+// Get returns a live session and slides its expiry forward. The new expiry
+// and last-seen time are written back at most once per sessionTouchInterval.
 func (m *SessionManager) Get(token string) (Session, bool) {
 	now := time.Now()
 
 	m.mu.RLock()
 	session, ok := m.sessions[token]
-	m.mu.RUnlock()
 	if ok {
-		if now.After(session.ExpiresAt) {
-			m.Delete(token)
+		session = m.withLimits(session)
+	}
+	m.mu.RUnlock()
+	if !ok {
+		if m.store == nil {
+			return Session{}, false
+		}
+		if err := m.store.DeleteExpiredSessions(now); err != nil {
+			return Session{}, false
+		}
+		persisted, found, err := m.store.GetSession(token)
+		if err != nil || !found {
 			return Session{}, false
 		}
-		return session, true
+		m.mu.RLock()
+		session = m.withLimits(persisted)
+		m.mu.RUnlock()
 	}
-
-	if m.store == nil {
+	if expired(session, now) {
+		m.Delete(token)
 		return Session{}, false
 	}
 
-	if err := m.store.DeleteExpiredSessions(now); err != nil {
-		return Session{}, false
+	touched := false
+	if now.Sub(session.LastSeenAt) >= sessionTouchInterval {
+		session.LastSeenAt = now
+		session.ExpiresAt = now.Add(m.ttl)
+		if !session.MaxExpiresAt.IsZero() && session.ExpiresAt.After(session.MaxExpiresAt) {
+			session.ExpiresAt = session.MaxExpiresAt
+		}
+		touched = true
 	}
 
-	persisted, found, err := m.store.GetSession(token)
-	if err != nil || !found {
-		return Session{}, false
+	if touched || !ok {
+		m.mu.Lock()
+		// A session revoked while this request was in flight stays revoked.
+		if _, cached := m.sessions[token]; ok && !cached {
+			m.mu.Unlock()
+			return Session{}, false
+		}
+		m.sessions[token] = session
+		m.mu.Unlock()
 	}
-	if now.After(persisted.ExpiresAt) {
-		m.Delete(token)
-		return Session{}, false
+	if touched && m.store != nil {
+		_ = m.store.TouchSession(token, session.LastSeenAt, session.ExpiresAt)
 	}
 
-	m.mu.Lock()
-	m.sessions[token] = persisted
-	m.mu.Unlock()
+	return session, true
+}
+
+// List returns the live sessions of userID, or of every user when userID is
+// 0, most recently used first.
+func (m *SessionManager) List(userID int64) ([]Session, error) {
+	now := time.Now()
+
+	var sessions []Session
+	if m.store != nil {
+		if err := m.store.DeleteExpiredSessions(now); err != nil {
+			return nil, err
+		}
+		persisted, err := m.store.ListSessions(userID)
+		if err != nil {
+			return nil, err
+		}
+		m.mu.RLock()
+		for _, s := range persisted {
+			// The cached copy may have a newer last-seen time.
+			if cached, ok := m.sessions[s.Token]; ok {
+				s = cached
+			}
+			sessions = append(sessions, m.withLimits(s))
+		}
+		m.mu.RUnlock()
+	} else {
+		m.mu.RLock()
+		for _, s := range m.sessions {
+			if userID == 0 || s.UserID == userID {
+				sessions = append(sessions, m.withLimits(s))
+			}
+		}
+		m.mu.RUnlock()
+	}
+
+	out := make([]Session, 0, len(sessions))
+	for _, s := range sessions {
+		if !expired(s, now) {
+			out = append(out, s)
+		}
+	}
+	slices.SortFunc(out, func(a, b Session) int { return b.LastSeenAt.Compare(a.LastSeenAt) })
+	return out, nil
+}
 
-	return persisted, true
+// SessionByID returns the live session with the given public ID.
+func (m *SessionManager) SessionByID(id string) (Session, bool) {
+	if id == "" {
+		return Session{}, false
+	}
+	var (
+		session Session
+		found   bool
+	)
+	if m.store != nil {
+		persisted, ok, err := m.store.GetSessionByID(id)
+		if err != nil || !ok {
+			return Session{}, false
+		}
+		session, found = persisted, true
+		m.mu.RLock()
+		// The cached copy may have a newer last-seen time.
+		if cached, ok := m.sessions[session.Token]; ok {
+			session = cached
+		}
+		m.mu.RUnlock()
+	} else {
+		m.mu.RLock()
+		for token, s := range m.sessions {
+			if sessionID(token) == id {
+				session, found = s, true
+				break
+			}
+		}
+		m.mu.RUnlock()
+	}
+	if !found {
+		return Session{}, false
+	}
+	m.mu.RLock()
+	session = m.withLimits(session)
+	m.mu.RUnlock()
+	if expired(session, time.Now()) {
+		return Session{}, false
+	}
+	return session, true
 }
 
 func (m *SessionManager) Delete(token string) {
@@ -25,7 +25,7 @@ func TestSessionManagerCreateGetDelete(t *testing.T) {
 	mgr := NewSessionManager(2*time.Hour, nil)
 	user := User{ID: 7, Username: "alice", Role: RoleAdmin}
 
-	s, err := mgr.Create(user)
+	s, err := mgr.Create(user, "192.0.2.1", "test")
 	if err != nil {
 		t.Fatalf("Create() error = %v", err)
 	}
@@ -104,3 +104,98 @@ func TestSessionManagerLoadsFromStoreAndCaches(t *testing.T) {
 		t.Fatalf("session not cached in memory after store load")
 	}
 }
+
+func TestSessionManagerSlidingAndAbsoluteExpiry(t *testing.T) {
+	store := newSessionTestStore(t)
+	mgr := NewSessionManager(time.Hour, store)
+	mgr.SetMaxLifetime(4 * time.Hour)
+
+	s, err := mgr.Create(User{ID: 1, Username: "alice", Role: RoleViewer}, "203.0.113.5", "curl/8")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if s.ID == "" || s.ID == s.Token || !s.MaxExpiresAt.Equal(s.CreatedAt.Add(4*time.Hour)) {
+		t.Fatalf("Create() = %+v", s)
+	}
+
+	// Pretend the session was last used two minutes ago and is close to
+	// its idle timeout; using it slides the expiry forward.
+	now := time.Now()
+	mgr.mu.Lock()
+	cached := mgr.sessions[s.Token]
+	cached.LastSeenAt = now.Add(-2 * time.Minute)
+	cached.ExpiresAt = now.Add(time.Minute)
+	mgr.sessions[s.Token] = cached
+	mgr.mu.Unlock()
+
+	got, ok := mgr.Get(s.Token)
+	if !ok || got.ExpiresAt.Before(now.Add(59*time.Minute)) || got.LastSeenAt.Before(now) {
+		t.Fatalf("Get() did not slide expiry: %+v ok=%v", got, ok)
+	}
+	persisted, _, err := store.GetSession(s.Token)
+	if err != nil || persisted.ExpiresAt.Sub(got.ExpiresAt).Abs() > time.Second {
+		t.Fatalf("slid expiry not persisted: %+v err=%v", persisted, err)
+	}
+	if persisted.IP != "203.0.113.5" || persisted.UserAgent != "curl/8" {
+		t.Fatalf("client not persisted: %+v", persisted)
+	}
+
+	// Near the absolute limit the expiry stops at it.
+	mgr.mu.Lock()
+	cached = mgr.sessions[s.Token]
+	cached.CreatedAt = now.Add(-4*time.Hour + 10*time.Minute)
+	cached.LastSeenAt = now.Add(-2 * time.Minute)
+	mgr.sessions[s.Token] = cached
+	mgr.mu.Unlock()
+	got, ok = mgr.Get(s.Token)
+	if !ok || !got.ExpiresAt.Equal(got.MaxExpiresAt) {
+		t.Fatalf("expiry not capped at absolute limit: %+v ok=%v", got, ok)
+	}
+
+	// Past the absolute limit the session ends however recently it was used.
+	mgr.mu.Lock()
+	cached = mgr.sessions[s.Token]
+	cached.CreatedAt = now.Add(-4*time.Hour - time.Second)
+	mgr.sessions[s.Token] = cached
+	mgr.mu.Unlock()
+	if _, ok := mgr.Get(s.Token); ok {
+		t.Fatalf("Get() ok = true past the absolute limit")
+	}
+	if _, found, _ := store.GetSession(s.Token); found {
+		t.Fatalf("session past the absolute limit still stored")
+	}
+}
+
+func TestSessionManagerListAndSessionByID(t *testing.T) {
+	store := newSessionTestStore(t)
+	mgr := NewSessionManager(time.Hour, store)
+
+	a1, _ := mgr.Create(User{ID: 1, Username: "alice", Role: RoleViewer}, "203.0.113.5", "firefox")
+	a2, _ := mgr.Create(User{ID: 1, Username: "alice", Role: RoleViewer}, "203.0.113.6", "curl")
+	b1, _ := mgr.Create(User{ID: 2, Username: "bob", Role: RoleAdmin}, "203.0.113.7", "chrome")
+
+	own, err := mgr.List(1)
+	if err != nil || len(own) != 2 {
+		t.Fatalf("List(1) = %+v err=%v", own, err)
+	}
+	all, err := mgr.List(0)
+	if err != nil || len(all) != 3 {
+		t.Fatalf("List(0) = %+v err=%v", all, err)
+	}
+
+	// A fresh manager sees the same sessions through the store, with the
+	// same public IDs.
+	reloaded := NewSessionManager(time.Hour, store)
+	got, ok := reloaded.SessionByID(b1.ID)
+	if !ok || got.Token != b1.Token || got.IP != "203.0.113.7" || got.UserAgent != "chrome" {
+		t.Fatalf("SessionByID() = %+v ok=%v", got, ok)
+	}
+
+	mgr.Delete(a2.Token)
+	if _, ok := mgr.SessionByID(a2.ID); ok {
+		t.Fatalf("SessionByID() found a deleted session")
+	}
+	if _, ok := mgr.Get(a1.Token); !ok {
+		t.Fatalf("deleting one session ended another")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"golang.org/x/crypto/bcrypt"
@@ -23,6 +24,9 @@ var (
 
 type Store struct {
 	db *sql.DB
+
+	mu    sync.RWMutex
+	roles map[Role]RoleDefinition
 }
 
 func NewStore(path string) (*Store, error) {
@@ -58,7 +62,7 @@ CREATE TABLE IF NOT EXISTS users (
   id INTEGER PRIMARY KEY AUTOINCREMENT,
   username TEXT NOT NULL UNIQUE,
   password_hash TEXT NOT NULL,
-  role TEXT NOT NULL CHECK(role IN ('admin', 'viewer')),
+  role TEXT NOT NULL,
   created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
 );
 
@@ -66,7 +70,7 @@ CREATE TABLE IF NOT EXISTS sessions (
 	token TEXT PRIMARY KEY,
 	user_id INTEGER NOT NULL,
 	username TEXT NOT NULL,
-	role TEXT NOT NULL CHECK(role IN ('admin', 'viewer')),
+	role TEXT NOT NULL,
 	expires_at DATETIME NOT NULL,
 	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
 	FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
@@ -87,10 +91,148 @@ CREATE TABLE IF NOT EXISTS audit_user_actions (
 );
 
 CREATE INDEX IF NOT EXISTS idx_audit_user_actions_created_at ON audit_user_actions(created_at DESC);
+
+CREATE TABLE IF NOT EXISTS user_recovery_codes (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER NOT NULL,
+	code_hash TEXT NOT NULL,
+	used_at DATETIME,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_recovery_codes_user_id ON user_recovery_codes(user_id);
+
+CREATE TABLE IF NOT EXISTS api_tokens (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER NOT NULL,
+	name TEXT NOT NULL,
+	token_hash TEXT NOT NULL UNIQUE,
+	prefix TEXT NOT NULL,
+	scopes TEXT NOT NULL,
+	expires_at DATETIME,
+	last_used_at DATETIME,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_tokens_user_id ON api_tokens(user_id);
+
+CREATE TABLE IF NOT EXISTS auth_settings (
+	key TEXT PRIMARY KEY,
+	value TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS roles (
+	name TEXT PRIMARY KEY,
+	description TEXT NOT NULL DEFAULT '',
+	permissions TEXT NOT NULL DEFAULT '',
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
 `
 	if _, err := s.db.Exec(schema); err != nil {
 		return fmt.Errorf("migrate users table: %w", err)
 	}
+
+	for _, c := range []struct{ name, definition string }{
+		{"totp_secret", "TEXT NOT NULL DEFAULT ''"},
+		{"totp_pending_secret", "TEXT NOT NULL DEFAULT ''"},
+		{"totp_enabled", "INTEGER NOT NULL DEFAULT 0"},
+		// totp_last_step is the time step of the last accepted code, so a
+		// code cannot be replayed.
+		{"totp_last_step", "INTEGER NOT NULL DEFAULT 0"},
+	} {
+		if err := s.addColumnIfMissing("users", c.name, c.definition); err != nil {
+			return err
+		}
+	}
+
+	for _, c := range []struct{ name, definition string }{
+		{"ip", "TEXT NOT NULL DEFAULT ''"},
+		{"user_agent", "TEXT NOT NULL DEFAULT ''"},
+		{"last_seen_at", "DATETIME"},
+		// id is the public session ID (see sessionID), stored so a revoke
+		// can look it up without scanning every session.
+		{"id", "TEXT NOT NULL DEFAULT ''"},
+	} {
+		if err := s.addColumnIfMissing("sessions", c.name, c.definition); err != nil {
+			return err
+		}
+	}
+
+	// Databases created before custom roles constrain role to admin/viewer.
+	for _, table := range []string{"users", "sessions"} {
+		if err := s.dropRoleCheck(table); err != nil {
+			return err
+		}
+	}
+	if err := s.backfillSessionIDs(); err != nil {
+		return err
+	}
+	// Dropping the old sessions table also dropped its indexes.
+	if _, err := s.db.Exec(`
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions(user_id);
+CREATE INDEX IF NOT EXISTS idx_sessions_expires_at ON sessions(expires_at);
+CREATE INDEX IF NOT EXISTS idx_sessions_id ON sessions(id);
+`); err != nil {
+		return fmt.Errorf("migrate sessions indexes: %w", err)
+	}
+	return s.loadRoles()
+}
+
+// backfillSessionIDs fills in the public ID of sessions saved before it had
+// a column. The ID is a hash of the token, so it is computed here.
+func (s *Store) backfillSessionIDs() error {
+	rows, err := s.db.Query(`SELECT token FROM sessions WHERE id = ''`)
+	if err != nil {
+		return fmt.Errorf("list sessions without id: %w", err)
+	}
+	var tokens []string
+	for rows.Next() {
+		var token string
+		if err := rows.Scan(&token); err != nil {
+			rows.Close()
+			return fmt.Errorf("scan session token: %w", err)
+		}
+		tokens = append(tokens, token)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return fmt.Errorf("iterate sessions without id: %w", err)
+	}
+	rows.Close()
+
+	for _, token := range tokens {
+		if _, err := s.db.Exec(`UPDATE sessions SET id = ? WHERE token = ?`, sessionID(token), token); err != nil {
+			return fmt.Errorf("backfill session id: %w", err)
+		}
+	}
+	return nil
+}
+
+func (s *Store) addColumnIfMissing(table, column, definition string) error {
+	rows, err := s.db.Query(`SELECT name FROM pragma_table_info(?)`, table)
+	if err != nil {
+		return fmt.Errorf("inspect %s columns: %w", table, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return fmt.Errorf("scan %s column: %w", table, err)
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate %s columns: %w", table, err)
+	}
+	rows.Close()
+
+	if _, err := s.db.Exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, table, column, definition)); err != nil {
+		return fmt.Errorf("add %s.%s column: %w", table, column, err)
+	}
 	return nil
 }
 
@@ -98,6 +240,8 @@ func normalizeRole(role Role) (Role, error) {
 	switch Role(strings.ToLower(strings.TrimSpace(string(role)))) {
 	case RoleAdmin:
 		return RoleAdmin, nil
+	case RoleOperator:
+		return RoleOperator, nil
 	case RoleViewer:
 		return RoleViewer, nil
 	default:
@@ -150,7 +294,7 @@ func (s *Store) CreateUser(username, password string, role Role) (User, error) {
 	if err != nil {
 		return user, err
 	}
-	cleanRole, err := normalizeRole(role)
+	cleanRole, err := s.resolveRole(role)
 	if err != nil {
 		return user, err
 	}
@@ -194,10 +338,10 @@ func (s *Store) Authenticate(username, password string) (User, error) {
 	}
 
 	err = s.db.QueryRow(`
-SELECT id, username, role, password_hash
+SELECT id, username, role, totp_enabled, password_hash
 FROM users
 WHERE username = ?
-`, cleanUsername).Scan(&user.ID, &user.Username, &user.Role, &hash)
+`, cleanUsername).Scan(&user.ID, &user.Username, &user.Role, &user.TwoFactorEnabled, &hash)
 	if errors.Is(err, sql.ErrNoRows) {
 		return user, ErrInvalidCredentials
 	}
@@ -215,10 +359,10 @@ WHERE username = ?
 func (s *Store) GetUserByID(id int64) (User, error) {
 	var user User
 	err := s.db.QueryRow(`
-SELECT id, username, role
+SELECT id, username, role, totp_enabled
 FROM users
 WHERE id = ?
-`, id).Scan(&user.ID, &user.Username, &user.Role)
+`, id).Scan(&user.ID, &user.Username, &user.Role, &user.TwoFactorEnabled)
 	if errors.Is(err, sql.ErrNoRows) {
 		return user, ErrNotFound
 	}
@@ -230,7 +374,7 @@ WHERE id = ?
 
 func (s *Store) ListUsers() ([]User, error) {
 	rows, err := s.db.Query(`
-SELECT id, username, role
+SELECT id, username, role, totp_enabled
 FROM users
 ORDER BY id ASC
 `)
@@ -242,7 +386,7 @@ ORDER BY id ASC
 	users := make([]User, 0, 8)
 	for rows.Next() {
 		var user User
-		if err := rows.Scan(&user.ID, &user.Username, &user.Role); err != nil {
+		if err := rows.Scan(&user.ID, &user.Username, &user.Role, &user.TwoFactorEnabled); err != nil {
 			return nil, fmt.Errorf("scan user: %w", err)
 		}
 		users = append(users, user)
@@ -270,7 +414,7 @@ func (s *Store) UpdateUser(id int64, role *Role, password *string) (User, error)
 	}
 
 	if role != nil {
-		cleanRole, err := normalizeRole(*role)
+		cleanRole, err := s.resolveRole(*role)
 		if err != nil {
 			return User{}, err
 		}
@@ -321,6 +465,12 @@ func (s *Store) DeleteUser(id int64) error {
 		}
 	}
 
+	if _, err := s.db.Exec(`DELETE FROM user_recovery_codes WHERE user_id = ?`, id); err != nil {
+		return fmt.Errorf("delete recovery codes: %w", err)
+	}
+	if _, err := s.db.Exec(`DELETE FROM api_tokens WHERE user_id = ?`, id); err != nil {
+		return fmt.Errorf("delete api tokens: %w", err)
+	}
 	res, err := s.db.Exec(`DELETE FROM users WHERE id = ?`, id)
 	if err != nil {
 		return fmt.Errorf("delete user: %w", err)
@@ -336,41 +486,122 @@ func (s *Store) DeleteUser(id int64) error {
 }
 
 func (s *Store) SaveSession(session Session) error {
+	createdAt := session.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+	lastSeenAt := session.LastSeenAt
+	if lastSeenAt.IsZero() {
+		lastSeenAt = createdAt
+	}
 	_, err := s.db.Exec(`
-INSERT INTO sessions (token, user_id, username, role, expires_at)
-VALUES (?, ?, ?, ?, ?)
+INSERT INTO sessions (token, id, user_id, username, role, ip, user_agent, expires_at, last_seen_at, created_at)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 ON CONFLICT(token) DO UPDATE SET
   user_id = excluded.user_id,
   username = excluded.username,
   role = excluded.role,
-  expires_at = excluded.expires_at
-`, session.Token, session.UserID, session.Username, string(session.Role), session.ExpiresAt.UTC())
+  ip = excluded.ip,
+  user_agent = excluded.user_agent,
+  expires_at = excluded.expires_at,
+  last_seen_at = excluded.last_seen_at
+`, session.Token, sessionID(session.Token), session.UserID, session.Username, string(session.Role), session.IP, session.UserAgent,
+		session.ExpiresAt.UTC(), lastSeenAt.UTC(), createdAt.UTC())
 	if err != nil {
 		return fmt.Errorf("save session: %w", err)
 	}
 	return nil
 }
 
-func (s *Store) GetSession(token string) (Session, bool, error) {
-	var session Session
-	var role string
+const sessionColumns = `token, user_id, username, role, ip, user_agent, expires_at, last_seen_at, created_at`
 
-	err := s.db.QueryRow(`
-SELECT token, user_id, username, role, expires_at
-FROM sessions
-WHERE token = ?
-`, token).Scan(&session.Token, &session.UserID, &session.Username, &role, &session.ExpiresAt)
+func scanSession(row rowScanner) (Session, error) {
+	var (
+		session  Session
+		role     string
+		lastSeen sql.NullTime
+	)
+	if err := row.Scan(
+		&session.Token,
+		&session.UserID,
+		&session.Username,
+		&role,
+		&session.IP,
+		&session.UserAgent,
+		&session.ExpiresAt,
+		&lastSeen,
+		&session.CreatedAt,
+	); err != nil {
+		return Session{}, err
+	}
+	session.Role = Role(role)
+	if lastSeen.Valid {
+		session.LastSeenAt = lastSeen.Time
+	}
+	return session, nil
+}
+
+func (s *Store) GetSession(token string) (Session, bool, error) {
+	session, err := scanSession(s.db.QueryRow(`SELECT `+sessionColumns+` FROM sessions WHERE token = ?`, token))
 	if errors.Is(err, sql.ErrNoRows) {
 		return Session{}, false, nil
 	}
 	if err != nil {
 		return Session{}, false, fmt.Errorf("get session: %w", err)
 	}
+	return session, true, nil
+}
 
-	session.Role = Role(role)
+// GetSessionByID returns the stored session with the given public ID.
+func (s *Store) GetSessionByID(id string) (Session, bool, error) {
+	session, err := scanSession(s.db.QueryRow(`SELECT `+sessionColumns+` FROM sessions WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Session{}, false, nil
+	}
+	if err != nil {
+		return Session{}, false, fmt.Errorf("get session by id: %w", err)
+	}
 	return session, true, nil
 }
 
+// ListSessions returns the stored sessions of userID, or of every user when
+// userID is 0.
+func (s *Store) ListSessions(userID int64) ([]Session, error) {
+	query := `SELECT ` + sessionColumns + ` FROM sessions`
+	args := []any{}
+	if userID != 0 {
+		query += ` WHERE user_id = ?`
+		args = append(args, userID)
+	}
+	rows, err := s.db.Query(query+` ORDER BY created_at DESC`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list sessions: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := make([]Session, 0)
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan session: %w", err)
+		}
+		sessions = append(sessions, session)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate sessions: %w", err)
+	}
+	return sessions, nil
+}
+
+// TouchSession records use of a session and its slid expiry.
+func (s *Store) TouchSession(token string, lastSeenAt, expiresAt time.Time) error {
+	if _, err := s.db.Exec(`UPDATE sessions SET last_seen_at = ?, expires_at = ? WHERE token = ?`,
+		lastSeenAt.UTC(), expiresAt.UTC(), token); err != nil {
+		return fmt.Errorf("touch session: %w", err)
+	}
+	return nil
+}
+
 func (s *Store) DeleteSession(token string) error {
 	if _, err := s.db.Exec(`DELETE FROM sessions WHERE token = ?`, token); err != nil {
 		return fmt.Errorf("delete session: %w", err)
@@ -0,0 +1,389 @@
+package auth
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// loginFreeFailures is how many failures a key gets before delays start.
+	loginFreeFailures = 3
+	// loginBaseDelay is the first delay; each further failure doubles it up
+	// to loginMaxDelay.
+	loginBaseDelay = time.Second
+	loginMaxDelay  = time.Minute
+	// loginFailureWindow is how long a key remembers failures after its last
+	// one.
+	loginFailureWindow = 15 * time.Minute
+	// loginUsernameLockout and loginIPLockout are the failure counts that lock
+	// a username or an address for loginLockoutDuration. Addresses get more
+	// room because several users may share one.
+	loginUsernameLockout = 10
+	loginIPLockout       = 30
+	loginLockoutDuration = 15 * time.Minute
+	// loginBurstThreshold failures across all keys within loginBurstWindow
+	// report a burst, at most once per loginBurstCooldown.
+	loginBurstThreshold = 20
+	loginBurstWindow    = 5 * time.Minute
+	loginBurstCooldown  = 15 * time.Minute
+	// loginMaxEntries caps the addresses and the usernames remembered, so a
+	// spray of distinct keys cannot grow the throttle without bound.
+	loginMaxEntries = 10000
+)
+
+// LockoutKind is what a login throttle entry is keyed by.
+type LockoutKind string
+
+const (
+	LockoutIP       LockoutKind = "ip"
+	LockoutUsername LockoutKind = "username"
+)
+
+// Lockout is the failed-login state of one address or username.
+type Lockout struct {
+	Kind        LockoutKind `json:"kind"`
+	Key         string      `json:"key"`
+	Failures    int         `json:"failures"`
+	LastFailure time.Time   `json:"last_failure"`
+	// RetryAt is when the next attempt is accepted, if it has to wait.
+	RetryAt     *time.Time `json:"retry_at,omitempty"`
+	LockedUntil *time.Time `json:"locked_until,omitempty"`
+}
+
+// LoginFailure describes what a recorded failure changed.
+type LoginFailure struct {
+	Failures int
+	// Locked is set when this failure locked the username or the address.
+	Locked      bool
+	LockedKind  LockoutKind
+	LockedUntil time.Time
+	// Burst is set when failures across all keys crossed the burst
+	// threshold; BurstCount is how many fell in the window.
+	Burst      bool
+	BurstCount int
+}
+
+type throttleEntry struct {
+	failures    int
+	lastFailure time.Time
+	lockedUntil time.Time
+	// inflight counts the attempts begun but not yet failed or released.
+	inflight int
+}
+
+// loginThrottle slows down and locks out repeated failed logins per address
+// and per username. State is in memory only, so a restart clears it.
+type loginThrottle struct {
+	mu         sync.Mutex
+	entries    map[LockoutKind]map[string]*throttleEntry
+	maxEntries int
+	recent     []time.Time
+	lastBurst  time.Time
+}
+
+func newLoginThrottle() *loginThrottle {
+	return &loginThrottle{
+		entries: map[LockoutKind]map[string]*throttleEntry{
+			LockoutIP:       {},
+			LockoutUsername: {},
+		},
+		maxEntries: loginMaxEntries,
+	}
+}
+
+func throttleKey(kind LockoutKind, key string) string {
+	key = strings.TrimSpace(key)
+	if kind == LockoutUsername {
+		key = strings.ToLower(key)
+	}
+	return key
+}
+
+// retryAt returns when e accepts the next attempt; zero means now. An
+// attempt still in flight counts as a failure at now, so concurrent guesses
+// wait as if they had been made one after another.
+func (e *throttleEntry) retryAt(now time.Time) time.Time {
+	if !e.lockedUntil.IsZero() {
+		return e.lockedUntil
+	}
+	n := e.failures + e.inflight
+	if n <= loginFreeFailures {
+		return time.Time{}
+	}
+	delay := loginBaseDelay
+	for i := loginFreeFailures + 1; i < n && delay < loginMaxDelay; i++ {
+		delay *= 2
+	}
+	last := e.lastFailure
+	if e.inflight > 0 {
+		last = now
+	}
+	return last.Add(min(delay, loginMaxDelay))
+}
+
+// entryLocked returns the live entry for key, dropping it once its failures
+// and lockout have aged out.
+func (t *loginThrottle) entryLocked(kind LockoutKind, key string, now time.Time) *throttleEntry {
+	e, ok := t.entries[kind][key]
+	if !ok {
+		return nil
+	}
+	if now.Before(e.lockedUntil) {
+		return e
+	}
+	if !e.lockedUntil.IsZero() || (e.inflight == 0 && now.Sub(e.lastFailure) > loginFailureWindow) {
+		delete(t.entries[kind], key)
+		return nil
+	}
+	return e
+}
+
+// pruneLocked drops the aged-out entries of kind and, while the map is full,
+// evicts the entry with the oldest failure, preferring unlocked ones so a
+// spray cannot flush an active lockout or a pending attempt. The full scan is cheap next to the
+// password check every failure has already paid for.
+func (t *loginThrottle) pruneLocked(kind LockoutKind, now time.Time) {
+	entries := t.entries[kind]
+	for key := range entries {
+		t.entryLocked(kind, key, now)
+	}
+	for len(entries) > 0 && len(entries) >= t.maxEntries {
+		var victim string
+		var victimEntry *throttleEntry
+		for key, e := range entries {
+			if victimEntry == nil || evictBefore(e, victimEntry, now) {
+				victim, victimEntry = key, e
+			}
+		}
+		delete(entries, victim)
+	}
+}
+
+// evictBefore reports whether a should be evicted before b. Entries with an
+// attempt in flight are kept like locked ones.
+func evictBefore(a, b *throttleEntry, now time.Time) bool {
+	aLocked := now.Before(a.lockedUntil) || a.inflight > 0
+	bLocked := now.Before(b.lockedUntil) || b.inflight > 0
+	if aLocked != bLocked {
+		return bLocked
+	}
+	return a.lastFailure.Before(b.lastFailure)
+}
+
+type loginKey struct {
+	kind      LockoutKind
+	key       string
+	threshold int
+}
+
+func loginKeys(ip, username string) []loginKey {
+	return []loginKey{
+		{LockoutIP, throttleKey(LockoutIP, ip), loginIPLockout},
+		{LockoutUsername, throttleKey(LockoutUsername, username), loginUsernameLockout},
+	}
+}
+
+// LoginAllowed reports whether a login from ip for username may be tried
+// now. When it may not, the returned time is when it may.
+func (m *SessionManager) LoginAllowed(ip, username string, now time.Time) (time.Time, bool) {
+	t := m.throttle
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.waitLocked(ip, username, now)
+}
+
+func (t *loginThrottle) waitLocked(ip, username string, now time.Time) (time.Time, bool) {
+	var wait time.Time
+	for _, k := range loginKeys(ip, username) {
+		if k.key == "" {
+			continue
+		}
+		if e := t.entryLocked(k.kind, k.key, now); e != nil {
+			if at := e.retryAt(now); at.After(now) && at.After(wait) {
+				wait = at
+			}
+		}
+	}
+	return wait, wait.IsZero()
+}
+
+// LoginAttempt is a login try reserved by BeginLoginAttempt. It is ended by
+// Fail, or by Release when it succeeded or could not be checked; calls
+// after the first are no-ops.
+type LoginAttempt struct {
+	m            *SessionManager
+	ip, username string
+	done         bool
+}
+
+// BeginLoginAttempt checks the throttle and, when the login may be tried,
+// reserves the attempt in the same step so concurrent requests cannot all
+// pass the check before any failure is counted. When it may not, the
+// returned time is when it may.
+func (m *SessionManager) BeginLoginAttempt(ip, username string, now time.Time) (*LoginAttempt, time.Time, bool) {
+	t := m.throttle
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if wait, ok := t.waitLocked(ip, username, now); !ok {
+		return nil, wait, false
+	}
+	for _, k := range loginKeys(ip, username) {
+		if k.key == "" {
+			continue
+		}
+		t.entryOrNewLocked(k.kind, k.key, now).inflight++
+	}
+	return &LoginAttempt{m: m, ip: ip, username: username}, time.Time{}, true
+}
+
+// Fail counts the attempt as a failed login.
+func (a *LoginAttempt) Fail(now time.Time) LoginFailure {
+	t := a.m.throttle
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if a.done {
+		return LoginFailure{}
+	}
+	a.done = true
+	t.releaseLocked(a.ip, a.username, now)
+	return t.recordFailureLocked(a.ip, a.username, now)
+}
+
+// Release ends the attempt without counting it.
+func (a *LoginAttempt) Release() {
+	t := a.m.throttle
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if a.done {
+		return
+	}
+	a.done = true
+	t.releaseLocked(a.ip, a.username, time.Now())
+}
+
+func (t *loginThrottle) releaseLocked(ip, username string, now time.Time) {
+	for _, k := range loginKeys(ip, username) {
+		// The entry may have been cleared or evicted meanwhile.
+		if e, ok := t.entries[k.kind][k.key]; ok && e.inflight > 0 {
+			e.inflight--
+			t.entryLocked(k.kind, k.key, now)
+		}
+	}
+}
+
+// entryOrNewLocked returns the live entry for key, adding an empty one.
+func (t *loginThrottle) entryOrNewLocked(kind LockoutKind, key string, now time.Time) *throttleEntry {
+	e := t.entryLocked(kind, key, now)
+	if e == nil {
+		t.pruneLocked(kind, now)
+		e = &throttleEntry{}
+		t.entries[kind][key] = e
+	}
+	return e
+}
+
+// RecordLoginFailure counts a failed login from ip for username.
+func (m *SessionManager) RecordLoginFailure(ip, username string, now time.Time) LoginFailure {
+	t := m.throttle
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.recordFailureLocked(ip, username, now)
+}
+
+func (t *loginThrottle) recordFailureLocked(ip, username string, now time.Time) LoginFailure {
+	var out LoginFailure
+	for _, k := range loginKeys(ip, username) {
+		if k.key == "" {
+			continue
+		}
+		e := t.entryOrNewLocked(k.kind, k.key, now)
+		e.failures++
+		e.lastFailure = now
+		if e.failures >= k.threshold && e.lockedUntil.IsZero() {
+			e.lockedUntil = now.Add(loginLockoutDuration)
+			out.Locked = true
+			out.LockedKind = k.kind
+			out.LockedUntil = e.lockedUntil
+		}
+		if k.kind == LockoutUsername {
+			out.Failures = e.failures
+		}
+	}
+
+	cutoff := now.Add(-loginBurstWindow)
+	t.recent = slices.DeleteFunc(t.recent, func(at time.Time) bool { return at.Before(cutoff) })
+	t.recent = append(t.recent, now)
+	out.BurstCount = len(t.recent)
+	if len(t.recent) >= loginBurstThreshold && now.Sub(t.lastBurst) >= loginBurstCooldown {
+		t.lastBurst = now
+		out.Burst = true
+	}
+	return out
+}
+
+// RecordLoginSuccess forgets the failures of username. The address keeps
+// its count, so one valid account does not reset a spraying source.
+func (m *SessionManager) RecordLoginSuccess(username string) {
+	t := m.throttle
+	t.mu.Lock()
+	delete(t.entries[LockoutUsername], throttleKey(LockoutUsername, username))
+	t.mu.Unlock()
+}
+
+// Lockouts returns every address and username with recent failures, locked
+// ones first.
+func (m *SessionManager) Lockouts(now time.Time) []Lockout {
+	t := m.throttle
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	out := make([]Lockout, 0)
+	for _, kind := range []LockoutKind{LockoutIP, LockoutUsername} {
+		for key := range t.entries[kind] {
+			e := t.entryLocked(kind, key, now)
+			if e == nil || e.failures == 0 {
+				continue
+			}
+			l := Lockout{Kind: kind, Key: key, Failures: e.failures, LastFailure: e.lastFailure}
+			if at := e.retryAt(now); at.After(now) {
+				l.RetryAt = &at
+			}
+			if now.Before(e.lockedUntil) {
+				until := e.lockedUntil
+				l.LockedUntil = &until
+			}
+			out = append(out, l)
+		}
+	}
+	slices.SortFunc(out, func(a, b Lockout) int {
+		if (a.LockedUntil != nil) != (b.LockedUntil != nil) {
+			if a.LockedUntil != nil {
+				return -1
+			}
+			return 1
+		}
+		return b.LastFailure.Compare(a.LastFailure)
+	})
+	return out
+}
+
+// ClearLockout forgets the failures of one address or username.
+func (m *SessionManager) ClearLockout(kind LockoutKind, key string) error {
+	t := m.throttle
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	entries, ok := t.entries[kind]
+	if !ok {
+		return ErrNotFound
+	}
+	key = throttleKey(kind, key)
+	if _, ok := entries[key]; !ok {
+		return ErrNotFound
+	}
+	delete(entries, key)
+	return nil
+}
@@ -0,0 +1,222 @@
+package auth
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLoginThrottleDelaysAndLockout(t *testing.T) {
+	m := NewSessionManager(time.Hour, nil)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	for i := 0; i < loginFreeFailures; i++ {
+		if _, ok := m.LoginAllowed("10.0.0.1", "alice", now); !ok {
+			t.Fatalf("attempt %d throttled before the free failures ran out", i+1)
+		}
+		m.RecordLoginFailure("10.0.0.1", "alice", now)
+	}
+	if _, ok := m.LoginAllowed("10.0.0.1", "alice", now); !ok {
+		t.Fatalf("throttled after only free failures")
+	}
+
+	m.RecordLoginFailure("10.0.0.1", "alice", now)
+	retryAt, ok := m.LoginAllowed("10.0.0.1", "ALICE", now)
+	if ok || !retryAt.Equal(now.Add(loginBaseDelay)) {
+		t.Fatalf("LoginAllowed() = %v, %v; want wait of %s", retryAt, ok, loginBaseDelay)
+	}
+	// Another address trying the same username waits as well.
+	if _, ok := m.LoginAllowed("10.0.0.2", "alice", now); ok {
+		t.Fatalf("username throttle ignored from a new address")
+	}
+	if _, ok := m.LoginAllowed("10.0.0.2", "bob", now); !ok {
+		t.Fatalf("unrelated login throttled")
+	}
+
+	now = now.Add(loginBaseDelay)
+	m.RecordLoginFailure("10.0.0.1", "alice", now)
+	if retryAt, _ := m.LoginAllowed("10.0.0.1", "alice", now); !retryAt.Equal(now.Add(2 * loginBaseDelay)) {
+		t.Fatalf("second delay retryAt = %v, want doubled", retryAt)
+	}
+
+	var last LoginFailure
+	for i := 5; i < loginUsernameLockout; i++ {
+		now = now.Add(loginMaxDelay)
+		last = m.RecordLoginFailure("10.0.0.1", "alice", now)
+	}
+	if !last.Locked || last.LockedKind != LockoutUsername || !last.LockedUntil.Equal(now.Add(loginLockoutDuration)) {
+		t.Fatalf("lockout failure = %+v", last)
+	}
+	lockouts := m.Lockouts(now)
+	if len(lockouts) != 2 || lockouts[0].Kind != LockoutUsername || lockouts[0].LockedUntil == nil {
+		t.Fatalf("Lockouts() = %+v", lockouts)
+	}
+
+	if _, ok := m.LoginAllowed("10.0.0.9", "alice", now.Add(loginLockoutDuration-time.Second)); ok {
+		t.Fatalf("locked username allowed before lockout ended")
+	}
+	if _, ok := m.LoginAllowed("10.0.0.9", "alice", now.Add(loginLockoutDuration)); !ok {
+		t.Fatalf("username still locked after lockout ended")
+	}
+}
+
+func TestLoginThrottleClearAndSuccess(t *testing.T) {
+	m := NewSessionManager(time.Hour, nil)
+	now := time.Now()
+
+	for i := 0; i < loginUsernameLockout; i++ {
+		m.RecordLoginFailure("10.0.0.1", "alice", now)
+	}
+	if _, ok := m.LoginAllowed("", "alice", now); ok {
+		t.Fatalf("username not locked")
+	}
+	if err := m.ClearLockout(LockoutUsername, "Alice"); err != nil {
+		t.Fatalf("ClearLockout() error = %v", err)
+	}
+	if _, ok := m.LoginAllowed("", "alice", now); !ok {
+		t.Fatalf("username still locked after ClearLockout")
+	}
+	if err := m.ClearLockout(LockoutUsername, "alice"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("ClearLockout() twice error = %v", err)
+	}
+	if err := m.ClearLockout("email", "alice"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("ClearLockout() unknown kind error = %v", err)
+	}
+
+	m.RecordLoginFailure("10.0.0.2", "bob", now)
+	m.RecordLoginSuccess("bob")
+	for _, l := range m.Lockouts(now) {
+		if l.Kind == LockoutUsername && l.Key == "bob" {
+			t.Fatalf("RecordLoginSuccess() kept bob's failures")
+		}
+	}
+
+	// Failures age out after the window.
+	if got := len(m.Lockouts(now.Add(loginFailureWindow + time.Second))); got != 0 {
+		t.Fatalf("Lockouts() after window = %d entries", got)
+	}
+}
+
+func TestLoginThrottleBurst(t *testing.T) {
+	m := NewSessionManager(time.Hour, nil)
+	now := time.Now()
+
+	var bursts int
+	for i := 0; i < loginBurstThreshold*2; i++ {
+		// Spread over many addresses and usernames, as a spray would be.
+		f := m.RecordLoginFailure("10.0.1."+string(rune('a'+i%26)), "user"+string(rune('a'+i%26)), now)
+		if f.Burst {
+			bursts++
+			if f.BurstCount != loginBurstThreshold {
+				t.Fatalf("burst count = %d, want %d", f.BurstCount, loginBurstThreshold)
+			}
+		}
+	}
+	if bursts != 1 {
+		t.Fatalf("bursts = %d, want 1 within the cooldown", bursts)
+	}
+
+	later := now.Add(loginBurstCooldown)
+	var f LoginFailure
+	for i := 0; i < loginBurstThreshold; i++ {
+		f = m.RecordLoginFailure("10.0.2.1", "", later)
+	}
+	if !f.Burst {
+		t.Fatalf("no burst after cooldown: %+v", f)
+	}
+}
+
+func TestLoginThrottlePrunesAndCapsEntries(t *testing.T) {
+	m := NewSessionManager(time.Hour, nil)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	m.RecordLoginFailure("10.0.0.1", "alice", now)
+	later := now.Add(loginFailureWindow + time.Second)
+	m.RecordLoginFailure("10.0.0.2", "bob", later)
+	if _, ok := m.throttle.entries[LockoutIP]["10.0.0.1"]; ok {
+		t.Fatalf("aged-out address kept after a new failure")
+	}
+	if _, ok := m.throttle.entries[LockoutUsername]["alice"]; ok {
+		t.Fatalf("aged-out username kept after a new failure")
+	}
+
+	m = NewSessionManager(time.Hour, nil)
+	m.throttle.maxEntries = 3
+	for i := 0; i < loginUsernameLockout; i++ {
+		m.RecordLoginFailure("", "mallory", now)
+	}
+	for i, name := range []string{"u1", "u2", "u3", "u4", "u5"} {
+		m.RecordLoginFailure("", name, now.Add(time.Duration(i+1)*time.Second))
+	}
+	entries := m.throttle.entries[LockoutUsername]
+	if len(entries) != 3 {
+		t.Fatalf("username entries = %d, want the cap of 3", len(entries))
+	}
+	for _, name := range []string{"mallory", "u4", "u5"} {
+		if _, ok := entries[name]; !ok {
+			t.Fatalf("entries = %v, want mallory (locked) and the newest", entries)
+		}
+	}
+}
+
+func TestBeginLoginAttemptReservesConcurrentGuesses(t *testing.T) {
+	m := NewSessionManager(time.Hour, nil)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	const tries = 50
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		attempts []*LoginAttempt
+	)
+	for i := 0; i < tries; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if a, _, ok := m.BeginLoginAttempt("10.0.0.1", "alice", now); ok {
+				mu.Lock()
+				attempts = append(attempts, a)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	// As many as would pass one after another: the free failures plus the
+	// one that earns the first delay.
+	if len(attempts) != loginFreeFailures+1 {
+		t.Fatalf("%d of %d concurrent attempts allowed, want %d", len(attempts), tries, loginFreeFailures+1)
+	}
+
+	for _, a := range attempts {
+		a.Fail(now)
+		a.Fail(now)
+		a.Release()
+	}
+	lockouts := m.Lockouts(now)
+	if len(lockouts) != 2 || lockouts[0].Failures != loginFreeFailures+1 {
+		t.Fatalf("Lockouts() = %+v, want each attempt counted once", lockouts)
+	}
+	if _, _, ok := m.BeginLoginAttempt("10.0.0.1", "alice", now); ok {
+		t.Fatal("attempt allowed before the delay")
+	}
+}
+
+func TestLoginAttemptReleaseDoesNotCount(t *testing.T) {
+	m := NewSessionManager(time.Hour, nil)
+	now := time.Now()
+
+	for i := 0; i < loginUsernameLockout*2; i++ {
+		a, _, ok := m.BeginLoginAttempt("10.0.0.1", "alice", now)
+		if !ok {
+			t.Fatalf("attempt %d throttled after released attempts", i+1)
+		}
+		a.Release()
+	}
+	if lockouts := m.Lockouts(now); len(lockouts) != 0 {
+		t.Fatalf("Lockouts() = %+v after released attempts", lockouts)
+	}
+	if len(m.throttle.entries[LockoutIP]) != 0 || len(m.throttle.entries[LockoutUsername]) != 0 {
+		t.Fatal("released attempts left throttle entries behind")
+	}
+}
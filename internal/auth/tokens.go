@@ -0,0 +1,286 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidAPIToken = errors.New("invalid API token")
+	ErrInvalidScope    = errors.New("invalid token scope")
+	ErrInvalidName     = errors.New("invalid token name")
+)
+
+// Scope is a permission granted to an API token. Scopes only narrow what the
+// token's user may do; they never grant more than the user's role.
+type Scope string
+
+const (
+	ScopeMetricsRead Scope = "metrics:read"
+	ScopeAlertsRead  Scope = "alerts:read"
+	ScopeAlertsWrite Scope = "alerts:write"
+	ScopePortsKill   Scope = "ports:kill"
+)
+
+var validScopes = []Scope{ScopeMetricsRead, ScopeAlertsRead, ScopeAlertsWrite, ScopePortsKill}
+
+// apiTokenPrefix marks QuickVPS tokens so secret scanners can spot them.
+const apiTokenPrefix = "qvps_"
+
+// APIToken is a personal access token. Only a hash of the secret is stored;
+// Prefix is the start of the secret, shown so users can tell tokens apart.
+type APIToken struct {
+	ID         int64      `json:"id"`
+	UserID     int64      `json:"user_id"`
+	Username   string     `json:"username"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []Scope    `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// HasScope reports whether the token grants scope.
+func (t APIToken) HasScope(scope Scope) bool {
+	return slices.Contains(t.Scopes, scope)
+}
+
+func normalizeScopes(scopes []Scope) ([]Scope, error) {
+	out := make([]Scope, 0, len(scopes))
+	for _, sc := range scopes {
+		sc = Scope(strings.ToLower(strings.TrimSpace(string(sc))))
+		if !slices.Contains(validScopes, sc) {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidScope, sc)
+		}
+		if !slices.Contains(out, sc) {
+			out = append(out, sc)
+		}
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("%w: at least one scope is required", ErrInvalidScope)
+	}
+	slices.Sort(out)
+	return out, nil
+}
+
+func normalizeTokenName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > 64 {
+		return "", ErrInvalidName
+	}
+	return name, nil
+}
+
+func hashAPIToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateAPIToken issues a token for userID and returns it with the secret,
+// which is not stored and cannot be shown again. A nil expiresAt never
+// expires.
+func (s *Store) CreateAPIToken(userID int64, name string, scopes []Scope, expiresAt *time.Time) (APIToken, string, error) {
+	cleanName, err := normalizeTokenName(name)
+	if err != nil {
+		return APIToken{}, "", err
+	}
+	cleanScopes, err := normalizeScopes(scopes)
+	if err != nil {
+		return APIToken{}, "", err
+	}
+	if _, err := s.GetUserByID(userID); err != nil {
+		return APIToken{}, "", err
+	}
+
+	secret, err := randomToken(20)
+	if err != nil {
+		return APIToken{}, "", fmt.Errorf("generate api token: %w", err)
+	}
+	raw := apiTokenPrefix + secret
+	prefix := raw[:len(apiTokenPrefix)+6]
+
+	var expires any
+	if expiresAt != nil {
+		expires = expiresAt.UTC()
+	}
+	res, err := s.db.Exec(`
+INSERT INTO api_tokens (user_id, name, token_hash, prefix, scopes, expires_at)
+VALUES (?, ?, ?, ?, ?, ?)
+`, userID, cleanName, hashAPIToken(raw), prefix, joinScopes(cleanScopes), expires)
+	if err != nil {
+		return APIToken{}, "", fmt.Errorf("insert api token: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return APIToken{}, "", fmt.Errorf("last insert id: %w", err)
+	}
+	token, err := s.GetAPIToken(id)
+	if err != nil {
+		return APIToken{}, "", err
+	}
+	return token, raw, nil
+}
+
+const apiTokenColumns = `t.id, t.user_id, u.username, t.name, t.prefix, t.scopes, t.expires_at, t.last_used_at, t.created_at`
+
+func (s *Store) GetAPIToken(id int64) (APIToken, error) {
+	token, err := scanAPIToken(s.db.QueryRow(`
+SELECT `+apiTokenColumns+`
+FROM api_tokens t JOIN users u ON u.id = t.user_id
+WHERE t.id = ?
+`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return APIToken{}, ErrNotFound
+	}
+	return token, err
+}
+
+// ListAPITokens lists the tokens of userID, or of every user when userID is 0.
+func (s *Store) ListAPITokens(userID int64) ([]APIToken, error) {
+	rows, err := s.db.Query(`
+SELECT `+apiTokenColumns+`
+FROM api_tokens t JOIN users u ON u.id = t.user_id
+WHERE ? = 0 OR t.user_id = ?
+ORDER BY t.id ASC
+`, userID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list api tokens: %w", err)
+	}
+	defer rows.Close()
+
+	tokens := make([]APIToken, 0, 4)
+	for rows.Next() {
+		token, err := scanAPIToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate api tokens: %w", err)
+	}
+	return tokens, nil
+}
+
+// UpdateAPIToken renames a token and/or replaces its scopes.
+func (s *Store) UpdateAPIToken(id int64, name *string, scopes []Scope) (APIToken, error) {
+	if _, err := s.GetAPIToken(id); err != nil {
+		return APIToken{}, err
+	}
+	if name != nil {
+		cleanName, err := normalizeTokenName(*name)
+		if err != nil {
+			return APIToken{}, err
+		}
+		if _, err := s.db.Exec(`UPDATE api_tokens SET name = ? WHERE id = ?`, cleanName, id); err != nil {
+			return APIToken{}, fmt.Errorf("update api token name: %w", err)
+		}
+	}
+	if scopes != nil {
+		cleanScopes, err := normalizeScopes(scopes)
+		if err != nil {
+			return APIToken{}, err
+		}
+		if _, err := s.db.Exec(`UPDATE api_tokens SET scopes = ? WHERE id = ?`, joinScopes(cleanScopes), id); err != nil {
+			return APIToken{}, fmt.Errorf("update api token scopes: %w", err)
+		}
+	}
+	return s.GetAPIToken(id)
+}
+
+func (s *Store) DeleteAPIToken(id int64) error {
+	res, err := s.db.Exec(`DELETE FROM api_tokens WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("delete api token: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// AuthenticateAPIToken resolves a bearer token to its token record and
+// current user, and stamps its last use.
+func (s *Store) AuthenticateAPIToken(raw string, now time.Time) (token APIToken, user User, err error) {
+	if !strings.HasPrefix(raw, apiTokenPrefix) {
+		return APIToken{}, User{}, ErrInvalidAPIToken
+	}
+	token, err = scanAPIToken(s.db.QueryRow(`
+SELECT `+apiTokenColumns+`
+FROM api_tokens t JOIN users u ON u.id = t.user_id
+WHERE t.token_hash = ?
+`, hashAPIToken(raw)))
+	if errors.Is(err, sql.ErrNoRows) {
+		return APIToken{}, User{}, ErrInvalidAPIToken
+	}
+	if err != nil {
+		return APIToken{}, User{}, err
+	}
+	if token.ExpiresAt != nil && !now.Before(*token.ExpiresAt) {
+		return APIToken{}, User{}, ErrInvalidAPIToken
+	}
+	user, err = s.GetUserByID(token.UserID)
+	if err != nil {
+		return APIToken{}, User{}, ErrInvalidAPIToken
+	}
+
+	if _, err := s.db.Exec(`UPDATE api_tokens SET last_used_at = ? WHERE id = ?`, now.UTC(), token.ID); err != nil {
+		return APIToken{}, User{}, fmt.Errorf("touch api token: %w", err)
+	}
+	used := now.UTC()
+	token.LastUsedAt = &used
+	return token, user, nil
+}
+
+func joinScopes(scopes []Scope) string {
+	parts := make([]string, len(scopes))
+	for i, sc := range scopes {
+		parts[i] = string(sc)
+	}
+	return strings.Join(parts, ",")
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanAPIToken(row rowScanner) (APIToken, error) {
+	var (
+		token     APIToken
+		scopes    string
+		expiresAt sql.NullTime
+		lastUsed  sql.NullTime
+	)
+	err := row.Scan(
+		&token.ID, &token.UserID, &token.Username, &token.Name, &token.Prefix,
+		&scopes, &expiresAt, &lastUsed, &token.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return APIToken{}, err
+		}
+		return APIToken{}, fmt.Errorf("scan api token: %w", err)
+	}
+	token.Scopes = make([]Scope, 0, 4)
+	for _, sc := range strings.Split(scopes, ",") {
+		if sc != "" {
+			token.Scopes = append(token.Scopes, Scope(sc))
+		}
+	}
+	if expiresAt.Valid {
+		t := expiresAt.Time
+		token.ExpiresAt = &t
+	}
+	if lastUsed.Valid {
+		t := lastUsed.Time
+		token.LastUsedAt = &t
+	}
+	return token, nil
+}
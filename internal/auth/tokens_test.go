@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAPITokenLifecycle(t *testing.T) {
+	store := newTestStore(t)
+	user, err := store.CreateUser("ci-bot", "secret123", RoleViewer)
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+
+	if _, _, err := store.CreateAPIToken(user.ID, "ci", []Scope{"root"}, nil); !errors.Is(err, ErrInvalidScope) {
+		t.Fatalf("CreateAPIToken() unknown scope error = %v", err)
+	}
+	if _, _, err := store.CreateAPIToken(user.ID, " ", []Scope{ScopeMetricsRead}, nil); !errors.Is(err, ErrInvalidName) {
+		t.Fatalf("CreateAPIToken() empty name error = %v", err)
+	}
+
+	token, raw, err := store.CreateAPIToken(user.ID, "ci", []Scope{ScopeMetricsRead, "ALERTS:READ", ScopeMetricsRead}, nil)
+	if err != nil {
+		t.Fatalf("CreateAPIToken() error = %v", err)
+	}
+	if !strings.HasPrefix(raw, token.Prefix) || len(token.Scopes) != 2 || !token.HasScope(ScopeAlertsRead) {
+		t.Fatalf("token = %+v raw=%q", token, raw)
+	}
+
+	var storedHash string
+	if err := store.db.QueryRow(`SELECT token_hash FROM api_tokens WHERE id = ?`, token.ID).Scan(&storedHash); err != nil {
+		t.Fatalf("select token_hash error = %v", err)
+	}
+	if storedHash == raw || strings.Contains(storedHash, raw[len(apiTokenPrefix):]) {
+		t.Fatalf("token stored in plain text")
+	}
+
+	now := time.Now()
+	got, gotUser, err := store.AuthenticateAPIToken(raw, now)
+	if err != nil || got.ID != token.ID || gotUser.Username != "ci-bot" || got.LastUsedAt == nil {
+		t.Fatalf("AuthenticateAPIToken() = %+v, %+v, %v", got, gotUser, err)
+	}
+	if _, _, err := store.AuthenticateAPIToken(raw+"x", now); !errors.Is(err, ErrInvalidAPIToken) {
+		t.Fatalf("wrong token error = %v", err)
+	}
+
+	if err := store.DeleteAPIToken(token.ID); err != nil {
+		t.Fatalf("DeleteAPIToken() error = %v", err)
+	}
+	if _, _, err := store.AuthenticateAPIToken(raw, now); !errors.Is(err, ErrInvalidAPIToken) {
+		t.Fatalf("revoked token error = %v", err)
+	}
+}
+
+func TestAPITokenExpiry(t *testing.T) {
+	store := newTestStore(t)
+	user, _ := store.CreateUser("ci-bot", "secret123", RoleViewer)
+	expires := time.Now().Add(time.Hour)
+	_, raw, err := store.CreateAPIToken(user.ID, "short", []Scope{ScopeMetricsRead}, &expires)
+	if err != nil {
+		t.Fatalf("CreateAPIToken() error = %v", err)
+	}
+	if _, _, err := store.AuthenticateAPIToken(raw, time.Now()); err != nil {
+		t.Fatalf("AuthenticateAPIToken() before expiry error = %v", err)
+	}
+	if _, _, err := store.AuthenticateAPIToken(raw, expires.Add(time.Second)); !errors.Is(err, ErrInvalidAPIToken) {
+		t.Fatalf("AuthenticateAPIToken() after expiry error = %v", err)
+	}
+}
@@ -0,0 +1,55 @@
+package auth
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTOTPCodeMatchesRFC6238Vectors(t *testing.T) {
+	// RFC 6238 appendix B, SHA-1 seed, truncated to 6 digits.
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+	for _, tc := range []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	} {
+		got, err := TOTPCode(secret, time.Unix(tc.unix, 0))
+		if err != nil {
+			t.Fatalf("TOTPCode() error = %v", err)
+		}
+		if got != tc.want {
+			t.Errorf("TOTPCode(%d) = %q, want %q", tc.unix, got, tc.want)
+		}
+	}
+}
+
+func TestValidateTOTPAllowsOneStepOfSkew(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatalf("GenerateTOTPSecret() error = %v", err)
+	}
+	now := time.Unix(1_700_000_000, 0)
+	for _, offset := range []time.Duration{-30 * time.Second, 0, 30 * time.Second} {
+		code, _ := TOTPCode(secret, now.Add(offset))
+		if _, ok := validateTOTP(secret, code, now); !ok {
+			t.Errorf("code at offset %s rejected", offset)
+		}
+	}
+	code, _ := TOTPCode(secret, now.Add(-2*time.Minute))
+	if _, ok := validateTOTP(secret, code, now); ok {
+		t.Errorf("code two minutes old accepted")
+	}
+}
+
+func TestTOTPURI(t *testing.T) {
+	uri := TOTPURI("QuickVPS", "admin@web 1", "ABC")
+	if !strings.HasPrefix(uri, "otpauth://totp/QuickVPS:admin@web%201?") || !strings.Contains(uri, "secret=ABC") || !strings.Contains(uri, "issuer=QuickVPS") {
+		t.Fatalf("TOTPURI() = %q", uri)
+	}
+}
@@ -0,0 +1,320 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidTwoFactorCode = errors.New("invalid two-factor code")
+	ErrTwoFactorEnabled     = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotEnabled  = errors.New("two-factor authentication is not enabled")
+	ErrTwoFactorNotStarted  = errors.New("two-factor enrollment has not been started")
+	ErrTwoFactorRequired    = errors.New("two-factor authentication is required for admins")
+)
+
+// RecoveryCodeCount is how many one-time recovery codes a user gets on
+// enrollment or regeneration.
+const RecoveryCodeCount = 10
+
+const settingRequireAdminTwoFactor = "require_admin_2fa"
+
+// TwoFactorStatus is a user's TOTP enrollment state.
+type TwoFactorStatus struct {
+	Enabled bool `json:"enabled"`
+	// Pending is set between setup and the confirmation code.
+	Pending                bool `json:"pending"`
+	RecoveryCodesRemaining int  `json:"recovery_codes_remaining"`
+}
+
+func (s *Store) TwoFactorStatus(userID int64) (TwoFactorStatus, error) {
+	var (
+		status  TwoFactorStatus
+		enabled int
+		pending string
+	)
+	err := s.db.QueryRow(`SELECT totp_enabled, totp_pending_secret FROM users WHERE id = ?`, userID).Scan(&enabled, &pending)
+	if errors.Is(err, sql.ErrNoRows) {
+		return status, ErrNotFound
+	}
+	if err != nil {
+		return status, fmt.Errorf("get two-factor status: %w", err)
+	}
+	status.Enabled = enabled == 1
+	status.Pending = pending != ""
+	if err := s.db.QueryRow(`SELECT COUNT(1) FROM user_recovery_codes WHERE user_id = ? AND used_at IS NULL`, userID).Scan(&status.RecoveryCodesRemaining); err != nil {
+		return status, fmt.Errorf("count recovery codes: %w", err)
+	}
+	return status, nil
+}
+
+// BeginTOTPEnrollment generates a new secret for userID. It only takes effect
+// once ConfirmTOTPEnrollment sees a valid code for it.
+func (s *Store) BeginTOTPEnrollment(userID int64) (string, error) {
+	status, err := s.TwoFactorStatus(userID)
+	if err != nil {
+		return "", err
+	}
+	if status.Enabled {
+		return "", ErrTwoFactorEnabled
+	}
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		return "", fmt.Errorf("generate totp secret: %w", err)
+	}
+	if _, err := s.db.Exec(`UPDATE users SET totp_pending_secret = ? WHERE id = ?`, secret, userID); err != nil {
+		return "", fmt.Errorf("save pending totp secret: %w", err)
+	}
+	return secret, nil
+}
+
+// ConfirmTOTPEnrollment enables TOTP when code matches the pending secret and
+// returns a fresh set of recovery codes. They are only shown this once.
+func (s *Store) ConfirmTOTPEnrollment(userID int64, code string, now time.Time) ([]string, error) {
+	var (
+		pending string
+		enabled int
+	)
+	err := s.db.QueryRow(`SELECT totp_pending_secret, totp_enabled FROM users WHERE id = ?`, userID).Scan(&pending, &enabled)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get pending totp secret: %w", err)
+	}
+	if enabled == 1 {
+		return nil, ErrTwoFactorEnabled
+	}
+	if pending == "" {
+		return nil, ErrTwoFactorNotStarted
+	}
+	step, ok := validateTOTP(pending, code, now)
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("begin totp enrollment: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	if _, err := tx.Exec(`
+UPDATE users
+SET totp_secret = ?, totp_pending_secret = '', totp_enabled = 1, totp_last_step = ?
+WHERE id = ?
+`, pending, step, userID); err != nil {
+		return nil, fmt.Errorf("enable totp: %w", err)
+	}
+	codes, err := replaceRecoveryCodes(tx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit totp enrollment: %w", err)
+	}
+	return codes, nil
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes of userID.
+func (s *Store) RegenerateRecoveryCodes(userID int64) ([]string, error) {
+	status, err := s.TwoFactorStatus(userID)
+	if err != nil {
+		return nil, err
+	}
+	if !status.Enabled {
+		return nil, ErrTwoFactorNotEnabled
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("begin recovery code regeneration: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	codes, err := replaceRecoveryCodes(tx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit recovery codes: %w", err)
+	}
+	return codes, nil
+}
+
+// DisableTOTP removes the secret and recovery codes of userID.
+func (s *Store) DisableTOTP(userID int64) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin totp disable: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	res, err := tx.Exec(`
+UPDATE users
+SET totp_secret = '', totp_pending_secret = '', totp_enabled = 0, totp_last_step = 0
+WHERE id = ?
+`, userID)
+	if err != nil {
+		return fmt.Errorf("disable totp: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+	if _, err := tx.Exec(`DELETE FROM user_recovery_codes WHERE user_id = ?`, userID); err != nil {
+		return fmt.Errorf("delete recovery codes: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit totp disable: %w", err)
+	}
+	return nil
+}
+
+// VerifySecondFactor checks a TOTP code or, failing that, consumes a recovery
+// code. A TOTP code is accepted once: replaying it, even within its period,
+// fails. usedRecovery tells the caller a recovery code was spent.
+func (s *Store) VerifySecondFactor(userID int64, code string, now time.Time) (usedRecovery bool, err error) {
+	var (
+		secret   string
+		enabled  int
+		lastStep int64
+	)
+	err = s.db.QueryRow(`SELECT totp_secret, totp_enabled, totp_last_step FROM users WHERE id = ?`, userID).Scan(&secret, &enabled, &lastStep)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, ErrNotFound
+	}
+	if err != nil {
+		return false, fmt.Errorf("get totp secret: %w", err)
+	}
+	if enabled != 1 {
+		return false, ErrTwoFactorNotEnabled
+	}
+
+	code = strings.TrimSpace(code)
+	if step, ok := validateTOTP(secret, code, now); ok {
+		if step <= lastStep {
+			return false, ErrInvalidTwoFactorCode
+		}
+		res, err := s.db.Exec(`UPDATE users SET totp_last_step = ? WHERE id = ? AND totp_last_step < ?`, step, userID, step)
+		if err != nil {
+			return false, fmt.Errorf("save totp step: %w", err)
+		}
+		if n, err := res.RowsAffected(); err == nil && n == 0 {
+			return false, ErrInvalidTwoFactorCode
+		}
+		return false, nil
+	}
+
+	res, err := s.db.Exec(`
+UPDATE user_recovery_codes
+SET used_at = ?
+WHERE user_id = ? AND code_hash = ? AND used_at IS NULL
+`, now.UTC(), userID, hashRecoveryCode(code))
+	if err != nil {
+		return false, fmt.Errorf("use recovery code: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return false, ErrInvalidTwoFactorCode
+	}
+	return true, nil
+}
+
+// RequireAdminTwoFactor reports whether admins must have TOTP enabled. An
+// admin here is any role that can manage users (see AdminTwoFactorApplies).
+func (s *Store) RequireAdminTwoFactor() (bool, error) {
+	var value string
+	err := s.db.QueryRow(`SELECT value FROM auth_settings WHERE key = ?`, settingRequireAdminTwoFactor).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("get auth setting: %w", err)
+	}
+	return value == "1", nil
+}
+
+func (s *Store) SetRequireAdminTwoFactor(required bool) error {
+	value := "0"
+	if required {
+		value = "1"
+	}
+	_, err := s.db.Exec(`
+INSERT INTO auth_settings (key, value) VALUES (?, ?)
+ON CONFLICT(key) DO UPDATE SET value = excluded.value
+`, settingRequireAdminTwoFactor, value)
+	if err != nil {
+		return fmt.Errorf("save auth setting: %w", err)
+	}
+	return nil
+}
+
+// AdminTwoFactorApplies reports whether the admin 2FA policy covers role.
+// It follows users:manage rather than the role name, since a custom role
+// with that permission can grant itself everything else.
+func (s *Store) AdminTwoFactorApplies(role Role) bool {
+	return s.HasPermission(role, PermUsersManage)
+}
+
+// TwoFactorEnrollmentRequired reports whether user is an admin without TOTP
+// while the admin policy requires it. Such a user may only enroll.
+func (s *Store) TwoFactorEnrollmentRequired(user User) (bool, error) {
+	if !s.AdminTwoFactorApplies(user.Role) {
+		return false, nil
+	}
+	required, err := s.RequireAdminTwoFactor()
+	if err != nil || !required {
+		return false, err
+	}
+	status, err := s.TwoFactorStatus(user.ID)
+	if err != nil {
+		return false, err
+	}
+	return !status.Enabled, nil
+}
+
+func replaceRecoveryCodes(tx *sql.Tx, userID int64) ([]string, error) {
+	if _, err := tx.Exec(`DELETE FROM user_recovery_codes WHERE user_id = ?`, userID); err != nil {
+		return nil, fmt.Errorf("delete recovery codes: %w", err)
+	}
+	codes := make([]string, 0, RecoveryCodeCount)
+	for i := 0; i < RecoveryCodeCount; i++ {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := tx.Exec(`INSERT INTO user_recovery_codes (user_id, code_hash) VALUES (?, ?)`, userID, hashRecoveryCode(code)); err != nil {
+			return nil, fmt.Errorf("insert recovery code: %w", err)
+		}
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
+
+// generateRecoveryCode returns 64 random bits as "xxxx-xxxx-xxxx-xxxx".
+func generateRecoveryCode() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate recovery code: %w", err)
+	}
+	raw := hex.EncodeToString(b)
+	return raw[0:4] + "-" + raw[4:8] + "-" + raw[8:12] + "-" + raw[12:16], nil
+}
+
+// hashRecoveryCode hashes a recovery code after dropping case, spaces and
+// dashes. The codes are random, so a plain SHA-256 is enough.
+func hashRecoveryCode(code string) string {
+	normalized := strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(strings.TrimSpace(code)))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,107 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTwoFactorEnrollmentAndVerification(t *testing.T) {
+	store := newTestStore(t)
+	user, err := store.CreateUser("alice", "secret123", RoleAdmin)
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	now := time.Unix(1_700_000_000, 0)
+
+	if _, err := store.ConfirmTOTPEnrollment(user.ID, "123456", now); !errors.Is(err, ErrTwoFactorNotStarted) {
+		t.Fatalf("ConfirmTOTPEnrollment() before setup error = %v", err)
+	}
+	secret, err := store.BeginTOTPEnrollment(user.ID)
+	if err != nil {
+		t.Fatalf("BeginTOTPEnrollment() error = %v", err)
+	}
+	if _, err := store.ConfirmTOTPEnrollment(user.ID, "000000", now); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Fatalf("ConfirmTOTPEnrollment() wrong code error = %v", err)
+	}
+	code, _ := TOTPCode(secret, now)
+	recovery, err := store.ConfirmTOTPEnrollment(user.ID, code, now)
+	if err != nil {
+		t.Fatalf("ConfirmTOTPEnrollment() error = %v", err)
+	}
+	if len(recovery) != RecoveryCodeCount {
+		t.Fatalf("recovery codes = %d, want %d", len(recovery), RecoveryCodeCount)
+	}
+	if authed, _ := store.Authenticate("alice", "secret123"); !authed.TwoFactorEnabled {
+		t.Fatalf("Authenticate() user = %+v, want two_factor_enabled", authed)
+	}
+
+	// The enrollment code was consumed; the next period's code works once.
+	if _, err := store.VerifySecondFactor(user.ID, code, now); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Fatalf("replayed code error = %v", err)
+	}
+	next, _ := TOTPCode(secret, now.Add(30*time.Second))
+	if used, err := store.VerifySecondFactor(user.ID, next, now.Add(30*time.Second)); err != nil || used {
+		t.Fatalf("VerifySecondFactor(totp) = %v, %v", used, err)
+	}
+
+	// Recovery codes are accepted once, ignoring case and dashes.
+	if used, err := store.VerifySecondFactor(user.ID, " "+recovery[0]+" ", now); err != nil || !used {
+		t.Fatalf("VerifySecondFactor(recovery) = %v, %v", used, err)
+	}
+	if _, err := store.VerifySecondFactor(user.ID, recovery[0], now); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Fatalf("reused recovery code error = %v", err)
+	}
+	status, err := store.TwoFactorStatus(user.ID)
+	if err != nil || !status.Enabled || status.RecoveryCodesRemaining != RecoveryCodeCount-1 {
+		t.Fatalf("TwoFactorStatus() = %+v, %v", status, err)
+	}
+
+	if err := store.DisableTOTP(user.ID); err != nil {
+		t.Fatalf("DisableTOTP() error = %v", err)
+	}
+	if status, _ := store.TwoFactorStatus(user.ID); status.Enabled || status.RecoveryCodesRemaining != 0 {
+		t.Fatalf("status after disable = %+v", status)
+	}
+}
+
+func TestTwoFactorEnrollmentRequiredForAdmins(t *testing.T) {
+	store := newTestStore(t)
+	admin, _ := store.CreateUser("admin", "secret123", RoleAdmin)
+	viewer, _ := store.CreateUser("viewer", "secret123", RoleViewer)
+
+	if required, _ := store.TwoFactorEnrollmentRequired(admin); required {
+		t.Fatalf("enrollment required without the policy")
+	}
+	if err := store.SetRequireAdminTwoFactor(true); err != nil {
+		t.Fatalf("SetRequireAdminTwoFactor() error = %v", err)
+	}
+	if required, _ := store.TwoFactorEnrollmentRequired(admin); !required {
+		t.Fatalf("admin without 2FA not required to enroll")
+	}
+	if required, _ := store.TwoFactorEnrollmentRequired(viewer); required {
+		t.Fatalf("viewer required to enroll")
+	}
+}
+
+func TestTwoFactorEnrollmentRequiredFollowsUsersManage(t *testing.T) {
+	store := newTestStore(t)
+	if _, err := store.CreateRole("useradmin", "Manages accounts", []Permission{PermUsersManage}); err != nil {
+		t.Fatalf("CreateRole() error = %v", err)
+	}
+	if _, err := store.CreateRole("alerter", "Manages alerts", []Permission{PermAlertsManage}); err != nil {
+		t.Fatalf("CreateRole() error = %v", err)
+	}
+	manager, _ := store.CreateUser("manager", "secret123", "useradmin")
+	alerter, _ := store.CreateUser("alerter", "secret123", "alerter")
+	if err := store.SetRequireAdminTwoFactor(true); err != nil {
+		t.Fatalf("SetRequireAdminTwoFactor() error = %v", err)
+	}
+
+	if required, _ := store.TwoFactorEnrollmentRequired(manager); !required {
+		t.Fatalf("custom users:manage role not required to enroll")
+	}
+	if required, _ := store.TwoFactorEnrollmentRequired(alerter); required {
+		t.Fatalf("custom role without users:manage required to enroll")
+	}
+}
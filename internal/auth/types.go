@@ -5,14 +5,16 @@ import "time"
 type Role string
 
 const (
-	RoleAdmin  Role = "admin"
-	RoleViewer Role = "viewer"
+	RoleAdmin    Role = "admin"
+	RoleOperator Role = "operator"
+	RoleViewer   Role = "viewer"
 )
 
 type User struct {
-	ID       int64  `json:"id"`
-	Username string `json:"username"`
-	Role     Role   `json:"role"`
+	ID               int64  `json:"id"`
+	Username         string `json:"username"`
+	Role             Role   `json:"role"`
+	TwoFactorEnabled bool   `json:"two_factor_enabled"`
 }
 
 type UserAuditEntry struct {
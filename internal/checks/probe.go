@@ -0,0 +1,112 @@
+package checks
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+)
+
+// maxBodyBytes is how much of an HTTP response is searched for BodyContains.
+const maxBodyBytes = 1 << 20
+
+// Prober runs single probes. The zero value is not usable; use NewProber.
+type Prober struct {
+	client *http.Client
+	dialer *net.Dialer
+	now    func() time.Time
+}
+
+func NewProber() *Prober {
+	return &Prober{
+		client: &http.Client{},
+		dialer: &net.Dialer{},
+		now:    time.Now,
+	}
+}
+
+// Probe runs c once and reports the outcome. The probe is bounded by
+// c.TimeoutSec as well as ctx.
+func (p *Prober) Probe(ctx context.Context, c Check) Result {
+	timeout := time.Duration(c.TimeoutSec) * time.Second
+	if timeout <= 0 {
+		timeout = time.Duration(defaultTimeoutSec) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := p.now()
+	res := Result{CheckID: c.ID, CheckedAt: start.UTC()}
+	var err error
+	switch c.Kind {
+	case KindTCP:
+		err = p.probeTCP(ctx, c)
+	default:
+		err = p.probeHTTP(ctx, c, &res)
+	}
+	res.LatencyMs = float64(p.now().Sub(start).Microseconds()) / 1000
+	if err == nil && c.MaxLatencyMs > 0 && res.LatencyMs > float64(c.MaxLatencyMs) {
+		err = fmt.Errorf("latency %.0fms exceeds %dms", res.LatencyMs, c.MaxLatencyMs)
+	}
+	if err == nil && res.TLSExpiresAt != nil && c.TLSExpiryDays > 0 {
+		left := res.TLSExpiresAt.Sub(start)
+		if left < time.Duration(c.TLSExpiryDays)*24*time.Hour {
+			err = fmt.Errorf("TLS certificate expires in %.1f days", left.Hours()/24)
+		}
+	}
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	res.OK = true
+	return res
+}
+
+func (p *Prober) probeTCP(ctx context.Context, c Check) error {
+	conn, err := p.dialer.DialContext(ctx, "tcp", c.Target)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+func (p *Prober) probeHTTP(ctx context.Context, c Check, res *Result) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.Target, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", "QuickVPS-Check/1")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	res.StatusCode = resp.StatusCode
+	if resp.TLS != nil && len(resp.TLS.PeerCertificates) > 0 {
+		notAfter := resp.TLS.PeerCertificates[0].NotAfter.UTC()
+		res.TLSExpiresAt = &notAfter
+	}
+
+	if c.ExpectStatus != 0 {
+		if resp.StatusCode != c.ExpectStatus {
+			return fmt.Errorf("status %d, want %d", resp.StatusCode, c.ExpectStatus)
+		}
+	} else if resp.StatusCode < 200 || resp.StatusCode > 399 {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+
+	if c.BodyContains != "" {
+		body, err := io.ReadAll(io.LimitReader(resp.Body, maxBodyBytes))
+		if err != nil {
+			return fmt.Errorf("read body: %w", err)
+		}
+		if !bytes.Contains(body, []byte(c.BodyContains)) {
+			return fmt.Errorf("body does not contain %q", c.BodyContains)
+		}
+	}
+	return nil
+}
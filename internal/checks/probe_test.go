@@ -0,0 +1,102 @@
+package checks
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProbeHTTP(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/broken" {
+			http.Error(w, "bad gateway", http.StatusBadGateway)
+			return
+		}
+		_, _ = w.Write([]byte("status: healthy"))
+	}))
+	defer srv.Close()
+
+	p := NewProber()
+	ctx := context.Background()
+	base := Check{Kind: KindHTTP, Target: srv.URL + "/health", TimeoutSec: 5}
+
+	if res := p.Probe(ctx, base); !res.OK || res.StatusCode != 200 || res.Error != "" {
+		t.Fatalf("healthy probe = %+v", res)
+	}
+
+	withBody := base
+	withBody.BodyContains = "healthy"
+	if res := p.Probe(ctx, withBody); !res.OK {
+		t.Fatalf("body probe = %+v", res)
+	}
+	withBody.BodyContains = "degraded"
+	if res := p.Probe(ctx, withBody); res.OK || !strings.Contains(res.Error, "body") {
+		t.Fatalf("missing body probe = %+v", res)
+	}
+
+	broken := base
+	broken.Target = srv.URL + "/broken"
+	if res := p.Probe(ctx, broken); res.OK || res.StatusCode != http.StatusBadGateway {
+		t.Fatalf("502 probe = %+v", res)
+	}
+	broken.ExpectStatus = http.StatusBadGateway
+	if res := p.Probe(ctx, broken); !res.OK {
+		t.Fatalf("expected-status probe = %+v", res)
+	}
+}
+
+func TestProbeHTTPLatencyLimit(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+	}))
+	defer srv.Close()
+
+	res := NewProber().Probe(context.Background(), Check{Kind: KindHTTP, Target: srv.URL, TimeoutSec: 5, MaxLatencyMs: 10})
+	if res.OK || !strings.Contains(res.Error, "latency") || res.LatencyMs < 50 {
+		t.Fatalf("slow probe = %+v", res)
+	}
+}
+
+func TestProbeTLSExpiry(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	p := NewProber()
+	p.client = srv.Client()
+	cert := srv.Certificate()
+	c := Check{Kind: KindHTTP, Target: srv.URL, TimeoutSec: 5, TLSExpiryDays: 14}
+
+	res := p.Probe(context.Background(), c)
+	if !res.OK || res.TLSExpiresAt == nil || !res.TLSExpiresAt.Equal(cert.NotAfter) {
+		t.Fatalf("TLS probe = %+v, want expiry %s", res, cert.NotAfter)
+	}
+
+	// Pretend the certificate is about to expire.
+	p.now = func() time.Time { return cert.NotAfter.Add(-72 * time.Hour) }
+	res = p.Probe(context.Background(), c)
+	if res.OK || !strings.Contains(res.Error, "expires in 3.0 days") {
+		t.Fatalf("expiring TLS probe = %+v", res)
+	}
+}
+
+func TestProbeTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen() error = %v", err)
+	}
+	addr := ln.Addr().String()
+
+	p := NewProber()
+	c := Check{Kind: KindTCP, Target: addr, TimeoutSec: 2}
+	if res := p.Probe(context.Background(), c); !res.OK {
+		t.Fatalf("open port probe = %+v", res)
+	}
+	_ = ln.Close()
+	if res := p.Probe(context.Background(), c); res.OK || res.Error == "" {
+		t.Fatalf("closed port probe = %+v", res)
+	}
+}
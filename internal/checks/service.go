@@ -0,0 +1,454 @@
+package checks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"quickvps/internal/alerts"
+)
+
+const (
+	defaultIntervalSec   = 60
+	defaultTimeoutSec    = 10
+	defaultTLSExpiryDays = 14
+	defaultFailThreshold = 2
+	minIntervalSec       = 10
+	maxIntervalSec       = 24 * 60 * 60
+	maxTimeoutSec        = 60
+	maxFailThreshold     = 10
+	maxChecks            = 100
+
+	// ResultRetention is how long probe results are kept.
+	ResultRetention = 30 * 24 * time.Hour
+	schedulerTick   = time.Second
+	cleanupEvery    = time.Hour
+)
+
+// Alerter receives check state changes; *alerts.Service implements it.
+type Alerter interface {
+	NotifyExternal(ctx context.Context, a alerts.ExternalAlert) (alerts.Event, error)
+}
+
+// checkRuntime is the in-memory scheduling state of one check.
+type checkRuntime struct {
+	failures int
+	nextRun  time.Time
+	running  bool
+}
+
+type Service struct {
+	mu       sync.Mutex
+	store    *Store
+	prober   *Prober
+	alerter  Alerter
+	checks   []Check
+	runtimes map[int64]*checkRuntime
+
+	lastCleanup time.Time
+}
+
+func NewService(store *Store, alerter Alerter) (*Service, error) {
+	if store == nil {
+		return nil, errors.New("checks store is required")
+	}
+	checks, err := store.ListChecks()
+	if err != nil {
+		return nil, err
+	}
+	runtimes := make(map[int64]*checkRuntime, len(checks))
+	for _, c := range checks {
+		rt := &checkRuntime{}
+		if c.State == StateDown {
+			rt.failures = c.FailThreshold
+		}
+		runtimes[c.ID] = rt
+	}
+	return &Service{
+		store:    store,
+		prober:   NewProber(),
+		alerter:  alerter,
+		checks:   checks,
+		runtimes: runtimes,
+	}, nil
+}
+
+// Run probes every enabled check when it is due until ctx is done.
+func (s *Service) Run(ctx context.Context) {
+	ticker := time.NewTicker(schedulerTick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.runDue(ctx, now.UTC())
+		}
+	}
+}
+
+func (s *Service) runDue(ctx context.Context, now time.Time) {
+	s.mu.Lock()
+	var due []Check
+	for _, c := range s.checks {
+		rt := s.runtimes[c.ID]
+		if !c.Enabled || rt.running || now.Before(rt.nextRun) {
+			continue
+		}
+		rt.running = true
+		rt.nextRun = now.Add(time.Duration(c.IntervalSec) * time.Second)
+		due = append(due, c)
+	}
+	cleanup := now.Sub(s.lastCleanup) >= cleanupEvery
+	if cleanup {
+		s.lastCleanup = now
+	}
+	s.mu.Unlock()
+
+	for _, c := range due {
+		go s.execute(ctx, c)
+	}
+	if cleanup {
+		_ = s.store.CleanupResults(now.Add(-ResultRetention))
+	}
+}
+
+// execute probes c, records the result and applies the state change.
+func (s *Service) execute(ctx context.Context, c Check) Result {
+	res := s.prober.Probe(ctx, c)
+	if id, err := s.store.SaveResult(res); err == nil {
+		res.ID = id
+	}
+
+	s.mu.Lock()
+	idx := s.checkIndex(c.ID)
+	if idx < 0 {
+		// Deleted while the probe ran.
+		s.mu.Unlock()
+		return res
+	}
+	rt := s.runtimes[c.ID]
+	rt.running = false
+	if res.OK {
+		rt.failures = 0
+	} else {
+		rt.failures++
+	}
+
+	current := s.checks[idx]
+	next := current.State
+	switch {
+	case res.OK:
+		next = StateUp
+	case rt.failures >= current.FailThreshold:
+		next = StateDown
+	}
+	changed := next != current.State
+	if changed {
+		since := res.CheckedAt
+		s.checks[idx].State = next
+		s.checks[idx].StateSince = &since
+		_ = s.store.SetState(c.ID, next, since)
+	}
+	failures := rt.failures
+	s.mu.Unlock()
+
+	switch {
+	case changed && next == StateDown:
+		s.notify(ctx, current, alerts.LevelCritical, res, failures)
+	case changed && current.State == StateDown:
+		s.notify(ctx, current, alerts.LevelRecovery, res, failures)
+	}
+	return res
+}
+
+func (s *Service) notify(ctx context.Context, c Check, level alerts.Level, res Result, failures int) {
+	if s.alerter == nil {
+		return
+	}
+	a := alerts.ExternalAlert{
+		Level:     level,
+		Source:    fmt.Sprintf("Check %q", c.Name),
+		Series:    "check:" + c.Name,
+		ValueText: "down",
+		Time:      res.CheckedAt,
+	}
+	if level == alerts.LevelRecovery {
+		a.Value, a.ValueText = 1, "up"
+		a.Detail = fmt.Sprintf("Target=%s State=UP Latency=%.0fms", c.Target, res.LatencyMs)
+	} else {
+		a.Detail = fmt.Sprintf("Target=%s State=DOWN Error=%q Failures=%d", c.Target, res.Error, failures)
+	}
+	_, _ = s.alerter.NotifyExternal(ctx, a)
+}
+
+// RunNow probes a check immediately, outside its schedule.
+func (s *Service) RunNow(ctx context.Context, id int64) (Result, error) {
+	s.mu.Lock()
+	idx := s.checkIndex(id)
+	if idx < 0 {
+		s.mu.Unlock()
+		return Result{}, ErrCheckNotFound
+	}
+	c := s.checks[idx]
+	s.mu.Unlock()
+	return s.execute(ctx, c), nil
+}
+
+func (s *Service) List() ([]CheckStatus, error) {
+	s.mu.Lock()
+	checks := append([]Check(nil), s.checks...)
+	s.mu.Unlock()
+
+	out := make([]CheckStatus, 0, len(checks))
+	now := time.Now().UTC()
+	for _, c := range checks {
+		st, err := s.status(c, now)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, st)
+	}
+	return out, nil
+}
+
+func (s *Service) Get(id int64) (CheckStatus, error) {
+	s.mu.Lock()
+	idx := s.checkIndex(id)
+	if idx < 0 {
+		s.mu.Unlock()
+		return CheckStatus{}, ErrCheckNotFound
+	}
+	c := s.checks[idx]
+	s.mu.Unlock()
+	return s.status(c, time.Now().UTC())
+}
+
+func (s *Service) status(c Check, now time.Time) (CheckStatus, error) {
+	st := CheckStatus{Check: c}
+	last, err := s.store.ListResults(c.ID, 1, 0)
+	if err != nil {
+		return CheckStatus{}, err
+	}
+	if len(last) > 0 {
+		st.LastResult = &last[0]
+	}
+	for _, w := range []struct {
+		dst    **float64
+		window time.Duration
+	}{
+		{&st.Uptime24h, 24 * time.Hour},
+		{&st.Uptime7d, 7 * 24 * time.Hour},
+		{&st.Uptime30d, 30 * 24 * time.Hour},
+	} {
+		pct, ok, err := s.store.Uptime(c.ID, now.Add(-w.window))
+		if err != nil {
+			return CheckStatus{}, err
+		}
+		if ok {
+			*w.dst = &pct
+		}
+	}
+	return st, nil
+}
+
+func (s *Service) Results(id int64, limit int, beforeID int64) ([]Result, error) {
+	s.mu.Lock()
+	idx := s.checkIndex(id)
+	s.mu.Unlock()
+	if idx < 0 {
+		return nil, ErrCheckNotFound
+	}
+	return s.store.ListResults(id, limit, beforeID)
+}
+
+func defaultCheck() Check {
+	return Check{
+		Kind:          KindHTTP,
+		Enabled:       true,
+		IntervalSec:   defaultIntervalSec,
+		TimeoutSec:    defaultTimeoutSec,
+		TLSExpiryDays: defaultTLSExpiryDays,
+		FailThreshold: defaultFailThreshold,
+		State:         StatePending,
+	}
+}
+
+func (s *Service) Create(in CheckInput) (Check, error) {
+	c := defaultCheck()
+	applyInput(&c, in)
+	if err := validateCheck(c); err != nil {
+		return Check{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.checks) >= maxChecks {
+		return Check{}, fmt.Errorf("at most %d checks are allowed", maxChecks)
+	}
+	if err := s.checkNameFree(c.Name, 0); err != nil {
+		return Check{}, err
+	}
+	created, err := s.store.CreateCheck(c)
+	if err != nil {
+		return Check{}, err
+	}
+	s.checks = append(s.checks, created)
+	s.runtimes[created.ID] = &checkRuntime{}
+	return created, nil
+}
+
+// Update changes a check's settings. Its state starts over as pending and it
+// is probed on the next scheduler tick.
+func (s *Service) Update(id int64, in CheckInput) (Check, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	idx := s.checkIndex(id)
+	if idx < 0 {
+		return Check{}, ErrCheckNotFound
+	}
+	c := s.checks[idx]
+	applyInput(&c, in)
+	if err := validateCheck(c); err != nil {
+		return Check{}, err
+	}
+	if err := s.checkNameFree(c.Name, id); err != nil {
+		return Check{}, err
+	}
+	updated, err := s.store.UpdateCheck(c)
+	if err != nil {
+		return Check{}, err
+	}
+	s.checks[idx] = updated
+	s.runtimes[id] = &checkRuntime{}
+	return updated, nil
+}
+
+func (s *Service) Delete(id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	idx := s.checkIndex(id)
+	if idx < 0 {
+		return ErrCheckNotFound
+	}
+	if err := s.store.DeleteCheck(id); err != nil {
+		return err
+	}
+	s.checks = append(s.checks[:idx], s.checks[idx+1:]...)
+	delete(s.runtimes, id)
+	return nil
+}
+
+// checkIndex must be called with s.mu held.
+func (s *Service) checkIndex(id int64) int {
+	for i, c := range s.checks {
+		if c.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// checkNameFree must be called with s.mu held.
+func (s *Service) checkNameFree(name string, exceptID int64) error {
+	for _, c := range s.checks {
+		if c.ID != exceptID && strings.EqualFold(c.Name, name) {
+			return fmt.Errorf("a check named %q already exists", name)
+		}
+	}
+	return nil
+}
+
+func applyInput(c *Check, in CheckInput) {
+	if in.Name != nil {
+		c.Name = strings.TrimSpace(*in.Name)
+	}
+	if in.Kind != nil {
+		c.Kind = Kind(strings.ToLower(strings.TrimSpace(string(*in.Kind))))
+	}
+	if in.Target != nil {
+		c.Target = strings.TrimSpace(*in.Target)
+	}
+	if in.Enabled != nil {
+		c.Enabled = *in.Enabled
+	}
+	if in.IntervalSec != nil {
+		c.IntervalSec = *in.IntervalSec
+	}
+	if in.TimeoutSec != nil {
+		c.TimeoutSec = *in.TimeoutSec
+	}
+	if in.ExpectStatus != nil {
+		c.ExpectStatus = *in.ExpectStatus
+	}
+	if in.BodyContains != nil {
+		c.BodyContains = *in.BodyContains
+	}
+	if in.MaxLatencyMs != nil {
+		c.MaxLatencyMs = *in.MaxLatencyMs
+	}
+	if in.TLSExpiryDays != nil {
+		c.TLSExpiryDays = *in.TLSExpiryDays
+	}
+	if in.FailThreshold != nil {
+		c.FailThreshold = *in.FailThreshold
+	}
+}
+
+func validateCheck(c Check) error {
+	if c.Name == "" || len(c.Name) > 100 {
+		return errors.New("name is required and must be at most 100 characters")
+	}
+	switch c.Kind {
+	case KindHTTP:
+		u, err := url.Parse(c.Target)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return errors.New("target must be an http:// or https:// URL")
+		}
+		if c.ExpectStatus != 0 && (c.ExpectStatus < 100 || c.ExpectStatus > 599) {
+			return errors.New("expect_status must be 0 or an HTTP status code")
+		}
+		if len(c.BodyContains) > 1000 {
+			return errors.New("body_contains must be at most 1000 characters")
+		}
+	case KindTCP:
+		host, port, err := net.SplitHostPort(c.Target)
+		if err != nil || host == "" {
+			return errors.New("target must be host:port")
+		}
+		if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+			return errors.New("target port must be between 1 and 65535")
+		}
+		if c.ExpectStatus != 0 || c.BodyContains != "" {
+			return errors.New("expect_status and body_contains apply to http checks only")
+		}
+	default:
+		return errors.New("kind must be http or tcp")
+	}
+	if c.IntervalSec < minIntervalSec || c.IntervalSec > maxIntervalSec {
+		return fmt.Errorf("interval_sec must be between %d and %d", minIntervalSec, maxIntervalSec)
+	}
+	if c.TimeoutSec < 1 || c.TimeoutSec > maxTimeoutSec || c.TimeoutSec > c.IntervalSec {
+		return fmt.Errorf("timeout_sec must be between 1 and %d and at most interval_sec", maxTimeoutSec)
+	}
+	if c.MaxLatencyMs < 0 || c.MaxLatencyMs > c.TimeoutSec*1000 {
+		return errors.New("max_latency_ms must be between 0 and the timeout")
+	}
+	if c.TLSExpiryDays < 0 || c.TLSExpiryDays > 365 {
+		return errors.New("tls_expiry_days must be between 0 and 365")
+	}
+	if c.FailThreshold < 1 || c.FailThreshold > maxFailThreshold {
+		return fmt.Errorf("fail_threshold must be between 1 and %d", maxFailThreshold)
+	}
+	return nil
+}
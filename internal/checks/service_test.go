@@ -0,0 +1,144 @@
+package checks
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"quickvps/internal/alerts"
+)
+
+type recordingAlerter struct {
+	mu     sync.Mutex
+	alerts []alerts.ExternalAlert
+}
+
+func (r *recordingAlerter) NotifyExternal(_ context.Context, a alerts.ExternalAlert) (alerts.Event, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.alerts = append(r.alerts, a)
+	return alerts.Event{}, nil
+}
+
+func newServiceForTests(t *testing.T) (*Service, *Store, *recordingAlerter) {
+	t.Helper()
+	store, err := NewStore(filepath.Join(t.TempDir(), "checks.db"))
+	if err != nil {
+		t.Fatalf("NewStore() error = %v", err)
+	}
+	t.Cleanup(func() {
+		_ = store.Close()
+	})
+	alerter := &recordingAlerter{}
+	svc, err := NewService(store, alerter)
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	return svc, store, alerter
+}
+
+func ptr[T any](v T) *T { return &v }
+
+func TestServiceDownAndRecovery(t *testing.T) {
+	var failing atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing.Load() {
+			http.Error(w, "bad gateway", http.StatusBadGateway)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	svc, store, alerter := newServiceForTests(t)
+	ctx := context.Background()
+	c, err := svc.Create(CheckInput{Name: ptr("web"), Target: ptr(srv.URL), FailThreshold: ptr(2)})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if c.State != StatePending || c.IntervalSec != defaultIntervalSec {
+		t.Fatalf("created check = %+v", c)
+	}
+
+	run := func() Result {
+		t.Helper()
+		res, err := svc.RunNow(ctx, c.ID)
+		if err != nil {
+			t.Fatalf("RunNow() error = %v", err)
+		}
+		return res
+	}
+
+	run()
+	failing.Store(true)
+	run()
+	if len(alerter.alerts) != 0 {
+		t.Fatalf("alerted before the fail threshold: %+v", alerter.alerts)
+	}
+	run()
+	if len(alerter.alerts) != 1 || alerter.alerts[0].Level != alerts.LevelCritical || alerter.alerts[0].Series != "check:web" {
+		t.Fatalf("alerts = %+v, want one critical", alerter.alerts)
+	}
+	run()
+	if len(alerter.alerts) != 1 {
+		t.Fatal("alerted again while already down")
+	}
+
+	// The down state survives a restart, so recovery still notifies.
+	svc, err = NewService(store, alerter)
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	failing.Store(false)
+	run()
+	if len(alerter.alerts) != 2 || alerter.alerts[1].Level != alerts.LevelRecovery {
+		t.Fatalf("alerts = %+v, want a recovery", alerter.alerts)
+	}
+
+	st, err := svc.Get(c.ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if st.State != StateUp || st.LastResult == nil || !st.LastResult.OK {
+		t.Fatalf("status = %+v", st)
+	}
+	if st.Uptime24h == nil || *st.Uptime24h != 40 {
+		t.Fatalf("uptime 24h = %v, want 40", st.Uptime24h)
+	}
+	results, err := svc.Results(c.ID, 10, 0)
+	if err != nil {
+		t.Fatalf("Results() error = %v", err)
+	}
+	if len(results) != 5 || results[0].StatusCode != 200 || results[1].StatusCode != http.StatusBadGateway {
+		t.Fatalf("results = %+v", results)
+	}
+}
+
+func TestServiceValidation(t *testing.T) {
+	svc, _, _ := newServiceForTests(t)
+	for name, in := range map[string]CheckInput{
+		"name":      {Target: ptr("https://example.com")},
+		"scheme":    {Name: ptr("a"), Target: ptr("ftp://example.com")},
+		"tcp":       {Name: ptr("a"), Kind: ptr(KindTCP), Target: ptr("example.com")},
+		"tcp body":  {Name: ptr("a"), Kind: ptr(KindTCP), Target: ptr("example.com:443"), BodyContains: ptr("x")},
+		"kind":      {Name: ptr("a"), Kind: ptr(Kind("icmp")), Target: ptr("example.com")},
+		"interval":  {Name: ptr("a"), Target: ptr("https://example.com"), IntervalSec: ptr(1)},
+		"timeout":   {Name: ptr("a"), Target: ptr("https://example.com"), IntervalSec: ptr(10), TimeoutSec: ptr(20)},
+		"threshold": {Name: ptr("a"), Target: ptr("https://example.com"), FailThreshold: ptr(0)},
+	} {
+		if _, err := svc.Create(in); err == nil {
+			t.Errorf("%s: expected validation error", name)
+		}
+	}
+
+	if _, err := svc.Create(CheckInput{Name: ptr("db"), Kind: ptr(KindTCP), Target: ptr("127.0.0.1:5432")}); err != nil {
+		t.Fatalf("Create(tcp) error = %v", err)
+	}
+	if _, err := svc.Create(CheckInput{Name: ptr("DB"), Target: ptr("https://example.com")}); err == nil {
+		t.Fatal("expected duplicate name error")
+	}
+}
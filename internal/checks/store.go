@@ -0,0 +1,328 @@
+package checks
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+var ErrCheckNotFound = errors.New("check not found")
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(path string) (*Store, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("open sqlite: %w", err)
+	}
+
+	if _, err := db.Exec(`PRAGMA journal_mode = WAL;`); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("set sqlite journal mode: %w", err)
+	}
+
+	s := &Store{db: db}
+	if err := s.migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *Store) Close() error {
+	if s == nil || s.db == nil {
+		return nil
+	}
+	return s.db.Close()
+}
+
+func (s *Store) migrate() error {
+	const schema = `
+CREATE TABLE IF NOT EXISTS checks (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  name TEXT NOT NULL UNIQUE,
+  kind TEXT NOT NULL,
+  target TEXT NOT NULL,
+  enabled INTEGER NOT NULL DEFAULT 1,
+  interval_sec INTEGER NOT NULL,
+  timeout_sec INTEGER NOT NULL,
+  expect_status INTEGER NOT NULL DEFAULT 0,
+  body_contains TEXT NOT NULL DEFAULT '',
+  max_latency_ms INTEGER NOT NULL DEFAULT 0,
+  tls_expiry_days INTEGER NOT NULL DEFAULT 0,
+  fail_threshold INTEGER NOT NULL DEFAULT 1,
+  state TEXT NOT NULL DEFAULT 'pending',
+  state_since DATETIME,
+  created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS check_results (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  check_id INTEGER NOT NULL,
+  ok INTEGER NOT NULL,
+  status_code INTEGER NOT NULL DEFAULT 0,
+  latency_ms REAL NOT NULL,
+  error TEXT NOT NULL DEFAULT '',
+  tls_expires_at DATETIME,
+  checked_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_check_results_check_checked ON check_results(check_id, checked_at);
+`
+	if _, err := s.db.Exec(schema); err != nil {
+		return fmt.Errorf("migrate checks tables: %w", err)
+	}
+	return nil
+}
+
+const checkColumns = `id, name, kind, target, enabled, interval_sec, timeout_sec,
+  expect_status, body_contains, max_latency_ms, tls_expiry_days, fail_threshold,
+  state, state_since, created_at, updated_at`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func (s *Store) ListChecks() ([]Check, error) {
+	rows, err := s.db.Query(`SELECT ` + checkColumns + ` FROM checks ORDER BY id ASC`)
+	if err != nil {
+		return nil, fmt.Errorf("list checks: %w", err)
+	}
+	defer rows.Close()
+
+	checks := make([]Check, 0)
+	for rows.Next() {
+		c, err := scanCheck(rows)
+		if err != nil {
+			return nil, err
+		}
+		checks = append(checks, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate checks: %w", err)
+	}
+	return checks, nil
+}
+
+func (s *Store) GetCheck(id int64) (Check, error) {
+	c, err := scanCheck(s.db.QueryRow(`SELECT `+checkColumns+` FROM checks WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Check{}, ErrCheckNotFound
+	}
+	return c, err
+}
+
+func (s *Store) CreateCheck(c Check) (Check, error) {
+	result, err := s.db.Exec(`
+INSERT INTO checks(
+  name, kind, target, enabled, interval_sec, timeout_sec,
+  expect_status, body_contains, max_latency_ms, tls_expiry_days, fail_threshold
+)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+`,
+		c.Name, string(c.Kind), c.Target, boolToInt(c.Enabled), c.IntervalSec, c.TimeoutSec,
+		c.ExpectStatus, c.BodyContains, c.MaxLatencyMs, c.TLSExpiryDays, c.FailThreshold,
+	)
+	if err != nil {
+		return Check{}, fmt.Errorf("create check: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return Check{}, fmt.Errorf("create check last insert id: %w", err)
+	}
+	return s.GetCheck(id)
+}
+
+// UpdateCheck saves the settings of c and resets its state to pending.
+func (s *Store) UpdateCheck(c Check) (Check, error) {
+	result, err := s.db.Exec(`
+UPDATE checks
+SET
+  name = ?,
+  kind = ?,
+  target = ?,
+  enabled = ?,
+  interval_sec = ?,
+  timeout_sec = ?,
+  expect_status = ?,
+  body_contains = ?,
+  max_latency_ms = ?,
+  tls_expiry_days = ?,
+  fail_threshold = ?,
+  state = ?,
+  state_since = NULL,
+  updated_at = CURRENT_TIMESTAMP
+WHERE id = ?
+`,
+		c.Name, string(c.Kind), c.Target, boolToInt(c.Enabled), c.IntervalSec, c.TimeoutSec,
+		c.ExpectStatus, c.BodyContains, c.MaxLatencyMs, c.TLSExpiryDays, c.FailThreshold,
+		string(StatePending), c.ID,
+	)
+	if err != nil {
+		return Check{}, fmt.Errorf("update check: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return Check{}, ErrCheckNotFound
+	}
+	return s.GetCheck(c.ID)
+}
+
+// DeleteCheck removes a check and its results.
+func (s *Store) DeleteCheck(id int64) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin delete check tx: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	result, err := tx.Exec(`DELETE FROM checks WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("delete check: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrCheckNotFound
+	}
+	if _, err := tx.Exec(`DELETE FROM check_results WHERE check_id = ?`, id); err != nil {
+		return fmt.Errorf("delete check results: %w", err)
+	}
+	return tx.Commit()
+}
+
+func (s *Store) SetState(id int64, state State, since time.Time) error {
+	if _, err := s.db.Exec(`UPDATE checks SET state = ?, state_since = ? WHERE id = ?`, string(state), since.UTC(), id); err != nil {
+		return fmt.Errorf("update check state: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) SaveResult(r Result) (int64, error) {
+	var tlsExpiresAt any
+	if r.TLSExpiresAt != nil {
+		tlsExpiresAt = r.TLSExpiresAt.UTC()
+	}
+	result, err := s.db.Exec(`
+INSERT INTO check_results(check_id, ok, status_code, latency_ms, error, tls_expires_at, checked_at)
+VALUES (?, ?, ?, ?, ?, ?, ?)
+`, r.CheckID, boolToInt(r.OK), r.StatusCode, r.LatencyMs, r.Error, tlsExpiresAt, r.CheckedAt.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("save check result: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("save check result last insert id: %w", err)
+	}
+	return id, nil
+}
+
+// ListResults returns results of a check newest first, before beforeID when
+// it is positive.
+func (s *Store) ListResults(checkID int64, limit int, beforeID int64) ([]Result, error) {
+	if limit <= 0 || limit > 1000 {
+		limit = 100
+	}
+	query := `SELECT id, check_id, ok, status_code, latency_ms, error, tls_expires_at, checked_at
+FROM check_results WHERE check_id = ?`
+	args := []any{checkID}
+	if beforeID > 0 {
+		query += ` AND id < ?`
+		args = append(args, beforeID)
+	}
+	query += ` ORDER BY id DESC LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list check results: %w", err)
+	}
+	defer rows.Close()
+
+	results := make([]Result, 0)
+	for rows.Next() {
+		var (
+			r            Result
+			ok           int
+			tlsExpiresAt sql.NullTime
+		)
+		if err := rows.Scan(&r.ID, &r.CheckID, &ok, &r.StatusCode, &r.LatencyMs, &r.Error, &tlsExpiresAt, &r.CheckedAt); err != nil {
+			return nil, fmt.Errorf("scan check result: %w", err)
+		}
+		r.OK = ok == 1
+		if tlsExpiresAt.Valid {
+			t := tlsExpiresAt.Time
+			r.TLSExpiresAt = &t
+		}
+		results = append(results, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate check results: %w", err)
+	}
+	return results, nil
+}
+
+// Uptime returns the percentage of successful results of a check since from,
+// and false when there are none.
+func (s *Store) Uptime(checkID int64, from time.Time) (float64, bool, error) {
+	var okCount, total int
+	err := s.db.QueryRow(`
+SELECT COALESCE(SUM(ok), 0), COUNT(*)
+FROM check_results
+WHERE check_id = ? AND checked_at >= ?
+`, checkID, from.UTC()).Scan(&okCount, &total)
+	if err != nil {
+		return 0, false, fmt.Errorf("check uptime: %w", err)
+	}
+	if total == 0 {
+		return 0, false, nil
+	}
+	return float64(okCount) * 100 / float64(total), true, nil
+}
+
+func (s *Store) CleanupResults(before time.Time) error {
+	if _, err := s.db.Exec(`DELETE FROM check_results WHERE checked_at < ?`, before.UTC()); err != nil {
+		return fmt.Errorf("cleanup check results: %w", err)
+	}
+	return nil
+}
+
+func scanCheck(row rowScanner) (Check, error) {
+	var (
+		c          Check
+		kind       string
+		state      string
+		enabled    int
+		stateSince sql.NullTime
+	)
+	err := row.Scan(
+		&c.ID, &c.Name, &kind, &c.Target, &enabled, &c.IntervalSec, &c.TimeoutSec,
+		&c.ExpectStatus, &c.BodyContains, &c.MaxLatencyMs, &c.TLSExpiryDays, &c.FailThreshold,
+		&state, &stateSince, &c.CreatedAt, &c.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Check{}, err
+		}
+		return Check{}, fmt.Errorf("scan check: %w", err)
+	}
+	c.Kind = Kind(kind)
+	c.State = State(state)
+	c.Enabled = enabled == 1
+	if stateSince.Valid {
+		t := stateSince.Time
+		c.StateSince = &t
+	}
+	return c, nil
+}
+
+func boolToInt(v bool) int {
+	if v {
+		return 1
+	}
+	return 0
+}
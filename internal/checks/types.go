@@ -0,0 +1,86 @@
+package checks
+
+import "time"
+
+type Kind string
+
+const (
+	// KindHTTP requests an http:// or https:// URL.
+	KindHTTP Kind = "http"
+	// KindTCP opens a connection to host:port.
+	KindTCP Kind = "tcp"
+)
+
+type State string
+
+const (
+	StatePending State = "pending"
+	StateUp      State = "up"
+	StateDown    State = "down"
+)
+
+// Check is a synthetic probe run every IntervalSec. A probe fails when the
+// target is unreachable or any configured expectation is not met; the check
+// goes down after FailThreshold consecutive failures and up again on the
+// first success.
+type Check struct {
+	ID      int64  `json:"id"`
+	Name    string `json:"name"`
+	Kind    Kind   `json:"kind"`
+	Target  string `json:"target"`
+	Enabled bool   `json:"enabled"`
+
+	IntervalSec int `json:"interval_sec"`
+	TimeoutSec  int `json:"timeout_sec"`
+	// ExpectStatus is the required HTTP status; 0 accepts 200-399.
+	ExpectStatus int `json:"expect_status"`
+	// BodyContains must appear in the first MiB of the HTTP body.
+	BodyContains string `json:"body_contains"`
+	// MaxLatencyMs fails slower probes; 0 disables the limit.
+	MaxLatencyMs int `json:"max_latency_ms"`
+	// TLSExpiryDays fails HTTPS probes whose certificate expires sooner;
+	// 0 disables the check.
+	TLSExpiryDays int `json:"tls_expiry_days"`
+	FailThreshold int `json:"fail_threshold"`
+
+	State      State      `json:"state"`
+	StateSince *time.Time `json:"state_since,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+type CheckInput struct {
+	Name          *string `json:"name"`
+	Kind          *Kind   `json:"kind"`
+	Target        *string `json:"target"`
+	Enabled       *bool   `json:"enabled"`
+	IntervalSec   *int    `json:"interval_sec"`
+	TimeoutSec    *int    `json:"timeout_sec"`
+	ExpectStatus  *int    `json:"expect_status"`
+	BodyContains  *string `json:"body_contains"`
+	MaxLatencyMs  *int    `json:"max_latency_ms"`
+	TLSExpiryDays *int    `json:"tls_expiry_days"`
+	FailThreshold *int    `json:"fail_threshold"`
+}
+
+// Result is the outcome of one probe.
+type Result struct {
+	ID           int64      `json:"id"`
+	CheckID      int64      `json:"check_id"`
+	OK           bool       `json:"ok"`
+	StatusCode   int        `json:"status_code,omitempty"`
+	LatencyMs    float64    `json:"latency_ms"`
+	Error        string     `json:"error,omitempty"`
+	TLSExpiresAt *time.Time `json:"tls_expires_at,omitempty"`
+	CheckedAt    time.Time  `json:"checked_at"`
+}
+
+// CheckStatus is a check with its latest result and uptime. Uptime is the
+// percentage of successful probes in the window, nil without probes.
+type CheckStatus struct {
+	Check
+	LastResult *Result  `json:"last_result,omitempty"`
+	Uptime24h  *float64 `json:"uptime_24h,omitempty"`
+	Uptime7d   *float64 `json:"uptime_7d,omitempty"`
+	Uptime30d  *float64 `json:"uptime_30d,omitempty"`
+}
@@ -0,0 +1,149 @@
+package history
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"quickvps/internal/metrics"
+)
+
+var defaultSeries = []string{
+	metrics.SeriesCPUPercent,
+	metrics.SeriesMemoryPercent,
+	metrics.SeriesSwapPercent,
+}
+
+type Recorder struct {
+	store        *Store
+	retention    Retention
+	cleanupEvery time.Duration
+	now          func() time.Time
+
+	mu             sync.Mutex
+	lastCleanupRun time.Time
+}
+
+func NewRecorder(store *Store, retention Retention) *Recorder {
+	if retention == nil {
+		retention = DefaultRetention()
+	}
+	return &Recorder{
+		store:        store,
+		retention:    retention,
+		cleanupEvery: time.Hour,
+		now:          time.Now,
+	}
+}
+
+func (r *Recorder) Run(ctx context.Context, sub <-chan *metrics.Snapshot) {
+	if sub == nil {
+		return
+	}
+	cleanupTicker := time.NewTicker(r.cleanupEvery)
+	defer cleanupTicker.Stop()
+
+	r.cleanup()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-cleanupTicker.C:
+			r.cleanup()
+		case snap, ok := <-sub:
+			if !ok {
+				return
+			}
+			if snap == nil {
+				continue
+			}
+			if err := r.store.Record(snap.Timestamp, snap.Series()); err != nil {
+				log.Printf("history: %v", err)
+			}
+		}
+	}
+}
+
+func (r *Recorder) Retention() Retention {
+	return r.retention
+}
+
+// Query picks the finest tier whose bucket fits the step and whose retention
+// still covers q.From, then re-buckets rows to the effective step.
+func (r *Recorder) Query(q Query) (Result, error) {
+	now := r.now()
+	if q.To.IsZero() {
+		q.To = now
+	}
+	if q.From.IsZero() {
+		q.From = q.To.Add(-time.Hour)
+	}
+	if !q.From.Before(q.To) {
+		return Result{}, ErrInvalidRange
+	}
+	if len(q.Series) == 0 {
+		q.Series = defaultSeries
+	}
+
+	span := int64(q.To.Sub(q.From).Seconds())
+	step := q.StepSec
+	if step <= 0 {
+		step = span / defaultPoints
+	}
+	if minStep := (span + maxPoints - 1) / maxPoints; step < minStep {
+		step = minStep
+	}
+	if step < 1 {
+		step = 1
+	}
+
+	tier := r.pickTier(step, q.From, now)
+	size := tier.BucketSec()
+	step = ((step + size - 1) / size) * size
+
+	series, err := r.store.QueryTier(tier, step, q.From, q.To, q.Series)
+	if err != nil {
+		return Result{}, err
+	}
+
+	return Result{
+		From:    q.From.UTC(),
+		To:      q.To.UTC(),
+		StepSec: step,
+		Tier:    tier,
+		Series:  series,
+	}, nil
+}
+
+func (r *Recorder) pickTier(step int64, from time.Time, now time.Time) Tier {
+	chosen := TierRaw
+	for _, tier := range Tiers {
+		if tier.BucketSec() <= step {
+			chosen = tier
+		}
+	}
+	for i, tier := range Tiers {
+		if tier.BucketSec() < chosen.BucketSec() {
+			continue
+		}
+		keep := r.retention[tier]
+		if keep <= 0 || !from.Before(now.Add(-keep)) || i == len(Tiers)-1 {
+			return tier
+		}
+	}
+	return chosen
+}
+
+func (r *Recorder) cleanup() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.now()
+	if now.Sub(r.lastCleanupRun) < r.cleanupEvery {
+		return
+	}
+	r.lastCleanupRun = now
+	if err := r.store.Cleanup(r.retention, now); err != nil {
+		log.Printf("history: %v", err)
+	}
+}
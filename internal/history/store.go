@@ -0,0 +1,193 @@
+package history
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(path string) (*Store, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("open sqlite: %w", err)
+	}
+
+	if _, err := db.Exec(`PRAGMA journal_mode = WAL;`); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("set sqlite journal mode: %w", err)
+	}
+
+	s := &Store{db: db}
+	if err := s.migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *Store) Close() error {
+	if s == nil || s.db == nil {
+		return nil
+	}
+	return s.db.Close()
+}
+
+func (s *Store) migrate() error {
+	const schema = `
+CREATE TABLE IF NOT EXISTS metrics_history (
+  tier TEXT NOT NULL,
+  series TEXT NOT NULL,
+  bucket_ts INTEGER NOT NULL,
+  sum REAL NOT NULL,
+  count INTEGER NOT NULL,
+  min REAL NOT NULL,
+  max REAL NOT NULL,
+  PRIMARY KEY (tier, series, bucket_ts)
+);
+
+CREATE INDEX IF NOT EXISTS idx_metrics_history_tier_bucket ON metrics_history(tier, bucket_ts);
+`
+	if _, err := s.db.Exec(schema); err != nil {
+		return fmt.Errorf("migrate metrics history table: %w", err)
+	}
+	return nil
+}
+
+// Record writes one sample per series into every tier. Coarser tiers are
+// rolled up in place: sum/count/min/max of the bucket are merged on conflict.
+func (s *Store) Record(ts time.Time, values map[string]float64) error {
+	if len(values) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin history tx: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	stmt, err := tx.Prepare(`
+INSERT INTO metrics_history (tier, series, bucket_ts, sum, count, min, max)
+VALUES (?, ?, ?, ?, 1, ?, ?)
+ON CONFLICT(tier, series, bucket_ts) DO UPDATE SET
+  sum = sum + excluded.sum,
+  count = count + 1,
+  min = MIN(min, excluded.min),
+  max = MAX(max, excluded.max)
+`)
+	if err != nil {
+		return fmt.Errorf("prepare history insert: %w", err)
+	}
+	defer stmt.Close()
+
+	unix := ts.Unix()
+	for _, tier := range Tiers {
+		size := tier.BucketSec()
+		bucket := unix - unix%size
+		for name, v := range values {
+			if _, err := stmt.Exec(string(tier), name, bucket, v, v, v); err != nil {
+				return fmt.Errorf("record history %s/%s: %w", tier, name, err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit history tx: %w", err)
+	}
+	return nil
+}
+
+// QueryTier reads a tier between from and to (inclusive), re-bucketing rows
+// into stepSec-wide buckets. A requested name without a label also matches
+// every labelled series with that base ("net.recv_bps" → "net.recv_bps:eth0").
+func (s *Store) QueryTier(tier Tier, stepSec int64, from, to time.Time, names []string) ([]Series, error) {
+	if stepSec <= 0 {
+		stepSec = tier.BucketSec()
+	}
+
+	query := `
+SELECT series, (bucket_ts / ?) * ? AS b, SUM(sum), SUM(count), MIN(min), MAX(max)
+FROM metrics_history
+WHERE tier = ? AND bucket_ts >= ? AND bucket_ts <= ?
+`
+	args := []any{stepSec, stepSec, string(tier), from.Unix(), to.Unix()}
+
+	if len(names) > 0 {
+		clauses := make([]string, 0, len(names))
+		for _, name := range names {
+			if strings.Contains(name, ":") {
+				clauses = append(clauses, `series = ?`)
+				args = append(args, name)
+				continue
+			}
+			clauses = append(clauses, `(series = ? OR substr(series, 1, ?) = ?)`)
+			args = append(args, name, len(name)+1, name+":")
+		}
+		query += `AND (` + strings.Join(clauses, " OR ") + `) `
+	}
+	query += `GROUP BY series, b ORDER BY series, b`
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query metrics history: %w", err)
+	}
+	defer rows.Close()
+
+	out := make([]Series, 0, len(names))
+	index := make(map[string]int)
+	for rows.Next() {
+		var (
+			name     string
+			bucket   int64
+			sum      float64
+			count    int64
+			min, max float64
+		)
+		if err := rows.Scan(&name, &bucket, &sum, &count, &min, &max); err != nil {
+			return nil, fmt.Errorf("scan metrics history: %w", err)
+		}
+		i, ok := index[name]
+		if !ok {
+			i = len(out)
+			index[name] = i
+			out = append(out, Series{Name: name, Points: make([]Point, 0, 64)})
+		}
+		avg := 0.0
+		if count > 0 {
+			avg = sum / float64(count)
+		}
+		out[i].Points = append(out[i].Points, Point{
+			Timestamp: time.Unix(bucket, 0).UTC(),
+			Avg:       avg,
+			Min:       min,
+			Max:       max,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate metrics history: %w", err)
+	}
+
+	return out, nil
+}
+
+func (s *Store) Cleanup(retention Retention, now time.Time) error {
+	for _, tier := range Tiers {
+		keep, ok := retention[tier]
+		if !ok || keep <= 0 {
+			continue
+		}
+		cutoff := now.Add(-keep).Unix()
+		if _, err := s.db.Exec(`DELETE FROM metrics_history WHERE tier = ? AND bucket_ts < ?`, string(tier), cutoff); err != nil {
+			return fmt.Errorf("cleanup metrics history %s: %w", tier, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package history
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	store, err := NewStore(filepath.Join(t.TempDir(), "history-test.db"))
+	if err != nil {
+		t.Fatalf("NewStore() error = %v", err)
+	}
+	t.Cleanup(func() {
+		_ = store.Close()
+	})
+	return store
+}
+
+func TestStoreRecordRollsUpTiers(t *testing.T) {
+	store := newTestStore(t)
+	base := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	for i, v := range []float64{10, 20, 30, 40} {
+		values := map[string]float64{
+			"cpu.percent":       v,
+			"net.recv_bps:eth0": v * 100,
+			"net.recv_bps:eth1": v * 10,
+		}
+		if err := store.Record(base.Add(time.Duration(i)*20*time.Second), values); err != nil {
+			t.Fatalf("Record() error = %v", err)
+		}
+	}
+
+	series, err := store.QueryTier(Tier1m, 60, base, base.Add(2*time.Minute), []string{"cpu.percent"})
+	if err != nil {
+		t.Fatalf("QueryTier() error = %v", err)
+	}
+	if len(series) != 1 || len(series[0].Points) != 2 {
+		t.Fatalf("QueryTier() = %+v, want 1 series with 2 points", series)
+	}
+	first := series[0].Points[0]
+	if first.Avg != 20 || first.Min != 10 || first.Max != 30 {
+		t.Fatalf("first 1m bucket = %+v, want avg=20 min=10 max=30", first)
+	}
+
+	series, err = store.QueryTier(Tier1h, 3600, base, base.Add(time.Hour), []string{"net.recv_bps"})
+	if err != nil {
+		t.Fatalf("QueryTier(prefix) error = %v", err)
+	}
+	if len(series) != 2 {
+		t.Fatalf("QueryTier(prefix) len = %d, want 2 labelled series", len(series))
+	}
+	if series[0].Name != "net.recv_bps:eth0" || series[0].Points[0].Avg != 2500 {
+		t.Fatalf("QueryTier(prefix) first = %+v", series[0])
+	}
+}
+
+func TestStoreCleanupHonoursTierRetention(t *testing.T) {
+	store := newTestStore(t)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	old := now.Add(-2 * time.Hour)
+
+	if err := store.Record(old, map[string]float64{"cpu.percent": 50}); err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+
+	retention := DefaultRetention()
+	retention[TierRaw] = time.Hour
+	if err := store.Cleanup(retention, now); err != nil {
+		t.Fatalf("Cleanup() error = %v", err)
+	}
+
+	raw, err := store.QueryTier(TierRaw, 1, old.Add(-time.Minute), now, []string{"cpu.percent"})
+	if err != nil {
+		t.Fatalf("QueryTier(raw) error = %v", err)
+	}
+	if len(raw) != 0 {
+		t.Fatalf("raw tier should be cleaned up, got %+v", raw)
+	}
+
+	minute, err := store.QueryTier(Tier1m, 60, old.Add(-time.Minute), now, []string{"cpu.percent"})
+	if err != nil {
+		t.Fatalf("QueryTier(1m) error = %v", err)
+	}
+	if len(minute) != 1 {
+		t.Fatalf("1m tier should survive cleanup, got %+v", minute)
+	}
+}
+
+func TestRecorderQueryPicksTier(t *testing.T) {
+	store := newTestStore(t)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	rec := NewRecorder(store, DefaultRetention())
+	rec.now = func() time.Time { return now }
+
+	res, err := rec.Query(Query{From: now.Add(-10 * time.Minute), To: now})
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if res.Tier != TierRaw || res.StepSec != 2 {
+		t.Fatalf("Query(10m) tier=%s step=%d, want raw/2", res.Tier, res.StepSec)
+	}
+
+	res, err = rec.Query(Query{From: now.Add(-24 * time.Hour), To: now})
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if res.Tier != Tier1m || res.StepSec != 300 {
+		t.Fatalf("Query(24h) tier=%s step=%d, want 1m/300", res.Tier, res.StepSec)
+	}
+
+	res, err = rec.Query(Query{From: now.Add(-30 * 24 * time.Hour), To: now, StepSec: 60})
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if res.Tier != Tier15m {
+		t.Fatalf("Query(30d, step=60) tier=%s, want 15m (1m retention exceeded)", res.Tier)
+	}
+
+	if _, err := rec.Query(Query{From: now, To: now.Add(-time.Hour)}); err != ErrInvalidRange {
+		t.Fatalf("Query(inverted) error = %v, want %v", err, ErrInvalidRange)
+	}
+}
+
+func TestParseRetention(t *testing.T) {
+	got, err := ParseRetention("raw=2h, 1h=30d")
+	if err != nil {
+		t.Fatalf("ParseRetention() error = %v", err)
+	}
+	if got[TierRaw] != 2*time.Hour || got[Tier1h] != 30*24*time.Hour {
+		t.Fatalf("ParseRetention() = %+v", got)
+	}
+	if got[Tier1m] != DefaultRetention()[Tier1m] {
+		t.Fatalf("ParseRetention() should keep default for 1m, got %s", got[Tier1m])
+	}
+
+	if _, err := ParseRetention("5m=1d"); err == nil {
+		t.Fatalf("ParseRetention() expected error for unknown tier")
+	}
+}
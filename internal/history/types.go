@@ -0,0 +1,116 @@
+package history
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Tier string
+
+const (
+	TierRaw Tier = "raw"
+	Tier1m  Tier = "1m"
+	Tier15m Tier = "15m"
+	Tier1h  Tier = "1h"
+)
+
+const (
+	maxPoints     = 1000
+	defaultPoints = 300
+)
+
+// Tiers is ordered finest to coarsest.
+var Tiers = []Tier{TierRaw, Tier1m, Tier15m, Tier1h}
+
+func (t Tier) BucketSec() int64 {
+	switch t {
+	case Tier1m:
+		return 60
+	case Tier15m:
+		return 900
+	case Tier1h:
+		return 3600
+	default:
+		return 1
+	}
+}
+
+type Retention map[Tier]time.Duration
+
+func DefaultRetention() Retention {
+	return Retention{
+		TierRaw: 6 * time.Hour,
+		Tier1m:  7 * 24 * time.Hour,
+		Tier15m: 90 * 24 * time.Hour,
+		Tier1h:  365 * 24 * time.Hour,
+	}
+}
+
+// ParseRetention parses "raw=6h,1m=7d,15m=90d,1h=365d". Missing tiers keep
+// their defaults.
+func ParseRetention(raw string) (Retention, error) {
+	out := DefaultRetention()
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid retention entry %q", part)
+		}
+		tier := Tier(strings.TrimSpace(key))
+		if _, known := out[tier]; !known {
+			return nil, fmt.Errorf("unknown history tier %q", tier)
+		}
+		d, err := parseDays(strings.TrimSpace(value))
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid retention for tier %q", tier)
+		}
+		out[tier] = d
+	}
+	return out, nil
+}
+
+func parseDays(v string) (time.Duration, error) {
+	if strings.HasSuffix(v, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(v, "d"))
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(v)
+}
+
+type Point struct {
+	Timestamp time.Time `json:"t"`
+	Avg       float64   `json:"avg"`
+	Min       float64   `json:"min"`
+	Max       float64   `json:"max"`
+}
+
+type Series struct {
+	Name   string  `json:"name"`
+	Points []Point `json:"points"`
+}
+
+type Query struct {
+	From    time.Time
+	To      time.Time
+	StepSec int64
+	Series  []string
+}
+
+type Result struct {
+	From    time.Time `json:"from"`
+	To      time.Time `json:"to"`
+	StepSec int64     `json:"step_sec"`
+	Tier    Tier      `json:"tier"`
+	Series  []Series  `json:"series"`
+}
+
+var ErrInvalidRange = errors.New("from must be before to")
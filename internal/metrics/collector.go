@@ -15,6 +15,8 @@ type Collector struct {
 	prevDiskIO map[string]diskIOCounter
 	prevNet    map[string]netCounter
 	prevTime   time.Time
+	processes  *ProcessCollector
+	forecaster *DiskForecaster
 	interval   time.Duration
 	intervalMu sync.RWMutex
 	intervalCh chan time.Duration
@@ -32,6 +34,7 @@ func NewCollector(interval time.Duration) *Collector {
 		prevDiskIO: collectDiskIO(),
 		prevNet:    collectNet(),
 		prevTime:   time.Now(),
+		forecaster: NewDiskForecaster(),
 	}
 	return c
 }
@@ -69,7 +72,9 @@ func (c *Collector) collect(now time.Time) *Snapshot {
 
 	cpuM := collectCPU()
 	memM, swapM := collectMemory()
+	loadM := collectLoad()
 	disks := collectDisks()
+	c.forecaster.Observe(now, disks)
 	currDiskIO := collectDiskIO()
 	currNet := collectNet()
 
@@ -80,15 +85,42 @@ func (c *Collector) collect(now time.Time) *Snapshot {
 	c.prevNet = currNet
 	c.prevTime = now
 
-	return &Snapshot{
+	snap := &Snapshot{
 		Timestamp: now,
 		CPU:       cpuM,
 		Memory:    memM,
 		Swap:      swapM,
+		Load:      loadM,
 		Disks:     disks,
 		DiskIO:    diskIO,
 		Network:   network,
 	}
+	if c.processes != nil {
+		snap.Processes = c.processes.Collect(now, memM.TotalBytes)
+	}
+	return snap
+}
+
+// EnableProcesses turns on per-tick top-N process sampling. It must be called
+// before Run; topN <= 0 leaves process sampling disabled.
+func (c *Collector) EnableProcesses(topN int) {
+	if topN <= 0 {
+		c.processes = nil
+		return
+	}
+	c.processes = NewProcessCollector(topN)
+	// Prime the counters so the first tick already has rates.
+	c.processes.Collect(time.Now(), 0)
+}
+
+// SeedDiskUsage loads earlier used-bytes samples of a mountpoint into the
+// disk forecast. Call it before Run.
+func (c *Collector) SeedDiskUsage(mountpoint string, samples []UsageSample) {
+	c.forecaster.Seed(mountpoint, samples)
+}
+
+func (c *Collector) ProcessesEnabled() bool {
+	return c.processes != nil
 }
 
 func (c *Collector) Latest() *Snapshot {
@@ -75,11 +75,13 @@ func calcDiskIO(prev, curr map[string]diskIOCounter, elapsed float64) []DiskIOMe
 			continue
 		}
 		result = append(result, DiskIOMetrics{
-			Device:   name,
-			ReadBps:  float64(c.readBytes-p.readBytes) / elapsed,
-			WriteBps: float64(c.writeBytes-p.writeBytes) / elapsed,
-			ReadOps:  float64(c.readCount-p.readCount) / elapsed,
-			WriteOps: float64(c.writeCount-p.writeCount) / elapsed,
+			Device:     name,
+			ReadBps:    float64(c.readBytes-p.readBytes) / elapsed,
+			WriteBps:   float64(c.writeBytes-p.writeBytes) / elapsed,
+			ReadOps:    float64(c.readCount-p.readCount) / elapsed,
+			WriteOps:   float64(c.writeCount-p.writeCount) / elapsed,
+			TotalRead:  c.readBytes,
+			TotalWrite: c.writeBytes,
 		})
 	}
 	return result
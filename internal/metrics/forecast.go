@@ -0,0 +1,140 @@
+package metrics
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// DiskTrendWindow is how much usage history the disk forecast fits.
+	DiskTrendWindow = 6 * time.Hour
+	// diskTrendStep is the minimum spacing of the samples kept for the fit.
+	diskTrendStep = time.Minute
+	// diskTrendMinSpan and diskTrendMinSamples keep a few noisy minutes
+	// after startup from producing a forecast.
+	diskTrendMinSpan    = 30 * time.Minute
+	diskTrendMinSamples = 10
+	// DiskForecastMaxHours caps the forecast: a disk not filling within a
+	// year gets no full time, which also keeps FullAt within time.Duration.
+	DiskForecastMaxHours = 365 * 24
+)
+
+// DiskForecast is a least-squares linear fit of used bytes over the recent
+// history of one mountpoint.
+type DiskForecast struct {
+	GrowthBytesPerHour float64 `json:"growth_bytes_per_hour"`
+	// HoursToFull and FullAt are set only while usage is growing fast enough
+	// to fill within DiskForecastMaxHours; full means no free bytes left for
+	// unprivileged users.
+	HoursToFull *float64   `json:"hours_to_full,omitempty"`
+	FullAt      *time.Time `json:"full_at,omitempty"`
+	Samples     int        `json:"samples"`
+	WindowSec   int64      `json:"window_sec"`
+}
+
+// UsageSample is one used-bytes reading of a mountpoint.
+type UsageSample struct {
+	Time      time.Time
+	UsedBytes float64
+}
+
+// DiskForecaster keeps a downsampled usage history per mountpoint and fits
+// DiskForecast from it.
+type DiskForecaster struct {
+	mu      sync.Mutex
+	samples map[string][]UsageSample
+}
+
+func NewDiskForecaster() *DiskForecaster {
+	return &DiskForecaster{samples: make(map[string][]UsageSample)}
+}
+
+// Seed loads older samples of a mountpoint (from the metrics history) so a
+// restart does not wait diskTrendMinSpan for the first forecast.
+func (f *DiskForecaster) Seed(mountpoint string, samples []UsageSample) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var kept []UsageSample
+	for _, s := range samples {
+		if n := len(kept); n > 0 && s.Time.Sub(kept[n-1].Time) < diskTrendStep {
+			continue
+		}
+		kept = append(kept, s)
+	}
+	f.samples[mountpoint] = kept
+}
+
+// Observe records the disks of one tick and sets their Forecast.
+func (f *DiskForecaster) Observe(now time.Time, disks []DiskMetrics) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	cutoff := now.Add(-DiskTrendWindow)
+	for i := range disks {
+		d := &disks[i]
+		samples := f.samples[d.Mountpoint]
+		if n := len(samples); n == 0 || now.Sub(samples[n-1].Time) >= diskTrendStep {
+			samples = append(samples, UsageSample{Time: now, UsedBytes: float64(d.UsedBytes)})
+		}
+		samples = trimSamples(samples, cutoff)
+		f.samples[d.Mountpoint] = samples
+		d.Forecast = forecastDisk(samples, now, d.FreeBytes)
+	}
+	// Forget mountpoints that have been gone for a whole window.
+	for mount, samples := range f.samples {
+		if len(samples) == 0 || samples[len(samples)-1].Time.Before(cutoff) {
+			delete(f.samples, mount)
+		}
+	}
+}
+
+func trimSamples(samples []UsageSample, cutoff time.Time) []UsageSample {
+	i := 0
+	for i < len(samples) && samples[i].Time.Before(cutoff) {
+		i++
+	}
+	return samples[i:]
+}
+
+// forecastDisk fits used bytes against time. It returns nil until the samples
+// cover diskTrendMinSpan.
+func forecastDisk(samples []UsageSample, now time.Time, freeBytes uint64) *DiskForecast {
+	n := len(samples)
+	if n < diskTrendMinSamples || samples[n-1].Time.Sub(samples[0].Time) < diskTrendMinSpan {
+		return nil
+	}
+
+	origin := samples[0].Time
+	var meanX, meanY float64
+	for _, s := range samples {
+		meanX += s.Time.Sub(origin).Hours()
+		meanY += s.UsedBytes
+	}
+	meanX /= float64(n)
+	meanY /= float64(n)
+
+	var sxy, sxx float64
+	for _, s := range samples {
+		dx := s.Time.Sub(origin).Hours() - meanX
+		sxy += dx * (s.UsedBytes - meanY)
+		sxx += dx * dx
+	}
+	if sxx == 0 {
+		return nil
+	}
+
+	slope := sxy / sxx
+	out := &DiskForecast{
+		GrowthBytesPerHour: slope,
+		Samples:            n,
+		WindowSec:          int64(samples[n-1].Time.Sub(samples[0].Time).Seconds()),
+	}
+	if slope > 0 {
+		if hours := float64(freeBytes) / slope; hours <= DiskForecastMaxHours {
+			fullAt := now.Add(time.Duration(hours * float64(time.Hour))).UTC()
+			out.HoursToFull = &hours
+			out.FullAt = &fullAt
+		}
+	}
+	return out
+}
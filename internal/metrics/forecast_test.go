@@ -0,0 +1,84 @@
+package metrics
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestDiskForecasterLinearGrowth(t *testing.T) {
+	f := NewDiskForecaster()
+	start := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	const gib = 1 << 30
+
+	var disks []DiskMetrics
+	// 2 GiB/h growth with 40 GiB free at the end, sampled every 30s for 1h.
+	for i := 0; i <= 120; i++ {
+		now := start.Add(time.Duration(i) * 30 * time.Second)
+		used := 10*gib + uint64(float64(i)/120*2*gib)
+		disks = []DiskMetrics{{Mountpoint: "/", UsedBytes: used, FreeBytes: 52*gib - used}}
+		f.Observe(now, disks)
+		if i == 20 && disks[0].Forecast != nil {
+			t.Fatal("forecast produced before the minimum span")
+		}
+	}
+
+	fc := disks[0].Forecast
+	if fc == nil || fc.HoursToFull == nil {
+		t.Fatalf("forecast = %+v, want hours to full", fc)
+	}
+	if math.Abs(fc.GrowthBytesPerHour-2*gib) > 0.01*gib {
+		t.Fatalf("growth = %.0f B/h, want 2 GiB/h", fc.GrowthBytesPerHour)
+	}
+	if math.Abs(*fc.HoursToFull-20) > 0.2 {
+		t.Fatalf("hours to full = %.2f, want 20", *fc.HoursToFull)
+	}
+	if fc.Samples != 61 {
+		t.Fatalf("samples = %d, want one per minute", fc.Samples)
+	}
+}
+
+func TestDiskForecasterShrinkingUsage(t *testing.T) {
+	f := NewDiskForecaster()
+	start := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	seed := make([]UsageSample, 0, 60)
+	for i := 0; i < 60; i++ {
+		seed = append(seed, UsageSample{Time: start.Add(time.Duration(i) * time.Minute), UsedBytes: float64(100 - i)})
+	}
+	f.Seed("/var", seed)
+
+	disks := []DiskMetrics{{Mountpoint: "/var", UsedBytes: 40, FreeBytes: 60}}
+	f.Observe(start.Add(time.Hour), disks)
+	fc := disks[0].Forecast
+	if fc == nil || fc.GrowthBytesPerHour >= 0 || fc.HoursToFull != nil || fc.FullAt != nil {
+		t.Fatalf("forecast = %+v, want shrinking usage without a full time", fc)
+	}
+
+	// Samples older than the window are dropped.
+	f.Observe(start.Add(DiskTrendWindow+2*time.Hour), disks)
+	if disks[0].Forecast != nil {
+		t.Fatalf("forecast = %+v after the history aged out", disks[0].Forecast)
+	}
+}
+
+func TestDiskForecasterTinyGrowthHasNoFullTime(t *testing.T) {
+	f := NewDiskForecaster()
+	start := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	const tib = 1 << 40
+	// One byte per minute against 500 TiB free would overflow time.Duration.
+	seed := make([]UsageSample, 0, 60)
+	for i := 0; i < 60; i++ {
+		seed = append(seed, UsageSample{Time: start.Add(time.Duration(i) * time.Minute), UsedBytes: float64(i)})
+	}
+	f.Seed("/", seed)
+
+	disks := []DiskMetrics{{Mountpoint: "/", UsedBytes: 60, FreeBytes: 500 * tib}}
+	f.Observe(start.Add(time.Hour), disks)
+	fc := disks[0].Forecast
+	if fc == nil || fc.GrowthBytesPerHour <= 0 {
+		t.Fatalf("forecast = %+v, want slow growth", fc)
+	}
+	if fc.HoursToFull != nil || fc.FullAt != nil {
+		t.Fatalf("hours to full = %v, full at = %v beyond %dh", *fc.HoursToFull, fc.FullAt, DiskForecastMaxHours)
+	}
+}
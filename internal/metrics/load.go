@@ -0,0 +1,13 @@
+package metrics
+
+import (
+	"github.com/shirou/gopsutil/v3/load"
+)
+
+func collectLoad() LoadMetrics {
+	avg, err := load.Avg()
+	if err != nil {
+		return LoadMetrics{}
+	}
+	return LoadMetrics{Load1: avg.Load1, Load5: avg.Load5, Load15: avg.Load15}
+}
@@ -0,0 +1,302 @@
+package metrics
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// clockTicks is USER_HZ; it is 100 on every mainstream Linux build.
+const clockTicks = 100
+
+// maxCmdlineLen keeps the per-tick WebSocket payload bounded.
+const maxCmdlineLen = 512
+
+var procRoot = "/proc"
+
+type ProcessInfo struct {
+	PID        int       `json:"pid"`
+	PPID       int       `json:"ppid"`
+	Name       string    `json:"name"`
+	User       string    `json:"user"`
+	Cmdline    string    `json:"cmdline"`
+	State      string    `json:"state"`
+	StartTime  time.Time `json:"start_time"`
+	CPUPercent float64   `json:"cpu_percent"`
+	RSSBytes   uint64    `json:"rss_bytes"`
+	MemPercent float64   `json:"mem_percent"`
+	ReadBps    float64   `json:"read_bps"`
+	WriteBps   float64   `json:"write_bps"`
+}
+
+type ProcessList struct {
+	Total    int           `json:"total"`
+	ByCPU    []ProcessInfo `json:"by_cpu"`
+	ByMemory []ProcessInfo `json:"by_memory"`
+	ByIO     []ProcessInfo `json:"by_io"`
+}
+
+type procCounter struct {
+	cpuTicks   uint64
+	startTicks uint64
+	readBytes  uint64
+	writeBytes uint64
+}
+
+type procSample struct {
+	info    ProcessInfo
+	counter procCounter
+}
+
+// ProcessCollector samples /proc and turns successive counter readings into
+// per-process rates. It is not safe for concurrent use; the Collector only
+// calls it from the ticker goroutine.
+type ProcessCollector struct {
+	topN     int
+	prev     map[int]procCounter
+	prevTime time.Time
+	users    map[string]string
+	bootTime time.Time
+}
+
+func NewProcessCollector(topN int) *ProcessCollector {
+	return &ProcessCollector{
+		topN:     topN,
+		prev:     make(map[int]procCounter),
+		users:    make(map[string]string),
+		bootTime: readBootTime(),
+	}
+}
+
+func (p *ProcessCollector) Collect(now time.Time, memTotal uint64) *ProcessList {
+	entries, err := os.ReadDir(procRoot)
+	if err != nil {
+		return nil
+	}
+
+	elapsed := now.Sub(p.prevTime).Seconds()
+	samples := make([]procSample, 0, len(entries))
+	next := make(map[int]procCounter, len(entries))
+
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil || !entry.IsDir() {
+			continue
+		}
+		sample, ok := p.readProcess(pid)
+		if !ok {
+			continue
+		}
+		next[pid] = sample.counter
+
+		// Same PID with a different start time is a recycled PID: no rate yet.
+		if prev, seen := p.prev[pid]; seen && prev.startTicks == sample.counter.startTicks && elapsed > 0 {
+			sample.info.CPUPercent = float64(sample.counter.cpuTicks-prev.cpuTicks) / clockTicks / elapsed * 100
+			sample.info.ReadBps = counterRate(prev.readBytes, sample.counter.readBytes, elapsed)
+			sample.info.WriteBps = counterRate(prev.writeBytes, sample.counter.writeBytes, elapsed)
+		}
+		if memTotal > 0 {
+			sample.info.MemPercent = float64(sample.info.RSSBytes) / float64(memTotal) * 100
+		}
+		samples = append(samples, sample)
+	}
+
+	p.prev = next
+	p.prevTime = now
+
+	infos := make([]ProcessInfo, len(samples))
+	for i, s := range samples {
+		infos[i] = s.info
+	}
+
+	return &ProcessList{
+		Total: len(infos),
+		ByCPU: topProcesses(infos, p.topN, func(a, b ProcessInfo) bool {
+			return a.CPUPercent > b.CPUPercent
+		}),
+		ByMemory: topProcesses(infos, p.topN, func(a, b ProcessInfo) bool {
+			return a.RSSBytes > b.RSSBytes
+		}),
+		ByIO: topProcesses(infos, p.topN, func(a, b ProcessInfo) bool {
+			return a.ReadBps+a.WriteBps > b.ReadBps+b.WriteBps
+		}),
+	}
+}
+
+func (p *ProcessCollector) readProcess(pid int) (procSample, bool) {
+	dir := filepath.Join(procRoot, strconv.Itoa(pid))
+
+	stat, err := os.ReadFile(filepath.Join(dir, "stat"))
+	if err != nil {
+		return procSample{}, false
+	}
+	open := bytes.IndexByte(stat, '(')
+	closing := bytes.LastIndexByte(stat, ')')
+	if open < 0 || closing < open {
+		return procSample{}, false
+	}
+	fields := strings.Fields(string(stat[closing+1:]))
+	// fields[0] is field 3 (state) of proc(5).
+	if len(fields) < 22 {
+		return procSample{}, false
+	}
+
+	utime, _ := strconv.ParseUint(fields[11], 10, 64)
+	stime, _ := strconv.ParseUint(fields[12], 10, 64)
+	startTicks, _ := strconv.ParseUint(fields[19], 10, 64)
+	rssPages, _ := strconv.ParseInt(fields[21], 10, 64)
+	ppid, _ := strconv.Atoi(fields[1])
+
+	s := procSample{
+		info: ProcessInfo{
+			PID:   pid,
+			PPID:  ppid,
+			Name:  string(stat[open+1 : closing]),
+			State: fields[0],
+		},
+		counter: procCounter{
+			cpuTicks:   utime + stime,
+			startTicks: startTicks,
+		},
+	}
+	if rssPages > 0 {
+		s.info.RSSBytes = uint64(rssPages) * uint64(os.Getpagesize())
+	}
+	if !p.bootTime.IsZero() {
+		s.info.StartTime = p.bootTime.Add(time.Duration(startTicks) * time.Second / clockTicks).UTC()
+	}
+
+	if raw, err := os.ReadFile(filepath.Join(dir, "cmdline")); err == nil {
+		s.info.Cmdline = strings.TrimSpace(strings.ReplaceAll(string(raw), "\x00", " "))
+		s.info.Cmdline = truncateCmdline(s.info.Cmdline)
+	}
+	if s.info.Cmdline == "" {
+		s.info.Cmdline = "[" + s.info.Name + "]"
+	}
+
+	s.info.User = p.lookupUser(readStatusUID(filepath.Join(dir, "status")))
+	s.counter.readBytes, s.counter.writeBytes = readProcIO(filepath.Join(dir, "io"))
+
+	return s, true
+}
+
+// truncateCmdline cuts cmdline to at most maxCmdlineLen bytes on a rune
+// boundary, so a multi-byte character is never split.
+func truncateCmdline(cmdline string) string {
+	if len(cmdline) <= maxCmdlineLen {
+		return cmdline
+	}
+	cut := maxCmdlineLen
+	for cut > 0 && !utf8.RuneStart(cmdline[cut]) {
+		cut--
+	}
+	return cmdline[:cut] + "…"
+}
+
+func (p *ProcessCollector) lookupUser(uid string) string {
+	if uid == "" {
+		return ""
+	}
+	if name, ok := p.users[uid]; ok {
+		return name
+	}
+	name := uid
+	if u, err := user.LookupId(uid); err == nil {
+		name = u.Username
+	}
+	p.users[uid] = name
+	return name
+}
+
+func readStatusUID(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "Uid:") {
+			fields := strings.Fields(strings.TrimPrefix(line, "Uid:"))
+			if len(fields) > 0 {
+				return fields[0]
+			}
+		}
+	}
+	return ""
+}
+
+// readProcIO needs the same UID or CAP_SYS_PTRACE; other processes read as zero.
+func readProcIO(path string) (uint64, uint64) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return 0, 0
+	}
+	var readBytes, writeBytes uint64
+	for _, line := range strings.Split(string(raw), "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		n, err := strconv.ParseUint(strings.TrimSpace(value), 10, 64)
+		if err != nil {
+			continue
+		}
+		switch key {
+		case "read_bytes":
+			readBytes = n
+		case "write_bytes":
+			writeBytes = n
+		}
+	}
+	return readBytes, writeBytes
+}
+
+func readBootTime() time.Time {
+	raw, err := os.ReadFile(filepath.Join(procRoot, "stat"))
+	if err != nil {
+		return time.Time{}
+	}
+	for _, line := range strings.Split(string(raw), "\n") {
+		if strings.HasPrefix(line, "btime ") {
+			sec, err := strconv.ParseInt(strings.TrimSpace(strings.TrimPrefix(line, "btime ")), 10, 64)
+			if err == nil {
+				return time.Unix(sec, 0)
+			}
+		}
+	}
+	return time.Time{}
+}
+
+func counterRate(prev, curr uint64, elapsed float64) float64 {
+	if curr < prev || elapsed <= 0 {
+		return 0
+	}
+	return float64(curr-prev) / elapsed
+}
+
+func topProcesses(all []ProcessInfo, n int, less func(a, b ProcessInfo) bool) []ProcessInfo {
+	sorted := append([]ProcessInfo(nil), all...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if less(sorted[i], sorted[j]) {
+			return true
+		}
+		if less(sorted[j], sorted[i]) {
+			return false
+		}
+		return sorted[i].PID < sorted[j].PID
+	})
+	if n > 0 && len(sorted) > n {
+		sorted = sorted[:n]
+	}
+	return sorted
+}
@@ -0,0 +1,104 @@
+package metrics
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func writeFakeProc(t *testing.T, root string, pid int, comm string, cpuTicks, startTicks, rssPages, readBytes, writeBytes uint64) {
+	t.Helper()
+	dir := filepath.Join(root, fmt.Sprint(pid))
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatalf("MkdirAll() error = %v", err)
+	}
+	stat := fmt.Sprintf("%d (%s) S 1 %d %d 0 -1 4194304 0 0 0 0 %d 0 0 0 20 0 1 0 %d 1000 %d\n",
+		pid, comm, pid, pid, cpuTicks, startTicks, rssPages)
+	files := map[string]string{
+		"stat":    stat,
+		"cmdline": "/usr/bin/" + comm + "\x00--flag\x00",
+		"status":  "Name:\t" + comm + "\nUid:\t0\t0\t0\t0\n",
+		"io":      fmt.Sprintf("rchar: 1\nread_bytes: %d\nwrite_bytes: %d\n", readBytes, writeBytes),
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("WriteFile(%s) error = %v", name, err)
+		}
+	}
+}
+
+func TestProcessCollectorRatesAndRanking(t *testing.T) {
+	root := t.TempDir()
+	orig := procRoot
+	procRoot = root
+	t.Cleanup(func() { procRoot = orig })
+
+	if err := os.WriteFile(filepath.Join(root, "stat"), []byte("cpu 1 2 3\nbtime 1700000000\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile(stat) error = %v", err)
+	}
+	writeFakeProc(t, root, 100, "my app", 100, 500, 10, 0, 0)
+	writeFakeProc(t, root, 200, "db", 100, 600, 1000, 0, 0)
+
+	pc := NewProcessCollector(1)
+	base := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	first := pc.Collect(base, 0)
+	if first == nil || first.Total != 2 {
+		t.Fatalf("Collect() first = %+v, want 2 processes", first)
+	}
+	if first.ByCPU[0].CPUPercent != 0 {
+		t.Fatalf("first sample should have no CPU rate, got %v", first.ByCPU[0].CPUPercent)
+	}
+
+	// pid 100 burns 150 ticks (1.5s CPU) over 2s; pid 200 writes 4 MiB.
+	writeFakeProc(t, root, 100, "my app", 250, 500, 10, 0, 0)
+	writeFakeProc(t, root, 200, "db", 120, 600, 1000, 0, 4<<20)
+
+	second := pc.Collect(base.Add(2*time.Second), 1000*uint64(os.Getpagesize()))
+	if len(second.ByCPU) != 1 {
+		t.Fatalf("ByCPU len = %d, want topN=1", len(second.ByCPU))
+	}
+	top := second.ByCPU[0]
+	if top.PID != 100 || top.CPUPercent != 75 {
+		t.Fatalf("ByCPU[0] = pid %d cpu %v, want pid 100 cpu 75", top.PID, top.CPUPercent)
+	}
+	if top.Name != "my app" || top.Cmdline != "/usr/bin/my app --flag" {
+		t.Fatalf("ByCPU[0] name/cmdline = %q/%q", top.Name, top.Cmdline)
+	}
+	if want := time.Unix(1700000005, 0).UTC(); !top.StartTime.Equal(want) {
+		t.Fatalf("StartTime = %s, want %s", top.StartTime, want)
+	}
+	if second.ByMemory[0].PID != 200 || second.ByMemory[0].MemPercent != 100 {
+		t.Fatalf("ByMemory[0] = %+v, want pid 200 at 100%%", second.ByMemory[0])
+	}
+	if second.ByIO[0].PID != 200 || second.ByIO[0].WriteBps != float64(2<<20) {
+		t.Fatalf("ByIO[0] = %+v, want pid 200 writing 2 MiB/s", second.ByIO[0])
+	}
+
+	// A recycled PID (new start time) must not produce a bogus rate.
+	writeFakeProc(t, root, 100, "other", 10, 900, 10, 0, 0)
+	third := pc.Collect(base.Add(4*time.Second), 0)
+	for _, p := range third.ByCPU {
+		if p.PID == 100 && p.CPUPercent != 0 {
+			t.Fatalf("recycled pid CPU = %v, want 0", p.CPUPercent)
+		}
+	}
+}
+
+func TestTruncateCmdlineKeepsRunes(t *testing.T) {
+	// Three-byte runes starting one byte in put a rune across the cut.
+	long := "x" + strings.Repeat("日", maxCmdlineLen)
+	got := truncateCmdline(long)
+	if !utf8.ValidString(got) || !strings.HasSuffix(got, "…") {
+		t.Fatalf("truncateCmdline() = %q, want valid UTF-8 ending in an ellipsis", got)
+	}
+	if n := len(strings.TrimSuffix(got, "…")); n > maxCmdlineLen || n < maxCmdlineLen-2 {
+		t.Fatalf("kept %d bytes, want just under %d", n, maxCmdlineLen)
+	}
+	if short := "/usr/bin/日本"; truncateCmdline(short) != short {
+		t.Fatalf("short cmdline changed")
+	}
+}
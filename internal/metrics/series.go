@@ -0,0 +1,95 @@
+package metrics
+
+import "strings"
+
+const (
+	SeriesCPUPercent      = "cpu.percent"
+	SeriesMemoryPercent   = "memory.percent"
+	SeriesMemoryUsedBytes = "memory.used_bytes"
+	SeriesSwapPercent     = "swap.percent"
+	SeriesLoad1           = "load.1"
+	SeriesLoad5           = "load.5"
+	SeriesLoad15          = "load.15"
+	SeriesDiskPercent     = "disk.percent"
+	SeriesDiskUsedBytes   = "disk.used_bytes"
+	SeriesDiskReadBps     = "diskio.read_bps"
+	SeriesDiskWriteBps    = "diskio.write_bps"
+	SeriesNetRecvBps      = "net.recv_bps"
+	SeriesNetSentBps      = "net.sent_bps"
+)
+
+// labeledSeries maps every known base series to whether it needs a label.
+var labeledSeries = map[string]bool{
+	SeriesCPUPercent:      false,
+	SeriesMemoryPercent:   false,
+	SeriesMemoryUsedBytes: false,
+	SeriesSwapPercent:     false,
+	SeriesLoad1:           false,
+	SeriesLoad5:           false,
+	SeriesLoad15:          false,
+	SeriesDiskPercent:     true,
+	SeriesDiskUsedBytes:   true,
+	SeriesDiskReadBps:     true,
+	SeriesDiskWriteBps:    true,
+	SeriesNetRecvBps:      true,
+	SeriesNetSentBps:      true,
+}
+
+// KnownSeries reports whether name is a series Snapshot.Series can produce,
+// with a label exactly when the base series is per mountpoint/device/interface.
+func KnownSeries(name string) bool {
+	base, label := SplitSeriesName(name)
+	labeled, ok := labeledSeries[base]
+	if !ok {
+		return false
+	}
+	return labeled == (label != "")
+}
+
+// SeriesName joins a base series name with an optional label such as a
+// mountpoint, device or interface ("disk.percent:/var").
+func SeriesName(base, label string) string {
+	if label == "" {
+		return base
+	}
+	return base + ":" + label
+}
+
+// SplitSeriesName is the inverse of SeriesName.
+func SplitSeriesName(name string) (base string, label string) {
+	idx := strings.Index(name, ":")
+	if idx < 0 {
+		return name, ""
+	}
+	return name[:idx], name[idx+1:]
+}
+
+// Series flattens a snapshot into named scalar values.
+func (s *Snapshot) Series() map[string]float64 {
+	if s == nil {
+		return nil
+	}
+
+	out := map[string]float64{
+		SeriesCPUPercent:      s.CPU.TotalPercent,
+		SeriesMemoryPercent:   s.Memory.Percent,
+		SeriesMemoryUsedBytes: float64(s.Memory.UsedBytes),
+		SeriesSwapPercent:     s.Swap.Percent,
+		SeriesLoad1:           s.Load.Load1,
+		SeriesLoad5:           s.Load.Load5,
+		SeriesLoad15:          s.Load.Load15,
+	}
+	for _, d := range s.Disks {
+		out[SeriesName(SeriesDiskPercent, d.Mountpoint)] = d.Percent
+		out[SeriesName(SeriesDiskUsedBytes, d.Mountpoint)] = float64(d.UsedBytes)
+	}
+	for _, io := range s.DiskIO {
+		out[SeriesName(SeriesDiskReadBps, io.Device)] = io.ReadBps
+		out[SeriesName(SeriesDiskWriteBps, io.Device)] = io.WriteBps
+	}
+	for _, n := range s.Network {
+		out[SeriesName(SeriesNetRecvBps, n.Interface)] = n.RecvBps
+		out[SeriesName(SeriesNetSentBps, n.Interface)] = n.SentBps
+	}
+	return out
+}
@@ -3,13 +3,15 @@ package metrics
 import "time"
 
 type Snapshot struct {
-	Timestamp time.Time      `json:"timestamp"`
-	CPU       CPUMetrics     `json:"cpu"`
-	Memory    MemMetrics     `json:"memory"`
-	Swap      SwapMetrics    `json:"swap"`
-	Disks     []DiskMetrics  `json:"disks"`
+	Timestamp time.Time       `json:"timestamp"`
+	CPU       CPUMetrics      `json:"cpu"`
+	Memory    MemMetrics      `json:"memory"`
+	Swap      SwapMetrics     `json:"swap"`
+	Load      LoadMetrics     `json:"load"`
+	Disks     []DiskMetrics   `json:"disks"`
 	DiskIO    []DiskIOMetrics `json:"disk_io"`
-	Network   []NetMetrics   `json:"network"`
+	Network   []NetMetrics    `json:"network"`
+	Processes *ProcessList    `json:"processes,omitempty"`
 }
 
 type CPUMetrics struct {
@@ -36,6 +38,12 @@ type SwapMetrics struct {
 	Percent    float64 `json:"percent"`
 }
 
+type LoadMetrics struct {
+	Load1  float64 `json:"load1"`
+	Load5  float64 `json:"load5"`
+	Load15 float64 `json:"load15"`
+}
+
 type DiskMetrics struct {
 	Mountpoint string  `json:"mountpoint"`
 	Device     string  `json:"device"`
@@ -44,14 +52,18 @@ type DiskMetrics struct {
 	UsedBytes  uint64  `json:"used_bytes"`
 	FreeBytes  uint64  `json:"free_bytes"`
 	Percent    float64 `json:"percent"`
+	// Forecast is nil until enough usage history has been collected.
+	Forecast *DiskForecast `json:"forecast,omitempty"`
 }
 
 type DiskIOMetrics struct {
-	Device   string  `json:"device"`
-	ReadBps  float64 `json:"read_bps"`
-	WriteBps float64 `json:"write_bps"`
-	ReadOps  float64 `json:"read_ops"`
-	WriteOps float64 `json:"write_ops"`
+	Device     string  `json:"device"`
+	ReadBps    float64 `json:"read_bps"`
+	WriteBps   float64 `json:"write_bps"`
+	ReadOps    float64 `json:"read_ops"`
+	WriteOps   float64 `json:"write_ops"`
+	TotalRead  uint64  `json:"total_read"`
+	TotalWrite uint64  `json:"total_write"`
 }
 
 type NetMetrics struct {
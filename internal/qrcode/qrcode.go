@@ -0,0 +1,485 @@
+// Package qrcode encodes short strings as QR codes (ISO/IEC 18004) so the UI
+// can show otpauth:// URIs without a client-side dependency. Only byte mode
+// at error correction level M and versions 1-10 are supported, which fits
+// up to 213 bytes; that is plenty for a TOTP enrollment URI.
+package qrcode
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrTooLong is returned when the input does not fit in a version 10 code.
+var ErrTooLong = errors.New("qrcode: data too long")
+
+// blockSpec describes the error correction layout of one version at level M:
+// the EC codewords per block and the data codewords of each block group.
+type blockSpec struct {
+	ecPerBlock int
+	groups     []blockGroup
+}
+
+type blockGroup struct {
+	blocks    int
+	dataWords int
+}
+
+// levelM holds the level M block layout for versions 1-10 (table 9 of the
+// standard), indexed by version-1.
+var levelM = []blockSpec{
+	{10, []blockGroup{{1, 16}}},
+	{16, []blockGroup{{1, 28}}},
+	{26, []blockGroup{{1, 44}}},
+	{18, []blockGroup{{2, 32}}},
+	{24, []blockGroup{{2, 43}}},
+	{16, []blockGroup{{4, 27}}},
+	{18, []blockGroup{{4, 31}}},
+	{22, []blockGroup{{2, 38}, {2, 39}}},
+	{22, []blockGroup{{3, 36}, {2, 37}}},
+	{26, []blockGroup{{4, 43}, {1, 44}}},
+}
+
+// alignmentCenters lists the alignment pattern coordinates per version.
+var alignmentCenters = [][]int{
+	nil,
+	{6, 18},
+	{6, 22},
+	{6, 26},
+	{6, 30},
+	{6, 34},
+	{6, 22, 38},
+	{6, 24, 42},
+	{6, 26, 46},
+	{6, 28, 50},
+}
+
+// formatLevelM is the two-bit level indicator for M in the format string.
+const formatLevelM = 0
+
+// Code is an encoded QR symbol. Modules are addressed by column x and row y;
+// the quiet zone is not included.
+type Code struct {
+	Version int
+	Size    int
+
+	modules  [][]bool
+	function [][]bool
+}
+
+// Encode returns the smallest QR code that holds text in byte mode.
+func Encode(text string) (*Code, error) {
+	data := []byte(text)
+	for version := 1; version <= len(levelM); version++ {
+		spec := levelM[version-1]
+		capacity := spec.dataWords()
+		if 4+countBits(version)+8*len(data) > 8*capacity {
+			continue
+		}
+		codewords := spec.interleave(encodeData(data, version, capacity))
+		return build(version, codewords), nil
+	}
+	return nil, ErrTooLong
+}
+
+// Dark reports whether the module at column x, row y is dark.
+func (c *Code) Dark(x, y int) bool {
+	return c.modules[y][x]
+}
+
+// SVG renders the code as a standalone SVG document with a four-module quiet
+// zone. The image scales with its container; one unit is one module.
+func (c *Code) SVG() string {
+	const quiet = 4
+	dim := c.Size + 2*quiet
+	var path strings.Builder
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if c.modules[y][x] {
+				fmt.Fprintf(&path, "M%d %dh1v1h-1z", x+quiet, y+quiet)
+			}
+		}
+	}
+	return fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" shape-rendering="crispEdges">`+
+		`<rect width="100%%" height="100%%" fill="#fff"/><path d="%s" fill="#000"/></svg>`, dim, dim, path.String())
+}
+
+func (s blockSpec) dataWords() int {
+	n := 0
+	for _, g := range s.groups {
+		n += g.blocks * g.dataWords
+	}
+	return n
+}
+
+// countBits is the width of the byte mode character count indicator.
+func countBits(version int) int {
+	if version < 10 {
+		return 8
+	}
+	return 16
+}
+
+// encodeData builds the data codeword sequence: mode indicator, character
+// count, payload, terminator and the alternating pad bytes.
+func encodeData(data []byte, version, capacity int) []byte {
+	var bits bitBuffer
+	bits.append(0x4, 4)
+	bits.append(len(data), countBits(version))
+	for _, b := range data {
+		bits.append(int(b), 8)
+	}
+	bits.append(0, min(4, 8*capacity-bits.len()))
+	bits.append(0, (8-bits.len()%8)%8)
+
+	out := bits.bytes()
+	for pad := 0; len(out) < capacity; pad++ {
+		if pad%2 == 0 {
+			out = append(out, 0xEC)
+		} else {
+			out = append(out, 0x11)
+		}
+	}
+	return out
+}
+
+// interleave splits data into blocks, appends the Reed-Solomon codewords of
+// each and interleaves them in the order they are placed in the symbol.
+func (s blockSpec) interleave(data []byte) []byte {
+	var blocks, ecBlocks [][]byte
+	offset := 0
+	for _, g := range s.groups {
+		for i := 0; i < g.blocks; i++ {
+			block := data[offset : offset+g.dataWords]
+			offset += g.dataWords
+			blocks = append(blocks, block)
+			ecBlocks = append(ecBlocks, reedSolomon(block, s.ecPerBlock))
+		}
+	}
+
+	var out []byte
+	maxData := s.groups[len(s.groups)-1].dataWords
+	for i := 0; i < maxData; i++ {
+		for _, block := range blocks {
+			if i < len(block) {
+				out = append(out, block[i])
+			}
+		}
+	}
+	for i := 0; i < s.ecPerBlock; i++ {
+		for _, ec := range ecBlocks {
+			out = append(out, ec[i])
+		}
+	}
+	return out
+}
+
+// build lays out the function patterns and codewords, then applies the mask
+// with the lowest penalty score.
+func build(version int, codewords []byte) *Code {
+	size := 17 + 4*version
+	c := &Code{
+		Version:  version,
+		Size:     size,
+		modules:  newGrid(size),
+		function: newGrid(size),
+	}
+	c.drawFunctionPatterns()
+	c.placeCodewords(codewords)
+
+	best, bestPenalty := 0, -1
+	for mask := 0; mask < 8; mask++ {
+		c.applyMask(mask)
+		c.drawFormatBits(mask)
+		if p := c.penalty(); bestPenalty < 0 || p < bestPenalty {
+			best, bestPenalty = mask, p
+		}
+		c.applyMask(mask) // XOR again to undo
+	}
+	c.applyMask(best)
+	c.drawFormatBits(best)
+	return c
+}
+
+func newGrid(size int) [][]bool {
+	grid := make([][]bool, size)
+	for i := range grid {
+		grid[i] = make([]bool, size)
+	}
+	return grid
+}
+
+func (c *Code) set(x, y int, dark bool) {
+	c.modules[y][x] = dark
+	c.function[y][x] = true
+}
+
+func (c *Code) drawFunctionPatterns() {
+	for i := 0; i < c.Size; i++ {
+		c.set(6, i, i%2 == 0)
+		c.set(i, 6, i%2 == 0)
+	}
+	c.drawFinder(3, 3)
+	c.drawFinder(c.Size-4, 3)
+	c.drawFinder(3, c.Size-4)
+
+	centers := alignmentCenters[c.Version-1]
+	last := len(centers) - 1
+	for i, cx := range centers {
+		for j, cy := range centers {
+			// Skip the three corners already taken by finder patterns.
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue
+			}
+			c.drawAlignment(cx, cy)
+		}
+	}
+
+	// Reserve the format areas; drawFormatBits fills them per mask.
+	c.drawFormatBits(0)
+	c.drawVersionBits()
+}
+
+// drawFinder draws a finder pattern and its separator centred on (cx, cy).
+func (c *Code) drawFinder(cx, cy int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			x, y := cx+dx, cy+dy
+			if x < 0 || x >= c.Size || y < 0 || y >= c.Size {
+				continue
+			}
+			dist := max(abs(dx), abs(dy))
+			c.set(x, y, dist != 2 && dist != 4)
+		}
+	}
+}
+
+func (c *Code) drawAlignment(cx, cy int) {
+	for dy := -2; dy <= 2; dy++ {
+		for dx := -2; dx <= 2; dx++ {
+			c.set(cx+dx, cy+dy, max(abs(dx), abs(dy)) != 1)
+		}
+	}
+}
+
+// formatBits returns the 15-bit BCH protected format string for a mask.
+func formatBits(mask int) int {
+	data := formatLevelM<<3 | mask
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = rem<<1 ^ (rem>>9)*0x537
+	}
+	return (data<<10 | rem) ^ 0x5412
+}
+
+// versionBits returns the 18-bit BCH protected version string.
+func versionBits(version int) int {
+	rem := version
+	for i := 0; i < 12; i++ {
+		rem = rem<<1 ^ (rem>>11)*0x1F25
+	}
+	return version<<12 | rem
+}
+
+func (c *Code) drawFormatBits(mask int) {
+	bits := formatBits(mask)
+	bit := func(i int) bool { return bits>>i&1 != 0 }
+
+	// Copy next to the top-left finder.
+	for i := 0; i <= 5; i++ {
+		c.set(8, i, bit(i))
+	}
+	c.set(8, 7, bit(6))
+	c.set(8, 8, bit(7))
+	c.set(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		c.set(14-i, 8, bit(i))
+	}
+
+	// Copy split between the other two finders.
+	for i := 0; i < 8; i++ {
+		c.set(c.Size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		c.set(8, c.Size-15+i, bit(i))
+	}
+	c.set(8, c.Size-8, true) // the dark module
+}
+
+func (c *Code) drawVersionBits() {
+	if c.Version < 7 {
+		return
+	}
+	bits := versionBits(c.Version)
+	for i := 0; i < 18; i++ {
+		dark := bits>>i&1 != 0
+		a, b := c.Size-11+i%3, i/3
+		c.set(a, b, dark)
+		c.set(b, a, dark)
+	}
+}
+
+// placeCodewords fills the non-function modules in the zigzag order of the
+// standard: two-module columns from the right, alternating up and down,
+// skipping the vertical timing pattern.
+func (c *Code) placeCodewords(codewords []byte) {
+	i := 0
+	total := len(codewords) * 8
+	for right := c.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		upward := (right+1)&2 == 0
+		for vert := 0; vert < c.Size; vert++ {
+			y := vert
+			if upward {
+				y = c.Size - 1 - vert
+			}
+			for j := 0; j < 2; j++ {
+				x := right - j
+				if c.function[y][x] || i >= total {
+					continue
+				}
+				c.modules[y][x] = codewords[i>>3]>>(7-i&7)&1 != 0
+				i++
+			}
+		}
+	}
+}
+
+func (c *Code) applyMask(mask int) {
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if c.function[y][x] {
+				continue
+			}
+			var flip bool
+			switch mask {
+			case 0:
+				flip = (x+y)%2 == 0
+			case 1:
+				flip = y%2 == 0
+			case 2:
+				flip = x%3 == 0
+			case 3:
+				flip = (x+y)%3 == 0
+			case 4:
+				flip = (x/3+y/2)%2 == 0
+			case 5:
+				flip = x*y%2+x*y%3 == 0
+			case 6:
+				flip = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				flip = ((x+y)%2+x*y%3)%2 == 0
+			}
+			if flip {
+				c.modules[y][x] = !c.modules[y][x]
+			}
+		}
+	}
+}
+
+// penalty scores the symbol with the four rules of the standard; lower is
+// easier for scanners.
+func (c *Code) penalty() int {
+	score := 0
+	line := make([]bool, c.Size)
+	for horizontal := 0; horizontal < 2; horizontal++ {
+		for a := 0; a < c.Size; a++ {
+			for b := 0; b < c.Size; b++ {
+				if horizontal == 0 {
+					line[b] = c.modules[a][b]
+				} else {
+					line[b] = c.modules[b][a]
+				}
+			}
+			score += linePenalty(line)
+		}
+	}
+
+	dark := 0
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if c.modules[y][x] {
+				dark++
+			}
+			if x > 0 && y > 0 {
+				v := c.modules[y][x]
+				if v == c.modules[y-1][x] && v == c.modules[y][x-1] && v == c.modules[y-1][x-1] {
+					score += 3
+				}
+			}
+		}
+	}
+	total := c.Size * c.Size
+	score += abs(dark*100/total-50) / 5 * 10
+	return score
+}
+
+// finderLike matches the 1:1:3:1:1 finder ratio with four light modules on
+// either side.
+var finderLike = [2][]bool{
+	{true, false, true, true, true, false, true, false, false, false, false},
+	{false, false, false, false, true, false, true, true, true, false, true},
+}
+
+// linePenalty applies the run-length and finder-like rules to one row or
+// column.
+func linePenalty(line []bool) int {
+	score := 0
+	run := 1
+	for i := 1; i <= len(line); i++ {
+		if i < len(line) && line[i] == line[i-1] {
+			run++
+			continue
+		}
+		if run >= 5 {
+			score += 3 + run - 5
+		}
+		run = 1
+	}
+	for i := 0; i+11 <= len(line); i++ {
+		for _, pattern := range finderLike {
+			match := true
+			for j, v := range pattern {
+				if line[i+j] != v {
+					match = false
+					break
+				}
+			}
+			if match {
+				score += 40
+			}
+		}
+	}
+	return score
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+type bitBuffer struct {
+	bits []bool
+}
+
+func (b *bitBuffer) append(value, n int) {
+	for i := n - 1; i >= 0; i-- {
+		b.bits = append(b.bits, value>>i&1 != 0)
+	}
+}
+
+func (b *bitBuffer) len() int { return len(b.bits) }
+
+func (b *bitBuffer) bytes() []byte {
+	out := make([]byte, (len(b.bits)+7)/8)
+	for i, bit := range b.bits {
+		if bit {
+			out[i/8] |= 0x80 >> (i % 8)
+		}
+	}
+	return out
+}
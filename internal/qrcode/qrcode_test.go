@@ -0,0 +1,97 @@
+package qrcode
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestReedSolomonMatchesReferenceVector(t *testing.T) {
+	// "HELLO WORLD" at 1-M, the worked example from the standard's annex.
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	want := []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}
+	if got := reedSolomon(data, 10); !bytes.Equal(got, want) {
+		t.Fatalf("reedSolomon() = %v, want %v", got, want)
+	}
+}
+
+func TestFormatAndVersionBits(t *testing.T) {
+	for _, tc := range []struct {
+		mask int
+		want int
+	}{
+		{0, 0b101010000010010},
+		{4, 0b100010111111001},
+		{7, 0b100101010100000},
+	} {
+		if got := formatBits(tc.mask); got != tc.want {
+			t.Errorf("formatBits(%d) = %015b, want %015b", tc.mask, got, tc.want)
+		}
+	}
+	if got, want := versionBits(7), 0b000111110010010100; got != want {
+		t.Errorf("versionBits(7) = %018b, want %018b", got, want)
+	}
+}
+
+func TestEncodePicksSmallestVersion(t *testing.T) {
+	for _, tc := range []struct {
+		length  int
+		version int
+	}{
+		{14, 1},
+		{15, 2},
+		{100, 6},
+		{180, 9},
+		{213, 10},
+	} {
+		code, err := Encode(strings.Repeat("a", tc.length))
+		if err != nil {
+			t.Fatalf("Encode(%d bytes) error = %v", tc.length, err)
+		}
+		if code.Version != tc.version || code.Size != 17+4*tc.version {
+			t.Errorf("Encode(%d bytes) = version %d size %d, want version %d", tc.length, code.Version, code.Size, tc.version)
+		}
+	}
+
+	if _, err := Encode(strings.Repeat("a", 214)); !errors.Is(err, ErrTooLong) {
+		t.Fatalf("Encode(214 bytes) error = %v, want ErrTooLong", err)
+	}
+}
+
+func TestEncodeDrawsFinderPatterns(t *testing.T) {
+	code, err := Encode("otpauth://totp/QuickVPS:admin?secret=JBSWY3DPEHPK3PXP&issuer=QuickVPS")
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	last := code.Size - 1
+	for _, corner := range [][2]int{{0, 0}, {last - 6, 0}, {0, last - 6}} {
+		for i := 0; i < 7; i++ {
+			x, y := corner[0], corner[1]
+			if !code.Dark(x+i, y) || !code.Dark(x, y+i) || !code.Dark(x+3, y+3) {
+				t.Fatalf("finder at %v is not drawn", corner)
+			}
+		}
+		if code.Dark(corner[0]+1, corner[1]+1) {
+			t.Fatalf("finder at %v has a dark inner ring", corner)
+		}
+	}
+	if !code.Dark(8, code.Size-8) {
+		t.Fatal("dark module is missing")
+	}
+}
+
+func TestSVGRendersDarkModules(t *testing.T) {
+	code, err := Encode("hello")
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	svg := code.SVG()
+	if !strings.HasPrefix(svg, "<svg ") || !strings.Contains(svg, `viewBox="0 0 29 29"`) {
+		t.Fatalf("SVG() = %q, want a 29x29 viewBox", svg)
+	}
+	// The top-left module of the top-left finder sits inside the quiet zone.
+	if !strings.Contains(svg, "M4 4h1v1h-1z") {
+		t.Fatalf("SVG() is missing the first finder module")
+	}
+}
@@ -0,0 +1,59 @@
+package qrcode
+
+// GF(256) arithmetic over the QR polynomial x^8 + x^4 + x^3 + x^2 + 1.
+var gfExp, gfLog = func() ([512]byte, [256]byte) {
+	var exp [512]byte
+	var log [256]byte
+	x := 1
+	for i := 0; i < 255; i++ {
+		exp[i] = byte(x)
+		log[x] = byte(i)
+		x <<= 1
+		if x&0x100 != 0 {
+			x ^= 0x11D
+		}
+	}
+	for i := 255; i < len(exp); i++ {
+		exp[i] = exp[i-255]
+	}
+	return exp, log
+}()
+
+func gfMul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+int(gfLog[b])]
+}
+
+// generator returns the coefficients, highest degree first, of the
+// Reed-Solomon generator polynomial (x - a^0)(x - a^1)...(x - a^(n-1)).
+func generator(n int) []byte {
+	g := []byte{1}
+	for i := 0; i < n; i++ {
+		next := make([]byte, len(g)+1)
+		for j, coef := range g {
+			next[j] ^= coef
+			next[j+1] ^= gfMul(coef, gfExp[i])
+		}
+		g = next
+	}
+	return g
+}
+
+// reedSolomon returns the n error correction codewords for data.
+func reedSolomon(data []byte, n int) []byte {
+	g := generator(n)
+	msg := make([]byte, len(data)+n)
+	copy(msg, data)
+	for i := range data {
+		coef := msg[i]
+		if coef == 0 {
+			continue
+		}
+		for j := 1; j < len(g); j++ {
+			msg[i+j] ^= gfMul(g[j], coef)
+		}
+	}
+	return msg[len(data):]
+}
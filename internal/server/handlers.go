@@ -18,6 +18,7 @@ import (
 	"quickvps/internal/alerts"
 	"quickvps/internal/auth"
 	"quickvps/internal/firewall"
+	"quickvps/internal/metrics"
 	"quickvps/internal/ncdu"
 	packagesaudit "quickvps/internal/packages"
 	"quickvps/internal/ports"
@@ -75,28 +76,40 @@ func (s *Server) currentUser(r *http.Request) (auth.User, bool) {
 	return auth.User{ID: session.UserID, Username: session.Username, Role: session.Role}, true
 }
 
-func (s *Server) requireAdmin(w http.ResponseWriter, r *http.Request) (auth.User, bool) {
+// requirePermission rejects the request unless the signed-in user's role
+// grants perm.
+func (s *Server) requirePermission(w http.ResponseWriter, r *http.Request, perm auth.Permission) (auth.User, bool) {
 	user, ok := s.currentUser(r)
 	if !ok {
 		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
 		return auth.User{}, false
 	}
-	if user.Role != auth.RoleAdmin {
+	if !s.authStore.HasPermission(user.Role, perm) {
 		writeJSON(w, http.StatusForbidden, map[string]string{"error": "forbidden"})
 		return auth.User{}, false
 	}
 	return user, true
 }
 
-func (s *Server) requireAlertMutationAccess(w http.ResponseWriter, r *http.Request) bool {
+// requireMutationPermission is requirePermission for alert-side settings,
+// which are read-only in public mode.
+func (s *Server) requireMutationPermission(w http.ResponseWriter, r *http.Request, perm auth.Permission) bool {
 	if s.authDisabled {
 		writeJSON(w, http.StatusForbidden, map[string]string{"error": "read-only in public mode"})
 		return false
 	}
-	if _, ok := s.requireAdmin(w, r); !ok {
-		return false
+	_, ok := s.requirePermission(w, r, perm)
+	return ok
+}
+
+// requireActionPermission gates host actions such as killing a port. Public
+// mode keeps them open, as it always has.
+func (s *Server) requireActionPermission(w http.ResponseWriter, r *http.Request, perm auth.Permission) bool {
+	if s.authDisabled {
+		return true
 	}
-	return true
+	_, ok := s.requirePermission(w, r, perm)
+	return ok
 }
 
 func (s *Server) handleAuthLogin(w http.ResponseWriter, r *http.Request) {
@@ -118,9 +131,20 @@ func (s *Server) handleAuthLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	ip := clientIP(r)
+	username := loginUsername(body.Username)
+	now := time.Now()
+	attempt, retryAt, ok := s.sessions.BeginLoginAttempt(ip, username, now)
+	if !ok {
+		writeLoginThrottled(w, retryAt, now)
+		return
+	}
+	defer attempt.Release()
+
 	user, err := s.authStore.Authenticate(body.Username, body.Password)
 	if err != nil {
 		if errors.Is(err, auth.ErrInvalidCredentials) {
+			s.recordLoginFailure(attempt, ip, username, "invalid_credentials")
 			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid credentials"})
 			return
 		}
@@ -128,23 +152,62 @@ func (s *Server) handleAuthLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	session, err := s.sessions.Create(user)
+	// With TOTP enabled the password only opens a challenge; the session is
+	// created by POST /api/auth/login/2fa.
+	if user.TwoFactorEnabled {
+		challenge, expiresAt, err := s.sessions.CreateLoginChallenge(user)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to create login challenge"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{
+			"two_factor_required": true,
+			"challenge":           challenge,
+			"expires_at":          expiresAt,
+		})
+		return
+	}
+
+	s.startSession(w, r, user)
+}
+
+// startSession creates a session for user, sets the cookie and writes the
+// login response.
+func (s *Server) startSession(w http.ResponseWriter, r *http.Request, user auth.User) {
+	enrollmentRequired, err := s.authStore.TwoFactorEnrollmentRequired(user)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+
+	userAgent := r.UserAgent()
+	if len(userAgent) > 256 {
+		userAgent = userAgent[:256]
+	}
+	session, err := s.sessions.Create(user, clientIP(r), userAgent)
 	if err != nil {
 		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to create session"})
 		return
 	}
+	s.sessions.RecordLoginSuccess(user.Username)
 
+	// The idle timeout slides with use, so the cookie is kept until the
+	// absolute limit and the server decides when the session ends.
 	http.SetCookie(w, &http.Cookie{
 		Name:     sessionCookieName,
 		Value:    session.Token,
 		Path:     "/",
 		HttpOnly: true,
 		SameSite: http.SameSiteLaxMode,
-		Expires:  session.ExpiresAt,
+		Expires:  session.MaxExpiresAt,
 		Secure:   r.TLS != nil,
 	})
 
-	writeJSON(w, http.StatusOK, map[string]any{"user": user})
+	writeJSON(w, http.StatusOK, map[string]any{
+		"user":                           user,
+		"permissions":                    s.authStore.RolePermissions(user.Role),
+		"two_factor_enrollment_required": enrollmentRequired,
+	})
 }
 
 func (s *Server) handleAuthLogout(w http.ResponseWriter, r *http.Request) {
@@ -185,17 +248,31 @@ func (s *Server) handleAuthMe(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, ok := s.currentUser(r)
+	session, ok := s.currentUser(r)
 	if !ok {
 		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
 		return
 	}
+	user, err := s.authStore.GetUserByID(session.ID)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		return
+	}
+	enrollmentRequired, err := s.authStore.TwoFactorEnrollmentRequired(user)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
 
-	writeJSON(w, http.StatusOK, map[string]any{"user": user})
+	writeJSON(w, http.StatusOK, map[string]any{
+		"user":                           user,
+		"permissions":                    s.authStore.RolePermissions(user.Role),
+		"two_factor_enrollment_required": enrollmentRequired,
+	})
 }
 
 func (s *Server) handleUsers(w http.ResponseWriter, r *http.Request) {
-	admin, ok := s.requireAdmin(w, r)
+	admin, ok := s.requirePermission(w, r, auth.PermUsersManage)
 	if !ok {
 		return
 	}
@@ -256,7 +333,7 @@ func (s *Server) handleUsers(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) handleUserByID(w http.ResponseWriter, r *http.Request) {
-	admin, ok := s.requireAdmin(w, r)
+	admin, ok := s.requirePermission(w, r, auth.PermUsersManage)
 	if !ok {
 		return
 	}
@@ -278,6 +355,9 @@ func (s *Server) handleUserByID(w http.ResponseWriter, r *http.Request) {
 		var body struct {
 			Role     *auth.Role `json:"role"`
 			Password *string    `json:"password"`
+			// ResetTwoFactor removes TOTP and recovery codes, e.g. after a
+			// lost phone.
+			ResetTwoFactor bool `json:"reset_two_factor"`
 		}
 		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
 			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON body"})
@@ -299,7 +379,15 @@ func (s *Server) handleUserByID(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		if body.Role != nil || body.Password != nil {
+		if body.ResetTwoFactor {
+			if err := s.authStore.DisableTOTP(userID); err != nil {
+				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+				return
+			}
+			updated.TwoFactorEnabled = false
+		}
+
+		if body.Role != nil || body.Password != nil || body.ResetTwoFactor {
 			s.sessions.DeleteByUserID(userID)
 		}
 
@@ -312,6 +400,7 @@ func (s *Server) handleUserByID(w http.ResponseWriter, r *http.Request) {
 			mustJSON(map[string]any{
 				"role_changed":     body.Role != nil,
 				"password_changed": body.Password != nil,
+				"two_factor_reset": body.ResetTwoFactor,
 				"new_role":         updated.Role,
 			}),
 		)
@@ -366,7 +455,7 @@ func (s *Server) handleUserByID(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) handleUserAudit(w http.ResponseWriter, r *http.Request) {
-	if _, ok := s.requireAdmin(w, r); !ok {
+	if _, ok := s.requirePermission(w, r, auth.PermUsersManage); !ok {
 		return
 	}
 
@@ -621,6 +710,51 @@ func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, snap)
 }
 
+func (s *Server) handleProcesses(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.collector.ProcessesEnabled() {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "process collector disabled"})
+		return
+	}
+
+	limit := 0
+	if raw := strings.TrimSpace(r.URL.Query().Get("limit")); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid limit"})
+			return
+		}
+		limit = parsed
+	}
+
+	snap := s.collector.Latest()
+	if snap == nil || snap.Processes == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "no data yet"})
+		return
+	}
+
+	list := *snap.Processes
+	if limit > 0 {
+		list.ByCPU = truncateProcesses(list.ByCPU, limit)
+		list.ByMemory = truncateProcesses(list.ByMemory, limit)
+		list.ByIO = truncateProcesses(list.ByIO, limit)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"timestamp": snap.Timestamp,
+		"processes": list,
+	})
+}
+
+func truncateProcesses(items []metrics.ProcessInfo, limit int) []metrics.ProcessInfo {
+	if len(items) > limit {
+		return items[:limit]
+	}
+	return items
+}
+
 func (s *Server) handleWS(w http.ResponseWriter, r *http.Request) {
 	client, err := ws.NewClient(s.hub, w, r)
 	if err != nil {
@@ -633,6 +767,9 @@ func (s *Server) handleWS(w http.ResponseWriter, r *http.Request) {
 func (s *Server) handleNcduScan(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodPost:
+		if !s.requireActionPermission(w, r, auth.PermStorageScan) {
+			return
+		}
 		var body struct {
 			Path string `json:"path"`
 		}
@@ -657,6 +794,9 @@ func (s *Server) handleNcduScan(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusAccepted, map[string]string{"status": "started", "path": body.Path})
 
 	case http.MethodDelete:
+		if !s.requireActionPermission(w, r, auth.PermStorageScan) {
+			return
+		}
 		s.runner.Cancel()
 		writeJSON(w, http.StatusOK, map[string]string{"status": "cancelled"})
 
@@ -731,6 +871,10 @@ func (s *Server) handlePortByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !s.requireActionPermission(w, r, auth.PermPortsKill) {
+		return
+	}
+
 	portPart := strings.TrimPrefix(r.URL.Path, "/api/ports/")
 	if portPart == "" || strings.Contains(portPart, "/") {
 		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid port"})
@@ -813,7 +957,7 @@ func (s *Server) handleAlertsConfig(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusOK, view)
 
 	case http.MethodPut:
-		if !s.requireAlertMutationAccess(w, r) {
+		if !s.requireMutationPermission(w, r, auth.PermAlertsManage) {
 			return
 		}
 
@@ -895,7 +1039,7 @@ func (s *Server) handleAlertsTest(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
 		return
 	}
-	if !s.requireAlertMutationAccess(w, r) {
+	if !s.requireMutationPermission(w, r, auth.PermAlertsManage) {
 		return
 	}
 
@@ -915,7 +1059,7 @@ func (s *Server) handleAlertsSilence(w http.ResponseWriter, r *http.Request) {
 
 	switch r.Method {
 	case http.MethodPost:
-		if !s.requireAlertMutationAccess(w, r) {
+		if !s.requireMutationPermission(w, r, auth.PermAlertsSilence) {
 			return
 		}
 		var body struct {
@@ -933,7 +1077,7 @@ func (s *Server) handleAlertsSilence(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusOK, map[string]any{"muted_until": until})
 
 	case http.MethodDelete:
-		if !s.requireAlertMutationAccess(w, r) {
+		if !s.requireMutationPermission(w, r, auth.PermAlertsSilence) {
 			return
 		}
 		if err := s.alerts.ClearSilence(); err != nil {
@@ -0,0 +1,526 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"quickvps/internal/alerts"
+	"quickvps/internal/auth"
+)
+
+func (s *Server) handleAlertRules(w http.ResponseWriter, r *http.Request) {
+	if s.alerts == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "alerts service unavailable"})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, map[string]any{"rules": s.alerts.ListRules()})
+
+	case http.MethodPost:
+		if !s.requireMutationPermission(w, r, auth.PermAlertsManage) {
+			return
+		}
+		var body alerts.RuleInput
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON body"})
+			return
+		}
+		rule, err := s.alerts.CreateRule(body)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusCreated, rule)
+
+	default:
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) handleAlertRuleByID(w http.ResponseWriter, r *http.Request) {
+	if s.alerts == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "alerts service unavailable"})
+		return
+	}
+
+	idPart := strings.TrimPrefix(r.URL.Path, "/api/alerts/rules/")
+	ruleID, err := strconv.ParseInt(idPart, 10, 64)
+	if err != nil || ruleID <= 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid rule id"})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		if !s.requireMutationPermission(w, r, auth.PermAlertsManage) {
+			return
+		}
+		var body alerts.RuleInput
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON body"})
+			return
+		}
+		rule, err := s.alerts.UpdateRule(ruleID, body)
+		if err != nil {
+			writeAlertRuleError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, rule)
+
+	case http.MethodDelete:
+		if !s.requireMutationPermission(w, r, auth.PermAlertsManage) {
+			return
+		}
+		if err := s.alerts.DeleteRule(ruleID); err != nil {
+			writeAlertRuleError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
+
+	default:
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func writeAlertRuleError(w http.ResponseWriter, err error) {
+	if errors.Is(err, alerts.ErrRuleNotFound) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+}
+
+func (s *Server) handleAlertOutbox(w http.ResponseWriter, r *http.Request) {
+	if s.alerts == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "alerts service unavailable"})
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	items, err := s.alerts.ListOutbox()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"items": items})
+}
+
+// handleAlertOutboxItem serves POST /api/alerts/outbox/{id}/retry and
+// DELETE /api/alerts/outbox/{id}.
+func (s *Server) handleAlertOutboxItem(w http.ResponseWriter, r *http.Request) {
+	if s.alerts == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "alerts service unavailable"})
+		return
+	}
+
+	rest := strings.TrimPrefix(r.URL.Path, "/api/alerts/outbox/")
+	idPart, action, _ := strings.Cut(rest, "/")
+	itemID, err := strconv.ParseInt(idPart, 10, 64)
+	if err != nil || itemID <= 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid outbox id"})
+		return
+	}
+
+	switch {
+	case action == "retry" && r.Method == http.MethodPost:
+		if !s.requireMutationPermission(w, r, auth.PermAlertsManage) {
+			return
+		}
+		result, err := s.alerts.RetryOutbox(r.Context(), itemID)
+		if err != nil {
+			writeAlertOutboxError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, result)
+
+	case action == "" && r.Method == http.MethodDelete:
+		if !s.requireMutationPermission(w, r, auth.PermAlertsManage) {
+			return
+		}
+		if err := s.alerts.DropOutbox(itemID); err != nil {
+			writeAlertOutboxError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"status": "dropped"})
+
+	case action != "" && action != "retry":
+		http.NotFound(w, r)
+
+	default:
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func writeAlertOutboxError(w http.ResponseWriter, err error) {
+	if errors.Is(err, alerts.ErrOutboxNotFound) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+}
+
+func (s *Server) handleAlertIncidents(w http.ResponseWriter, r *http.Request) {
+	if s.alerts == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "alerts service unavailable"})
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	status := alerts.IncidentStatus(strings.TrimSpace(query.Get("status")))
+	switch status {
+	case "", alerts.IncidentOpen, alerts.IncidentAcknowledged, alerts.IncidentResolved:
+	default:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid status"})
+		return
+	}
+
+	limit := 50
+	if raw := strings.TrimSpace(query.Get("limit")); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid limit"})
+			return
+		}
+		limit = parsed
+	}
+
+	var beforeID int64
+	if raw := strings.TrimSpace(query.Get("before_id")); raw != "" {
+		parsed, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || parsed <= 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid before_id"})
+			return
+		}
+		beforeID = parsed
+	}
+
+	incidents, err := s.alerts.ListIncidents(status, limit, beforeID)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"incidents": incidents})
+}
+
+// handleAlertIncidentByID serves GET /api/alerts/incidents/{id} and
+// POST /api/alerts/incidents/{id}/ack.
+func (s *Server) handleAlertIncidentByID(w http.ResponseWriter, r *http.Request) {
+	if s.alerts == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "alerts service unavailable"})
+		return
+	}
+
+	rest := strings.TrimPrefix(r.URL.Path, "/api/alerts/incidents/")
+	idPart, action, _ := strings.Cut(rest, "/")
+	incidentID, err := strconv.ParseInt(idPart, 10, 64)
+	if err != nil || incidentID <= 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid incident id"})
+		return
+	}
+
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		incident, err := s.alerts.GetIncident(incidentID)
+		if err != nil {
+			writeAlertIncidentError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, incident)
+
+	case action == "ack" && r.Method == http.MethodPost:
+		if !s.requireMutationPermission(w, r, auth.PermAlertsSilence) {
+			return
+		}
+		user, _ := s.currentUser(r)
+		var body struct {
+			Note string `json:"note"`
+		}
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON body"})
+				return
+			}
+		}
+		incident, err := s.alerts.AcknowledgeIncident(incidentID, user.Username, body.Note)
+		if err != nil {
+			writeAlertIncidentError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, incident)
+
+	case action != "" && action != "ack":
+		http.NotFound(w, r)
+
+	default:
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func writeAlertIncidentError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, alerts.ErrIncidentNotFound):
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+	case errors.Is(err, alerts.ErrIncidentResolved), errors.Is(err, alerts.ErrIncidentAcknowledged):
+		writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+	default:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+}
+
+func (s *Server) handleAlertMaintenance(w http.ResponseWriter, r *http.Request) {
+	if s.alerts == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "alerts service unavailable"})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, map[string]any{"windows": s.alerts.ListMaintenanceWindows()})
+
+	case http.MethodPost:
+		if !s.requireMutationPermission(w, r, auth.PermAlertsManage) {
+			return
+		}
+		var body alerts.MaintenanceWindowInput
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON body"})
+			return
+		}
+		window, err := s.alerts.CreateMaintenanceWindow(body)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusCreated, window)
+
+	default:
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) handleAlertMaintenanceByID(w http.ResponseWriter, r *http.Request) {
+	if s.alerts == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "alerts service unavailable"})
+		return
+	}
+
+	idPart := strings.TrimPrefix(r.URL.Path, "/api/alerts/maintenance/")
+	windowID, err := strconv.ParseInt(idPart, 10, 64)
+	if err != nil || windowID <= 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid maintenance window id"})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		if !s.requireMutationPermission(w, r, auth.PermAlertsManage) {
+			return
+		}
+		var body alerts.MaintenanceWindowInput
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON body"})
+			return
+		}
+		window, err := s.alerts.UpdateMaintenanceWindow(windowID, body)
+		if err != nil {
+			writeAlertMaintenanceError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, window)
+
+	case http.MethodDelete:
+		if !s.requireMutationPermission(w, r, auth.PermAlertsManage) {
+			return
+		}
+		if err := s.alerts.DeleteMaintenanceWindow(windowID); err != nil {
+			writeAlertMaintenanceError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
+
+	default:
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func writeAlertMaintenanceError(w http.ResponseWriter, err error) {
+	if errors.Is(err, alerts.ErrMaintenanceWindowNotFound) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+}
+
+func (s *Server) handleAlertTemplates(w http.ResponseWriter, r *http.Request) {
+	if s.alerts == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "alerts service unavailable"})
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"templates": s.alerts.ListTemplates(),
+		"channels":  alerts.TemplateChannels,
+		"levels":    alerts.TemplateLevels,
+	})
+}
+
+// handleAlertTemplateByKey serves PUT and DELETE on
+// /api/alerts/templates/{channel}/{level}; "*" matches any channel or level.
+func (s *Server) handleAlertTemplateByKey(w http.ResponseWriter, r *http.Request) {
+	if s.alerts == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "alerts service unavailable"})
+		return
+	}
+
+	rest := strings.TrimPrefix(r.URL.Path, "/api/alerts/templates/")
+	channel, level, ok := strings.Cut(rest, "/")
+	if !ok || channel == "" || level == "" || strings.Contains(level, "/") {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		if !s.requireMutationPermission(w, r, auth.PermAlertsManage) {
+			return
+		}
+		var body alerts.TemplateInput
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON body"})
+			return
+		}
+		saved, err := s.alerts.SaveTemplate(channel, alerts.Level(level), body)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, saved)
+
+	case http.MethodDelete:
+		if !s.requireMutationPermission(w, r, auth.PermAlertsManage) {
+			return
+		}
+		if err := s.alerts.DeleteTemplate(channel, alerts.Level(level)); err != nil {
+			if errors.Is(err, alerts.ErrTemplateNotFound) {
+				writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+				return
+			}
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
+
+	default:
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) handleAlertTemplatePreview(w http.ResponseWriter, r *http.Request) {
+	if s.alerts == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "alerts service unavailable"})
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var body alerts.TemplatePreviewInput
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON body"})
+		return
+	}
+	preview, err := s.alerts.PreviewTemplate(body)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, preview)
+}
+
+// handleAlertDigestPreview builds the digest for the period ending now
+// without sending it.
+func (s *Server) handleAlertDigestPreview(w http.ResponseWriter, r *http.Request) {
+	if s.alerts == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "alerts service unavailable"})
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, http.StatusOK, s.alerts.BuildDigest(time.Now()))
+}
+
+func (s *Server) handleAlertDigestSend(w http.ResponseWriter, r *http.Request) {
+	if s.alerts == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "alerts service unavailable"})
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.requireMutationPermission(w, r, auth.PermAlertsManage) {
+		return
+	}
+	event, err := s.alerts.SendDigest(r.Context(), time.Now())
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"event": event})
+}
+
+// handleAlertKeys reports the encryption key ring and which keys the stored
+// secrets are sealed under.
+func (s *Server) handleAlertKeys(w http.ResponseWriter, r *http.Request) {
+	if s.alerts == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "alerts service unavailable"})
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, http.StatusOK, s.alerts.KeyStatus())
+}
+
+// handleAlertKeysRotate re-encrypts the stored secrets under the current key.
+func (s *Server) handleAlertKeysRotate(w http.ResponseWriter, r *http.Request) {
+	if s.alerts == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "alerts service unavailable"})
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.requireMutationPermission(w, r, auth.PermAlertsManage) {
+		return
+	}
+	rotated, status, err := s.alerts.RotateSecrets()
+	switch {
+	case errors.Is(err, alerts.ErrMissingEncryptionKey):
+		writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+		return
+	case err != nil:
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"rotated": rotated, "keys": status})
+}
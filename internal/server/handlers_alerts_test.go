@@ -2,14 +2,18 @@ package server
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"net/http"
 	"net/http/httptest"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"quickvps/internal/alerts"
+	"quickvps/internal/metrics"
 )
 
 func newAlertsServiceForTests(t *testing.T) *alerts.Service {
@@ -98,3 +102,304 @@ func TestHandleAlertsPublicModeReadOnly(t *testing.T) {
 		t.Fatalf("read_only = %v, want true", statusBody["read_only"])
 	}
 }
+
+func TestHandleAlertRulesCRUD(t *testing.T) {
+	s, _, admin, viewer := newServerForAuthTests(t)
+	s.alerts = newAlertsServiceForTests(t)
+
+	viewerReq := withUser(httptest.NewRequest(http.MethodPost, "/api/alerts/rules", bytes.NewReader([]byte(`{"series":"memory.percent","warning":80,"critical":90,"recovery":70}`))), viewer)
+	viewerRec := httptest.NewRecorder()
+	s.handleAlertRules(viewerRec, viewerReq)
+	if viewerRec.Code != http.StatusForbidden {
+		t.Fatalf("handleAlertRules(POST viewer) status = %d, want %d", viewerRec.Code, http.StatusForbidden)
+	}
+
+	badReq := withUser(httptest.NewRequest(http.MethodPost, "/api/alerts/rules", bytes.NewReader([]byte(`{"series":"disk.percent","warning":80,"critical":90,"recovery":70}`))), admin)
+	badRec := httptest.NewRecorder()
+	s.handleAlertRules(badRec, badReq)
+	if badRec.Code != http.StatusBadRequest {
+		t.Fatalf("handleAlertRules(POST unlabeled disk) status = %d, want %d", badRec.Code, http.StatusBadRequest)
+	}
+
+	createReq := withUser(httptest.NewRequest(http.MethodPost, "/api/alerts/rules", bytes.NewReader([]byte(`{"name":"Root disk","series":"disk.percent:/","warning":80,"critical":90,"recovery":70}`))), admin)
+	createRec := httptest.NewRecorder()
+	s.handleAlertRules(createRec, createReq)
+	if createRec.Code != http.StatusCreated {
+		t.Fatalf("handleAlertRules(POST) status = %d, want %d body=%s", createRec.Code, http.StatusCreated, createRec.Body.String())
+	}
+	created := decodeBody(t, createRec)
+	id, _ := created["id"].(float64)
+	if id <= 0 || created["warning_for_sec"].(float64) != 300 {
+		t.Fatalf("created rule = %v, want id and default windows", created)
+	}
+	ruleURL := "/api/alerts/rules/" + strconv.Itoa(int(id))
+
+	putReq := withUser(httptest.NewRequest(http.MethodPut, ruleURL, bytes.NewReader([]byte(`{"critical":95}`))), admin)
+	putRec := httptest.NewRecorder()
+	s.handleAlertRuleByID(putRec, putReq)
+	if putRec.Code != http.StatusOK || decodeBody(t, putRec)["critical"].(float64) != 95 {
+		t.Fatalf("handleAlertRuleByID(PUT) status = %d body=%s", putRec.Code, putRec.Body.String())
+	}
+
+	listRec := httptest.NewRecorder()
+	s.handleAlertRules(listRec, withUser(httptest.NewRequest(http.MethodGet, "/api/alerts/rules", nil), viewer))
+	if rules, _ := decodeBody(t, listRec)["rules"].([]any); len(rules) != 1 {
+		t.Fatalf("rules = %v, want 1", rules)
+	}
+
+	delRec := httptest.NewRecorder()
+	s.handleAlertRuleByID(delRec, withUser(httptest.NewRequest(http.MethodDelete, ruleURL, nil), admin))
+	if delRec.Code != http.StatusOK {
+		t.Fatalf("handleAlertRuleByID(DELETE) status = %d, want %d", delRec.Code, http.StatusOK)
+	}
+	missingRec := httptest.NewRecorder()
+	s.handleAlertRuleByID(missingRec, withUser(httptest.NewRequest(http.MethodDelete, ruleURL, nil), admin))
+	if missingRec.Code != http.StatusNotFound {
+		t.Fatalf("handleAlertRuleByID(DELETE missing) status = %d, want %d", missingRec.Code, http.StatusNotFound)
+	}
+}
+
+func TestHandleAlertOutbox(t *testing.T) {
+	s, _, admin, viewer := newServerForAuthTests(t)
+	s.alerts = newAlertsServiceForTests(t)
+
+	listRec := httptest.NewRecorder()
+	s.handleAlertOutbox(listRec, withUser(httptest.NewRequest(http.MethodGet, "/api/alerts/outbox", nil), viewer))
+	if listRec.Code != http.StatusOK {
+		t.Fatalf("handleAlertOutbox(GET) status = %d, want %d", listRec.Code, http.StatusOK)
+	}
+	if items, ok := decodeBody(t, listRec)["items"].([]any); !ok || len(items) != 0 {
+		t.Fatalf("items = %v, want empty list", items)
+	}
+
+	cases := []struct {
+		method string
+		path   string
+		user   bool
+		want   int
+	}{
+		{http.MethodDelete, "/api/alerts/outbox/7", false, http.StatusForbidden},
+		{http.MethodDelete, "/api/alerts/outbox/7", true, http.StatusNotFound},
+		{http.MethodPost, "/api/alerts/outbox/7/retry", true, http.StatusNotFound},
+		{http.MethodPost, "/api/alerts/outbox/abc/retry", true, http.StatusBadRequest},
+		{http.MethodPost, "/api/alerts/outbox/7", true, http.StatusMethodNotAllowed},
+	}
+	for _, tc := range cases {
+		user := viewer
+		if tc.user {
+			user = admin
+		}
+		rec := httptest.NewRecorder()
+		s.handleAlertOutboxItem(rec, withUser(httptest.NewRequest(tc.method, tc.path, nil), user))
+		if rec.Code != tc.want {
+			t.Fatalf("%s %s status = %d, want %d", tc.method, tc.path, rec.Code, tc.want)
+		}
+	}
+}
+
+func TestHandleAlertIncidentsAck(t *testing.T) {
+	s, _, admin, viewer := newServerForAuthTests(t)
+	s.alerts = newAlertsServiceForTests(t)
+
+	off, warnFor := false, int64(1)
+	if _, err := s.alerts.UpdateConfig(alerts.UpdateConfigInput{TelegramEnabled: &off, EmailEnabled: &off, WarningForSec: &warnFor}); err != nil {
+		t.Fatalf("UpdateConfig() error = %v", err)
+	}
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 2; i++ {
+		s.alerts.EvaluateSnapshot(context.Background(), &metrics.Snapshot{
+			Timestamp: base.Add(time.Duration(i) * time.Second),
+			CPU:       metrics.CPUMetrics{TotalPercent: 80},
+		})
+	}
+
+	listRec := httptest.NewRecorder()
+	s.handleAlertIncidents(listRec, withUser(httptest.NewRequest(http.MethodGet, "/api/alerts/incidents?status=open", nil), viewer))
+	incidents, _ := decodeBody(t, listRec)["incidents"].([]any)
+	if listRec.Code != http.StatusOK || len(incidents) != 1 {
+		t.Fatalf("handleAlertIncidents(GET) status = %d body=%s", listRec.Code, listRec.Body.String())
+	}
+	id := int64(incidents[0].(map[string]any)["id"].(float64))
+	ackPath := "/api/alerts/incidents/" + strconv.FormatInt(id, 10) + "/ack"
+
+	viewerRec := httptest.NewRecorder()
+	s.handleAlertIncidentByID(viewerRec, withUser(httptest.NewRequest(http.MethodPost, ackPath, strings.NewReader(`{"note":"x"}`)), viewer))
+	if viewerRec.Code != http.StatusForbidden {
+		t.Fatalf("ack(viewer) status = %d, want %d", viewerRec.Code, http.StatusForbidden)
+	}
+
+	ackRec := httptest.NewRecorder()
+	s.handleAlertIncidentByID(ackRec, withUser(httptest.NewRequest(http.MethodPost, ackPath, strings.NewReader(`{"note":"restarting worker"}`)), admin))
+	if ackRec.Code != http.StatusOK {
+		t.Fatalf("ack(admin) status = %d body=%s", ackRec.Code, ackRec.Body.String())
+	}
+	body := decodeBody(t, ackRec)
+	if body["acked_by"] != admin.Username || body["ack_note"] != "restarting worker" || body["status"] != "acknowledged" {
+		t.Fatalf("ack body = %v", body)
+	}
+	if _, ok := body["time_to_ack_sec"]; !ok {
+		t.Fatalf("time_to_ack_sec missing: %v", body)
+	}
+
+	againRec := httptest.NewRecorder()
+	s.handleAlertIncidentByID(againRec, withUser(httptest.NewRequest(http.MethodPost, ackPath, nil), admin))
+	if againRec.Code != http.StatusConflict {
+		t.Fatalf("second ack status = %d, want %d", againRec.Code, http.StatusConflict)
+	}
+
+	missingRec := httptest.NewRecorder()
+	s.handleAlertIncidentByID(missingRec, withUser(httptest.NewRequest(http.MethodGet, "/api/alerts/incidents/999", nil), viewer))
+	if missingRec.Code != http.StatusNotFound {
+		t.Fatalf("GET missing status = %d, want %d", missingRec.Code, http.StatusNotFound)
+	}
+
+	badRec := httptest.NewRecorder()
+	s.handleAlertIncidents(badRec, withUser(httptest.NewRequest(http.MethodGet, "/api/alerts/incidents?status=bogus", nil), viewer))
+	if badRec.Code != http.StatusBadRequest {
+		t.Fatalf("bad status filter code = %d, want %d", badRec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestHandleAlertMaintenanceCRUD(t *testing.T) {
+	s, _, admin, viewer := newServerForAuthTests(t)
+	s.alerts = newAlertsServiceForTests(t)
+
+	payload := `{"name":"Nightly backup","kind":"weekly","weekdays":[0,6],"start_time":"23:30","end_time":"00:30","timezone":"Europe/Berlin"}`
+	viewerRec := httptest.NewRecorder()
+	s.handleAlertMaintenance(viewerRec, withUser(httptest.NewRequest(http.MethodPost, "/api/alerts/maintenance", strings.NewReader(payload)), viewer))
+	if viewerRec.Code != http.StatusForbidden {
+		t.Fatalf("create(viewer) status = %d, want %d", viewerRec.Code, http.StatusForbidden)
+	}
+
+	createRec := httptest.NewRecorder()
+	s.handleAlertMaintenance(createRec, withUser(httptest.NewRequest(http.MethodPost, "/api/alerts/maintenance", strings.NewReader(payload)), admin))
+	if createRec.Code != http.StatusCreated {
+		t.Fatalf("create status = %d body=%s", createRec.Code, createRec.Body.String())
+	}
+	created := decodeBody(t, createRec)
+	if created["timezone"] != "Europe/Berlin" || created["enabled"] != true {
+		t.Fatalf("created = %v", created)
+	}
+	path := "/api/alerts/maintenance/" + strconv.FormatInt(int64(created["id"].(float64)), 10)
+
+	invalidRec := httptest.NewRecorder()
+	s.handleAlertMaintenanceByID(invalidRec, withUser(httptest.NewRequest(http.MethodPut, path, strings.NewReader(`{"timezone":"Nowhere/City"}`)), admin))
+	if invalidRec.Code != http.StatusBadRequest {
+		t.Fatalf("invalid update status = %d, want %d", invalidRec.Code, http.StatusBadRequest)
+	}
+
+	updateRec := httptest.NewRecorder()
+	s.handleAlertMaintenanceByID(updateRec, withUser(httptest.NewRequest(http.MethodPut, path, strings.NewReader(`{"kind":"cron","cron":"0 4 * * 1-5","duration_min":45}`)), admin))
+	if updateRec.Code != http.StatusOK {
+		t.Fatalf("update status = %d body=%s", updateRec.Code, updateRec.Body.String())
+	}
+	if body := decodeBody(t, updateRec); body["cron"] != "0 4 * * 1-5" || body["kind"] != "cron" {
+		t.Fatalf("updated = %v", body)
+	}
+
+	listRec := httptest.NewRecorder()
+	s.handleAlertMaintenance(listRec, withUser(httptest.NewRequest(http.MethodGet, "/api/alerts/maintenance", nil), viewer))
+	if windows, _ := decodeBody(t, listRec)["windows"].([]any); listRec.Code != http.StatusOK || len(windows) != 1 {
+		t.Fatalf("list status = %d body=%s", listRec.Code, listRec.Body.String())
+	}
+
+	deleteRec := httptest.NewRecorder()
+	s.handleAlertMaintenanceByID(deleteRec, withUser(httptest.NewRequest(http.MethodDelete, path, nil), admin))
+	if deleteRec.Code != http.StatusOK {
+		t.Fatalf("delete status = %d", deleteRec.Code)
+	}
+	missingRec := httptest.NewRecorder()
+	s.handleAlertMaintenanceByID(missingRec, withUser(httptest.NewRequest(http.MethodDelete, path, nil), admin))
+	if missingRec.Code != http.StatusNotFound {
+		t.Fatalf("delete missing status = %d, want %d", missingRec.Code, http.StatusNotFound)
+	}
+}
+
+func TestHandleAlertTemplates(t *testing.T) {
+	s, _, admin, viewer := newServerForAuthTests(t)
+	s.alerts = newAlertsServiceForTests(t)
+
+	viewerRec := httptest.NewRecorder()
+	s.handleAlertTemplateByKey(viewerRec, withUser(httptest.NewRequest(http.MethodPut, "/api/alerts/templates/telegram/critical", strings.NewReader(`{"body":"x"}`)), viewer))
+	if viewerRec.Code != http.StatusForbidden {
+		t.Fatalf("PUT(viewer) status = %d, want %d", viewerRec.Code, http.StatusForbidden)
+	}
+
+	badRec := httptest.NewRecorder()
+	s.handleAlertTemplateByKey(badRec, withUser(httptest.NewRequest(http.MethodPut, "/api/alerts/templates/telegram/critical", strings.NewReader(`{"body":"{{.Missing}}"}`)), admin))
+	if badRec.Code != http.StatusBadRequest {
+		t.Fatalf("PUT(invalid) status = %d, want %d", badRec.Code, http.StatusBadRequest)
+	}
+
+	putRec := httptest.NewRecorder()
+	s.handleAlertTemplateByKey(putRec, withUser(httptest.NewRequest(http.MethodPut, "/api/alerts/templates/*/critical", strings.NewReader(`{"subject":"{{.Host}}","body":"{{.ValueText}}"}`)), admin))
+	if putRec.Code != http.StatusOK {
+		t.Fatalf("PUT status = %d body=%s", putRec.Code, putRec.Body.String())
+	}
+
+	listRec := httptest.NewRecorder()
+	s.handleAlertTemplates(listRec, withUser(httptest.NewRequest(http.MethodGet, "/api/alerts/templates", nil), viewer))
+	if templates, _ := decodeBody(t, listRec)["templates"].([]any); len(templates) != 1 {
+		t.Fatalf("GET templates body=%s", listRec.Body.String())
+	}
+
+	previewRec := httptest.NewRecorder()
+	s.handleAlertTemplatePreview(previewRec, withUser(httptest.NewRequest(http.MethodPost, "/api/alerts/templates/preview", strings.NewReader(`{"channel":"email","level":"warning","subject":"{{upper .Level}}","body":"{{.Rule}} {{.Thresholds.Warning}}"}`)), viewer))
+	if body := decodeBody(t, previewRec); previewRec.Code != http.StatusOK || body["subject"] != "WARNING" || body["message"] != "CPU 75" {
+		t.Fatalf("preview status = %d body=%v", previewRec.Code, body)
+	}
+
+	deleteRec := httptest.NewRecorder()
+	s.handleAlertTemplateByKey(deleteRec, withUser(httptest.NewRequest(http.MethodDelete, "/api/alerts/templates/*/critical", nil), admin))
+	if deleteRec.Code != http.StatusOK {
+		t.Fatalf("DELETE status = %d", deleteRec.Code)
+	}
+	missingRec := httptest.NewRecorder()
+	s.handleAlertTemplateByKey(missingRec, withUser(httptest.NewRequest(http.MethodDelete, "/api/alerts/templates/*/critical", nil), admin))
+	if missingRec.Code != http.StatusNotFound {
+		t.Fatalf("DELETE missing status = %d, want %d", missingRec.Code, http.StatusNotFound)
+	}
+}
+
+func TestHandleAlertDigest(t *testing.T) {
+	s, _, _, viewer := newServerForAuthTests(t)
+	s.alerts = newAlertsServiceForTests(t)
+
+	previewRec := httptest.NewRecorder()
+	s.handleAlertDigestPreview(previewRec, withUser(httptest.NewRequest(http.MethodGet, "/api/alerts/digest/preview", nil), viewer))
+	if body := decodeBody(t, previewRec); previewRec.Code != http.StatusOK || body["period"] != "daily" || body["text"] == "" {
+		t.Fatalf("preview status = %d body=%v", previewRec.Code, body)
+	}
+
+	sendRec := httptest.NewRecorder()
+	s.handleAlertDigestSend(sendRec, withUser(httptest.NewRequest(http.MethodPost, "/api/alerts/digest/send", nil), viewer))
+	if sendRec.Code != http.StatusForbidden {
+		t.Fatalf("send(viewer) status = %d, want %d", sendRec.Code, http.StatusForbidden)
+	}
+}
+
+func TestHandleAlertKeysRotate(t *testing.T) {
+	s, _, admin, viewer := newServerForAuthTests(t)
+	s.alerts = newAlertsServiceForTests(t)
+	if _, err := s.alerts.UpdateConfig(alerts.UpdateConfigInput{TelegramBotToken: "tg-token"}); err != nil {
+		t.Fatalf("UpdateConfig() error = %v", err)
+	}
+
+	getRec := httptest.NewRecorder()
+	s.handleAlertKeys(getRec, withUser(httptest.NewRequest(http.MethodGet, "/api/alerts/keys", nil), viewer))
+	if body := decodeBody(t, getRec); getRec.Code != http.StatusOK || body["current_key_id"] == "" || body["pending_rotation"] != float64(0) {
+		t.Fatalf("GET status = %d body=%v", getRec.Code, body)
+	}
+
+	viewerRec := httptest.NewRecorder()
+	s.handleAlertKeysRotate(viewerRec, withUser(httptest.NewRequest(http.MethodPost, "/api/alerts/keys/rotate", nil), viewer))
+	if viewerRec.Code != http.StatusForbidden {
+		t.Fatalf("rotate(viewer) status = %d, want %d", viewerRec.Code, http.StatusForbidden)
+	}
+
+	adminRec := httptest.NewRecorder()
+	s.handleAlertKeysRotate(adminRec, withUser(httptest.NewRequest(http.MethodPost, "/api/alerts/keys/rotate", nil), admin))
+	if body := decodeBody(t, adminRec); adminRec.Code != http.StatusOK || body["rotated"] != float64(0) {
+		t.Fatalf("rotate(admin) status = %d body=%v", adminRec.Code, body)
+	}
+}
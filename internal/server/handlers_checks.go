@@ -0,0 +1,152 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"quickvps/internal/auth"
+	"quickvps/internal/checks"
+)
+
+func (s *Server) handleChecks(w http.ResponseWriter, r *http.Request) {
+	if s.checks == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "checks service unavailable"})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		list, err := s.checks.List()
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"checks": list})
+
+	case http.MethodPost:
+		if !s.requireMutationPermission(w, r, auth.PermAlertsManage) {
+			return
+		}
+		var body checks.CheckInput
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON body"})
+			return
+		}
+		check, err := s.checks.Create(body)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusCreated, check)
+
+	default:
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleCheckByID serves GET/PUT/DELETE /api/checks/{id},
+// GET /api/checks/{id}/results and POST /api/checks/{id}/run.
+func (s *Server) handleCheckByID(w http.ResponseWriter, r *http.Request) {
+	if s.checks == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "checks service unavailable"})
+		return
+	}
+
+	rest := strings.TrimPrefix(r.URL.Path, "/api/checks/")
+	idPart, action, _ := strings.Cut(rest, "/")
+	checkID, err := strconv.ParseInt(idPart, 10, 64)
+	if err != nil || checkID <= 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid check id"})
+		return
+	}
+
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		st, err := s.checks.Get(checkID)
+		if err != nil {
+			writeCheckError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, st)
+
+	case action == "" && r.Method == http.MethodPut:
+		if !s.requireMutationPermission(w, r, auth.PermAlertsManage) {
+			return
+		}
+		var body checks.CheckInput
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON body"})
+			return
+		}
+		check, err := s.checks.Update(checkID, body)
+		if err != nil {
+			writeCheckError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, check)
+
+	case action == "" && r.Method == http.MethodDelete:
+		if !s.requireMutationPermission(w, r, auth.PermAlertsManage) {
+			return
+		}
+		if err := s.checks.Delete(checkID); err != nil {
+			writeCheckError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
+
+	case action == "results" && r.Method == http.MethodGet:
+		limit := 100
+		if raw := strings.TrimSpace(r.URL.Query().Get("limit")); raw != "" {
+			parsed, err := strconv.Atoi(raw)
+			if err != nil || parsed <= 0 {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid limit"})
+				return
+			}
+			limit = parsed
+		}
+		var beforeID int64
+		if raw := strings.TrimSpace(r.URL.Query().Get("before_id")); raw != "" {
+			parsed, err := strconv.ParseInt(raw, 10, 64)
+			if err != nil || parsed <= 0 {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid before_id"})
+				return
+			}
+			beforeID = parsed
+		}
+		results, err := s.checks.Results(checkID, limit, beforeID)
+		if err != nil {
+			writeCheckError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"results": results})
+
+	case action == "run" && r.Method == http.MethodPost:
+		if !s.requireMutationPermission(w, r, auth.PermAlertsManage) {
+			return
+		}
+		result, err := s.checks.RunNow(r.Context(), checkID)
+		if err != nil {
+			writeCheckError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, result)
+
+	case action == "" || action == "results" || action == "run":
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func writeCheckError(w http.ResponseWriter, err error) {
+	if errors.Is(err, checks.ErrCheckNotFound) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+}
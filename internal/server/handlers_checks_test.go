@@ -0,0 +1,82 @@
+package server
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"quickvps/internal/checks"
+)
+
+func newChecksServiceForTests(t *testing.T) *checks.Service {
+	t.Helper()
+	store, err := checks.NewStore(filepath.Join(t.TempDir(), "checks.db"))
+	if err != nil {
+		t.Fatalf("checks.NewStore() error = %v", err)
+	}
+	t.Cleanup(func() {
+		_ = store.Close()
+	})
+	svc, err := checks.NewService(store, nil)
+	if err != nil {
+		t.Fatalf("checks.NewService() error = %v", err)
+	}
+	return svc
+}
+
+func TestHandleChecksCRUDAndRun(t *testing.T) {
+	s, _, admin, viewer := newServerForAuthTests(t)
+	s.checks = newChecksServiceForTests(t)
+
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("pong"))
+	}))
+	defer target.Close()
+	payload := []byte(`{"name":"app","target":"` + target.URL + `","body_contains":"pong"}`)
+
+	viewerRec := httptest.NewRecorder()
+	s.handleChecks(viewerRec, withUser(httptest.NewRequest(http.MethodPost, "/api/checks", bytes.NewReader(payload)), viewer))
+	if viewerRec.Code != http.StatusForbidden {
+		t.Fatalf("create(viewer) status = %d, want %d", viewerRec.Code, http.StatusForbidden)
+	}
+
+	createRec := httptest.NewRecorder()
+	s.handleChecks(createRec, withUser(httptest.NewRequest(http.MethodPost, "/api/checks", bytes.NewReader(payload)), admin))
+	created := decodeBody(t, createRec)
+	if createRec.Code != http.StatusCreated || created["state"] != "pending" {
+		t.Fatalf("create status = %d body=%v", createRec.Code, created)
+	}
+	path := "/api/checks/" + strconv.FormatInt(int64(created["id"].(float64)), 10)
+
+	runRec := httptest.NewRecorder()
+	s.handleCheckByID(runRec, withUser(httptest.NewRequest(http.MethodPost, path+"/run", nil), admin))
+	if body := decodeBody(t, runRec); runRec.Code != http.StatusOK || body["ok"] != true {
+		t.Fatalf("run status = %d body=%v", runRec.Code, body)
+	}
+
+	getRec := httptest.NewRecorder()
+	s.handleCheckByID(getRec, withUser(httptest.NewRequest(http.MethodGet, path, nil), viewer))
+	if body := decodeBody(t, getRec); getRec.Code != http.StatusOK || body["state"] != "up" || body["uptime_24h"] != float64(100) {
+		t.Fatalf("get status = %d body=%v", getRec.Code, body)
+	}
+
+	resultsRec := httptest.NewRecorder()
+	s.handleCheckByID(resultsRec, withUser(httptest.NewRequest(http.MethodGet, path+"/results?limit=5", nil), viewer))
+	if body := decodeBody(t, resultsRec); resultsRec.Code != http.StatusOK || len(body["results"].([]any)) != 1 {
+		t.Fatalf("results status = %d body=%v", resultsRec.Code, body)
+	}
+
+	deleteRec := httptest.NewRecorder()
+	s.handleCheckByID(deleteRec, withUser(httptest.NewRequest(http.MethodDelete, path, nil), admin))
+	if deleteRec.Code != http.StatusOK {
+		t.Fatalf("delete status = %d", deleteRec.Code)
+	}
+	missingRec := httptest.NewRecorder()
+	s.handleCheckByID(missingRec, withUser(httptest.NewRequest(http.MethodGet, path, nil), viewer))
+	if missingRec.Code != http.StatusNotFound {
+		t.Fatalf("get(deleted) status = %d, want %d", missingRec.Code, http.StatusNotFound)
+	}
+}
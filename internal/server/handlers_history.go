@@ -0,0 +1,90 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"quickvps/internal/history"
+)
+
+func (s *Server) handleMetricsHistory(w http.ResponseWriter, r *http.Request) {
+	if s.history == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "metrics history unavailable"})
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	params := r.URL.Query()
+	from, err := parseTimeParam(params.Get("from"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid from"})
+		return
+	}
+	to, err := parseTimeParam(params.Get("to"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid to"})
+		return
+	}
+
+	var stepSec int64
+	if raw := strings.TrimSpace(params.Get("step")); raw != "" {
+		stepSec, err = parseStepSec(raw)
+		if err != nil || stepSec <= 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid step"})
+			return
+		}
+	}
+
+	var series []string
+	for _, name := range strings.Split(params.Get("series"), ",") {
+		if clean := strings.TrimSpace(name); clean != "" {
+			series = append(series, clean)
+		}
+	}
+
+	result, err := s.history.Query(history.Query{
+		From:    from,
+		To:      to,
+		StepSec: stepSec,
+		Series:  series,
+	})
+	if err != nil {
+		if errors.Is(err, history.ErrInvalidRange) {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// parseTimeParam accepts RFC3339 or unix seconds; empty means zero time.
+func parseTimeParam(raw string) (time.Time, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return time.Time{}, nil
+	}
+	if sec, err := strconv.ParseInt(raw, 10, 64); err == nil {
+		return time.Unix(sec, 0).UTC(), nil
+	}
+	return time.Parse(time.RFC3339, raw)
+}
+
+// parseStepSec accepts a Go duration ("5m") or plain seconds ("300").
+func parseStepSec(raw string) (int64, error) {
+	if sec, err := strconv.ParseInt(raw, 10, 64); err == nil {
+		return sec, nil
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil {
+		return 0, err
+	}
+	return int64(d.Seconds()), nil
+}
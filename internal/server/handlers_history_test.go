@@ -0,0 +1,51 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"quickvps/internal/history"
+)
+
+func TestHandleMetricsHistory(t *testing.T) {
+	s, _, _ := newServerForSystemTests()
+
+	store, err := history.NewStore(filepath.Join(t.TempDir(), "history.db"))
+	if err != nil {
+		t.Fatalf("history.NewStore() error = %v", err)
+	}
+	t.Cleanup(func() {
+		_ = store.Close()
+	})
+	now := time.Now().UTC().Truncate(time.Minute)
+	if err := store.Record(now.Add(-5*time.Minute), map[string]float64{"cpu.percent": 42}); err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+	s.history = history.NewRecorder(store, history.DefaultRetention())
+
+	req := httptest.NewRequest(http.MethodGet, "/api/metrics/history?from="+now.Add(-time.Hour).Format(time.RFC3339)+"&step=1m&series=cpu.percent", nil)
+	rec := httptest.NewRecorder()
+	s.handleMetricsHistory(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("handleMetricsHistory() status = %d, want %d body=%s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+
+	body := decodeBody(t, rec)
+	if body["tier"] != string(history.Tier1m) {
+		t.Fatalf("tier = %v, want %q", body["tier"], history.Tier1m)
+	}
+	series, ok := body["series"].([]any)
+	if !ok || len(series) != 1 {
+		t.Fatalf("series = %v, want 1 entry", body["series"])
+	}
+
+	badReq := httptest.NewRequest(http.MethodGet, "/api/metrics/history?step=abc", nil)
+	badRec := httptest.NewRecorder()
+	s.handleMetricsHistory(badRec, badReq)
+	if badRec.Code != http.StatusBadRequest {
+		t.Fatalf("handleMetricsHistory(bad step) status = %d, want %d", badRec.Code, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,154 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"quickvps/internal/alerts"
+	"quickvps/internal/auth"
+)
+
+// loginAlertTimeout bounds delivery of a failed-login burst alert.
+const loginAlertTimeout = time.Minute
+
+// clientIP is the address the request came from. Forwarding headers are
+// ignored because any client can set them.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// loginUsername trims a submitted username to the form used as its throttle
+// key and in the audit log, so failed logins cannot stuff either with
+// arbitrary text and the check and the count always agree.
+func loginUsername(username string) string {
+	username = strings.TrimSpace(username)
+	if len(username) > 64 {
+		username = username[:64]
+	}
+	return username
+}
+
+// writeLoginThrottled answers a login attempt that has to wait until
+// retryAt.
+func writeLoginThrottled(w http.ResponseWriter, retryAt, now time.Time) {
+	wait := int(retryAt.Sub(now).Round(time.Second) / time.Second)
+	if wait < 1 {
+		wait = 1
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(wait))
+	writeJSON(w, http.StatusTooManyRequests, map[string]any{
+		"error":           "too many failed login attempts",
+		"retry_after_sec": wait,
+	})
+}
+
+// recordLoginFailure counts attempt as a failed password or second-factor
+// check, audits it, and raises an alert when failures across all sources
+// burst.
+func (s *Server) recordLoginFailure(attempt *auth.LoginAttempt, ip, username, reason string) {
+	now := time.Now()
+	username = loginUsername(username)
+	failure := attempt.Fail(now)
+
+	_ = s.authStore.LogUserAudit(
+		0,
+		username,
+		"login_failed",
+		0,
+		username,
+		mustJSON(map[string]any{"ip": ip, "reason": reason, "failures": failure.Failures}),
+	)
+	if failure.Locked {
+		key := ip
+		if failure.LockedKind == auth.LockoutUsername {
+			key = username
+		}
+		_ = s.authStore.LogUserAudit(
+			0,
+			username,
+			"login_locked",
+			0,
+			username,
+			mustJSON(map[string]any{"kind": failure.LockedKind, "key": key, "locked_until": failure.LockedUntil}),
+		)
+	}
+
+	// Delivery retries can take a while, so the attempt is not held up.
+	if failure.Burst && s.alerts != nil {
+		go s.notifyLoginBurst(ip, username, failure.BurstCount, now)
+	}
+}
+
+func (s *Server) notifyLoginBurst(ip, username string, count int, now time.Time) {
+	ctx, cancel := context.WithTimeout(context.Background(), loginAlertTimeout)
+	defer cancel()
+	_, _ = s.alerts.NotifyExternal(ctx, alerts.ExternalAlert{
+		Level:     alerts.LevelWarning,
+		Source:    "Login",
+		Series:    "auth:login_failures",
+		Value:     float64(count),
+		ValueText: fmt.Sprintf("%d failed logins", count),
+		Detail:    fmt.Sprintf("FailedLogins=%d Window=5m LastIP=%s LastUser=%q", count, ip, username),
+		Time:      now,
+	})
+}
+
+// handleLockouts serves GET /api/lockouts, the addresses and usernames with
+// recent failed logins and whether they are throttled or locked.
+func (s *Server) handleLockouts(w http.ResponseWriter, r *http.Request) {
+	if _, ok := s.requirePermission(w, r, auth.PermUsersManage); !ok {
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"lockouts": s.sessions.Lockouts(time.Now())})
+}
+
+// handleLockoutByKey serves DELETE /api/lockouts/{ip|username}/{key}, which
+// forgets the failures of one address or username.
+func (s *Server) handleLockoutByKey(w http.ResponseWriter, r *http.Request) {
+	admin, ok := s.requirePermission(w, r, auth.PermUsersManage)
+	if !ok {
+		return
+	}
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	kind, key, found := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/lockouts/"), "/")
+	if !found || key == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid lockout"})
+		return
+	}
+	if err := s.sessions.ClearLockout(auth.LockoutKind(kind), key); err != nil {
+		if errors.Is(err, auth.ErrNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "lockout not found"})
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+
+	_ = s.authStore.LogUserAudit(
+		admin.ID,
+		admin.Username,
+		"clear_lockout",
+		0,
+		key,
+		mustJSON(map[string]any{"kind": kind, "key": key}),
+	)
+	writeJSON(w, http.StatusOK, map[string]any{"status": "cleared", "kind": kind, "key": key})
+}
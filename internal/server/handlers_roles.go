@@ -0,0 +1,137 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"quickvps/internal/auth"
+)
+
+// handleRoles serves GET /api/roles, the built-in and custom roles with their
+// permissions, and POST /api/roles to define a custom role.
+func (s *Server) handleRoles(w http.ResponseWriter, r *http.Request) {
+	admin, ok := s.requirePermission(w, r, auth.PermUsersManage)
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, map[string]any{
+			"roles":       s.authStore.ListRoles(),
+			"permissions": auth.AllPermissions,
+		})
+
+	case http.MethodPost:
+		var body struct {
+			Name        auth.Role         `json:"name"`
+			Description string            `json:"description"`
+			Permissions []auth.Permission `json:"permissions"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON body"})
+			return
+		}
+		role, err := s.authStore.CreateRole(body.Name, body.Description, body.Permissions)
+		if err != nil {
+			writeRoleError(w, err)
+			return
+		}
+		_ = s.authStore.LogUserAudit(
+			admin.ID,
+			admin.Username,
+			"create_role",
+			admin.ID,
+			admin.Username,
+			mustJSON(map[string]any{"role": role.Name, "permissions": role.Permissions}),
+		)
+		writeJSON(w, http.StatusCreated, role)
+
+	default:
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleRoleByName serves GET, PUT and DELETE /api/roles/{name}. Built-in
+// roles can be read but not changed.
+func (s *Server) handleRoleByName(w http.ResponseWriter, r *http.Request) {
+	admin, ok := s.requirePermission(w, r, auth.PermUsersManage)
+	if !ok {
+		return
+	}
+
+	name := auth.Role(strings.TrimPrefix(r.URL.Path, "/api/roles/"))
+	if name == "" || strings.Contains(string(name), "/") {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid role"})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		role, err := s.authStore.GetRole(name)
+		if err != nil {
+			writeRoleError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, role)
+
+	case http.MethodPut:
+		var body struct {
+			Description *string           `json:"description"`
+			Permissions []auth.Permission `json:"permissions"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON body"})
+			return
+		}
+		role, err := s.authStore.UpdateRole(name, body.Description, body.Permissions)
+		if err != nil {
+			writeRoleError(w, err)
+			return
+		}
+		_ = s.authStore.LogUserAudit(
+			admin.ID,
+			admin.Username,
+			"update_role",
+			admin.ID,
+			admin.Username,
+			mustJSON(map[string]any{"role": role.Name, "permissions": role.Permissions}),
+		)
+		writeJSON(w, http.StatusOK, role)
+
+	case http.MethodDelete:
+		if err := s.authStore.DeleteRole(name); err != nil {
+			writeRoleError(w, err)
+			return
+		}
+		_ = s.authStore.LogUserAudit(
+			admin.ID,
+			admin.Username,
+			"delete_role",
+			admin.ID,
+			admin.Username,
+			mustJSON(map[string]any{"role": name}),
+		)
+		writeJSON(w, http.StatusOK, map[string]any{"status": "deleted", "name": name})
+
+	default:
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func writeRoleError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, auth.ErrNotFound):
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "role not found"})
+	case errors.Is(err, auth.ErrRoleExists), errors.Is(err, auth.ErrRoleInUse):
+		writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+	case errors.Is(err, auth.ErrInvalidRole),
+		errors.Is(err, auth.ErrInvalidPermission),
+		errors.Is(err, auth.ErrBuiltinRole):
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+	default:
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+}
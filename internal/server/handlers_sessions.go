@@ -0,0 +1,78 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+
+	"quickvps/internal/auth"
+)
+
+// sessionView is a session as listed by the API, marked when it is the one
+// making the request.
+type sessionView struct {
+	auth.Session
+	Current bool `json:"current"`
+}
+
+// handleSessions serves GET /api/sessions, the caller's signed-in sessions,
+// or every user's for users:manage.
+func (s *Server) handleSessions(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.requireUser(w, r)
+	if !ok {
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ownerID := user.ID
+	if s.authStore.HasPermission(user.Role, auth.PermUsersManage) {
+		ownerID = 0
+	}
+	sessions, err := s.sessions.List(ownerID)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+
+	current, _ := s.currentSession(r)
+	views := make([]sessionView, 0, len(sessions))
+	for _, session := range sessions {
+		views = append(views, sessionView{Session: session, Current: current.ID != "" && session.ID == current.ID})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"sessions": views})
+}
+
+// handleSessionByID serves DELETE /api/sessions/{id}, which signs a session
+// out. Users revoke their own sessions; users:manage can revoke anyone's.
+func (s *Server) handleSessionByID(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.requireUser(w, r)
+	if !ok {
+		return
+	}
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := strings.TrimPrefix(r.URL.Path, "/api/sessions/")
+	session, found := s.sessions.SessionByID(id)
+	if !found || (session.UserID != user.ID && !s.authStore.HasPermission(user.Role, auth.PermUsersManage)) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "session not found"})
+		return
+	}
+	s.sessions.Delete(session.Token)
+
+	_ = s.authStore.LogUserAudit(
+		user.ID,
+		user.Username,
+		"revoke_session",
+		session.UserID,
+		session.Username,
+		mustJSON(map[string]any{"session_id": session.ID, "ip": session.IP}),
+	)
+
+	current, _ := s.currentSession(r)
+	writeJSON(w, http.StatusOK, map[string]any{"status": "revoked", "id": session.ID, "current": session.ID == current.ID})
+}
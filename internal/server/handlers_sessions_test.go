@@ -0,0 +1,113 @@
+package server
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandleSessionsListAndRevoke(t *testing.T) {
+	s, store, admin, viewer := newServerForAuthTests(t)
+
+	loginReq := httptest.NewRequest(http.MethodPost, "/api/auth/login", bytes.NewReader([]byte(`{"username":"viewer","password":"secret123"}`)))
+	loginReq.RemoteAddr = "198.51.100.20:5123"
+	loginReq.Header.Set("User-Agent", "Mozilla/5.0 test")
+	loginRec := httptest.NewRecorder()
+	s.handleAuthLogin(loginRec, loginReq)
+	if loginRec.Code != http.StatusOK {
+		t.Fatalf("login status = %d body=%s", loginRec.Code, loginRec.Body.String())
+	}
+	var cookie *http.Cookie
+	for _, c := range loginRec.Result().Cookies() {
+		if c.Name == sessionCookieName {
+			cookie = c
+		}
+	}
+	if cookie == nil {
+		t.Fatalf("login set no session cookie")
+	}
+	current, ok := s.sessions.Get(cookie.Value)
+	if !ok || current.IP != "198.51.100.20" || current.UserAgent != "Mozilla/5.0 test" {
+		t.Fatalf("login session = %+v ok=%v", current, ok)
+	}
+
+	other, err := s.sessions.Create(viewer, "198.51.100.21", "curl/8")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	adminSession, err := s.sessions.Create(admin, "198.51.100.22", "firefox")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	asViewer := func(req *http.Request) *http.Request {
+		return req.WithContext(withSession(req.Context(), current))
+	}
+
+	listRec := httptest.NewRecorder()
+	s.handleSessions(listRec, asViewer(httptest.NewRequest(http.MethodGet, "/api/sessions", nil)))
+	sessions, _ := decodeBody(t, listRec)["sessions"].([]any)
+	if len(sessions) != 2 {
+		t.Fatalf("viewer sessions = %v", sessions)
+	}
+	var currentCount int
+	for _, raw := range sessions {
+		item, _ := raw.(map[string]any)
+		if _, leaked := item["token"]; leaked {
+			t.Fatalf("session token in response: %v", item)
+		}
+		if item["current"] == true {
+			currentCount++
+			if item["id"] != current.ID {
+				t.Fatalf("wrong session marked current: %v", item)
+			}
+		}
+	}
+	if currentCount != 1 {
+		t.Fatalf("current sessions = %d, want 1", currentCount)
+	}
+
+	adminListRec := httptest.NewRecorder()
+	s.handleSessions(adminListRec, withUser(httptest.NewRequest(http.MethodGet, "/api/sessions", nil), admin))
+	if all, _ := decodeBody(t, adminListRec)["sessions"].([]any); len(all) != 3 {
+		t.Fatalf("admin sessions = %v", all)
+	}
+
+	forbiddenRec := httptest.NewRecorder()
+	s.handleSessionByID(forbiddenRec, asViewer(httptest.NewRequest(http.MethodDelete, "/api/sessions/"+adminSession.ID, nil)))
+	if forbiddenRec.Code != http.StatusNotFound {
+		t.Fatalf("viewer revoke admin session status = %d, want %d", forbiddenRec.Code, http.StatusNotFound)
+	}
+
+	ownRec := httptest.NewRecorder()
+	s.handleSessionByID(ownRec, asViewer(httptest.NewRequest(http.MethodDelete, "/api/sessions/"+other.ID, nil)))
+	if ownRec.Code != http.StatusOK {
+		t.Fatalf("viewer revoke own session status = %d body=%s", ownRec.Code, ownRec.Body.String())
+	}
+	if _, ok := s.sessions.Get(other.Token); ok {
+		t.Fatalf("revoked session still valid")
+	}
+
+	adminRevokeRec := httptest.NewRecorder()
+	s.handleSessionByID(adminRevokeRec, withUser(httptest.NewRequest(http.MethodDelete, "/api/sessions/"+current.ID, nil), admin))
+	if adminRevokeRec.Code != http.StatusOK {
+		t.Fatalf("admin revoke status = %d body=%s", adminRevokeRec.Code, adminRevokeRec.Body.String())
+	}
+	if _, ok := s.sessions.Get(cookie.Value); ok {
+		t.Fatalf("session revoked by admin still valid")
+	}
+
+	entries, err := store.ListUserAudits(10)
+	if err != nil {
+		t.Fatalf("ListUserAudits() error = %v", err)
+	}
+	var revoked int
+	for _, e := range entries {
+		if e.Action == "revoke_session" && e.TargetUserID == viewer.ID {
+			revoked++
+		}
+	}
+	if revoked != 2 {
+		t.Fatalf("revoke_session audit entries = %d, want 2", revoked)
+	}
+}
@@ -76,8 +76,9 @@ func newServerForSystemTests() (*Server, *metrics.Collector, *ncdu.Runner) {
 	runner := ncdu.NewRunner()
 
 	s := &Server{
-		collector: collector,
-		runner:    runner,
+		collector:    collector,
+		runner:       runner,
+		authDisabled: true,
 	}
 
 	return s, collector, runner
@@ -564,3 +565,20 @@ func TestHandleNcduCacheStatusAndCancel(t *testing.T) {
 		t.Fatalf("runner.Result().Status = %q, want %q", runner.Result().Status, ncdu.StatusIdle)
 	}
 }
+
+func TestHandleProcessesDisabled(t *testing.T) {
+	s, _, _ := newServerForSystemTests()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/processes", nil)
+	rec := httptest.NewRecorder()
+	s.handleProcesses(rec, req)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("handleProcesses() status = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+
+	postRec := httptest.NewRecorder()
+	s.handleProcesses(postRec, httptest.NewRequest(http.MethodPost, "/api/processes", nil))
+	if postRec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("handleProcesses(POST) status = %d, want %d", postRec.Code, http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,255 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"quickvps/internal/auth"
+)
+
+// tokenRoute grants API tokens access to one route. An empty method matches
+// any method; a path ending in "/" matches everything below it.
+type tokenRoute struct {
+	method string
+	path   string
+	scopes []auth.Scope // any one of them is enough
+}
+
+// tokenRoutes is the complete list of what API tokens can reach. Anything
+// else, including user, token and 2FA management, needs a browser session.
+var tokenRoutes = []tokenRoute{
+	{http.MethodGet, "/api/info", []auth.Scope{auth.ScopeMetricsRead}},
+	{http.MethodGet, "/api/interval", []auth.Scope{auth.ScopeMetricsRead}},
+	{http.MethodGet, "/api/metrics", []auth.Scope{auth.ScopeMetricsRead}},
+	{http.MethodGet, "/api/metrics/history", []auth.Scope{auth.ScopeMetricsRead}},
+	{http.MethodGet, "/api/processes", []auth.Scope{auth.ScopeMetricsRead}},
+	{http.MethodGet, "/api/ports", []auth.Scope{auth.ScopeMetricsRead, auth.ScopePortsKill}},
+	{http.MethodDelete, "/api/ports/", []auth.Scope{auth.ScopePortsKill}},
+	{http.MethodGet, "/api/alerts/", []auth.Scope{auth.ScopeAlertsRead, auth.ScopeAlertsWrite}},
+	{"", "/api/alerts/", []auth.Scope{auth.ScopeAlertsWrite}},
+	{http.MethodGet, "/api/checks", []auth.Scope{auth.ScopeMetricsRead, auth.ScopeAlertsWrite}},
+	{http.MethodGet, "/api/checks/", []auth.Scope{auth.ScopeMetricsRead, auth.ScopeAlertsWrite}},
+	{"", "/api/checks", []auth.Scope{auth.ScopeAlertsWrite}},
+	{"", "/api/checks/", []auth.Scope{auth.ScopeAlertsWrite}},
+	{http.MethodGet, "/api/watch", []auth.Scope{auth.ScopeMetricsRead, auth.ScopeAlertsWrite}},
+	{http.MethodGet, "/api/watch/", []auth.Scope{auth.ScopeMetricsRead, auth.ScopeAlertsWrite}},
+	{"", "/api/watch", []auth.Scope{auth.ScopeAlertsWrite}},
+	{"", "/api/watch/", []auth.Scope{auth.ScopeAlertsWrite}},
+}
+
+// tokenRouteAllowed reports whether token may call method on path.
+func tokenRouteAllowed(token auth.APIToken, method, path string) bool {
+	for _, route := range tokenRoutes {
+		if route.method != "" && route.method != method {
+			continue
+		}
+		if strings.HasSuffix(route.path, "/") {
+			if !strings.HasPrefix(path, route.path) {
+				continue
+			}
+		} else if path != route.path {
+			continue
+		}
+		for _, scope := range route.scopes {
+			if token.HasScope(scope) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// bearerToken returns the token of an "Authorization: Bearer" header.
+func bearerToken(r *http.Request) (string, bool) {
+	header := r.Header.Get("Authorization")
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// authenticateAPIToken authenticates a bearer-token request and returns the
+// session it acts as: the token's user, limited to tokenRoutes by its scopes.
+// It writes the error response itself.
+func (s *Server) authenticateAPIToken(w http.ResponseWriter, r *http.Request, raw string) (context.Context, bool) {
+	token, user, err := s.authStore.AuthenticateAPIToken(raw, time.Now())
+	if err != nil {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="quickvps"`)
+		writeUnauthorized(w)
+		return nil, false
+	}
+	// Every authenticated use is audited, including those the scopes deny.
+	allowed := tokenRouteAllowed(token, r.Method, r.URL.Path)
+	_ = s.authStore.LogUserAudit(
+		user.ID,
+		user.Username,
+		"use_api_token",
+		user.ID,
+		user.Username,
+		mustJSON(map[string]any{"token_id": token.ID, "name": token.Name, "method": r.Method, "path": r.URL.Path, "allowed": allowed}),
+	)
+	if !allowed {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "token scope does not allow this request"})
+		return nil, false
+	}
+
+	session := auth.Session{
+		UserID:    user.ID,
+		Username:  user.Username,
+		Role:      user.Role,
+		ExpiresAt: time.Now().Add(time.Minute),
+	}
+	return withSession(r.Context(), session), true
+}
+
+// handleTokens serves GET /api/tokens (own tokens, or every user's for
+// admins) and POST /api/tokens.
+func (s *Server) handleTokens(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		ownerID := user.ID
+		if s.authStore.HasPermission(user.Role, auth.PermUsersManage) {
+			ownerID = 0
+		}
+		tokens, err := s.authStore.ListAPITokens(ownerID)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"tokens": tokens})
+
+	case http.MethodPost:
+		var body struct {
+			Name   string       `json:"name"`
+			Scopes []auth.Scope `json:"scopes"`
+			// ExpiresInDays of 0 or omitted creates a token that never
+			// expires.
+			ExpiresInDays int `json:"expires_in_days"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON body"})
+			return
+		}
+		if body.ExpiresInDays < 0 || body.ExpiresInDays > 3650 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "expires_in_days must be between 0 and 3650"})
+			return
+		}
+		var expiresAt *time.Time
+		if body.ExpiresInDays > 0 {
+			t := time.Now().Add(time.Duration(body.ExpiresInDays) * 24 * time.Hour)
+			expiresAt = &t
+		}
+
+		token, secret, err := s.authStore.CreateAPIToken(user.ID, body.Name, body.Scopes, expiresAt)
+		if err != nil {
+			writeTokenError(w, err)
+			return
+		}
+		_ = s.authStore.LogUserAudit(
+			user.ID,
+			user.Username,
+			"create_api_token",
+			user.ID,
+			user.Username,
+			mustJSON(map[string]any{"token_id": token.ID, "name": token.Name, "scopes": token.Scopes, "expires_at": token.ExpiresAt}),
+		)
+		writeJSON(w, http.StatusCreated, map[string]any{"token": secret, "api_token": token})
+
+	default:
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleTokenByID serves GET, PUT and DELETE /api/tokens/{id}. Users manage
+// their own tokens; admins can also revoke anyone's.
+func (s *Server) handleTokenByID(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	idPart := strings.TrimPrefix(r.URL.Path, "/api/tokens/")
+	tokenID, err := strconv.ParseInt(idPart, 10, 64)
+	if err != nil || tokenID <= 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid token id"})
+		return
+	}
+	token, err := s.authStore.GetAPIToken(tokenID)
+	if err == nil && token.UserID != user.ID && !s.authStore.HasPermission(user.Role, auth.PermUsersManage) {
+		err = auth.ErrNotFound
+	}
+	if err != nil {
+		writeTokenError(w, err)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, token)
+
+	case http.MethodPut:
+		var body struct {
+			Name   *string      `json:"name"`
+			Scopes []auth.Scope `json:"scopes"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON body"})
+			return
+		}
+		updated, err := s.authStore.UpdateAPIToken(tokenID, body.Name, body.Scopes)
+		if err != nil {
+			writeTokenError(w, err)
+			return
+		}
+		_ = s.authStore.LogUserAudit(
+			user.ID,
+			user.Username,
+			"update_api_token",
+			updated.UserID,
+			updated.Username,
+			mustJSON(map[string]any{"token_id": updated.ID, "name": updated.Name, "scopes": updated.Scopes}),
+		)
+		writeJSON(w, http.StatusOK, updated)
+
+	case http.MethodDelete:
+		if err := s.authStore.DeleteAPIToken(tokenID); err != nil {
+			writeTokenError(w, err)
+			return
+		}
+		_ = s.authStore.LogUserAudit(
+			user.ID,
+			user.Username,
+			"delete_api_token",
+			token.UserID,
+			token.Username,
+			mustJSON(map[string]any{"token_id": token.ID, "name": token.Name}),
+		)
+		writeJSON(w, http.StatusOK, map[string]any{"status": "deleted", "id": tokenID})
+
+	default:
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func writeTokenError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, auth.ErrNotFound):
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "token not found"})
+	case errors.Is(err, auth.ErrInvalidScope), errors.Is(err, auth.ErrInvalidName):
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+	default:
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+}
@@ -0,0 +1,82 @@
+package server
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"quickvps/internal/auth"
+)
+
+func TestHandleTokensCreateAndBearerAuth(t *testing.T) {
+	s, store, admin, viewer := newServerForAuthTests(t)
+
+	createRec := httptest.NewRecorder()
+	createReq := httptest.NewRequest(http.MethodPost, "/api/tokens", bytes.NewReader([]byte(`{"name":"grafana","scopes":["metrics:read"],"expires_in_days":30}`)))
+	s.handleTokens(createRec, withUser(createReq, viewer))
+	body := decodeBody(t, createRec)
+	if createRec.Code != http.StatusCreated {
+		t.Fatalf("create status = %d body=%v", createRec.Code, body)
+	}
+	raw, _ := body["token"].(string)
+	if raw == "" {
+		t.Fatalf("create body missing token: %v", body)
+	}
+
+	handler := sessionAuthMiddleware(s, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, _ := s.currentUser(r)
+		writeJSON(w, http.StatusOK, map[string]any{"user": user.Username})
+	}))
+	for _, tc := range []struct {
+		method, path, token string
+		want                int
+	}{
+		{http.MethodGet, "/api/metrics", raw, http.StatusOK},
+		{http.MethodGet, "/api/metrics/history", raw, http.StatusOK},
+		{http.MethodDelete, "/api/ports/8080", raw, http.StatusForbidden},
+		{http.MethodGet, "/api/users", raw, http.StatusForbidden},
+		{http.MethodGet, "/api/tokens", raw, http.StatusForbidden},
+		{http.MethodGet, "/api/metrics", "qvps_wrong", http.StatusUnauthorized},
+	} {
+		req := httptest.NewRequest(tc.method, tc.path, nil)
+		req.Header.Set("Authorization", "Bearer "+tc.token)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tc.want {
+			t.Errorf("%s %s status = %d, want %d", tc.method, tc.path, rec.Code, tc.want)
+		}
+	}
+
+	entries, err := store.ListUserAudits(10)
+	if err != nil {
+		t.Fatalf("ListUserAudits() error = %v", err)
+	}
+	actions := map[string]int{}
+	for _, e := range entries {
+		actions[e.Action]++
+	}
+	// Each authenticated request is audited, allowed or not; the unknown
+	// token is not.
+	if actions["create_api_token"] != 1 || actions["use_api_token"] != 5 {
+		t.Fatalf("audit actions = %v", actions)
+	}
+
+	// Viewers see their own tokens, admins everyone's.
+	tokens, _ := store.ListAPITokens(0)
+	otherRec := httptest.NewRecorder()
+	s.handleTokens(otherRec, withUser(httptest.NewRequest(http.MethodGet, "/api/tokens", nil), admin))
+	if list, _ := decodeBody(t, otherRec)["tokens"].([]any); len(list) != len(tokens) {
+		t.Fatalf("admin list = %v", list)
+	}
+	adminToken, _, err := store.CreateAPIToken(admin.ID, "admin", []auth.Scope{auth.ScopeAlertsWrite}, nil)
+	if err != nil {
+		t.Fatalf("CreateAPIToken() error = %v", err)
+	}
+	deleteRec := httptest.NewRecorder()
+	s.handleTokenByID(deleteRec, withUser(httptest.NewRequest(http.MethodDelete, "/api/tokens/"+strconv.FormatInt(adminToken.ID, 10), nil), viewer))
+	if deleteRec.Code != http.StatusNotFound {
+		t.Fatalf("viewer deleting admin token status = %d, want %d", deleteRec.Code, http.StatusNotFound)
+	}
+}
@@ -0,0 +1,316 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"quickvps/internal/auth"
+	"quickvps/internal/qrcode"
+)
+
+// totpIssuer is the issuer shown by authenticator apps.
+const totpIssuer = "QuickVPS"
+
+// handleAuthLoginTwoFactor is the second login step: it trades the challenge
+// from POST /api/auth/login plus a TOTP or recovery code for a session.
+func (s *Server) handleAuthLoginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.authDisabled {
+		writeJSON(w, http.StatusOK, map[string]any{"auth_disabled": true})
+		return
+	}
+
+	var body struct {
+		Challenge string `json:"challenge"`
+		Code      string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON body"})
+		return
+	}
+
+	user, ok := s.sessions.LoginChallenge(body.Challenge)
+	if !ok {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "login challenge expired"})
+		return
+	}
+
+	ip := clientIP(r)
+	now := time.Now()
+	attempt, retryAt, ok := s.sessions.BeginLoginAttempt(ip, user.Username, now)
+	if !ok {
+		writeLoginThrottled(w, retryAt, now)
+		return
+	}
+	defer attempt.Release()
+
+	usedRecovery, err := s.authStore.VerifySecondFactor(user.ID, body.Code, now)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidTwoFactorCode) {
+			s.sessions.FailLoginChallenge(body.Challenge)
+			s.recordLoginFailure(attempt, ip, user.Username, "invalid_two_factor_code")
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	s.sessions.DeleteLoginChallenge(body.Challenge)
+
+	if usedRecovery {
+		_ = s.authStore.LogUserAudit(user.ID, user.Username, "login_recovery_code", user.ID, user.Username, "")
+	}
+	s.startSession(w, r, user)
+}
+
+// handleAuthTwoFactor serves GET /api/auth/2fa, the TOTP state of the
+// current user.
+func (s *Server) handleAuthTwoFactor(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	user, ok := s.requireUser(w, r)
+	if !ok {
+		return
+	}
+	status, err := s.authStore.TwoFactorStatus(user.ID)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	enrollmentRequired, err := s.authStore.TwoFactorEnrollmentRequired(user)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"two_factor":          status,
+		"enrollment_required": enrollmentRequired,
+	})
+}
+
+// handleAuthTwoFactorSetup starts enrollment and returns the secret, its
+// otpauth:// URI for the authenticator app and the URI as an SVG QR code.
+func (s *Server) handleAuthTwoFactorSetup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	user, ok := s.requireUser(w, r)
+	if !ok {
+		return
+	}
+	secret, err := s.authStore.BeginTOTPEnrollment(user.ID)
+	if err != nil {
+		writeTwoFactorError(w, err)
+		return
+	}
+	account := user.Username
+	if hostname, _ := os.Hostname(); strings.TrimSpace(hostname) != "" {
+		account += "@" + hostname
+	}
+	uri := auth.TOTPURI(totpIssuer, account, secret)
+	resp := map[string]any{
+		"secret":      secret,
+		"otpauth_uri": uri,
+	}
+	// Render the QR code here so the UI needs no encoder of its own; the
+	// secret stays usable for manual entry if the URI is ever too long.
+	if code, err := qrcode.Encode(uri); err == nil {
+		resp["qr_svg"] = code.SVG()
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleAuthTwoFactorConfirm enables TOTP once the first code checks out and
+// returns the recovery codes.
+func (s *Server) handleAuthTwoFactorConfirm(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	user, ok := s.requireUser(w, r)
+	if !ok {
+		return
+	}
+	var body struct {
+		Code string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON body"})
+		return
+	}
+	codes, err := s.authStore.ConfirmTOTPEnrollment(user.ID, body.Code, time.Now())
+	if err != nil {
+		writeTwoFactorError(w, err)
+		return
+	}
+	_ = s.authStore.LogUserAudit(user.ID, user.Username, "enable_2fa", user.ID, user.Username, "")
+	writeJSON(w, http.StatusOK, map[string]any{"recovery_codes": codes})
+}
+
+// handleAuthTwoFactorRecoveryCodes replaces the recovery codes; a current
+// TOTP code is required.
+func (s *Server) handleAuthTwoFactorRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	user, ok := s.requireUser(w, r)
+	if !ok {
+		return
+	}
+	var body struct {
+		Code string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON body"})
+		return
+	}
+	if _, err := s.authStore.VerifySecondFactor(user.ID, body.Code, time.Now()); err != nil {
+		writeTwoFactorError(w, err)
+		return
+	}
+	codes, err := s.authStore.RegenerateRecoveryCodes(user.ID)
+	if err != nil {
+		writeTwoFactorError(w, err)
+		return
+	}
+	_ = s.authStore.LogUserAudit(user.ID, user.Username, "regenerate_recovery_codes", user.ID, user.Username, "")
+	writeJSON(w, http.StatusOK, map[string]any{"recovery_codes": codes})
+}
+
+// handleAuthTwoFactorDisable turns TOTP off for the current user after
+// checking the password and a code. Admins cannot while the policy requires it.
+func (s *Server) handleAuthTwoFactorDisable(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	user, ok := s.requireUser(w, r)
+	if !ok {
+		return
+	}
+	var body struct {
+		Password string `json:"password"`
+		Code     string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON body"})
+		return
+	}
+	if s.authStore.AdminTwoFactorApplies(user.Role) {
+		required, err := s.authStore.RequireAdminTwoFactor()
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		if required {
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": auth.ErrTwoFactorRequired.Error()})
+			return
+		}
+	}
+	if _, err := s.authStore.Authenticate(user.Username, body.Password); err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid credentials"})
+		return
+	}
+	if _, err := s.authStore.VerifySecondFactor(user.ID, body.Code, time.Now()); err != nil {
+		writeTwoFactorError(w, err)
+		return
+	}
+	if err := s.authStore.DisableTOTP(user.ID); err != nil {
+		writeTwoFactorError(w, err)
+		return
+	}
+	_ = s.authStore.LogUserAudit(user.ID, user.Username, "disable_2fa", user.ID, user.Username, "")
+	writeJSON(w, http.StatusOK, map[string]string{"status": "disabled"})
+}
+
+// handleAuthTwoFactorPolicy serves GET and PUT /api/auth/2fa/policy, whether
+// admins must use two-factor authentication.
+func (s *Server) handleAuthTwoFactorPolicy(w http.ResponseWriter, r *http.Request) {
+	admin, ok := s.requirePermission(w, r, auth.PermUsersManage)
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		required, err := s.authStore.RequireAdminTwoFactor()
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"require_admin_2fa": required})
+
+	case http.MethodPut:
+		var body struct {
+			RequireAdmin2FA bool `json:"require_admin_2fa"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON body"})
+			return
+		}
+		// Turning the policy on without 2FA would lock the caller into
+		// enrollment, so they have to enroll first.
+		if body.RequireAdmin2FA {
+			status, err := s.authStore.TwoFactorStatus(admin.ID)
+			if err != nil {
+				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+				return
+			}
+			if !status.Enabled {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "enable two-factor authentication on your own account first"})
+				return
+			}
+		}
+		if err := s.authStore.SetRequireAdminTwoFactor(body.RequireAdmin2FA); err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		_ = s.authStore.LogUserAudit(
+			admin.ID,
+			admin.Username,
+			"update_2fa_policy",
+			admin.ID,
+			admin.Username,
+			mustJSON(map[string]any{"require_admin_2fa": body.RequireAdmin2FA}),
+		)
+		writeJSON(w, http.StatusOK, map[string]any{"require_admin_2fa": body.RequireAdmin2FA})
+
+	default:
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) requireUser(w http.ResponseWriter, r *http.Request) (auth.User, bool) {
+	user, ok := s.currentUser(r)
+	if !ok {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		return auth.User{}, false
+	}
+	return user, true
+}
+
+func writeTwoFactorError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, auth.ErrInvalidTwoFactorCode):
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+	case errors.Is(err, auth.ErrTwoFactorEnabled),
+		errors.Is(err, auth.ErrTwoFactorNotEnabled),
+		errors.Is(err, auth.ErrTwoFactorNotStarted):
+		writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+	case errors.Is(err, auth.ErrNotFound):
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "user not found"})
+	default:
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+}
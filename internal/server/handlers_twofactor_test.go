@@ -0,0 +1,153 @@
+package server
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"quickvps/internal/auth"
+)
+
+func enrollTOTPForTests(t *testing.T, store *auth.Store, user auth.User, now time.Time) string {
+	t.Helper()
+	secret, err := store.BeginTOTPEnrollment(user.ID)
+	if err != nil {
+		t.Fatalf("BeginTOTPEnrollment() error = %v", err)
+	}
+	code, _ := auth.TOTPCode(secret, now)
+	if _, err := store.ConfirmTOTPEnrollment(user.ID, code, now); err != nil {
+		t.Fatalf("ConfirmTOTPEnrollment() error = %v", err)
+	}
+	return secret
+}
+
+func TestHandleAuthLoginTwoStep(t *testing.T) {
+	s, store, admin, _ := newServerForAuthTests(t)
+	// Enroll with a code from the past so the current one is still unused.
+	secret := enrollTOTPForTests(t, store, admin, time.Now().Add(-time.Minute))
+
+	loginRec := httptest.NewRecorder()
+	s.handleAuthLogin(loginRec, httptest.NewRequest(http.MethodPost, "/api/auth/login", bytes.NewReader([]byte(`{"username":"admin","password":"secret123"}`))))
+	body := decodeBody(t, loginRec)
+	if loginRec.Code != http.StatusOK || body["two_factor_required"] != true || len(loginRec.Result().Cookies()) != 0 {
+		t.Fatalf("login status = %d body=%v cookies=%v", loginRec.Code, body, loginRec.Result().Cookies())
+	}
+	challenge, _ := body["challenge"].(string)
+
+	badRec := httptest.NewRecorder()
+	s.handleAuthLoginTwoFactor(badRec, httptest.NewRequest(http.MethodPost, "/api/auth/login/2fa", bytes.NewReader([]byte(`{"challenge":"`+challenge+`","code":"000000"}`))))
+	if badRec.Code != http.StatusUnauthorized {
+		t.Fatalf("wrong code status = %d, want %d", badRec.Code, http.StatusUnauthorized)
+	}
+
+	code, _ := auth.TOTPCode(secret, time.Now())
+	okRec := httptest.NewRecorder()
+	s.handleAuthLoginTwoFactor(okRec, httptest.NewRequest(http.MethodPost, "/api/auth/login/2fa", bytes.NewReader([]byte(`{"challenge":"`+challenge+`","code":"`+code+`"}`))))
+	if okRec.Code != http.StatusOK || len(okRec.Result().Cookies()) != 1 {
+		t.Fatalf("second step status = %d body=%s", okRec.Code, okRec.Body.String())
+	}
+
+	// The challenge is single-use.
+	againRec := httptest.NewRecorder()
+	s.handleAuthLoginTwoFactor(againRec, httptest.NewRequest(http.MethodPost, "/api/auth/login/2fa", bytes.NewReader([]byte(`{"challenge":"`+challenge+`","code":"`+code+`"}`))))
+	if againRec.Code != http.StatusUnauthorized {
+		t.Fatalf("reused challenge status = %d, want %d", againRec.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestHandleAuthTwoFactorSetupReturnsQRCode(t *testing.T) {
+	s, _, admin, _ := newServerForAuthTests(t)
+
+	rec := httptest.NewRecorder()
+	s.handleAuthTwoFactorSetup(rec, withUser(httptest.NewRequest(http.MethodPost, "/api/auth/2fa/setup", nil), admin))
+	body := decodeBody(t, rec)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("setup status = %d body=%v", rec.Code, body)
+	}
+	uri, _ := body["otpauth_uri"].(string)
+	svg, _ := body["qr_svg"].(string)
+	if !strings.HasPrefix(uri, "otpauth://totp/") || !strings.HasPrefix(svg, "<svg ") {
+		t.Fatalf("setup body = %v, want otpauth_uri and qr_svg", body)
+	}
+}
+
+func TestAdminTwoFactorPolicyEnforcement(t *testing.T) {
+	s, store, admin, _ := newServerForAuthTests(t)
+	second, err := store.CreateUser("admin2", "secret123", auth.RoleAdmin)
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+
+	putPolicy := func(user auth.User) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPut, "/api/auth/2fa/policy", bytes.NewReader([]byte(`{"require_admin_2fa":true}`)))
+		s.handleAuthTwoFactorPolicy(rec, withUser(req, user))
+		return rec
+	}
+	if rec := putPolicy(admin); rec.Code != http.StatusBadRequest {
+		t.Fatalf("policy without own 2FA status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	enrollTOTPForTests(t, store, admin, time.Now())
+	if rec := putPolicy(admin); rec.Code != http.StatusOK {
+		t.Fatalf("policy status = %d body=%s", rec.Code, rec.Body.String())
+	}
+
+	session, err := s.sessions.Create(second, "192.0.2.1", "test")
+	if err != nil {
+		t.Fatalf("sessions.Create() error = %v", err)
+	}
+	handler := sessionAuthMiddleware(s, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	}))
+	for path, want := range map[string]int{
+		"/api/users":          http.StatusForbidden,
+		"/api/auth/2fa/setup": http.StatusOK,
+	} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: session.Token})
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != want {
+			t.Errorf("%s status = %d, want %d", path, rec.Code, want)
+		}
+	}
+
+	disableRec := httptest.NewRecorder()
+	disableReq := httptest.NewRequest(http.MethodPost, "/api/auth/2fa/disable", bytes.NewReader([]byte(`{"password":"secret123","code":"000000"}`)))
+	s.handleAuthTwoFactorDisable(disableRec, withUser(disableReq, admin))
+	if disableRec.Code != http.StatusForbidden {
+		t.Fatalf("disable under policy status = %d, want %d", disableRec.Code, http.StatusForbidden)
+	}
+}
+
+func TestTwoFactorPolicyCoversCustomUsersManageRole(t *testing.T) {
+	s, store, _, _ := newServerForAuthTests(t)
+	if _, err := store.CreateRole("useradmin", "Manages accounts", []auth.Permission{auth.PermUsersManage}); err != nil {
+		t.Fatalf("CreateRole() error = %v", err)
+	}
+	manager, err := store.CreateUser("manager", "secret123", "useradmin")
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	if err := store.SetRequireAdminTwoFactor(true); err != nil {
+		t.Fatalf("SetRequireAdminTwoFactor() error = %v", err)
+	}
+
+	session, err := s.sessions.Create(manager, "192.0.2.1", "test")
+	if err != nil {
+		t.Fatalf("sessions.Create() error = %v", err)
+	}
+	handler := sessionAuthMiddleware(s, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/api/users", nil)
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: session.Token})
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if body := decodeBody(t, rec); rec.Code != http.StatusForbidden || body["two_factor_enrollment_required"] != true {
+		t.Fatalf("users:manage role without 2FA status = %d body=%v", rec.Code, body)
+	}
+}
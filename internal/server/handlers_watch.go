@@ -0,0 +1,106 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"quickvps/internal/auth"
+	"quickvps/internal/watch"
+)
+
+// handleWatch serves GET /api/watch, the watch list with the state of each
+// target, and POST /api/watch.
+func (s *Server) handleWatch(w http.ResponseWriter, r *http.Request) {
+	if s.watch == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "watch service unavailable"})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, map[string]any{"targets": s.watch.List()})
+
+	case http.MethodPost:
+		if !s.requireMutationPermission(w, r, auth.PermAlertsManage) {
+			return
+		}
+		var body watch.TargetInput
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON body"})
+			return
+		}
+		target, err := s.watch.Create(body)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusCreated, target)
+
+	default:
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) handleWatchByID(w http.ResponseWriter, r *http.Request) {
+	if s.watch == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "watch service unavailable"})
+		return
+	}
+
+	idPart := strings.TrimPrefix(r.URL.Path, "/api/watch/")
+	targetID, err := strconv.ParseInt(idPart, 10, 64)
+	if err != nil || targetID <= 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid watch target id"})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		st, err := s.watch.Get(targetID)
+		if err != nil {
+			writeWatchError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, st)
+
+	case http.MethodPut:
+		if !s.requireMutationPermission(w, r, auth.PermAlertsManage) {
+			return
+		}
+		var body watch.TargetInput
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON body"})
+			return
+		}
+		target, err := s.watch.Update(targetID, body)
+		if err != nil {
+			writeWatchError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, target)
+
+	case http.MethodDelete:
+		if !s.requireMutationPermission(w, r, auth.PermAlertsManage) {
+			return
+		}
+		if err := s.watch.Delete(targetID); err != nil {
+			writeWatchError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
+
+	default:
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func writeWatchError(w http.ResponseWriter, err error) {
+	if errors.Is(err, watch.ErrTargetNotFound) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,65 @@
+package server
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"quickvps/internal/watch"
+)
+
+func TestHandleWatchCRUDAndRBAC(t *testing.T) {
+	s, _, admin, viewer := newServerForAuthTests(t)
+	store, err := watch.NewStore(filepath.Join(t.TempDir(), "watch.db"))
+	if err != nil {
+		t.Fatalf("watch.NewStore() error = %v", err)
+	}
+	t.Cleanup(func() {
+		_ = store.Close()
+	})
+	s.watch, err = watch.NewService(store, nil)
+	if err != nil {
+		t.Fatalf("watch.NewService() error = %v", err)
+	}
+	payload := []byte(`{"name":"web","kind":"unit","pattern":"nginx"}`)
+
+	viewerRec := httptest.NewRecorder()
+	s.handleWatch(viewerRec, withUser(httptest.NewRequest(http.MethodPost, "/api/watch", bytes.NewReader(payload)), viewer))
+	if viewerRec.Code != http.StatusForbidden {
+		t.Fatalf("create(viewer) status = %d, want %d", viewerRec.Code, http.StatusForbidden)
+	}
+
+	createRec := httptest.NewRecorder()
+	s.handleWatch(createRec, withUser(httptest.NewRequest(http.MethodPost, "/api/watch", bytes.NewReader(payload)), admin))
+	created := decodeBody(t, createRec)
+	if createRec.Code != http.StatusCreated || created["pattern"] != "nginx.service" || created["state"] != "pending" {
+		t.Fatalf("create status = %d body=%v", createRec.Code, created)
+	}
+
+	listRec := httptest.NewRecorder()
+	s.handleWatch(listRec, withUser(httptest.NewRequest(http.MethodGet, "/api/watch", nil), viewer))
+	if body := decodeBody(t, listRec); listRec.Code != http.StatusOK || len(body["targets"].([]any)) != 1 {
+		t.Fatalf("list status = %d body=%v", listRec.Code, body)
+	}
+
+	path := "/api/watch/" + strconv.FormatInt(int64(created["id"].(float64)), 10)
+	updateRec := httptest.NewRecorder()
+	s.handleWatchByID(updateRec, withUser(httptest.NewRequest(http.MethodPut, path, bytes.NewReader([]byte(`{"pattern":"("}`))), admin))
+	if updateRec.Code != http.StatusBadRequest {
+		t.Fatalf("update(invalid) status = %d, want %d", updateRec.Code, http.StatusBadRequest)
+	}
+
+	deleteRec := httptest.NewRecorder()
+	s.handleWatchByID(deleteRec, withUser(httptest.NewRequest(http.MethodDelete, path, nil), admin))
+	if deleteRec.Code != http.StatusOK {
+		t.Fatalf("delete status = %d", deleteRec.Code)
+	}
+	missingRec := httptest.NewRecorder()
+	s.handleWatchByID(missingRec, withUser(httptest.NewRequest(http.MethodGet, path, nil), viewer))
+	if missingRec.Code != http.StatusNotFound {
+		t.Fatalf("get(deleted) status = %d, want %d", missingRec.Code, http.StatusNotFound)
+	}
+}
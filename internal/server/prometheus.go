@@ -0,0 +1,242 @@
+package server
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"quickvps/internal/alerts"
+	"quickvps/internal/metrics"
+	"quickvps/internal/ncdu"
+)
+
+const (
+	promContentType        = "text/plain; version=0.0.4; charset=utf-8"
+	openMetricsContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+)
+
+// handlePrometheus serves the latest snapshot in Prometheus text format, or
+// OpenMetrics when the scraper asks for it. It sits outside /api/ so the
+// session middleware never applies; access is gated by metricsToken instead.
+func (s *Server) handlePrometheus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.metricsScrapeAllowed(r) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="quickvps"`)
+		writeUnauthorized(w)
+		return
+	}
+
+	openMetrics := strings.Contains(r.Header.Get("Accept"), "application/openmetrics-text")
+	pw := &promWriter{openMetrics: openMetrics}
+
+	pw.family("quickvps_build_info", "QuickVPS build information.", "gauge")
+	pw.sample("quickvps_build_info", 1, "version", AppVersion)
+
+	if snap := s.collector.Latest(); snap != nil {
+		writeSnapshotMetrics(pw, snap)
+	}
+	if s.alerts != nil {
+		writeAlertMetrics(pw, s.alerts.Status(s.authDisabled), s.alerts.IsEnabled())
+	}
+	if s.runner != nil {
+		writeNcduMetrics(pw, s.runner.Result())
+	}
+
+	if openMetrics {
+		pw.buf.WriteString("# EOF\n")
+		w.Header().Set("Content-Type", openMetricsContentType)
+	} else {
+		w.Header().Set("Content-Type", promContentType)
+	}
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(pw.buf.Bytes())
+}
+
+// metricsScrapeAllowed requires the bearer token when one is configured.
+// Without a token the endpoint is only open in public (auth disabled) mode.
+func (s *Server) metricsScrapeAllowed(r *http.Request) bool {
+	if s.metricsToken == "" {
+		return s.authDisabled
+	}
+	raw := r.Header.Get("Authorization")
+	if !strings.HasPrefix(raw, "Bearer ") {
+		return false
+	}
+	got := strings.TrimSpace(strings.TrimPrefix(raw, "Bearer "))
+	return subtle.ConstantTimeCompare([]byte(got), []byte(s.metricsToken)) == 1
+}
+
+func writeSnapshotMetrics(pw *promWriter, snap *metrics.Snapshot) {
+	pw.family("quickvps_snapshot_timestamp_seconds", "Unix time of the latest metrics snapshot.", "gauge")
+	pw.sample("quickvps_snapshot_timestamp_seconds", float64(snap.Timestamp.UnixMilli())/1000)
+
+	pw.family("quickvps_cpu_usage_percent", "Total CPU usage percent.", "gauge")
+	pw.sample("quickvps_cpu_usage_percent", snap.CPU.TotalPercent)
+	pw.family("quickvps_cpu_core_usage_percent", "Per-core CPU usage percent.", "gauge")
+	for i, v := range snap.CPU.PerCore {
+		pw.sample("quickvps_cpu_core_usage_percent", v, "core", strconv.Itoa(i))
+	}
+	pw.family("quickvps_cpu_cores", "Number of logical CPU cores.", "gauge")
+	pw.sample("quickvps_cpu_cores", float64(snap.CPU.CoreCount))
+	pw.family("quickvps_cpu_frequency_mhz", "CPU frequency in MHz.", "gauge")
+	pw.sample("quickvps_cpu_frequency_mhz", snap.CPU.FreqMHz)
+	pw.family("quickvps_load_average", "System load average.", "gauge")
+	pw.sample("quickvps_load_average", snap.Load.Load1, "period", "1m")
+	pw.sample("quickvps_load_average", snap.Load.Load5, "period", "5m")
+	pw.sample("quickvps_load_average", snap.Load.Load15, "period", "15m")
+
+	pw.family("quickvps_memory_bytes", "Memory usage in bytes by kind.", "gauge")
+	pw.sample("quickvps_memory_bytes", float64(snap.Memory.TotalBytes), "kind", "total")
+	pw.sample("quickvps_memory_bytes", float64(snap.Memory.UsedBytes), "kind", "used")
+	pw.sample("quickvps_memory_bytes", float64(snap.Memory.FreeBytes), "kind", "free")
+	pw.sample("quickvps_memory_bytes", float64(snap.Memory.Cached), "kind", "cached")
+	pw.sample("quickvps_memory_bytes", float64(snap.Memory.Buffers), "kind", "buffers")
+	pw.family("quickvps_memory_usage_percent", "Memory usage percent.", "gauge")
+	pw.sample("quickvps_memory_usage_percent", snap.Memory.Percent)
+
+	pw.family("quickvps_swap_bytes", "Swap usage in bytes by kind.", "gauge")
+	pw.sample("quickvps_swap_bytes", float64(snap.Swap.TotalBytes), "kind", "total")
+	pw.sample("quickvps_swap_bytes", float64(snap.Swap.UsedBytes), "kind", "used")
+	pw.sample("quickvps_swap_bytes", float64(snap.Swap.FreeBytes), "kind", "free")
+	pw.family("quickvps_swap_usage_percent", "Swap usage percent.", "gauge")
+	pw.sample("quickvps_swap_usage_percent", snap.Swap.Percent)
+
+	pw.family("quickvps_disk_bytes", "Filesystem usage in bytes by kind.", "gauge")
+	for _, d := range snap.Disks {
+		labels := []string{"mountpoint", d.Mountpoint, "device", d.Device, "fstype", d.Fstype}
+		pw.sample("quickvps_disk_bytes", float64(d.TotalBytes), append(labels, "kind", "total")...)
+		pw.sample("quickvps_disk_bytes", float64(d.UsedBytes), append(labels, "kind", "used")...)
+		pw.sample("quickvps_disk_bytes", float64(d.FreeBytes), append(labels, "kind", "free")...)
+	}
+	pw.family("quickvps_disk_usage_percent", "Filesystem usage percent.", "gauge")
+	for _, d := range snap.Disks {
+		pw.sample("quickvps_disk_usage_percent", d.Percent, "mountpoint", d.Mountpoint, "device", d.Device, "fstype", d.Fstype)
+	}
+
+	diskIO := append([]metrics.DiskIOMetrics(nil), snap.DiskIO...)
+	sort.Slice(diskIO, func(i, j int) bool { return diskIO[i].Device < diskIO[j].Device })
+	pw.family("quickvps_disk_read_bytes_total", "Bytes read from the block device.", "counter")
+	for _, io := range diskIO {
+		pw.sample("quickvps_disk_read_bytes_total", float64(io.TotalRead), "device", io.Device)
+	}
+	pw.family("quickvps_disk_written_bytes_total", "Bytes written to the block device.", "counter")
+	for _, io := range diskIO {
+		pw.sample("quickvps_disk_written_bytes_total", float64(io.TotalWrite), "device", io.Device)
+	}
+	pw.family("quickvps_disk_io_bytes_per_second", "Block device throughput over the last interval.", "gauge")
+	for _, io := range diskIO {
+		pw.sample("quickvps_disk_io_bytes_per_second", io.ReadBps, "device", io.Device, "direction", "read")
+		pw.sample("quickvps_disk_io_bytes_per_second", io.WriteBps, "device", io.Device, "direction", "write")
+	}
+	pw.family("quickvps_disk_io_ops_per_second", "Block device operations over the last interval.", "gauge")
+	for _, io := range diskIO {
+		pw.sample("quickvps_disk_io_ops_per_second", io.ReadOps, "device", io.Device, "direction", "read")
+		pw.sample("quickvps_disk_io_ops_per_second", io.WriteOps, "device", io.Device, "direction", "write")
+	}
+
+	network := append([]metrics.NetMetrics(nil), snap.Network...)
+	sort.Slice(network, func(i, j int) bool { return network[i].Interface < network[j].Interface })
+	pw.family("quickvps_network_receive_bytes_total", "Bytes received on the interface.", "counter")
+	for _, n := range network {
+		pw.sample("quickvps_network_receive_bytes_total", float64(n.TotalRecv), "interface", n.Interface)
+	}
+	pw.family("quickvps_network_transmit_bytes_total", "Bytes sent on the interface.", "counter")
+	for _, n := range network {
+		pw.sample("quickvps_network_transmit_bytes_total", float64(n.TotalSent), "interface", n.Interface)
+	}
+	pw.family("quickvps_network_bytes_per_second", "Interface throughput over the last interval.", "gauge")
+	for _, n := range network {
+		pw.sample("quickvps_network_bytes_per_second", n.RecvBps, "interface", n.Interface, "direction", "receive")
+		pw.sample("quickvps_network_bytes_per_second", n.SentBps, "interface", n.Interface, "direction", "transmit")
+	}
+}
+
+func writeAlertMetrics(pw *promWriter, status alerts.Status, enabled bool) {
+	pw.family("quickvps_alerts_enabled", "Whether CPU alerting is enabled.", "gauge")
+	pw.sample("quickvps_alerts_enabled", boolGauge(enabled))
+	pw.family("quickvps_alert_state", "Current alert state (1 for the active state).", "gauge")
+	for _, level := range []alerts.Level{alerts.LevelNone, alerts.LevelWarning, alerts.LevelCritical} {
+		pw.sample("quickvps_alert_state", boolGauge(status.CurrentState == level), "state", string(level))
+	}
+	pw.family("quickvps_alert_silenced", "Whether alert notifications are muted.", "gauge")
+	pw.sample("quickvps_alert_silenced", boolGauge(status.Silenced))
+	pw.family("quickvps_alert_last_cpu_percent", "CPU percent seen by the last alert evaluation.", "gauge")
+	pw.sample("quickvps_alert_last_cpu_percent", status.LastCPUPercent)
+	pw.family("quickvps_alert_last_triggered_timestamp_seconds", "Unix time of the last notification per level.", "gauge")
+	if status.LastWarningAt != nil {
+		pw.sample("quickvps_alert_last_triggered_timestamp_seconds", float64(status.LastWarningAt.Unix()), "level", string(alerts.LevelWarning))
+	}
+	if status.LastCriticalAt != nil {
+		pw.sample("quickvps_alert_last_triggered_timestamp_seconds", float64(status.LastCriticalAt.Unix()), "level", string(alerts.LevelCritical))
+	}
+	if status.LastRecoveryAt != nil {
+		pw.sample("quickvps_alert_last_triggered_timestamp_seconds", float64(status.LastRecoveryAt.Unix()), "level", string(alerts.LevelRecovery))
+	}
+}
+
+func writeNcduMetrics(pw *promWriter, result ncdu.ScanResult) {
+	pw.family("quickvps_ncdu_scan_status", "Storage analyzer scan status (1 for the current status).", "gauge")
+	for _, st := range []ncdu.ScanStatus{ncdu.StatusIdle, ncdu.StatusRunning, ncdu.StatusDone, ncdu.StatusError} {
+		pw.sample("quickvps_ncdu_scan_status", boolGauge(result.Status == st), "status", string(st))
+	}
+	if result.Status == ncdu.StatusDone {
+		pw.family("quickvps_ncdu_last_scan_timestamp_seconds", "Unix time of the last completed scan.", "gauge")
+		pw.sample("quickvps_ncdu_last_scan_timestamp_seconds", float64(result.ScannedAt.Unix()), "path", result.Path)
+		pw.family("quickvps_ncdu_last_scan_size_bytes", "Total disk size reported by the last completed scan.", "gauge")
+		pw.sample("quickvps_ncdu_last_scan_size_bytes", float64(result.TotalSize), "path", result.Path)
+	}
+}
+
+// promWriter renders the text exposition format. In OpenMetrics mode counter
+// families drop their _total suffix on the TYPE/HELP lines as the spec requires.
+type promWriter struct {
+	buf         bytes.Buffer
+	openMetrics bool
+}
+
+func (p *promWriter) family(name, help, typ string) {
+	if p.openMetrics && typ == "counter" {
+		name = strings.TrimSuffix(name, "_total")
+	}
+	p.buf.WriteString("# HELP " + name + " " + help + "\n")
+	p.buf.WriteString("# TYPE " + name + " " + typ + "\n")
+}
+
+func (p *promWriter) sample(name string, value float64, labelPairs ...string) {
+	p.buf.WriteString(name)
+	if len(labelPairs) >= 2 {
+		p.buf.WriteByte('{')
+		for i := 0; i+1 < len(labelPairs); i += 2 {
+			if i > 0 {
+				p.buf.WriteByte(',')
+			}
+			p.buf.WriteString(labelPairs[i])
+			p.buf.WriteString(`="`)
+			p.buf.WriteString(escapeLabelValue(labelPairs[i+1]))
+			p.buf.WriteByte('"')
+		}
+		p.buf.WriteByte('}')
+	}
+	p.buf.WriteByte(' ')
+	p.buf.WriteString(strconv.FormatFloat(value, 'g', -1, 64))
+	p.buf.WriteByte('\n')
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabelValue(v string) string {
+	return labelValueEscaper.Replace(v)
+}
+
+func boolGauge(v bool) float64 {
+	if v {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"quickvps/internal/metrics"
+)
+
+func TestHandlePrometheusTokenGuard(t *testing.T) {
+	s, _, _ := newServerForSystemTests()
+	s.metricsToken = "scrape-secret"
+
+	noTokenReq := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	noTokenRec := httptest.NewRecorder()
+	s.handlePrometheus(noTokenRec, noTokenReq)
+	if noTokenRec.Code != http.StatusUnauthorized {
+		t.Fatalf("handlePrometheus() without token status = %d, want %d", noTokenRec.Code, http.StatusUnauthorized)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set("Authorization", "Bearer scrape-secret")
+	rec := httptest.NewRecorder()
+	s.handlePrometheus(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("handlePrometheus() with token status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if !strings.Contains(rec.Body.String(), "quickvps_ncdu_scan_status{status=\"idle\"} 1") {
+		t.Fatalf("ncdu status missing from exposition:\n%s", rec.Body.String())
+	}
+
+	s.metricsToken = ""
+	s.authDisabled = false
+	lockedRec := httptest.NewRecorder()
+	s.handlePrometheus(lockedRec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if lockedRec.Code != http.StatusUnauthorized {
+		t.Fatalf("handlePrometheus() auth mode without configured token status = %d, want %d", lockedRec.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestWriteSnapshotMetricsTypesAndLabels(t *testing.T) {
+	snap := &metrics.Snapshot{
+		Timestamp: time.Unix(1700000000, 0),
+		CPU:       metrics.CPUMetrics{TotalPercent: 12.5, PerCore: []float64{10, 15}, CoreCount: 2},
+		Disks:     []metrics.DiskMetrics{{Mountpoint: `/mnt/"x"`, Device: "/dev/sda1", Fstype: "ext4", Percent: 40}},
+		DiskIO:    []metrics.DiskIOMetrics{{Device: "sda", ReadBps: 1, TotalRead: 4096, TotalWrite: 8192}},
+		Network:   []metrics.NetMetrics{{Interface: "eth0", TotalRecv: 100, TotalSent: 200}},
+	}
+
+	pw := &promWriter{}
+	writeSnapshotMetrics(pw, snap)
+	out := pw.buf.String()
+
+	for _, want := range []string{
+		"# TYPE quickvps_cpu_usage_percent gauge",
+		"quickvps_cpu_core_usage_percent{core=\"1\"} 15",
+		"# TYPE quickvps_disk_read_bytes_total counter",
+		"quickvps_disk_read_bytes_total{device=\"sda\"} 4096",
+		"quickvps_network_transmit_bytes_total{interface=\"eth0\"} 200",
+		`quickvps_disk_usage_percent{mountpoint="/mnt/\"x\"",device="/dev/sda1",fstype="ext4"} 40`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("exposition missing %q:\n%s", want, out)
+		}
+	}
+
+	om := &promWriter{openMetrics: true}
+	writeSnapshotMetrics(om, snap)
+	if !strings.Contains(om.buf.String(), "# TYPE quickvps_network_receive_bytes counter") {
+		t.Fatalf("openmetrics counter family should drop _total suffix:\n%s", om.buf.String())
+	}
+}
@@ -11,8 +11,11 @@ import (
 
 	"quickvps/internal/alerts"
 	"quickvps/internal/auth"
+	"quickvps/internal/checks"
+	"quickvps/internal/history"
 	"quickvps/internal/metrics"
 	"quickvps/internal/ncdu"
+	"quickvps/internal/watch"
 	"quickvps/internal/ws"
 )
 
@@ -28,9 +31,13 @@ type Server struct {
 	hub          *ws.Hub
 	runner       *ncdu.Runner
 	alerts       *alerts.Service
+	history      *history.Recorder
+	checks       *checks.Service
+	watch        *watch.Service
 	authDisabled bool
 	authStore    *auth.Store
 	sessions     *auth.SessionManager
+	metricsToken string
 	webFS        embed.FS
 }
 
@@ -39,9 +46,13 @@ func New(
 	hub *ws.Hub,
 	runner *ncdu.Runner,
 	alertsService *alerts.Service,
+	historyRecorder *history.Recorder,
+	checksService *checks.Service,
+	watchService *watch.Service,
 	authDisabled bool,
 	authStore *auth.Store,
 	sessions *auth.SessionManager,
+	metricsToken string,
 	webFS embed.FS,
 ) *Server {
 	s := &Server{
@@ -50,9 +61,13 @@ func New(
 		hub:          hub,
 		runner:       runner,
 		alerts:       alertsService,
+		history:      historyRecorder,
+		checks:       checksService,
+		watch:        watchService,
 		authDisabled: authDisabled,
 		authStore:    authStore,
 		sessions:     sessions,
+		metricsToken: metricsToken,
 		webFS:        webFS,
 	}
 	s.registerRoutes()
@@ -68,15 +83,33 @@ func (s *Server) registerRoutes() {
 	fileServer := http.FileServer(http.FS(webSub))
 
 	s.mux.HandleFunc("/ws", s.handleWS)
+	s.mux.HandleFunc("/metrics", s.handlePrometheus)
 	s.mux.HandleFunc("/api/info", s.handleInfo)
 	s.mux.HandleFunc("/api/auth/login", s.handleAuthLogin)
 	s.mux.HandleFunc("/api/auth/logout", s.handleAuthLogout)
 	s.mux.HandleFunc("/api/auth/me", s.handleAuthMe)
+	s.mux.HandleFunc("/api/auth/login/2fa", s.handleAuthLoginTwoFactor)
+	s.mux.HandleFunc("/api/auth/2fa", s.handleAuthTwoFactor)
+	s.mux.HandleFunc("/api/auth/2fa/setup", s.handleAuthTwoFactorSetup)
+	s.mux.HandleFunc("/api/auth/2fa/confirm", s.handleAuthTwoFactorConfirm)
+	s.mux.HandleFunc("/api/auth/2fa/recovery-codes", s.handleAuthTwoFactorRecoveryCodes)
+	s.mux.HandleFunc("/api/auth/2fa/disable", s.handleAuthTwoFactorDisable)
+	s.mux.HandleFunc("/api/auth/2fa/policy", s.handleAuthTwoFactorPolicy)
 	s.mux.HandleFunc("/api/users", s.handleUsers)
 	s.mux.HandleFunc("/api/users/", s.handleUserByID)
+	s.mux.HandleFunc("/api/roles", s.handleRoles)
+	s.mux.HandleFunc("/api/roles/", s.handleRoleByName)
 	s.mux.HandleFunc("/api/audit/users", s.handleUserAudit)
+	s.mux.HandleFunc("/api/lockouts", s.handleLockouts)
+	s.mux.HandleFunc("/api/lockouts/", s.handleLockoutByKey)
+	s.mux.HandleFunc("/api/tokens", s.handleTokens)
+	s.mux.HandleFunc("/api/tokens/", s.handleTokenByID)
+	s.mux.HandleFunc("/api/sessions", s.handleSessions)
+	s.mux.HandleFunc("/api/sessions/", s.handleSessionByID)
 	s.mux.HandleFunc("/api/interval", s.handleInterval)
 	s.mux.HandleFunc("/api/metrics", s.handleMetrics)
+	s.mux.HandleFunc("/api/metrics/history", s.handleMetricsHistory)
+	s.mux.HandleFunc("/api/processes", s.handleProcesses)
 	s.mux.HandleFunc("/api/ports", s.handlePorts)
 	s.mux.HandleFunc("/api/ports/", s.handlePortByID)
 	s.mux.HandleFunc("/api/ncdu/scan", s.handleNcduScan)
@@ -87,6 +120,25 @@ func (s *Server) registerRoutes() {
 	s.mux.HandleFunc("/api/alerts/history", s.handleAlertsHistory)
 	s.mux.HandleFunc("/api/alerts/test", s.handleAlertsTest)
 	s.mux.HandleFunc("/api/alerts/silence", s.handleAlertsSilence)
+	s.mux.HandleFunc("/api/alerts/rules", s.handleAlertRules)
+	s.mux.HandleFunc("/api/alerts/rules/", s.handleAlertRuleByID)
+	s.mux.HandleFunc("/api/alerts/outbox", s.handleAlertOutbox)
+	s.mux.HandleFunc("/api/alerts/outbox/", s.handleAlertOutboxItem)
+	s.mux.HandleFunc("/api/alerts/incidents", s.handleAlertIncidents)
+	s.mux.HandleFunc("/api/alerts/incidents/", s.handleAlertIncidentByID)
+	s.mux.HandleFunc("/api/alerts/maintenance", s.handleAlertMaintenance)
+	s.mux.HandleFunc("/api/alerts/maintenance/", s.handleAlertMaintenanceByID)
+	s.mux.HandleFunc("/api/alerts/templates", s.handleAlertTemplates)
+	s.mux.HandleFunc("/api/alerts/templates/preview", s.handleAlertTemplatePreview)
+	s.mux.HandleFunc("/api/alerts/templates/", s.handleAlertTemplateByKey)
+	s.mux.HandleFunc("/api/alerts/digest/preview", s.handleAlertDigestPreview)
+	s.mux.HandleFunc("/api/alerts/digest/send", s.handleAlertDigestSend)
+	s.mux.HandleFunc("/api/alerts/keys", s.handleAlertKeys)
+	s.mux.HandleFunc("/api/alerts/keys/rotate", s.handleAlertKeysRotate)
+	s.mux.HandleFunc("/api/checks", s.handleChecks)
+	s.mux.HandleFunc("/api/checks/", s.handleCheckByID)
+	s.mux.HandleFunc("/api/watch", s.handleWatch)
+	s.mux.HandleFunc("/api/watch/", s.handleWatchByID)
 	s.mux.HandleFunc("/api/firewall/status", s.handleFirewallStatus)
 	s.mux.HandleFunc("/api/firewall/rules", s.handleFirewallRules)
 	s.mux.HandleFunc("/api/firewall/exposures", s.handleFirewallExposures)
@@ -132,25 +184,47 @@ func sessionAuthMiddleware(s *Server, next http.Handler) http.Handler {
 			return
 		}
 
-		tokenCookie, err := r.Cookie(sessionCookieName)
-		if err != nil {
-			writeUnauthorized(w)
-			return
+		var ctx context.Context
+		if raw, ok := bearerToken(r); ok {
+			tokenCtx, ok := s.authenticateAPIToken(w, r, raw)
+			if !ok {
+				return
+			}
+			ctx = tokenCtx
+		} else {
+			tokenCookie, err := r.Cookie(sessionCookieName)
+			if err != nil {
+				writeUnauthorized(w)
+				return
+			}
+			session, ok := s.sessions.Get(tokenCookie.Value)
+			if !ok {
+				writeUnauthorized(w)
+				return
+			}
+			ctx = withSession(r.Context(), session)
 		}
+		session, _ := sessionFromContext(ctx)
 
-		session, ok := s.sessions.Get(tokenCookie.Value)
-		if !ok {
-			writeUnauthorized(w)
-			return
+		// A user who can manage users but has no 2FA while the policy
+		// requires it may only use the auth endpoints, to enroll or sign out.
+		if s.authStore.AdminTwoFactorApplies(session.Role) && !strings.HasPrefix(r.URL.Path, "/api/auth/") {
+			user := auth.User{ID: session.UserID, Username: session.Username, Role: session.Role}
+			if required, err := s.authStore.TwoFactorEnrollmentRequired(user); err != nil || required {
+				writeJSON(w, http.StatusForbidden, map[string]any{
+					"error":                          "two-factor enrollment required",
+					"two_factor_enrollment_required": true,
+				})
+				return
+			}
 		}
 
-		ctx := withSession(r.Context(), session)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
 func isPublicPath(path string) bool {
-	if path == "/api/auth/login" {
+	if path == "/api/auth/login" || path == "/api/auth/login/2fa" {
 		return true
 	}
 	if !strings.HasPrefix(path, "/api/") && path != "/ws" {
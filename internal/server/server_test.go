@@ -18,6 +18,8 @@ func TestIsPublicPath(t *testing.T) {
 		{path: "/", want: true},
 		{path: "/dashboard", want: true},
 		{path: "/api/auth/login", want: true},
+		{path: "/api/auth/login/2fa", want: true},
+		{path: "/api/auth/2fa", want: false},
 		{path: "/api/info", want: false},
 		{path: "/api/metrics", want: false},
 		{path: "/ws", want: false},
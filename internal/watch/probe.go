@@ -0,0 +1,113 @@
+package watch
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// listProcesses reads the name and command line of every process in /proc.
+// Processes that exit during the walk are skipped.
+func listProcesses() ([]Process, error) {
+	if runtime.GOOS != "linux" {
+		return nil, errors.New("process watch requires Linux /proc")
+	}
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil, fmt.Errorf("read /proc: %w", err)
+	}
+	out := make([]Process, 0, len(entries))
+	for _, e := range entries {
+		pid, err := strconv.Atoi(e.Name())
+		if err != nil || !e.IsDir() {
+			continue
+		}
+		dir := filepath.Join("/proc", e.Name())
+		comm, err := os.ReadFile(filepath.Join(dir, "comm"))
+		if err != nil {
+			continue
+		}
+		cmdline, _ := os.ReadFile(filepath.Join(dir, "cmdline"))
+		out = append(out, Process{
+			PID:     pid,
+			Name:    strings.TrimSpace(string(comm)),
+			Cmdline: strings.TrimSpace(strings.ReplaceAll(string(cmdline), "\x00", " ")),
+		})
+	}
+	return out, nil
+}
+
+// systemctlUnitStates asks systemd for the state of units in one call.
+func systemctlUnitStates(ctx context.Context, units []string) (map[string]UnitState, error) {
+	if _, err := exec.LookPath("systemctl"); err != nil {
+		return nil, errors.New("systemctl not found")
+	}
+	args := append([]string{"show", "--property=Id,LoadState,ActiveState,SubState", "--"}, units...)
+	out, err := exec.CommandContext(ctx, "systemctl", args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("systemctl show: %w", err)
+	}
+	return parseSystemctlShow(string(out), units), nil
+}
+
+// parseSystemctlShow parses `systemctl show` output: one block of Key=Value
+// lines per unit, in argument order, separated by blank lines.
+func parseSystemctlShow(out string, units []string) map[string]UnitState {
+	states := make(map[string]UnitState, len(units))
+	idx := 0
+	var cur UnitState
+	seen := false
+	flush := func() {
+		if seen && idx < len(units) {
+			states[units[idx]] = cur
+			idx++
+		}
+		cur, seen = UnitState{}, false
+	}
+
+	sc := bufio.NewScanner(strings.NewReader(out))
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" {
+			flush()
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		seen = true
+		switch key {
+		case "LoadState":
+			cur.LoadState = value
+		case "ActiveState":
+			cur.ActiveState = value
+		case "SubState":
+			cur.SubState = value
+		}
+	}
+	flush()
+	return states
+}
+
+var unitSuffixes = []string{
+	".service", ".socket", ".timer", ".mount", ".automount", ".path",
+	".target", ".scope", ".slice", ".swap", ".device",
+}
+
+// unitName appends ".service" to a unit name without a unit type suffix.
+func unitName(pattern string) string {
+	for _, suffix := range unitSuffixes {
+		if strings.HasSuffix(pattern, suffix) {
+			return pattern
+		}
+	}
+	return pattern + ".service"
+}
@@ -0,0 +1,506 @@
+package watch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"quickvps/internal/alerts"
+	"quickvps/internal/metrics"
+)
+
+const (
+	defaultDownAfterSec = 10
+	maxDownAfterSec     = 3600
+	maxTargets          = 100
+	// maxStatusPIDs caps the PIDs listed per process target.
+	maxStatusPIDs = 10
+	unitTimeout   = 5 * time.Second
+)
+
+var unitNameRe = regexp.MustCompile(`^[A-Za-z0-9:_.@\\-]+$`)
+
+// Alerter receives watch state changes; *alerts.Service implements it.
+type Alerter interface {
+	NotifyExternal(ctx context.Context, a alerts.ExternalAlert) (alerts.Event, error)
+}
+
+// targetRuntime is what the last evaluation saw for one target.
+type targetRuntime struct {
+	re            *regexp.Regexp
+	lastCheckedAt *time.Time
+	missingSince  *time.Time
+	detail        string
+	pids          []int
+	err           string
+}
+
+// observation is the result of looking for one target.
+type observation struct {
+	up     bool
+	detail string
+	pids   []int
+	err    error
+}
+
+type transition struct {
+	target Target
+	level  alerts.Level
+	obs    observation
+	at     time.Time
+}
+
+type Service struct {
+	mu       sync.Mutex
+	store    *Store
+	alerter  Alerter
+	targets  []Target
+	runtimes map[int64]*targetRuntime
+
+	listProcesses func() ([]Process, error)
+	unitStates    func(ctx context.Context, units []string) (map[string]UnitState, error)
+}
+
+func NewService(store *Store, alerter Alerter) (*Service, error) {
+	if store == nil {
+		return nil, errors.New("watch store is required")
+	}
+	targets, err := store.ListTargets()
+	if err != nil {
+		return nil, err
+	}
+	runtimes := make(map[int64]*targetRuntime, len(targets))
+	for _, t := range targets {
+		runtimes[t.ID] = newRuntime(t)
+	}
+	return &Service{
+		store:         store,
+		alerter:       alerter,
+		targets:       targets,
+		runtimes:      runtimes,
+		listProcesses: listProcesses,
+		unitStates:    systemctlUnitStates,
+	}, nil
+}
+
+func newRuntime(t Target) *targetRuntime {
+	rt := &targetRuntime{}
+	if t.Kind == KindProcess {
+		// Patterns are validated before they are saved.
+		rt.re, _ = regexp.Compile(t.Pattern)
+	}
+	return rt
+}
+
+// Run evaluates the watch list on every collector snapshot until ctx is done
+// or sub is closed.
+func (s *Service) Run(ctx context.Context, sub <-chan *metrics.Snapshot) {
+	if sub == nil {
+		return
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case snap, ok := <-sub:
+			if !ok {
+				return
+			}
+			if snap == nil {
+				continue
+			}
+			s.Evaluate(ctx, snap.Timestamp)
+		}
+	}
+}
+
+// Evaluate looks for every enabled target once and sends alerts for the
+// targets that went down or came back up.
+func (s *Service) Evaluate(ctx context.Context, now time.Time) {
+	s.mu.Lock()
+	var targets []Target
+	res := make(map[int64]*regexp.Regexp)
+	for _, t := range s.targets {
+		if t.Enabled {
+			targets = append(targets, t)
+			res[t.ID] = s.runtimes[t.ID].re
+		}
+	}
+	s.mu.Unlock()
+	if len(targets) == 0 {
+		return
+	}
+
+	obs := s.observe(ctx, targets, res)
+
+	s.mu.Lock()
+	var changes []transition
+	for _, t := range targets {
+		idx := s.targetIndex(t.ID)
+		if idx < 0 {
+			continue
+		}
+		if tr, ok := s.applyLocked(idx, obs[t.ID], now); ok {
+			changes = append(changes, tr)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, tr := range changes {
+		s.notify(ctx, tr)
+	}
+}
+
+// observe lists processes and queries units at most once each.
+func (s *Service) observe(ctx context.Context, targets []Target, res map[int64]*regexp.Regexp) map[int64]observation {
+	var (
+		procs    []Process
+		procErr  error
+		units    []string
+		unitErr  error
+		states   map[string]UnitState
+		needProc bool
+	)
+	for _, t := range targets {
+		if t.Kind == KindUnit {
+			units = append(units, t.Pattern)
+		} else {
+			needProc = true
+		}
+	}
+	if needProc {
+		procs, procErr = s.listProcesses()
+	}
+	if len(units) > 0 {
+		uctx, cancel := context.WithTimeout(ctx, unitTimeout)
+		states, unitErr = s.unitStates(uctx, units)
+		cancel()
+	}
+
+	out := make(map[int64]observation, len(targets))
+	for _, t := range targets {
+		switch t.Kind {
+		case KindUnit:
+			if unitErr != nil {
+				out[t.ID] = observation{err: unitErr}
+				continue
+			}
+			out[t.ID] = observeUnit(states[t.Pattern])
+		default:
+			if procErr != nil {
+				out[t.ID] = observation{err: procErr}
+				continue
+			}
+			out[t.ID] = observeProcess(t, res[t.ID], procs)
+		}
+	}
+	return out
+}
+
+func observeProcess(t Target, re *regexp.Regexp, procs []Process) observation {
+	var pids []int
+	for _, p := range procs {
+		subject := p.Name
+		if t.MatchCmdline {
+			subject = p.Cmdline
+		}
+		if re != nil && re.MatchString(subject) {
+			pids = append(pids, p.PID)
+		}
+	}
+	if len(pids) == 0 {
+		return observation{detail: "no matching process"}
+	}
+	sort.Ints(pids)
+	detail := fmt.Sprintf("%d process(es)", len(pids))
+	if len(pids) > maxStatusPIDs {
+		pids = pids[:maxStatusPIDs]
+	}
+	return observation{up: true, detail: detail, pids: pids}
+}
+
+// observeUnit treats failed and inactive units as down. Units in transition
+// (activating, deactivating, reloading) count as up so a restart does not
+// start the down timer.
+func observeUnit(st UnitState) observation {
+	if st.LoadState == "not-found" || st.ActiveState == "" {
+		return observation{detail: "unit not found"}
+	}
+	detail := st.ActiveState
+	if st.SubState != "" {
+		detail += " (" + st.SubState + ")"
+	}
+	switch st.ActiveState {
+	case "failed", "inactive":
+		return observation{detail: detail}
+	default:
+		return observation{up: true, detail: detail}
+	}
+}
+
+// applyLocked records an observation and returns the state change it causes.
+// Must be called with s.mu held.
+func (s *Service) applyLocked(idx int, obs observation, now time.Time) (transition, bool) {
+	t := s.targets[idx]
+	rt := s.runtimes[t.ID]
+	checkedAt := now
+	rt.lastCheckedAt = &checkedAt
+	rt.detail, rt.pids, rt.err = obs.detail, obs.pids, ""
+	if obs.err != nil {
+		// Could not look; keep the current state.
+		rt.err = obs.err.Error()
+		return transition{}, false
+	}
+
+	next := t.State
+	if obs.up {
+		rt.missingSince = nil
+		next = StateUp
+	} else {
+		if rt.missingSince == nil {
+			since := now
+			rt.missingSince = &since
+		}
+		if now.Sub(*rt.missingSince) >= time.Duration(t.DownAfterSec)*time.Second {
+			next = StateDown
+		}
+	}
+	if next == t.State {
+		return transition{}, false
+	}
+
+	since := now
+	if next == StateDown {
+		since = *rt.missingSince
+	}
+	s.targets[idx].State = next
+	s.targets[idx].StateSince = &since
+	_ = s.store.SetState(t.ID, next, since)
+
+	switch {
+	case next == StateDown:
+		return transition{target: t, level: alerts.LevelCritical, obs: obs, at: now}, true
+	case t.State == StateDown:
+		return transition{target: t, level: alerts.LevelRecovery, obs: obs, at: now}, true
+	}
+	return transition{}, false
+}
+
+func (s *Service) notify(ctx context.Context, tr transition) {
+	if s.alerter == nil {
+		return
+	}
+	t := tr.target
+	label := "Process"
+	if t.Kind == KindUnit {
+		label = "Unit"
+	}
+	a := alerts.ExternalAlert{
+		Level:     tr.level,
+		Source:    fmt.Sprintf("%s %q", label, t.Name),
+		Series:    "watch:" + t.Name,
+		ValueText: "down",
+		Time:      tr.at,
+	}
+	state := "DOWN"
+	if tr.level == alerts.LevelRecovery {
+		a.Value, a.ValueText, state = 1, "up", "UP"
+	}
+	a.Detail = fmt.Sprintf("Pattern=%q State=%s Detail=%q", t.Pattern, state, tr.obs.detail)
+	if len(tr.obs.pids) > 0 {
+		pids := make([]string, len(tr.obs.pids))
+		for i, pid := range tr.obs.pids {
+			pids[i] = strconv.Itoa(pid)
+		}
+		a.Detail += " PIDs=" + strings.Join(pids, ",")
+	}
+	_, _ = s.alerter.NotifyExternal(ctx, a)
+}
+
+// List returns every target with what the last evaluation saw.
+func (s *Service) List() []TargetStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]TargetStatus, 0, len(s.targets))
+	for _, t := range s.targets {
+		out = append(out, s.statusLocked(t))
+	}
+	return out
+}
+
+func (s *Service) Get(id int64) (TargetStatus, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	idx := s.targetIndex(id)
+	if idx < 0 {
+		return TargetStatus{}, ErrTargetNotFound
+	}
+	return s.statusLocked(s.targets[idx]), nil
+}
+
+// statusLocked must be called with s.mu held.
+func (s *Service) statusLocked(t Target) TargetStatus {
+	st := TargetStatus{Target: t}
+	if rt := s.runtimes[t.ID]; rt != nil {
+		st.LastCheckedAt = rt.lastCheckedAt
+		st.Detail = rt.detail
+		st.PIDs = append([]int(nil), rt.pids...)
+		st.Error = rt.err
+		if t.State != StateDown {
+			st.MissingSince = rt.missingSince
+		}
+	}
+	return st
+}
+
+func defaultTarget() Target {
+	return Target{
+		Kind:         KindProcess,
+		Enabled:      true,
+		DownAfterSec: defaultDownAfterSec,
+		State:        StatePending,
+	}
+}
+
+func (s *Service) Create(in TargetInput) (Target, error) {
+	t := defaultTarget()
+	applyInput(&t, in)
+	if err := validateTarget(t); err != nil {
+		return Target{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.targets) >= maxTargets {
+		return Target{}, fmt.Errorf("at most %d watch targets are allowed", maxTargets)
+	}
+	if err := s.nameFreeLocked(t.Name, 0); err != nil {
+		return Target{}, err
+	}
+	created, err := s.store.CreateTarget(t)
+	if err != nil {
+		return Target{}, err
+	}
+	s.targets = append(s.targets, created)
+	s.runtimes[created.ID] = newRuntime(created)
+	return created, nil
+}
+
+// Update changes a target's settings; its state starts over as pending.
+func (s *Service) Update(id int64, in TargetInput) (Target, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	idx := s.targetIndex(id)
+	if idx < 0 {
+		return Target{}, ErrTargetNotFound
+	}
+	t := s.targets[idx]
+	applyInput(&t, in)
+	if err := validateTarget(t); err != nil {
+		return Target{}, err
+	}
+	if err := s.nameFreeLocked(t.Name, id); err != nil {
+		return Target{}, err
+	}
+	updated, err := s.store.UpdateTarget(t)
+	if err != nil {
+		return Target{}, err
+	}
+	s.targets[idx] = updated
+	s.runtimes[id] = newRuntime(updated)
+	return updated, nil
+}
+
+func (s *Service) Delete(id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	idx := s.targetIndex(id)
+	if idx < 0 {
+		return ErrTargetNotFound
+	}
+	if err := s.store.DeleteTarget(id); err != nil {
+		return err
+	}
+	s.targets = append(s.targets[:idx], s.targets[idx+1:]...)
+	delete(s.runtimes, id)
+	return nil
+}
+
+// targetIndex must be called with s.mu held.
+func (s *Service) targetIndex(id int64) int {
+	for i, t := range s.targets {
+		if t.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// nameFreeLocked must be called with s.mu held.
+func (s *Service) nameFreeLocked(name string, exceptID int64) error {
+	for _, t := range s.targets {
+		if t.ID != exceptID && strings.EqualFold(t.Name, name) {
+			return fmt.Errorf("a watch target named %q already exists", name)
+		}
+	}
+	return nil
+}
+
+func applyInput(t *Target, in TargetInput) {
+	if in.Name != nil {
+		t.Name = strings.TrimSpace(*in.Name)
+	}
+	if in.Kind != nil {
+		t.Kind = Kind(strings.ToLower(strings.TrimSpace(string(*in.Kind))))
+	}
+	if in.Pattern != nil {
+		t.Pattern = strings.TrimSpace(*in.Pattern)
+	}
+	if in.MatchCmdline != nil {
+		t.MatchCmdline = *in.MatchCmdline
+	}
+	if in.Enabled != nil {
+		t.Enabled = *in.Enabled
+	}
+	if in.DownAfterSec != nil {
+		t.DownAfterSec = *in.DownAfterSec
+	}
+	if t.Kind == KindUnit && t.Pattern != "" {
+		t.Pattern = unitName(t.Pattern)
+		t.MatchCmdline = false
+	}
+}
+
+func validateTarget(t Target) error {
+	if t.Name == "" || len(t.Name) > 100 {
+		return errors.New("name is required and must be at most 100 characters")
+	}
+	if t.Pattern == "" || len(t.Pattern) > 500 {
+		return errors.New("pattern is required and must be at most 500 characters")
+	}
+	switch t.Kind {
+	case KindProcess:
+		if _, err := regexp.Compile(t.Pattern); err != nil {
+			return fmt.Errorf("invalid pattern: %w", err)
+		}
+	case KindUnit:
+		if !unitNameRe.MatchString(t.Pattern) || strings.HasPrefix(t.Pattern, "-") {
+			return errors.New("pattern must be a systemd unit name")
+		}
+	default:
+		return errors.New("kind must be process or unit")
+	}
+	if t.DownAfterSec < 0 || t.DownAfterSec > maxDownAfterSec {
+		return fmt.Errorf("down_after_sec must be between 0 and %d", maxDownAfterSec)
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package watch
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"quickvps/internal/alerts"
+)
+
+type recordingAlerter struct {
+	alerts []alerts.ExternalAlert
+}
+
+func (r *recordingAlerter) NotifyExternal(_ context.Context, a alerts.ExternalAlert) (alerts.Event, error) {
+	r.alerts = append(r.alerts, a)
+	return alerts.Event{}, nil
+}
+
+func newServiceForTests(t *testing.T) (*Service, *Store, *recordingAlerter) {
+	t.Helper()
+	store, err := NewStore(filepath.Join(t.TempDir(), "watch.db"))
+	if err != nil {
+		t.Fatalf("NewStore() error = %v", err)
+	}
+	t.Cleanup(func() {
+		_ = store.Close()
+	})
+	alerter := &recordingAlerter{}
+	svc, err := NewService(store, alerter)
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	return svc, store, alerter
+}
+
+func ptr[T any](v T) *T { return &v }
+
+func TestProcessTargetDownAndUp(t *testing.T) {
+	svc, store, alerter := newServiceForTests(t)
+	procs := []Process{
+		{PID: 10, Name: "postgres", Cmdline: "/usr/lib/postgresql/16/bin/postgres -D /var/lib/postgresql"},
+		{PID: 11, Name: "postgres", Cmdline: "postgres: checkpointer"},
+		{PID: 20, Name: "myapp", Cmdline: "/opt/app/myapp --port 8080"},
+	}
+	svc.listProcesses = func() ([]Process, error) { return procs, nil }
+	ctx := context.Background()
+
+	pg, err := svc.Create(TargetInput{Name: ptr("db"), Pattern: ptr("^postgres$"), DownAfterSec: ptr(10)})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if _, err := svc.Create(TargetInput{Name: ptr("app"), Pattern: ptr("myapp --port"), MatchCmdline: ptr(true)}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	t0 := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	svc.Evaluate(ctx, t0)
+	list := svc.List()
+	if list[0].State != StateUp || len(list[0].PIDs) != 2 || list[1].State != StateUp {
+		t.Fatalf("status = %+v", list)
+	}
+	if len(alerter.alerts) != 0 {
+		t.Fatalf("pending -> up alerted: %+v", alerter.alerts)
+	}
+
+	procs = procs[2:]
+	svc.Evaluate(ctx, t0.Add(2*time.Second))
+	if st, _ := svc.Get(pg.ID); st.State != StateUp || st.MissingSince == nil {
+		t.Fatalf("status while missing = %+v", st)
+	}
+	svc.Evaluate(ctx, t0.Add(12*time.Second))
+	if len(alerter.alerts) != 1 || alerter.alerts[0].Level != alerts.LevelCritical || alerter.alerts[0].Series != "watch:db" {
+		t.Fatalf("alerts = %+v, want one critical", alerter.alerts)
+	}
+	st, _ := svc.Get(pg.ID)
+	if st.State != StateDown || st.StateSince == nil || !st.StateSince.Equal(t0.Add(2*time.Second)) {
+		t.Fatalf("down status = %+v", st)
+	}
+
+	// The down state is persisted, so recovery after a restart still alerts.
+	svc, err = NewService(store, alerter)
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	procs = append(procs, Process{PID: 30, Name: "postgres"})
+	svc.listProcesses = func() ([]Process, error) { return procs, nil }
+	svc.Evaluate(ctx, t0.Add(time.Minute))
+	if len(alerter.alerts) != 2 || alerter.alerts[1].Level != alerts.LevelRecovery {
+		t.Fatalf("alerts = %+v, want a recovery", alerter.alerts)
+	}
+}
+
+func TestUnitTarget(t *testing.T) {
+	svc, _, alerter := newServiceForTests(t)
+	states := map[string]UnitState{"nginx.service": {LoadState: "loaded", ActiveState: "active", SubState: "running"}}
+	var unitErr error
+	svc.unitStates = func(_ context.Context, units []string) (map[string]UnitState, error) {
+		return states, unitErr
+	}
+	ctx := context.Background()
+
+	unit, err := svc.Create(TargetInput{Name: ptr("nginx"), Kind: ptr(KindUnit), Pattern: ptr("nginx"), DownAfterSec: ptr(0)})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if unit.Pattern != "nginx.service" {
+		t.Fatalf("pattern = %q, want nginx.service", unit.Pattern)
+	}
+
+	t0 := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	svc.Evaluate(ctx, t0)
+	if st, _ := svc.Get(unit.ID); st.State != StateUp || st.Detail != "active (running)" {
+		t.Fatalf("status = %+v", st)
+	}
+
+	// A failing systemctl keeps the state.
+	unitErr = errors.New("systemctl not found")
+	svc.Evaluate(ctx, t0.Add(time.Second))
+	if st, _ := svc.Get(unit.ID); st.State != StateUp || st.Error == "" {
+		t.Fatalf("status on error = %+v", st)
+	}
+	unitErr = nil
+
+	states["nginx.service"] = UnitState{LoadState: "loaded", ActiveState: "failed", SubState: "failed"}
+	svc.Evaluate(ctx, t0.Add(2*time.Second))
+	if len(alerter.alerts) != 1 || alerter.alerts[0].Level != alerts.LevelCritical {
+		t.Fatalf("alerts = %+v, want a critical", alerter.alerts)
+	}
+}
+
+func TestParseSystemctlShow(t *testing.T) {
+	out := "Id=nginx.service\nLoadState=loaded\nActiveState=active\nSubState=running\n\n" +
+		"Id=nope.service\nLoadState=not-found\nActiveState=inactive\nSubState=dead\n"
+	states := parseSystemctlShow(out, []string{"nginx.service", "nope.service"})
+	if states["nginx.service"].SubState != "running" || states["nope.service"].LoadState != "not-found" {
+		t.Fatalf("states = %+v", states)
+	}
+	if obs := observeUnit(states["nope.service"]); obs.up || obs.detail != "unit not found" {
+		t.Fatalf("not-found observation = %+v", obs)
+	}
+}
+
+func TestValidateTarget(t *testing.T) {
+	svc, _, _ := newServiceForTests(t)
+	for name, in := range map[string]TargetInput{
+		"name":    {Pattern: ptr("nginx")},
+		"pattern": {Name: ptr("a")},
+		"regexp":  {Name: ptr("a"), Pattern: ptr("(")},
+		"unit":    {Name: ptr("a"), Kind: ptr(KindUnit), Pattern: ptr("--now")},
+		"kind":    {Name: ptr("a"), Kind: ptr(Kind("container")), Pattern: ptr("x")},
+		"delay":   {Name: ptr("a"), Pattern: ptr("x"), DownAfterSec: ptr(-1)},
+	} {
+		if _, err := svc.Create(in); err == nil {
+			t.Errorf("%s: expected validation error", name)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package watch
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+var ErrTargetNotFound = errors.New("watch target not found")
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(path string) (*Store, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("open sqlite: %w", err)
+	}
+
+	if _, err := db.Exec(`PRAGMA journal_mode = WAL;`); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("set sqlite journal mode: %w", err)
+	}
+
+	s := &Store{db: db}
+	if err := s.migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *Store) Close() error {
+	if s == nil || s.db == nil {
+		return nil
+	}
+	return s.db.Close()
+}
+
+func (s *Store) migrate() error {
+	const schema = `
+CREATE TABLE IF NOT EXISTS watch_targets (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  name TEXT NOT NULL UNIQUE,
+  kind TEXT NOT NULL,
+  pattern TEXT NOT NULL,
+  match_cmdline INTEGER NOT NULL DEFAULT 0,
+  enabled INTEGER NOT NULL DEFAULT 1,
+  down_after_sec INTEGER NOT NULL DEFAULT 0,
+  state TEXT NOT NULL DEFAULT 'pending',
+  state_since DATETIME,
+  created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+`
+	if _, err := s.db.Exec(schema); err != nil {
+		return fmt.Errorf("migrate watch targets table: %w", err)
+	}
+	return nil
+}
+
+const targetColumns = `id, name, kind, pattern, match_cmdline, enabled, down_after_sec,
+  state, state_since, created_at, updated_at`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func (s *Store) ListTargets() ([]Target, error) {
+	rows, err := s.db.Query(`SELECT ` + targetColumns + ` FROM watch_targets ORDER BY id ASC`)
+	if err != nil {
+		return nil, fmt.Errorf("list watch targets: %w", err)
+	}
+	defer rows.Close()
+
+	targets := make([]Target, 0)
+	for rows.Next() {
+		t, err := scanTarget(rows)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate watch targets: %w", err)
+	}
+	return targets, nil
+}
+
+func (s *Store) GetTarget(id int64) (Target, error) {
+	t, err := scanTarget(s.db.QueryRow(`SELECT `+targetColumns+` FROM watch_targets WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Target{}, ErrTargetNotFound
+	}
+	return t, err
+}
+
+func (s *Store) CreateTarget(t Target) (Target, error) {
+	result, err := s.db.Exec(`
+INSERT INTO watch_targets(name, kind, pattern, match_cmdline, enabled, down_after_sec)
+VALUES (?, ?, ?, ?, ?, ?)
+`, t.Name, string(t.Kind), t.Pattern, boolToInt(t.MatchCmdline), boolToInt(t.Enabled), t.DownAfterSec)
+	if err != nil {
+		return Target{}, fmt.Errorf("create watch target: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return Target{}, fmt.Errorf("create watch target last insert id: %w", err)
+	}
+	return s.GetTarget(id)
+}
+
+// UpdateTarget saves the settings of t and resets its state to pending.
+func (s *Store) UpdateTarget(t Target) (Target, error) {
+	result, err := s.db.Exec(`
+UPDATE watch_targets
+SET
+  name = ?,
+  kind = ?,
+  pattern = ?,
+  match_cmdline = ?,
+  enabled = ?,
+  down_after_sec = ?,
+  state = ?,
+  state_since = NULL,
+  updated_at = CURRENT_TIMESTAMP
+WHERE id = ?
+`, t.Name, string(t.Kind), t.Pattern, boolToInt(t.MatchCmdline), boolToInt(t.Enabled), t.DownAfterSec, string(StatePending), t.ID)
+	if err != nil {
+		return Target{}, fmt.Errorf("update watch target: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return Target{}, ErrTargetNotFound
+	}
+	return s.GetTarget(t.ID)
+}
+
+func (s *Store) DeleteTarget(id int64) error {
+	result, err := s.db.Exec(`DELETE FROM watch_targets WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("delete watch target: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrTargetNotFound
+	}
+	return nil
+}
+
+func (s *Store) SetState(id int64, state State, since time.Time) error {
+	if _, err := s.db.Exec(`UPDATE watch_targets SET state = ?, state_since = ? WHERE id = ?`, string(state), since.UTC(), id); err != nil {
+		return fmt.Errorf("update watch target state: %w", err)
+	}
+	return nil
+}
+
+func scanTarget(row rowScanner) (Target, error) {
+	var (
+		t            Target
+		kind, state  string
+		matchCmdline int
+		enabled      int
+		stateSince   sql.NullTime
+	)
+	err := row.Scan(
+		&t.ID, &t.Name, &kind, &t.Pattern, &matchCmdline, &enabled, &t.DownAfterSec,
+		&state, &stateSince, &t.CreatedAt, &t.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Target{}, err
+		}
+		return Target{}, fmt.Errorf("scan watch target: %w", err)
+	}
+	t.Kind = Kind(kind)
+	t.State = State(state)
+	t.MatchCmdline = matchCmdline == 1
+	t.Enabled = enabled == 1
+	if stateSince.Valid {
+		since := stateSince.Time
+		t.StateSince = &since
+	}
+	return t, nil
+}
+
+func boolToInt(v bool) int {
+	if v {
+		return 1
+	}
+	return 0
+}
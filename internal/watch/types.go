@@ -0,0 +1,76 @@
+package watch
+
+import "time"
+
+type Kind string
+
+const (
+	// KindProcess is up while at least one process matches Pattern.
+	KindProcess Kind = "process"
+	// KindUnit is a systemd unit, down while it is failed or inactive.
+	KindUnit Kind = "unit"
+)
+
+type State string
+
+const (
+	StatePending State = "pending"
+	StateUp      State = "up"
+	StateDown    State = "down"
+)
+
+// Target is one entry of the watch list. For processes Pattern is a regular
+// expression matched against the process name, or against the full command
+// line when MatchCmdline is set; for units it is the unit name (".service" is
+// implied when there is no suffix).
+type Target struct {
+	ID           int64  `json:"id"`
+	Name         string `json:"name"`
+	Kind         Kind   `json:"kind"`
+	Pattern      string `json:"pattern"`
+	MatchCmdline bool   `json:"match_cmdline"`
+	Enabled      bool   `json:"enabled"`
+	// DownAfterSec is how long a target must stay missing before it goes
+	// down, so restarts do not alert.
+	DownAfterSec int `json:"down_after_sec"`
+
+	State      State      `json:"state"`
+	StateSince *time.Time `json:"state_since,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+type TargetInput struct {
+	Name         *string `json:"name"`
+	Kind         *Kind   `json:"kind"`
+	Pattern      *string `json:"pattern"`
+	MatchCmdline *bool   `json:"match_cmdline"`
+	Enabled      *bool   `json:"enabled"`
+	DownAfterSec *int    `json:"down_after_sec"`
+}
+
+// TargetStatus is a target with what the last evaluation saw.
+type TargetStatus struct {
+	Target
+	LastCheckedAt *time.Time `json:"last_checked_at,omitempty"`
+	// Detail is the matching PIDs or the unit's active/sub state.
+	Detail string `json:"detail,omitempty"`
+	PIDs   []int  `json:"pids,omitempty"`
+	// MissingSince is set while a target that is not yet down is missing.
+	MissingSince *time.Time `json:"missing_since,omitempty"`
+	Error        string     `json:"error,omitempty"`
+}
+
+// Process is one running process as seen in /proc.
+type Process struct {
+	PID     int
+	Name    string
+	Cmdline string
+}
+
+// UnitState is the systemd state of one unit.
+type UnitState struct {
+	LoadState   string
+	ActiveState string
+	SubState    string
+}
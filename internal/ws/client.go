@@ -27,6 +27,9 @@ type Client struct {
 	hub  *Hub
 	conn *websocket.Conn
 	send chan []byte
+	// processes is set for /ws?processes=1; only those clients get the top
+	// process list in metrics frames.
+	processes bool
 }
 
 func NewClient(hub *Hub, w http.ResponseWriter, r *http.Request) (*Client, error) {
@@ -35,9 +38,10 @@ func NewClient(hub *Hub, w http.ResponseWriter, r *http.Request) (*Client, error
 		return nil, err
 	}
 	c := &Client{
-		hub:  hub,
-		conn: conn,
-		send: make(chan []byte, 64),
+		hub:       hub,
+		conn:      conn,
+		send:      make(chan []byte, 64),
+		processes: r.URL.Query().Get("processes") == "1",
 	}
 	hub.register <- c
 	return c, nil
@@ -7,7 +7,7 @@ import (
 
 type Hub struct {
 	clients    map[*Client]bool
-	broadcast  chan []byte
+	broadcast  chan frame
 	register   chan *Client
 	unregister chan *Client
 	mu         sync.RWMutex
@@ -16,7 +16,7 @@ type Hub struct {
 func NewHub() *Hub {
 	return &Hub{
 		clients:    make(map[*Client]bool),
-		broadcast:  make(chan []byte, 64),
+		broadcast:  make(chan frame, 64),
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
 	}
@@ -43,9 +43,13 @@ func (h *Hub) Run(ctx context.Context) {
 				close(c.send)
 			}
 			h.mu.Unlock()
-		case msg := <-h.broadcast:
+		case f := <-h.broadcast:
 			h.mu.RLock()
 			for c := range h.clients {
+				msg := f.msg
+				if c.processes && f.withProcesses != nil {
+					msg = f.withProcesses
+				}
 				select {
 				case c.send <- msg:
 				default:
@@ -57,9 +61,39 @@ func (h *Hub) Run(ctx context.Context) {
 	}
 }
 
+// frame is one broadcast; clients that asked for processes get
+// withProcesses when it is set.
+type frame struct {
+	msg           []byte
+	withProcesses []byte
+}
+
 func (h *Hub) Broadcast(msg []byte) {
+	h.send(frame{msg: msg})
+}
+
+// BroadcastMetrics sends withProcesses to the clients that asked for the
+// process list and msg to the rest, so the list is not pushed to every
+// dashboard on every tick.
+func (h *Hub) BroadcastMetrics(msg, withProcesses []byte) {
+	h.send(frame{msg: msg, withProcesses: withProcesses})
+}
+
+// WantsProcesses reports whether any connected client asked for processes.
+func (h *Hub) WantsProcesses() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for c := range h.clients {
+		if c.processes {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *Hub) send(f frame) {
 	select {
-	case h.broadcast <- msg:
+	case h.broadcast <- f:
 	default:
 	}
 }
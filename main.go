@@ -16,9 +16,14 @@ import (
 
 	"quickvps/internal/alerts"
 	"quickvps/internal/auth"
+	"quickvps/internal/checks"
+	"quickvps/internal/firewall"
+	"quickvps/internal/history"
 	"quickvps/internal/metrics"
 	"quickvps/internal/ncdu"
+	"quickvps/internal/packages"
 	"quickvps/internal/server"
+	"quickvps/internal/watch"
 	"quickvps/internal/ws"
 )
 
@@ -32,6 +37,9 @@ func main() {
 	password := flag.String("password", "", "Initial admin password when auth is enabled")
 	dbPath := flag.String("db", "quickvps.db", "SQLite database path")
 	interval := flag.Duration("interval", 2*time.Second, "Metrics push interval")
+	topProcesses := flag.Int("top-processes", 10, "Number of top processes sampled per interval (0 disables)")
+	sessionIdle := flag.Duration("session-idle", 24*time.Hour, "Sign out sessions unused for this long")
+	sessionMaxAge := flag.Duration("session-max-age", 7*24*time.Hour, "Sign out sessions this long after login, however active")
 	flag.Parse()
 
 	if v := strings.TrimSpace(os.Getenv("QUICKVPS_AUTH")); v != "" {
@@ -60,6 +68,7 @@ func main() {
 	defer stop()
 
 	collector := metrics.NewCollector(*interval)
+	collector.EnableProcesses(*topProcesses)
 	hub := ws.NewHub()
 	runner := ncdu.NewRunner()
 
@@ -77,11 +86,55 @@ func main() {
 	alertStore = as
 	defer alertStore.Close() //nolint:errcheck
 
-	alertService, err = alerts.NewService(alertStore, alerts.NewNotifier(), strings.TrimSpace(os.Getenv("QUICKVPS_ALERTS_KEY")))
+	// QUICKVPS_ALERTS_PREVIOUS_KEYS keeps retired keys around for decryption
+	// until POST /api/alerts/keys/rotate has moved every secret to the new one.
+	alertService, err = alerts.NewService(
+		alertStore,
+		alerts.NewNotifier(),
+		strings.TrimSpace(os.Getenv("QUICKVPS_ALERTS_KEY")),
+		strings.Split(os.Getenv("QUICKVPS_ALERTS_PREVIOUS_KEYS"), ",")...,
+	)
 	if err != nil {
 		log.Fatalf("failed to initialize alert service: %v", err)
 	}
 
+	historyRetention, err := history.ParseRetention(os.Getenv("QUICKVPS_HISTORY_RETENTION"))
+	if err != nil {
+		log.Fatalf("invalid QUICKVPS_HISTORY_RETENTION: %v", err)
+	}
+	historyStore, err := history.NewStore(*dbPath)
+	if err != nil {
+		log.Fatalf("failed to initialize metrics history store: %v", err)
+	}
+	defer historyStore.Close() //nolint:errcheck
+	historyRecorder := history.NewRecorder(historyStore, historyRetention)
+	seedDiskForecast(collector, historyRecorder)
+	alertService.SetDigestSources(alerts.DigestSources{
+		History:   historyRecorder.Query,
+		Updates:   packages.Updates,
+		Exposures: firewall.ListExposures,
+	})
+
+	checksStore, err := checks.NewStore(*dbPath)
+	if err != nil {
+		log.Fatalf("failed to initialize checks store: %v", err)
+	}
+	defer checksStore.Close() //nolint:errcheck
+	checksService, err := checks.NewService(checksStore, alertService)
+	if err != nil {
+		log.Fatalf("failed to initialize checks service: %v", err)
+	}
+
+	watchStore, err := watch.NewStore(*dbPath)
+	if err != nil {
+		log.Fatalf("failed to initialize watch store: %v", err)
+	}
+	defer watchStore.Close() //nolint:errcheck
+	watchService, err := watch.NewService(watchStore, alertService)
+	if err != nil {
+		log.Fatalf("failed to initialize watch service: %v", err)
+	}
+
 	if *authEnabled {
 		if bootstrapPassword == "" {
 			bootstrapPassword = "admin123"
@@ -93,7 +146,8 @@ func main() {
 			log.Fatalf("failed to initialize auth store: %v", err)
 		}
 		authStore = store
-		sessionStore = auth.NewSessionManager(24*time.Hour, authStore)
+		sessionStore = auth.NewSessionManager(*sessionIdle, authStore)
+		sessionStore.SetMaxLifetime(*sessionMaxAge)
 
 		if err := authStore.SeedAdmin(*user, bootstrapPassword); err != nil {
 			log.Fatalf("failed to seed admin user: %v", err)
@@ -107,6 +161,9 @@ func main() {
 	go collector.Run(ctx)
 	go hub.Run(ctx)
 	go alertService.Run(ctx, collector.Subscribe())
+	go historyRecorder.Run(ctx, collector.Subscribe())
+	go checksService.Run(ctx)
+	go watchService.Run(ctx, collector.Subscribe())
 
 	// Bridge: collector → hub (broadcast Snapshot as JSON)
 	go func() {
@@ -119,13 +176,20 @@ func main() {
 				if !ok {
 					return
 				}
-				msg := buildWSMessage(snap, runner.IsReady())
-				hub.Broadcast(msg)
+				ready := runner.IsReady()
+				// The process list goes only to clients that asked for it.
+				lite := *snap
+				lite.Processes = nil
+				var withProcesses []byte
+				if snap.Processes != nil && hub.WantsProcesses() {
+					withProcesses = buildWSMessage(snap, ready)
+				}
+				hub.BroadcastMetrics(buildWSMessage(&lite, ready), withProcesses)
 			}
 		}
 	}()
 
-	srv := server.New(collector, hub, runner, alertService, !*authEnabled, authStore, sessionStore, webFS)
+	srv := server.New(collector, hub, runner, alertService, historyRecorder, checksService, watchService, !*authEnabled, authStore, sessionStore, strings.TrimSpace(os.Getenv("QUICKVPS_METRICS_TOKEN")), webFS)
 
 	httpServer := &http.Server{
 		Addr:         *addr,
@@ -163,3 +227,27 @@ func buildWSMessage(snap interface{}, ncduReady bool) []byte {
 	})
 	return b
 }
+
+// seedDiskForecast loads recent disk usage from the metrics history so disk
+// forecasts are available right after a restart.
+func seedDiskForecast(collector *metrics.Collector, recorder *history.Recorder) {
+	now := time.Now()
+	res, err := recorder.Query(history.Query{
+		From:    now.Add(-metrics.DiskTrendWindow),
+		To:      now,
+		StepSec: 60,
+		Series:  []string{metrics.SeriesDiskUsedBytes},
+	})
+	if err != nil {
+		log.Printf("disk forecast: failed to load usage history: %v", err)
+		return
+	}
+	for _, series := range res.Series {
+		_, mountpoint := metrics.SplitSeriesName(series.Name)
+		samples := make([]metrics.UsageSample, 0, len(series.Points))
+		for _, p := range series.Points {
+			samples = append(samples, metrics.UsageSample{Time: p.Timestamp, UsedBytes: p.Avg})
+		}
+		collector.SeedDiskUsage(mountpoint, samples)
+	}
+}